@@ -0,0 +1,210 @@
+package client
+
+import (
+	"net"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+const (
+	// DefaultRetryQuotaCapacity is the default capacity of the retry quota
+	// shared by requests made with an AdaptiveRetryer.
+	DefaultRetryQuotaCapacity = 500
+
+	// retryCost is the quota cost of retrying a request.
+	retryCost = 5
+
+	// retryTimeoutCost is the quota cost of retrying a request which failed
+	// due to a timeout.
+	retryTimeoutCost = 10
+
+	// noRetryIncrement is the quota refunded when a request succeeds without
+	// being retried.
+	noRetryIncrement = 1
+)
+
+// AdaptiveRetryer extends the DefaultRetryer with a retry quota and a client
+// side rate limiter shared across all requests made with the retryer.
+//
+// Each retry attempt costs tokens from the retry quota, and successful
+// requests refund them. When the quota is exhausted failed requests will not
+// be retried until requests start succeeding again. This prevents a service
+// outage from being amplified by every request retrying independently.
+//
+// Once the service throttles a request the retryer also limits the rate
+// requests are sent at. The send rate is backed off when requests are
+// throttled, and increased as requests succeed, using the CUBIC congestion
+// control algorithm.
+//
+// The AdaptiveRetryer is used by service clients when the aws.Config's
+// RetryMode is set to aws.RetryModeAdaptive, and Retryer is not set. To
+// share the retry quota and rate limiter across multiple service clients
+// create an AdaptiveRetryer and set it as the aws.Config's Retryer.
+//
+//     retryer := client.NewAdaptiveRetryer(3)
+//     cfg := request.WithRetryer(aws.NewConfig(), retryer)
+//
+//     svcA := dynamodb.New(sess, cfg)
+//     svcB := dynamodb.New(sess, cfg)
+//
+// The AdaptiveRetryer is safe to use across multiple goroutines.
+type AdaptiveRetryer struct {
+	DefaultRetryer
+
+	quota   *retryQuota
+	limiter *rateLimiter
+}
+
+// NewAdaptiveRetryer returns an initialized AdaptiveRetryer that will retry
+// requests at most maxRetries times, with a retry quota capacity of
+// DefaultRetryQuotaCapacity.
+func NewAdaptiveRetryer(maxRetries int) *AdaptiveRetryer {
+	return &AdaptiveRetryer{
+		DefaultRetryer: DefaultRetryer{NumMaxRetries: maxRetries},
+		quota:          newRetryQuota(DefaultRetryQuotaCapacity),
+		limiter:        newRateLimiter(),
+	}
+}
+
+// adaptiveHandlerAdder is implemented by retryers which need handlers added
+// to the service client in order to track the requests made.
+type adaptiveHandlerAdder interface {
+	AddHandlers(*request.Handlers)
+}
+
+// AddHandlers adds the request handlers used by the retryer to track
+// requests to the handlers provided, replacing the handlers of any other
+// AdaptiveRetryer. Service clients add the handlers of their retryer when
+// created, and a service customization replacing the client's retryer with
+// an AdaptiveRetryer must add its handlers.
+func (d *AdaptiveRetryer) AddHandlers(handlers *request.Handlers) {
+	handlers.Sign.SetFrontNamed(request.NamedHandler{
+		Name: "awssdk.client.AdaptiveRetryer.AcquireSendToken",
+		Fn:   d.acquireSendToken,
+	})
+	handlers.CompleteAttempt.SetBackNamed(request.NamedHandler{
+		Name: "awssdk.client.AdaptiveRetryer.UpdateSendRate",
+		Fn:   d.updateSendRate,
+	})
+	handlers.AfterRetry.SetFrontNamed(request.NamedHandler{
+		Name: "awssdk.client.AdaptiveRetryer.AcquireRetryQuota",
+		Fn:   d.acquireRetryQuota,
+	})
+	handlers.Complete.SetBackNamed(request.NamedHandler{
+		Name: "awssdk.client.AdaptiveRetryer.ReleaseRetryQuota",
+		Fn:   d.releaseRetryQuota,
+	})
+}
+
+// acquireSendToken blocks the request attempt until the rate limiter allows
+// the request to be sent.
+func (d *AdaptiveRetryer) acquireSendToken(r *request.Request) {
+	if err := d.limiter.acquire(r.Context()); err != nil {
+		r.Error = awserr.New(request.CanceledErrorCode,
+			"request context canceled", err)
+	}
+}
+
+// updateSendRate updates the rate limiter's send rate with the result of
+// the request attempt.
+func (d *AdaptiveRetryer) updateSendRate(r *request.Request) {
+	d.limiter.update(r.HTTPResponse != nil && r.Error != nil && d.shouldThrottle(r))
+}
+
+// acquireRetryQuota determines if the failed request attempt should be
+// retried, and prevents the retry if the retry quota is exhausted.
+func (d *AdaptiveRetryer) acquireRetryQuota(r *request.Request) {
+	if r.HTTPResponse == nil {
+		return
+	}
+
+	retryable := d.ShouldRetry(r)
+	if retryable && r.RetryCount < r.MaxRetries() {
+		cost := retryCost
+		if isErrorTimeout(r.Error) {
+			cost = retryTimeoutCost
+		}
+		retryable = d.quota.acquire(r, cost)
+	}
+
+	r.Retryable = aws.Bool(retryable)
+}
+
+// releaseRetryQuota refunds the retry quota when the request completes
+// successfully.
+func (d *AdaptiveRetryer) releaseRetryQuota(r *request.Request) {
+	d.quota.release(r, r.Error == nil)
+}
+
+// isErrorTimeout returns if the error was caused by the request timing out.
+func isErrorTimeout(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	if aerr.Code() == request.ErrCodeResponseTimeout {
+		return true
+	}
+
+	netErr, ok := aerr.OrigErr().(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// A retryQuota is a token bucket of retry attempts shared across requests.
+// The quota keeps track of the cost of each request's most recent retry so
+// that it can be refunded when the request succeeds.
+type retryQuota struct {
+	m         sync.Mutex
+	capacity  int
+	available int
+	costs     map[*request.Request]int
+}
+
+func newRetryQuota(capacity int) *retryQuota {
+	return &retryQuota{
+		capacity:  capacity,
+		available: capacity,
+		costs:     map[*request.Request]int{},
+	}
+}
+
+// acquire removes the cost from the quota if available, returning false if
+// the quota does not have enough capacity for the retry.
+func (q *retryQuota) acquire(r *request.Request, cost int) bool {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	if cost > q.available {
+		return false
+	}
+
+	q.available -= cost
+	q.costs[r] = cost
+	return true
+}
+
+// release refunds the quota if the request succeeded. A request which was
+// retried refunds the cost of its last retry, otherwise the quota is
+// incremented by noRetryIncrement.
+func (q *retryQuota) release(r *request.Request, success bool) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	cost, retried := q.costs[r]
+	delete(q.costs, r)
+
+	if !success {
+		return
+	}
+	if !retried {
+		cost = noRetryIncrement
+	}
+
+	q.available += cost
+	if q.available > q.capacity {
+		q.available = q.capacity
+	}
+}
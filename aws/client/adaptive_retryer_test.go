@@ -0,0 +1,177 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/corehandlers"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+func TestRetryQuota(t *testing.T) {
+	q := newRetryQuota(12)
+	r1, r2 := &request.Request{}, &request.Request{}
+
+	if !q.acquire(r1, retryCost) {
+		t.Fatalf("expect first retry to acquire quota")
+	}
+	if !q.acquire(r2, retryTimeoutCost-retryCost) {
+		t.Fatalf("expect second retry to acquire quota")
+	}
+	if q.acquire(r1, retryCost) {
+		t.Fatalf("expect retry to be denied when quota is exhausted")
+	}
+	if e, a := 2, q.available; e != a {
+		t.Errorf("expect %v available, got %v", e, a)
+	}
+
+	// Failed requests do not refund their cost.
+	q.release(r2, false)
+	if e, a := 2, q.available; e != a {
+		t.Errorf("expect %v available, got %v", e, a)
+	}
+
+	// Successful retried requests refund the cost of their last retry.
+	q.release(r1, true)
+	if e, a := 2+retryCost, q.available; e != a {
+		t.Errorf("expect %v available, got %v", e, a)
+	}
+
+	// Successful requests which were not retried increment the quota, but
+	// not beyond its capacity.
+	for i := 0; i < 10; i++ {
+		q.release(&request.Request{}, true)
+	}
+	if e, a := 12, q.available; e != a {
+		t.Errorf("expect %v available, got %v", e, a)
+	}
+	if e, a := 0, len(q.costs); e != a {
+		t.Errorf("expect %v tracked costs, got %v", e, a)
+	}
+}
+
+func TestIsErrorTimeout(t *testing.T) {
+	cases := []struct {
+		err    error
+		expect bool
+	}{
+		{nil, false},
+		{awserr.New("ThrottlingException", "throttled", nil), false},
+		{awserr.New(request.ErrCodeResponseTimeout, "timeout", nil), true},
+		{awserr.New("RequestError", "send request failed", timeoutError{}), true},
+	}
+
+	for i, c := range cases {
+		if e, a := c.expect, isErrorTimeout(c.err); e != a {
+			t.Errorf("%d, expect %v timeout, got %v", i, e, a)
+		}
+	}
+}
+
+func TestNewClient_AdaptiveRetryMode(t *testing.T) {
+	c := New(aws.Config{RetryMode: aws.RetryModeAdaptive}, metadata.ClientInfo{}, request.Handlers{})
+
+	if _, ok := c.Retryer.(*AdaptiveRetryer); !ok {
+		t.Fatalf("expect %T retryer, got %T", &AdaptiveRetryer{}, c.Retryer)
+	}
+	if e, a := 3, c.MaxRetries(); e != a {
+		t.Errorf("expect %v max retries, got %v", e, a)
+	}
+
+	lists := map[string]request.HandlerList{
+		"Sign":            c.Handlers.Sign,
+		"CompleteAttempt": c.Handlers.CompleteAttempt,
+		"AfterRetry":      c.Handlers.AfterRetry,
+		"Complete":        c.Handlers.Complete,
+	}
+	for name, list := range lists {
+		if e, a := 1, list.Len(); e != a {
+			t.Errorf("expect %v %s handlers, got %v", e, name, a)
+		}
+	}
+}
+
+func TestAdaptiveRetryer_RetryQuotaExhausted(t *testing.T) {
+	clock := newMockClock()
+	retryer := NewAdaptiveRetryer(5)
+	retryer.quota = newRetryQuota(2 * retryCost)
+	retryer.limiter = newTestRateLimiter(clock)
+
+	handlers := request.Handlers{}
+	handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{StatusCode: http.StatusServiceUnavailable}
+		r.Error = awserr.New("ServiceUnavailable", "service unavailable", nil)
+	})
+	handlers.AfterRetry.PushBackNamed(corehandlers.AfterRetryHandler)
+
+	c := New(aws.Config{
+		Retryer:    retryer,
+		SleepDelay: func(time.Duration) {},
+	}, metadata.ClientInfo{}, handlers)
+
+	req := c.NewRequest(&request.Operation{Name: "Operation"}, nil, nil)
+	if err := req.Send(); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	// Only two retries fit within the retry quota.
+	if e, a := 2, req.RetryCount; e != a {
+		t.Errorf("expect %v retries, got %v", e, a)
+	}
+	if e, a := 0, retryer.quota.available; e != a {
+		t.Errorf("expect %v quota available, got %v", e, a)
+	}
+	if !retryer.limiter.enabled {
+		t.Errorf("expect rate limiter to be enabled by throttled responses")
+	}
+}
+
+func TestAdaptiveRetryer_RefundOnSuccess(t *testing.T) {
+	clock := newMockClock()
+	retryer := NewAdaptiveRetryer(3)
+	retryer.quota = newRetryQuota(2 * retryCost)
+	retryer.limiter = newTestRateLimiter(clock)
+
+	var attempts int
+	handlers := request.Handlers{}
+	handlers.Send.PushBack(func(r *request.Request) {
+		attempts++
+		if attempts == 1 {
+			r.HTTPResponse = &http.Response{StatusCode: http.StatusInternalServerError}
+			r.Error = awserr.New("InternalFailure", "internal failure", nil)
+			return
+		}
+		r.HTTPResponse = &http.Response{StatusCode: http.StatusOK}
+	})
+	handlers.AfterRetry.PushBackNamed(corehandlers.AfterRetryHandler)
+
+	c := New(aws.Config{
+		Retryer:    retryer,
+		SleepDelay: func(time.Duration) {},
+	}, metadata.ClientInfo{}, handlers)
+
+	req := c.NewRequest(&request.Operation{Name: "Operation"}, nil, nil)
+	if err := req.Send(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := 1, req.RetryCount; e != a {
+		t.Errorf("expect %v retries, got %v", e, a)
+	}
+	if e, a := 2*retryCost, retryer.quota.available; e != a {
+		t.Errorf("expect %v quota available, got %v", e, a)
+	}
+	if retryer.limiter.enabled {
+		t.Errorf("expect rate limiter not to be enabled")
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -66,7 +66,15 @@ func New(cfg aws.Config, info metadata.ClientInfo, handlers request.Handlers, op
 		if cfg.MaxRetries == nil || maxRetries == aws.UseServiceDefaultRetries {
 			maxRetries = 3
 		}
-		svc.Retryer = DefaultRetryer{NumMaxRetries: maxRetries}
+		if cfg.RetryMode == aws.RetryModeAdaptive {
+			svc.Retryer = NewAdaptiveRetryer(maxRetries)
+		} else {
+			svc.Retryer = DefaultRetryer{NumMaxRetries: maxRetries}
+		}
+	}
+
+	if retryer, ok := svc.Retryer.(adaptiveHandlerAdder); ok {
+		retryer.AddHandlers(&svc.Handlers)
 	}
 
 	svc.AddDebugHandlers()
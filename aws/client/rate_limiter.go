@@ -0,0 +1,184 @@
+package client
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const (
+	// minFillRate is the minimum rate, in requests per second, the send
+	// token bucket will be refilled at.
+	minFillRate = 0.5
+
+	// cubicScaleConstant and cubicBeta are the CUBIC congestion control
+	// parameters used to scale the send rate after a throttled response.
+	cubicScaleConstant = 0.4
+	cubicBeta          = 0.7
+
+	// rateSmoothing is the weight given to the most recent send rate
+	// measurement compared to the previously measured rate.
+	rateSmoothing = 0.8
+
+	// rateBucketScale is the number of send rate measurements taken per
+	// second.
+	rateBucketScale = 2
+)
+
+// A rateLimiter limits the rate requests are sent at once a request has
+// been throttled. The maximum send rate is adjusted using the CUBIC
+// congestion control algorithm, backing off when requests are throttled,
+// and increasing when requests succeed.
+//
+// The rateLimiter is safe to use across multiple goroutines.
+type rateLimiter struct {
+	m sync.Mutex
+
+	// now and sleep are used to allow the clock to be replaced in tests.
+	now   func() time.Time
+	sleep func(aws.Context, time.Duration) error
+
+	enabled bool
+
+	// Send token bucket
+	fillRate        float64
+	maxCapacity     float64
+	currentCapacity float64
+	lastRefill      time.Time
+
+	// CUBIC rate calculation
+	lastMaxRate      float64
+	lastThrottleTime time.Time
+	timeWindow       float64
+
+	// Measured send rate
+	measuredTxRate float64
+	lastTxBucket   float64
+	requestCount   int
+}
+
+func newRateLimiter() *rateLimiter {
+	l := &rateLimiter{
+		now:   time.Now,
+		sleep: aws.SleepWithContext,
+	}
+	l.init()
+	return l
+}
+
+// init initializes the limiter's state relative to the limiter's clock.
+func (l *rateLimiter) init() {
+	now := l.now()
+	l.lastMaxRate = minFillRate
+	l.lastThrottleTime = now
+	l.lastTxBucket = math.Floor(unixSeconds(now))
+	l.timeWindow = l.calculateTimeWindow()
+}
+
+// acquire blocks until a send token is available, or the context is
+// canceled. If the limiter has not been enabled by a throttled response
+// acquire returns immediately.
+func (l *rateLimiter) acquire(ctx aws.Context) error {
+	for {
+		l.m.Lock()
+		if !l.enabled {
+			l.m.Unlock()
+			return nil
+		}
+
+		l.refill()
+		if l.currentCapacity >= 1 {
+			l.currentCapacity--
+			l.m.Unlock()
+			return nil
+		}
+
+		wait := time.Duration((1 - l.currentCapacity) / l.fillRate * float64(time.Second))
+		l.m.Unlock()
+
+		if err := l.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// update adjusts the limiter's send rate based on the response of a request
+// attempt. The limiter will be enabled if the response was throttled.
+func (l *rateLimiter) update(throttled bool) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	now := l.now()
+	l.updateMeasuredRate(now)
+
+	var rate float64
+	if throttled {
+		rateToUse := l.measuredTxRate
+		if l.enabled {
+			rateToUse = math.Min(rateToUse, l.fillRate)
+		}
+
+		l.lastMaxRate = rateToUse
+		l.timeWindow = l.calculateTimeWindow()
+		l.lastThrottleTime = now
+		rate = rateToUse * cubicBeta
+		l.enabled = true
+	} else {
+		rate = l.cubicSuccess(now)
+	}
+
+	l.updateBucketRate(math.Min(rate, 2*l.measuredTxRate))
+}
+
+// calculateTimeWindow returns the time, in seconds, for the CUBIC function
+// to return to the last maximum rate since it was throttled.
+func (l *rateLimiter) calculateTimeWindow() float64 {
+	return math.Cbrt(l.lastMaxRate * (1 - cubicBeta) / cubicScaleConstant)
+}
+
+// cubicSuccess returns the send rate after a successful response.
+func (l *rateLimiter) cubicSuccess(now time.Time) float64 {
+	dt := now.Sub(l.lastThrottleTime).Seconds()
+	return cubicScaleConstant*math.Pow(dt-l.timeWindow, 3) + l.lastMaxRate
+}
+
+// refill adds the tokens accrued since the last refill to the bucket.
+func (l *rateLimiter) refill() {
+	now := l.now()
+	if l.lastRefill.IsZero() {
+		l.lastRefill = now
+		return
+	}
+
+	fill := now.Sub(l.lastRefill).Seconds() * l.fillRate
+	l.currentCapacity = math.Min(l.maxCapacity, l.currentCapacity+fill)
+	l.lastRefill = now
+}
+
+// updateBucketRate sets the send token bucket's fill rate.
+func (l *rateLimiter) updateBucketRate(rate float64) {
+	l.refill()
+	l.fillRate = math.Max(rate, minFillRate)
+	l.maxCapacity = math.Max(rate, 1)
+	l.currentCapacity = math.Min(l.currentCapacity, l.maxCapacity)
+}
+
+// updateMeasuredRate records a request attempt, and updates the smoothed
+// measurement of the rate requests are sent at.
+func (l *rateLimiter) updateMeasuredRate(now time.Time) {
+	l.requestCount++
+
+	bucket := math.Floor(unixSeconds(now)*rateBucketScale) / rateBucketScale
+	if bucket > l.lastTxBucket {
+		currentRate := float64(l.requestCount) / (bucket - l.lastTxBucket)
+		l.measuredTxRate = currentRate*rateSmoothing + l.measuredTxRate*(1-rateSmoothing)
+		l.requestCount = 0
+		l.lastTxBucket = bucket
+	}
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+type mockClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func newMockClock() *mockClock {
+	return &mockClock{now: time.Unix(1000, 0)}
+}
+
+func (c *mockClock) Now() time.Time {
+	return c.now
+}
+
+func (c *mockClock) Sleep(ctx aws.Context, d time.Duration) error {
+	c.slept = append(c.slept, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func (c *mockClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestRateLimiter(clock *mockClock) *rateLimiter {
+	l := &rateLimiter{
+		now:   clock.Now,
+		sleep: clock.Sleep,
+	}
+	l.init()
+	return l
+}
+
+func floatEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestRateLimiter_DisabledUntilThrottled(t *testing.T) {
+	clock := newMockClock()
+	l := newTestRateLimiter(clock)
+
+	for i := 0; i < 10; i++ {
+		if err := l.acquire(aws.BackgroundContext()); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		l.update(false)
+	}
+	if e, a := 0, len(clock.slept); e != a {
+		t.Errorf("expect %v sleeps, got %v", e, a)
+	}
+	if l.enabled {
+		t.Errorf("expect limiter not to be enabled")
+	}
+
+	l.update(true)
+	if !l.enabled {
+		t.Errorf("expect limiter to be enabled")
+	}
+}
+
+func TestRateLimiter_MeasuredRate(t *testing.T) {
+	clock := newMockClock()
+	l := newTestRateLimiter(clock)
+
+	// 10 requests sent within the first half second bucket.
+	for i := 0; i < 10; i++ {
+		clock.Advance(40 * time.Millisecond)
+		l.update(false)
+	}
+
+	// First request of the next bucket closes the measurement.
+	clock.Advance(100 * time.Millisecond)
+	l.update(false)
+
+	// 10 requests, plus the current, in 0.5 seconds, smoothed with the
+	// initial measurement of zero.
+	if e, a := 22*rateSmoothing, l.measuredTxRate; !floatEqual(e, a) {
+		t.Errorf("expect %v measured rate, got %v", e, a)
+	}
+}
+
+func TestRateLimiter_CubicRates(t *testing.T) {
+	clock := newMockClock()
+	l := newTestRateLimiter(clock)
+	l.measuredTxRate = 10
+	l.lastTxBucket = math.Inf(1) // freeze the measured rate
+
+	// Throttle sets the max rate to the measured rate, and backs off.
+	l.update(true)
+	if e, a := 10.0, l.lastMaxRate; !floatEqual(e, a) {
+		t.Errorf("expect %v last max rate, got %v", e, a)
+	}
+	if e, a := 10*cubicBeta, l.fillRate; !floatEqual(e, a) {
+		t.Errorf("expect %v fill rate, got %v", e, a)
+	}
+
+	timeWindow := math.Cbrt(10 * (1 - cubicBeta) / cubicScaleConstant)
+	if e, a := timeWindow, l.timeWindow; !floatEqual(e, a) {
+		t.Errorf("expect %v time window, got %v", e, a)
+	}
+
+	// The rate recovers to the max rate after the time window.
+	clock.Advance(time.Duration(timeWindow * float64(time.Second)))
+	l.update(false)
+	if e, a := 10.0, l.fillRate; !floatEqual(e, a) {
+		t.Errorf("expect %v fill rate, got %v", e, a)
+	}
+
+	// And increases beyond the max rate, limited by twice the measured rate.
+	clock.Advance(10 * time.Second)
+	l.update(false)
+	if e, a := 20.0, l.fillRate; !floatEqual(e, a) {
+		t.Errorf("expect %v fill rate, got %v", e, a)
+	}
+
+	// A second throttle uses the lower of the measured and current rate.
+	l.update(true)
+	if e, a := 10.0, l.lastMaxRate; !floatEqual(e, a) {
+		t.Errorf("expect %v last max rate, got %v", e, a)
+	}
+}
+
+func TestRateLimiter_AcquireWaitsForTokens(t *testing.T) {
+	clock := newMockClock()
+	l := newTestRateLimiter(clock)
+	l.update(true)
+
+	// Throttled with no measured rate uses the minimum fill rate.
+	if e, a := minFillRate, l.fillRate; !floatEqual(e, a) {
+		t.Errorf("expect %v fill rate, got %v", e, a)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := l.acquire(aws.BackgroundContext()); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+	}
+
+	var total time.Duration
+	for _, d := range clock.slept {
+		total += d
+	}
+
+	// Three tokens at half a token per second.
+	if e, a := 6*time.Second, total; e != a {
+		t.Errorf("expect %v total wait, got %v", e, a)
+	}
+}
+
+func TestRateLimiter_AcquireCanceled(t *testing.T) {
+	clock := newMockClock()
+	l := newTestRateLimiter(clock)
+	l.sleep = aws.SleepWithContext
+	l.update(true)
+
+	ctx := &canceledContext{done: make(chan struct{})}
+	close(ctx.done)
+	if err := l.acquire(ctx); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+}
+
+type canceledContext struct {
+	done chan struct{}
+}
+
+func (c *canceledContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c *canceledContext) Done() <-chan struct{}             { return c.done }
+func (c *canceledContext) Err() error                        { return fmt.Errorf("context canceled") }
+func (c *canceledContext) Value(key interface{}) interface{} { return nil }
@@ -1,7 +1,9 @@
 package aws
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -17,6 +19,33 @@ const UseServiceDefaultRetries = -1
 // interface.
 type RequestRetryer interface{}
 
+// RetryMode is the mode the SDK's retryer will use when deciding if, and
+// when, a failed request should be retried.
+type RetryMode string
+
+const (
+	// RetryModeLegacy retries requests using exponential backoff with
+	// jitter. Each request is retried independently of other requests.
+	RetryModeLegacy RetryMode = "legacy"
+
+	// RetryModeAdaptive retries requests using exponential backoff with
+	// jitter, limited by a retry quota shared across requests. Requests are
+	// also rate limited client side when the service throttles requests.
+	RetryModeAdaptive RetryMode = "adaptive"
+)
+
+// ParseRetryMode returns the RetryMode for the string provided, (case
+// insensitive). An error is returned if the string is not a known
+// RetryMode.
+func ParseRetryMode(v string) (RetryMode, error) {
+	switch mode := RetryMode(strings.ToLower(v)); mode {
+	case RetryModeLegacy, RetryModeAdaptive:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown retry mode, %v", v)
+	}
+}
+
 // A Config provides service configuration for service clients. By default,
 // all clients will use the defaults.DefaultConfig structure.
 //
@@ -107,6 +136,15 @@ type Config struct {
 	//
 	Retryer RequestRetryer
 
+	// RetryMode selects the retry behavior of the client.DefaultRetryer used
+	// when Retryer is not set. Defaults to RetryModeLegacy.
+	//
+	// With RetryModeAdaptive the client will use a client.AdaptiveRetryer
+	// which shares a retry quota across all requests made by the service
+	// client, and limits the rate requests are sent at once the service
+	// starts throttling requests.
+	RetryMode RetryMode
+
 	// Disables semantic parameter validation, which validates input for
 	// missing required fields and/or other semantic request input errors.
 	DisableParamValidation *bool
@@ -178,6 +216,18 @@ type Config struct {
 	//
 	EC2MetadataDisableTimeoutOverride *bool
 
+	// Set this to `true` to allow the EC2Metadata client to fall back to
+	// unauthenticated requests when a session token cannot be retrieved from
+	// the EC2 Metadata service. By default the client requires a session
+	// token for every request, and will fail requests if a token cannot be
+	// retrieved.
+	//
+	// The client only falls back if the token request is rejected as not
+	// supported (403, 404, or 405 status codes), or if the token request
+	// fails to reach the service, e.g. when the PUT response is dropped due
+	// to the instance's hop limit.
+	EC2MetadataEnableFallback *bool
+
 	// Instructs the endpoint to be generated for a service client to
 	// be the dual stack endpoint. The dual stack endpoint will support
 	// both IPv4 and IPv6 addressing.
@@ -321,6 +371,13 @@ func (c *Config) WithMaxRetries(max int) *Config {
 	return c
 }
 
+// WithRetryMode sets a config RetryMode value returning a Config pointer
+// for chaining.
+func (c *Config) WithRetryMode(mode RetryMode) *Config {
+	c.RetryMode = mode
+	return c
+}
+
 // WithDisableParamValidation sets a config DisableParamValidation value
 // returning a Config pointer for chaining.
 func (c *Config) WithDisableParamValidation(disable bool) *Config {
@@ -393,6 +450,13 @@ func (c *Config) WithEC2MetadataDisableTimeoutOverride(enable bool) *Config {
 	return c
 }
 
+// WithEC2MetadataEnableFallback sets a config EC2MetadataEnableFallback value
+// returning a Config pointer for chaining.
+func (c *Config) WithEC2MetadataEnableFallback(enable bool) *Config {
+	c.EC2MetadataEnableFallback = &enable
+	return c
+}
+
 // WithSleepDelay overrides the function used to sleep while waiting for the
 // next retry. Defaults to time.Sleep.
 func (c *Config) WithSleepDelay(fn func(time.Duration)) *Config {
@@ -469,6 +533,10 @@ func mergeInConfig(dst *Config, other *Config) {
 		dst.Retryer = other.Retryer
 	}
 
+	if len(other.RetryMode) != 0 {
+		dst.RetryMode = other.RetryMode
+	}
+
 	if other.DisableParamValidation != nil {
 		dst.DisableParamValidation = other.DisableParamValidation
 	}
@@ -501,6 +569,10 @@ func mergeInConfig(dst *Config, other *Config) {
 		dst.EC2MetadataDisableTimeoutOverride = other.EC2MetadataDisableTimeoutOverride
 	}
 
+	if other.EC2MetadataEnableFallback != nil {
+		dst.EC2MetadataEnableFallback = other.EC2MetadataEnableFallback
+	}
+
 	if other.SleepDelay != nil {
 		dst.SleepDelay = other.SleepDelay
 	}
@@ -0,0 +1,162 @@
+package stscreds
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	// ErrCodeWebIdentity will be used as an error code when constructing
+	// a new error to be returned during session creation or retrieval.
+	ErrCodeWebIdentity = "WebIdentityErr"
+
+	// WebIdentityProviderName is the web identity provider name
+	WebIdentityProviderName = "WebIdentityCredentials"
+)
+
+// now is used to return a time.Time object representing
+// the current time. This can be used to easily test and
+// compare test values.
+var now = time.Now
+
+// WebIdentityRoleAssumer represents the minimal subset of the STS client API
+// used by the WebIdentityRoleProvider.
+type WebIdentityRoleAssumer interface {
+	AssumeRoleWithWebIdentity(input *sts.AssumeRoleWithWebIdentityInput) (*sts.AssumeRoleWithWebIdentityOutput, error)
+}
+
+// WebIdentityRoleProvider retrieves temporary credentials from the STS
+// service using an OpenID Connect (OIDC) token read from a file, and keeps
+// track of their expiration time. The token file is read each time the
+// credentials are retrieved, so that a token rotated on disk, such as a
+// Kubernetes projected service account token, will be used when the
+// credentials are refreshed.
+//
+// This credential provider will be used by the SDK's default credential chain
+// when the AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN environment variables
+// are set, or when shared configuration is enabled and the profile sets
+// web_identity_token_file and role_arn. See Session docs for how to do this.
+//
+// WebIdentityRoleProvider does not provide any synchronization and it is not
+// safe to share this value across multiple Credentials, Sessions, or service
+// clients without also sharing the same Credentials instance.
+type WebIdentityRoleProvider struct {
+	credentials.Expiry
+
+	// STS client to make assume role with web identity request with.
+	Client WebIdentityRoleAssumer
+
+	// Role to be assumed.
+	RoleARN string
+
+	// Session name, if you wish to uniquely identify this session. If not
+	// set a session name based on the current time will be used.
+	RoleSessionName string
+
+	// Path to the file containing the OIDC token.
+	TokenFilePath string
+
+	// Expiry duration of the STS credentials. If not set the duration
+	// configured for the role will be used.
+	Duration time.Duration
+
+	// Optional IAM policy in JSON format to further restrict the permissions
+	// of the assumed role's credentials.
+	Policy *string
+
+	// ExpiryWindow will allow the credentials to trigger refreshing prior to
+	// the credentials actually expiring. This is beneficial so race conditions
+	// with expiring credentials do not cause request to fail unexpectedly
+	// due to ExpiredTokenException exceptions.
+	//
+	// So a ExpiryWindow of 10s would cause calls to IsExpired() to return true
+	// 10 seconds before the credentials are actually expired.
+	//
+	// If ExpiryWindow is 0 or less it will be ignored.
+	ExpiryWindow time.Duration
+}
+
+// NewWebIdentityCredentials returns a pointer to a new Credentials object
+// wrapping the WebIdentityRoleProvider. The role's credentials will be
+// retrieved with the OIDC token read from the file path provided.
+//
+// Takes a Config provider to create the STS client. The ConfigProvider is
+// satisfied by the session.Session type.
+//
+// It is safe to share the returned Credentials with multiple Sessions and
+// service clients. All access to the credentials and refreshing them
+// will be synchronized.
+func NewWebIdentityCredentials(c client.ConfigProvider, roleARN, roleSessionName, path string, options ...func(*WebIdentityRoleProvider)) *credentials.Credentials {
+	p := NewWebIdentityRoleProvider(sts.New(c), roleARN, roleSessionName, path)
+
+	for _, option := range options {
+		option(p)
+	}
+
+	return credentials.NewCredentials(p)
+}
+
+// NewWebIdentityRoleProvider returns a new WebIdentityRoleProvider which will
+// use the WebIdentityRoleAssumer provided to retrieve credentials. The
+// WebIdentityRoleAssumer is satisfied by the STS client.
+func NewWebIdentityRoleProvider(svc WebIdentityRoleAssumer, roleARN, roleSessionName, path string) *WebIdentityRoleProvider {
+	return &WebIdentityRoleProvider{
+		Client:          svc,
+		RoleARN:         roleARN,
+		RoleSessionName: roleSessionName,
+		TokenFilePath:   path,
+	}
+}
+
+// Retrieve reads the OIDC token from the provider's TokenFilePath, and uses
+// it to assume the provider's role. An error will be returned if the token
+// file cannot be read, or the role cannot be assumed.
+func (p *WebIdentityRoleProvider) Retrieve() (credentials.Value, error) {
+	b, err := ioutil.ReadFile(p.TokenFilePath)
+	if err != nil {
+		errMsg := fmt.Sprintf("unable to read file at %s", p.TokenFilePath)
+		return credentials.Value{ProviderName: WebIdentityProviderName},
+			awserr.New(ErrCodeWebIdentity, errMsg, err)
+	}
+
+	sessionName := p.RoleSessionName
+	if len(sessionName) == 0 {
+		// session name is used to uniquely identify a session. This simply
+		// uses unix time in nanoseconds to uniquely identify sessions.
+		sessionName = strconv.FormatInt(now().UnixNano(), 10)
+	}
+
+	input := &sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(p.RoleARN),
+		RoleSessionName:  aws.String(sessionName),
+		WebIdentityToken: aws.String(string(b)),
+		Policy:           p.Policy,
+	}
+	if p.Duration != 0 {
+		input.DurationSeconds = aws.Int64(int64(p.Duration / time.Second))
+	}
+
+	resp, err := p.Client.AssumeRoleWithWebIdentity(input)
+	if err != nil {
+		return credentials.Value{ProviderName: WebIdentityProviderName},
+			awserr.New(ErrCodeWebIdentity, "failed to retrieve credentials", err)
+	}
+
+	// We will proactively generate new credentials before they expire.
+	p.SetExpiration(aws.TimeValue(resp.Credentials.Expiration), p.ExpiryWindow)
+
+	return credentials.Value{
+		AccessKeyID:     aws.StringValue(resp.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(resp.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(resp.Credentials.SessionToken),
+		ProviderName:    WebIdentityProviderName,
+	}, nil
+}
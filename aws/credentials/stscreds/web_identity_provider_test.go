@@ -0,0 +1,138 @@
+package stscreds
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+type stubWebIdentitySTS struct {
+	TestInput func(*sts.AssumeRoleWithWebIdentityInput)
+	Expiry    time.Time
+}
+
+func (s *stubWebIdentitySTS) AssumeRoleWithWebIdentity(input *sts.AssumeRoleWithWebIdentityInput) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	if s.TestInput != nil {
+		s.TestInput(input)
+	}
+	return &sts.AssumeRoleWithWebIdentityOutput{
+		Credentials: &sts.Credentials{
+			// Just reflect the role arn to the provider.
+			AccessKeyId:     input.RoleArn,
+			SecretAccessKey: aws.String("assumedSecretAccessKey"),
+			SessionToken:    aws.String("assumedSessionToken"),
+			Expiration:      aws.Time(s.Expiry),
+		},
+	}, nil
+}
+
+func TestWebIdentityProviderRetrieve(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time {
+		return time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	cases := map[string]struct {
+		RoleSessionName     string
+		Duration            time.Duration
+		ExpectSessionName   string
+		ExpectDurationInput *int64
+	}{
+		"session name": {
+			RoleSessionName:   "foo",
+			ExpectSessionName: "foo",
+		},
+		"default session name": {
+			ExpectSessionName: "1559347200000000000",
+		},
+		"duration": {
+			RoleSessionName:     "foo",
+			Duration:            30 * time.Minute,
+			ExpectSessionName:   "foo",
+			ExpectDurationInput: aws.Int64(1800),
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			stub := &stubWebIdentitySTS{
+				Expiry: time.Now().Add(60 * time.Minute),
+				TestInput: func(in *sts.AssumeRoleWithWebIdentityInput) {
+					if e, a := "token", aws.StringValue(in.WebIdentityToken); e != a {
+						t.Errorf("expect %v token, got %v", e, a)
+					}
+					if e, a := c.ExpectSessionName, aws.StringValue(in.RoleSessionName); e != a {
+						t.Errorf("expect %v session name, got %v", e, a)
+					}
+					if e, a := aws.Int64Value(c.ExpectDurationInput), aws.Int64Value(in.DurationSeconds); e != a {
+						t.Errorf("expect %v duration, got %v", e, a)
+					}
+				},
+			}
+
+			p := NewWebIdentityRoleProvider(stub, "roleARN", c.RoleSessionName, "testdata/token.jwt")
+			p.Duration = c.Duration
+
+			creds, err := p.Retrieve()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			if e, a := "roleARN", creds.AccessKeyID; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := "assumedSecretAccessKey", creds.SecretAccessKey; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := "assumedSessionToken", creds.SessionToken; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := WebIdentityProviderName, creds.ProviderName; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if p.IsExpired() {
+				t.Errorf("expect credentials not to be expired")
+			}
+		})
+	}
+}
+
+func TestWebIdentityProviderRetrieve_MissingTokenFile(t *testing.T) {
+	p := NewWebIdentityRoleProvider(&stubWebIdentitySTS{}, "roleARN", "foo", "testdata/missing.jwt")
+
+	_, err := p.Retrieve()
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := ErrCodeWebIdentity, err.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v error code, got %v", e, a)
+	}
+}
+
+func TestWebIdentityProviderRefresh(t *testing.T) {
+	stub := &stubWebIdentitySTS{
+		Expiry: time.Now().Add(5 * time.Minute),
+	}
+	calls := 0
+	stub.TestInput = func(*sts.AssumeRoleWithWebIdentityInput) { calls++ }
+
+	p := NewWebIdentityRoleProvider(stub, "roleARN", "foo", "testdata/token.jwt")
+	p.ExpiryWindow = 10 * time.Minute
+	creds := credentials.NewCredentials(p)
+
+	for i := 0; i < 2; i++ {
+		if _, err := creds.Get(); err != nil {
+			t.Fatalf("%d, expect no error, got %v", i, err)
+		}
+	}
+
+	// Expiry window is larger than the credential's lifetime so they will
+	// always be refreshed.
+	if e, a := 2, calls; e != a {
+		t.Errorf("expect %v calls, got %v", e, a)
+	}
+}
@@ -4,14 +4,54 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/internal/sdkuri"
 )
 
+// getToken requests a session token with the TTL provided from the EC2
+// instance metadata service. An awserr.RequestFailure error is returned if
+// the request failed.
+func (c *EC2Metadata) getToken(ctx aws.Context, ttl time.Duration) (tokenOutput, error) {
+	op := &request.Operation{
+		Name:       "GetToken",
+		HTTPMethod: "PUT",
+		HTTPPath:   "/api/token",
+	}
+
+	output := &tokenOutput{}
+	req := c.NewRequest(op, nil, output)
+	req.SetContext(ctx)
+
+	// The token request must not be signed with, or refresh, the session
+	// token it is retrieving.
+	req.Handlers.Sign.RemoveByName(fetchTokenHandlerName)
+	req.Handlers.Retry.RemoveByName(refreshTokenHandlerName)
+	req.Handlers.Unmarshal.Swap(unmarshalMetadataHandlerName, unmarshalTokenHandler)
+
+	req.HTTPRequest.Header.Set(ttlHeader, strconv.FormatInt(int64(ttl/time.Second), 10))
+
+	if err := req.Send(); err != nil {
+		var statusCode int
+		if req.HTTPResponse != nil {
+			statusCode = req.HTTPResponse.StatusCode
+		}
+
+		aerr, ok := err.(awserr.Error)
+		if !ok {
+			aerr = awserr.New("EC2MetadataError", "failed to get session token", err)
+		}
+		return tokenOutput{}, awserr.NewRequestFailure(aerr, statusCode, req.RequestID)
+	}
+
+	return *output, nil
+}
+
 // GetMetadata uses the path provided to request information from the EC2
 // instance metdata service. The content will be returned as a string, or
 // error if the request failed.
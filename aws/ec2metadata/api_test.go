@@ -49,8 +49,21 @@ const unsuccessfulIamInfo = `{
   "InstanceProfileId" : "AIPAABCDEFGHIJKLMN123"
 }`
 
+const testToken = "token"
+
 func initTestServer(path string, resp string) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.RequestURI == "/latest/api/token" {
+			w.Header().Set("x-aws-ec2-metadata-token-ttl-seconds", r.Header.Get("x-aws-ec2-metadata-token-ttl-seconds"))
+			w.Write([]byte(testToken))
+			return
+		}
+
+		if r.Header.Get("x-aws-ec2-metadata-token") != testToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
 		if r.RequestURI != path {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
@@ -115,6 +128,12 @@ func TestGetUserData(t *testing.T) {
 
 func TestGetUserData_Error(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			w.Header().Set("x-aws-ec2-metadata-token-ttl-seconds", "21600")
+			w.Write([]byte(testToken))
+			return
+		}
+
 		reader := strings.NewReader(`<?xml version="1.0" encoding="iso-8859-1"?>
 <!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN"
          "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
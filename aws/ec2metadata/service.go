@@ -5,6 +5,12 @@
 // variable "AWS_EC2_METADATA_DISABLED=true". This environment variable set to
 // true instructs the SDK to disable the EC2 Metadata client. The client cannot
 // be used while the environment variable is set to true, (case insensitive).
+//
+// The client retrieves a session token from the EC2 Metadata service, and
+// sends the token with every request. Tokens are cached until they expire,
+// and are refreshed if the service rejects a request as unauthorized. Set
+// aws.Config.EC2MetadataEnableFallback to allow the client to make requests
+// without a session token if one cannot be retrieved.
 package ec2metadata
 
 import (
@@ -13,6 +19,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -80,11 +87,24 @@ func NewClient(cfg aws.Config, handlers request.Handlers, endpoint, signingRegio
 		),
 	}
 
-	svc.Handlers.Unmarshal.PushBack(unmarshalHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(unmarshalMetadataHandler)
 	svc.Handlers.UnmarshalError.PushBack(unmarshalError)
 	svc.Handlers.Validate.Clear()
 	svc.Handlers.Validate.PushBack(validateEndpointHandler)
 
+	// Session tokens are retrieved, cached, and added to every request made
+	// by the client. A request rejected as unauthorized will be retried
+	// with a new token.
+	tp := newTokenProvider(svc, defaultTTL)
+	svc.Handlers.Sign.PushBackNamed(request.NamedHandler{
+		Name: fetchTokenHandlerName,
+		Fn:   tp.fetchTokenHandler,
+	})
+	svc.Handlers.Retry.PushFrontNamed(request.NamedHandler{
+		Name: refreshTokenHandlerName,
+		Fn:   tp.refreshTokenHandler,
+	})
+
 	// Disable the EC2 Metadata service if the environment variable is set.
 	// This shortcirctes the service's functionality to always fail to send
 	// requests.
@@ -119,6 +139,43 @@ type metadataOutput struct {
 	Content string
 }
 
+type tokenOutput struct {
+	Token string
+	TTL   time.Duration
+}
+
+const unmarshalMetadataHandlerName = "unmarshalMetadataHandler"
+
+var unmarshalMetadataHandler = request.NamedHandler{
+	Name: unmarshalMetadataHandlerName,
+	Fn:   unmarshalHandler,
+}
+
+// unmarshalTokenHandler unmarshals a session token and its TTL from the
+// EC2 Metadata service's response.
+var unmarshalTokenHandler = request.NamedHandler{
+	Name: unmarshalMetadataHandlerName,
+	Fn: func(r *request.Request) {
+		defer r.HTTPResponse.Body.Close()
+		b := &bytes.Buffer{}
+		if _, err := io.Copy(b, r.HTTPResponse.Body); err != nil {
+			r.Error = awserr.New("SerializationError", "unable to unmarshal EC2 metadata token response", err)
+			return
+		}
+
+		ttl, err := strconv.ParseInt(r.HTTPResponse.Header.Get(ttlHeader), 10, 64)
+		if err != nil {
+			r.Error = awserr.New("SerializationError", "unable to parse EC2 metadata token TTL", err)
+			return
+		}
+
+		if data, ok := r.Data.(*tokenOutput); ok {
+			data.Token = b.String()
+			data.TTL = time.Duration(ttl) * time.Second
+		}
+	},
+}
+
 func unmarshalHandler(r *request.Request) {
 	defer r.HTTPResponse.Body.Close()
 	b := &bytes.Buffer{}
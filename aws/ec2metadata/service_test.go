@@ -2,7 +2,6 @@ package ec2metadata_test
 
 import (
 	"net/http"
-	"net/http/httptest"
 	"os"
 	"strings"
 	"sync"
@@ -59,20 +58,16 @@ func TestClientDisableOverrideDefaultHTTPClientTimeout(t *testing.T) {
 }
 
 func TestClientOverrideDefaultHTTPClientTimeoutRace(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("us-east-1a"))
-	}))
+	server := initTestServer("/latest/meta-data/placement/availability-zone", "us-east-1a")
 
-	cfg := aws.NewConfig().WithEndpoint(server.URL)
+	cfg := aws.NewConfig().WithEndpoint(server.URL + "/latest")
 	runEC2MetadataClients(t, cfg, 100)
 }
 
 func TestClientOverrideDefaultHTTPClientTimeoutRaceWithTransport(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("us-east-1a"))
-	}))
+	server := initTestServer("/latest/meta-data/placement/availability-zone", "us-east-1a")
 
-	cfg := aws.NewConfig().WithEndpoint(server.URL).WithHTTPClient(&http.Client{
+	cfg := aws.NewConfig().WithEndpoint(server.URL + "/latest").WithHTTPClient(&http.Client{
 		Transport: http.DefaultTransport,
 	})
 
@@ -0,0 +1,131 @@
+package ec2metadata
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+const (
+	// tokenHeader is the header the session token is sent to the EC2
+	// Metadata service with.
+	tokenHeader = "x-aws-ec2-metadata-token"
+
+	// ttlHeader is the header the requested, and returned, TTL of a session
+	// token is sent with in seconds.
+	ttlHeader = "x-aws-ec2-metadata-token-ttl-seconds"
+
+	// defaultTTL is the TTL requested for session tokens. This is the
+	// maximum TTL allowed by the EC2 Metadata service.
+	defaultTTL = 21600 * time.Second
+
+	// ttlExpirationWindow is how early a cached token is considered expired
+	// so that in flight requests are not sent with an expiring token.
+	ttlExpirationWindow = 30 * time.Second
+
+	fetchTokenHandlerName   = "FetchTokenHandler"
+	refreshTokenHandlerName = "RefreshTokenHandler"
+)
+
+// An ec2Token is a session token retrieved from the EC2 Metadata service,
+// and its expiry.
+type ec2Token struct {
+	token string
+	credentials.Expiry
+}
+
+// A tokenProvider retrieves, caches, and injects session tokens into the
+// requests made by an EC2Metadata client. The tokenProvider is safe to use
+// across multiple goroutines.
+type tokenProvider struct {
+	client        *EC2Metadata
+	configuredTTL time.Duration
+
+	m        sync.Mutex
+	token    *ec2Token
+	disabled bool
+}
+
+func newTokenProvider(c *EC2Metadata, ttl time.Duration) *tokenProvider {
+	return &tokenProvider{client: c, configuredTTL: ttl}
+}
+
+// fetchTokenHandler sets the session token header on the request, retrieving
+// a new token from the EC2 Metadata service if there is no valid cached
+// token.
+//
+// If the token cannot be retrieved and the client is configured with
+// EC2MetadataEnableFallback the token provider will be disabled, and
+// requests will be made without a token until the service responds with a
+// 401 Unauthorized status code.
+func (t *tokenProvider) fetchTokenHandler(r *request.Request) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if t.disabled {
+		return
+	}
+
+	if t.token != nil && !t.token.IsExpired() {
+		r.HTTPRequest.Header.Set(tokenHeader, t.token.token)
+		return
+	}
+
+	output, err := t.client.getToken(r.Context(), t.configuredTTL)
+	if err != nil {
+		if aws.BoolValue(r.Config.EC2MetadataEnableFallback) && canFallback(err) {
+			t.disabled = true
+			return
+		}
+		r.Error = err
+		return
+	}
+
+	token := &ec2Token{token: output.Token}
+	token.SetExpiration(time.Now().Add(output.TTL), ttlExpirationWindow)
+	t.token = token
+
+	r.HTTPRequest.Header.Set(tokenHeader, token.token)
+}
+
+// refreshTokenHandler clears the cached session token when the EC2 Metadata
+// service responds with 401 Unauthorized, and marks the request as
+// retryable so it will be sent again with a new token.
+func (t *tokenProvider) refreshTokenHandler(r *request.Request) {
+	if r.HTTPResponse == nil || r.HTTPResponse.StatusCode != http.StatusUnauthorized {
+		return
+	}
+
+	t.m.Lock()
+	t.token = nil
+	t.disabled = false
+	t.m.Unlock()
+
+	r.Retryable = aws.Bool(true)
+}
+
+// canFallback returns if the error retrieving a token indicates the EC2
+// Metadata service does not support session tokens, or could not be reached
+// with the token request.
+func canFallback(err error) bool {
+	reqErr, ok := err.(awserr.RequestFailure)
+	if !ok {
+		return false
+	}
+
+	switch reqErr.StatusCode() {
+	case http.StatusForbidden, http.StatusNotFound, http.StatusMethodNotAllowed:
+		return true
+	case 0:
+		// No response was received, e.g. the PUT response was dropped due
+		// to the hop limit, or the request timed out.
+		return reqErr.Code() == "RequestError"
+	}
+
+	return false
+}
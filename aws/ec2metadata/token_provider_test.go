@@ -0,0 +1,194 @@
+package ec2metadata_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+)
+
+type tokenTestServer struct {
+	*httptest.Server
+
+	tokenStatus  int
+	tokens       []string
+	tokenCalls   int32
+	requireToken bool
+}
+
+func newTokenTestServer(tokenStatus int, requireToken bool, tokens ...string) *tokenTestServer {
+	s := &tokenTestServer{
+		tokenStatus:  tokenStatus,
+		tokens:       tokens,
+		requireToken: requireToken,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *tokenTestServer) token() string {
+	n := int(atomic.LoadInt32(&s.tokenCalls))
+	if n == 0 || len(s.tokens) == 0 {
+		return ""
+	}
+	if n > len(s.tokens) {
+		n = len(s.tokens)
+	}
+	return s.tokens[n-1]
+}
+
+func (s *tokenTestServer) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/latest/api/token" {
+		if r.Method != "PUT" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if s.tokenStatus != http.StatusOK {
+			http.Error(w, "token error", s.tokenStatus)
+			return
+		}
+		atomic.AddInt32(&s.tokenCalls, 1)
+		w.Header().Set("x-aws-ec2-metadata-token-ttl-seconds", r.Header.Get("x-aws-ec2-metadata-token-ttl-seconds"))
+		w.Write([]byte(s.token()))
+		return
+	}
+
+	token := r.Header.Get("x-aws-ec2-metadata-token")
+	if (s.requireToken || len(token) != 0) && token != s.token() {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Write([]byte("us-west-2a"))
+}
+
+func TestTokenProvider_CachesToken(t *testing.T) {
+	server := newTokenTestServer(http.StatusOK, true, "token1")
+	defer server.Close()
+
+	c := ec2metadata.New(unit.Session, &aws.Config{Endpoint: aws.String(server.URL + "/latest")})
+
+	for i := 0; i < 3; i++ {
+		region, err := c.Region()
+		if err != nil {
+			t.Fatalf("%d, expect no error, got %v", i, err)
+		}
+		if e, a := "us-west-2", region; e != a {
+			t.Errorf("%d, expect %v region, got %v", i, e, a)
+		}
+	}
+
+	if e, a := int32(1), atomic.LoadInt32(&server.tokenCalls); e != a {
+		t.Errorf("expect %v token requests, got %v", e, a)
+	}
+}
+
+func TestTokenProvider_TTLHeader(t *testing.T) {
+	var ttl string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			ttl = r.Header.Get("x-aws-ec2-metadata-token-ttl-seconds")
+			w.Header().Set("x-aws-ec2-metadata-token-ttl-seconds", ttl)
+			w.Write([]byte("token"))
+			return
+		}
+		w.Write([]byte("us-west-2a"))
+	}))
+	defer server.Close()
+
+	c := ec2metadata.New(unit.Session, &aws.Config{Endpoint: aws.String(server.URL + "/latest")})
+	if _, err := c.Region(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := strconv.Itoa(21600), ttl; e != a {
+		t.Errorf("expect %v TTL, got %v", e, a)
+	}
+}
+
+func TestTokenProvider_RefreshOnUnauthorized(t *testing.T) {
+	server := newTokenTestServer(http.StatusOK, true, "token1", "token2")
+	defer server.Close()
+
+	c := ec2metadata.New(unit.Session, &aws.Config{Endpoint: aws.String(server.URL + "/latest")})
+	if _, err := c.Region(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	// Rotate the token the server expects, invalidating the cached token.
+	atomic.AddInt32(&server.tokenCalls, 1)
+
+	region, err := c.Region()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "us-west-2", region; e != a {
+		t.Errorf("expect %v region, got %v", e, a)
+	}
+	if e, a := int32(3), atomic.LoadInt32(&server.tokenCalls); e != a {
+		t.Errorf("expect %v token requests, got %v", e, a)
+	}
+}
+
+func TestTokenProvider_Fallback(t *testing.T) {
+	cases := map[string]struct {
+		TokenStatus    int
+		EnableFallback bool
+		ExpectErr      bool
+	}{
+		"forbidden with fallback": {
+			TokenStatus: http.StatusForbidden, EnableFallback: true,
+		},
+		"not found with fallback": {
+			TokenStatus: http.StatusNotFound, EnableFallback: true,
+		},
+		"method not allowed with fallback": {
+			TokenStatus: http.StatusMethodNotAllowed, EnableFallback: true,
+		},
+		"not found without fallback": {
+			TokenStatus: http.StatusNotFound, ExpectErr: true,
+		},
+		"bad request with fallback": {
+			TokenStatus: http.StatusBadRequest, EnableFallback: true, ExpectErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := newTokenTestServer(c.TokenStatus, false)
+			defer server.Close()
+
+			svc := ec2metadata.New(unit.Session, &aws.Config{
+				Endpoint:                  aws.String(server.URL + "/latest"),
+				EC2MetadataEnableFallback: aws.Bool(c.EnableFallback),
+			})
+
+			region, err := svc.Region()
+			if c.ExpectErr {
+				if err == nil {
+					t.Fatalf("expect error, got none")
+				}
+				reqErr, ok := err.(awserr.RequestFailure)
+				if !ok {
+					t.Fatalf("expect request failure, got %T", err)
+				}
+				if e, a := c.TokenStatus, reqErr.StatusCode(); e != a {
+					t.Errorf("expect %v status code, got %v", e, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := "us-west-2", region; e != a {
+				t.Errorf("expect %v region, got %v", e, a)
+			}
+		})
+	}
+}
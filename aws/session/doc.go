@@ -155,6 +155,12 @@ and signing requests.
 
 	region = us-east-1
 
+Retry mode selects how service clients retry failed requests when a Retryer
+is not provided. Either legacy, the default, or adaptive. See the
+client.AdaptiveRetryer documentation for more information.
+
+	retry_mode = adaptive
+
 Assume Role with MFA token
 
 To create a session with support for assuming an IAM role with MFA set the
@@ -186,6 +192,22 @@ be returned when creating the session.
 To setup assume role outside of a session see the stscreds.AssumeRoleProvider
 documentation.
 
+Assume Role with Web Identity
+
+A role can also be assumed with an OpenID Connect (OIDC) token read from a
+file, such as a Kubernetes projected service account token. The token file is
+read each time the role's credentials are refreshed. Set the role_arn and
+web_identity_token_file fields in the shared config to assume the role with
+the token. The web_identity_token_file field cannot be used with the
+source_profile or credential_source fields.
+
+	role_arn = arn:aws:iam::<account_number>:role/<role_name>
+	web_identity_token_file = /path/to/token
+	role_session_name = session_name
+
+To setup assume role with web identity outside of a session see the
+stscreds.WebIdentityRoleProvider documentation.
+
 Environment Variables
 
 When a Session is created several environment variables can be set to adjust
@@ -236,6 +258,19 @@ env values as well.
 
 	AWS_SDK_LOAD_CONFIG=1
 
+Retry mode of service clients can be set to either legacy or adaptive. The
+retry mode from the environment has precedence over the shared config.
+
+	AWS_RETRY_MODE=adaptive
+
+Web identity credentials can be configured with environment variables. The
+path to the OIDC token file, and the role to assume must be provided
+together. The role session name is optional.
+
+	AWS_WEB_IDENTITY_TOKEN_FILE=/path/to/token
+	AWS_ROLE_ARN=arn:aws:iam::<account_number>:role/<role_name>
+	AWS_ROLE_SESSION_NAME=session_name
+
 Shared credentials file path can be set to instruct the SDK to use an alternative
 file for the shared credentials. If not set the file will be loaded from
 $HOME/.aws/credentials on Linux/Unix based systems, and
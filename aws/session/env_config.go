@@ -108,6 +108,30 @@ type envConfig struct {
 	//
 	//	AWS_ENABLE_ENDPOINT_DISCOVERY=true
 	EnableEndpointDiscovery *bool
+
+	// Specifies the retry mode service clients will use when a Retryer is
+	// not provided. Must be either "legacy" or "adaptive".
+	//
+	//	AWS_RETRY_MODE=adaptive
+	RetryMode string
+
+	// Specifies the WebIdentity token the SDK should use to assume a role
+	// with.
+	//
+	//	AWS_WEB_IDENTITY_TOKEN_FILE=file_path
+	WebIdentityTokenFilePath string
+
+	// Specifies the IAM role arn to use when assuming a role with a
+	// WebIdentity token.
+	//
+	//	AWS_ROLE_ARN=role_arn
+	RoleARN string
+
+	// Specifies the IAM role session name to use when assuming a role with a
+	// WebIdentity token.
+	//
+	//	AWS_ROLE_SESSION_NAME=session_name
+	RoleSessionName string
 }
 
 var (
@@ -150,6 +174,18 @@ var (
 	sharedConfigFileEnvKey = []string{
 		"AWS_CONFIG_FILE",
 	}
+	retryModeEnvKey = []string{
+		"AWS_RETRY_MODE",
+	}
+	webIdentityTokenFilePathEnvKey = []string{
+		"AWS_WEB_IDENTITY_TOKEN_FILE",
+	}
+	roleARNEnvKey = []string{
+		"AWS_ROLE_ARN",
+	}
+	roleSessionNameEnvKey = []string{
+		"AWS_ROLE_SESSION_NAME",
+	}
 )
 
 // loadEnvConfig retrieves the SDK's environment configuration.
@@ -182,6 +218,13 @@ func envConfigLoad(enableSharedConfig bool) envConfig {
 	setFromEnvVal(&cfg.Creds.SecretAccessKey, credSecretEnvKey)
 	setFromEnvVal(&cfg.Creds.SessionToken, credSessionEnvKey)
 
+	// Role Metadata
+	setFromEnvVal(&cfg.RoleARN, roleARNEnvKey)
+	setFromEnvVal(&cfg.RoleSessionName, roleSessionNameEnvKey)
+
+	// Web identity environment variables
+	setFromEnvVal(&cfg.WebIdentityTokenFilePath, webIdentityTokenFilePathEnvKey)
+
 	// CSM environment variables
 	setFromEnvVal(&cfg.csmEnabled, csmEnabledEnvKey)
 	setFromEnvVal(&cfg.CSMPort, csmPortEnvKey)
@@ -211,6 +254,8 @@ func envConfigLoad(enableSharedConfig bool) envConfig {
 		cfg.EnableEndpointDiscovery = aws.Bool(cfg.enableEndpointDiscovery != "false")
 	}
 
+	setFromEnvVal(&cfg.RetryMode, retryModeEnvKey)
+
 	setFromEnvVal(&cfg.SharedCredentialsFile, sharedCredsFileEnvKey)
 	setFromEnvVal(&cfg.SharedConfigFile, sharedConfigFileEnvKey)
 
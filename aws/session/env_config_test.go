@@ -264,6 +264,20 @@ func TestLoadEnvConfig(t *testing.T) {
 				SharedConfigFile:      "/path/to/config/file",
 			},
 		},
+		{
+			Env: map[string]string{
+				"AWS_WEB_IDENTITY_TOKEN_FILE": "/path/to/token/file",
+				"AWS_ROLE_ARN":                "role_arn",
+				"AWS_ROLE_SESSION_NAME":       "session_name",
+			},
+			Config: envConfig{
+				WebIdentityTokenFilePath: "/path/to/token/file",
+				RoleARN:                  "role_arn",
+				RoleSessionName:          "session_name",
+				SharedCredentialsFile:    shareddefaults.SharedCredentialsFilename(),
+				SharedConfigFile:         shareddefaults.SharedConfigFilename(),
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -27,12 +27,25 @@ const (
 	// ErrCodeSharedConfig represents an error that occurs in the shared
 	// configuration logic
 	ErrCodeSharedConfig = "SharedConfigErr"
+
+	// ErrCodeInvalidRetryMode represents an error that occurs when the
+	// retry mode loaded from the environment or shared config is not valid
+	ErrCodeInvalidRetryMode = "InvalidRetryModeErr"
 )
 
 // ErrSharedConfigSourceCollision will be returned if a section contains both
 // source_profile and credential_source
 var ErrSharedConfigSourceCollision = awserr.New(ErrCodeSharedConfig, "only source profile or credential source can be specified, not both", nil)
 
+// ErrSharedConfigWebIdentitySourceCollision will be returned if a section
+// contains web_identity_token_file, and either source_profile or
+// credential_source
+var ErrSharedConfigWebIdentitySourceCollision = awserr.New(ErrCodeSharedConfig, "web identity token file cannot be specified with source profile or credential source", nil)
+
+// WebIdentityEmptyRoleARNErr will be returned if the web identity token file
+// environment variable is set, but the role ARN environment variable is not
+var WebIdentityEmptyRoleARNErr = awserr.New(stscreds.ErrCodeWebIdentity, "role ARN is not set", nil)
+
 // ErrSharedConfigECSContainerEnvVarEmpty will be returned if the environment
 // variables are empty and Environment was set as the credential source
 var ErrSharedConfigECSContainerEnvVarEmpty = awserr.New(ErrCodeSharedConfig, "EcsContainer was specified as the credential_source, but 'AWS_CONTAINER_CREDENTIALS_RELATIVE_URI' was not set", nil)
@@ -464,6 +477,24 @@ func mergeConfigSrcs(cfg, userCfg *aws.Config, envCfg envConfig, sharedCfg share
 		}
 	}
 
+	if len(cfg.RetryMode) == 0 {
+		var mode string
+		if len(envCfg.RetryMode) > 0 {
+			mode = envCfg.RetryMode
+		} else if envCfg.EnableSharedConfig && len(sharedCfg.RetryMode) > 0 {
+			mode = sharedCfg.RetryMode
+		}
+
+		if len(mode) > 0 {
+			retryMode, err := aws.ParseRetryMode(mode)
+			if err != nil {
+				return awserr.New(ErrCodeInvalidRetryMode,
+					"retry mode must be legacy or adaptive", err)
+			}
+			cfg.WithRetryMode(retryMode)
+		}
+	}
+
 	// Configure credentials if not already set
 	if cfg.Credentials == credentials.AnonymousCredentials && userCfg.Credentials == nil {
 
@@ -475,6 +506,9 @@ func mergeConfigSrcs(cfg, userCfg *aws.Config, envCfg envConfig, sharedCfg share
 			if len(sharedCfg.AssumeRole.SourceProfile) > 0 {
 				return ErrSharedConfigSourceCollision
 			}
+			if len(sharedCfg.AssumeRole.WebIdentityTokenFile) > 0 {
+				return ErrSharedConfigWebIdentitySourceCollision
+			}
 
 			// valid credential source values
 			const (
@@ -521,6 +555,21 @@ func mergeConfigSrcs(cfg, userCfg *aws.Config, envCfg envConfig, sharedCfg share
 			cfg.Credentials = credentials.NewStaticCredentialsFromCreds(
 				envCfg.Creds,
 			)
+		} else if len(envCfg.WebIdentityTokenFilePath) > 0 {
+			if len(envCfg.RoleARN) == 0 {
+				return WebIdentityEmptyRoleARNErr
+			}
+
+			cfg.Credentials = webIdentityCredentials(*cfg, handlers,
+				envCfg.RoleARN, envCfg.RoleSessionName, envCfg.WebIdentityTokenFilePath)
+		} else if envCfg.EnableSharedConfig && len(sharedCfg.AssumeRole.RoleARN) > 0 && len(sharedCfg.AssumeRole.WebIdentityTokenFile) > 0 {
+			if len(sharedCfg.AssumeRole.SourceProfile) > 0 {
+				return ErrSharedConfigWebIdentitySourceCollision
+			}
+
+			cfg.Credentials = webIdentityCredentials(*cfg, handlers,
+				sharedCfg.AssumeRole.RoleARN, sharedCfg.AssumeRole.RoleSessionName,
+				sharedCfg.AssumeRole.WebIdentityTokenFile)
 		} else if envCfg.EnableSharedConfig && len(sharedCfg.AssumeRole.RoleARN) > 0 && sharedCfg.AssumeRoleSource != nil {
 			cfgCp := *cfg
 			cfgCp.Credentials = credentials.NewStaticCredentialsFromCreds(
@@ -584,6 +633,18 @@ func assumeRoleCredentials(cfg aws.Config, handlers request.Handlers, sharedCfg
 	)
 }
 
+func webIdentityCredentials(cfg aws.Config, handlers request.Handlers, roleARN, roleSessionName, tokenFilePath string) *credentials.Credentials {
+	return stscreds.NewWebIdentityCredentials(
+		&Session{
+			Config:   &cfg,
+			Handlers: handlers.Copy(),
+		},
+		roleARN,
+		roleSessionName,
+		tokenFilePath,
+	)
+}
+
 // AssumeRoleTokenProviderNotSetError is an error returned when creating a session when the
 // MFAToken option is not set when shared config is configured load assume a
 // role with an MFA token.
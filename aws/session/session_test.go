@@ -11,7 +11,9 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/defaults"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/awstesting"
@@ -458,6 +460,134 @@ func TestSesisonAssumeRole(t *testing.T) {
 	}
 }
 
+const assumeRoleWithWebIdentityRespMsg = `
+<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <SubjectFromWebIdentityToken>amzn1.account.AF6RHO7KZU5XRVQJGXK6HB56KR2A</SubjectFromWebIdentityToken>
+    <AssumedRoleUser>
+      <Arn>arn:aws:sts::account_id:assumed-role/role/session_name</Arn>
+      <AssumedRoleId>AKID:session_name</AssumedRoleId>
+    </AssumedRoleUser>
+    <Credentials>
+      <AccessKeyId>AKID</AccessKeyId>
+      <SecretAccessKey>SECRET</SecretAccessKey>
+      <SessionToken>SESSION_TOKEN</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+  <ResponseMetadata>
+    <RequestId>request-id</RequestId>
+  </ResponseMetadata>
+</AssumeRoleWithWebIdentityResponse>
+`
+
+func TestSessionWebIdentity(t *testing.T) {
+	cases := map[string]struct {
+		Env               map[string]string
+		ExpectRoleARN     string
+		ExpectSessionName string
+		ExpectErr         error
+	}{
+		"env": {
+			Env: map[string]string{
+				"AWS_WEB_IDENTITY_TOKEN_FILE": "testdata/wit.txt",
+				"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/env_role",
+				"AWS_ROLE_SESSION_NAME":       "env_session_name",
+			},
+			ExpectRoleARN:     "arn:aws:iam::123456789012:role/env_role",
+			ExpectSessionName: "env_session_name",
+		},
+		"env missing role arn": {
+			Env: map[string]string{
+				"AWS_WEB_IDENTITY_TOKEN_FILE": "testdata/wit.txt",
+			},
+			ExpectErr: WebIdentityEmptyRoleARNErr,
+		},
+		"shared config": {
+			Env: map[string]string{
+				"AWS_SDK_LOAD_CONFIG": "1",
+				"AWS_CONFIG_FILE":     testConfigFilename,
+				"AWS_PROFILE":         "web_identity",
+			},
+			ExpectRoleARN:     "web_identity_role_arn",
+			ExpectSessionName: "web_identity_session_name",
+		},
+		"env precedence over shared config": {
+			Env: map[string]string{
+				"AWS_SDK_LOAD_CONFIG":         "1",
+				"AWS_CONFIG_FILE":             testConfigFilename,
+				"AWS_PROFILE":                 "web_identity",
+				"AWS_WEB_IDENTITY_TOKEN_FILE": "testdata/wit.txt",
+				"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/env_role",
+			},
+			ExpectRoleARN: "arn:aws:iam::123456789012:role/env_role",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			oldEnv := initSessionTestEnv()
+			defer awstesting.PopEnv(oldEnv)
+
+			os.Setenv("AWS_REGION", "us-east-1")
+			for k, v := range c.Env {
+				os.Setenv(k, v)
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Errorf("expect no error parsing form, got %v", err)
+				}
+				if e, a := "AssumeRoleWithWebIdentity", r.Form.Get("Action"); e != a {
+					t.Errorf("expect %v action, got %v", e, a)
+				}
+				if e, a := c.ExpectRoleARN, r.Form.Get("RoleArn"); e != a {
+					t.Errorf("expect %v role arn, got %v", e, a)
+				}
+				if len(c.ExpectSessionName) != 0 {
+					if e, a := c.ExpectSessionName, r.Form.Get("RoleSessionName"); e != a {
+						t.Errorf("expect %v session name, got %v", e, a)
+					}
+				}
+				if e, a := "YXdzIHNkayBmb3IgZ28gd2ViIGlkZW50aXR5IHRva2Vu", r.Form.Get("WebIdentityToken"); e != a {
+					t.Errorf("expect %v token, got %v", e, a)
+				}
+
+				w.Write([]byte(fmt.Sprintf(assumeRoleWithWebIdentityRespMsg, time.Now().Add(15*time.Minute).Format("2006-01-02T15:04:05Z"))))
+			}))
+			defer server.Close()
+
+			s, err := NewSession(&aws.Config{Endpoint: aws.String(server.URL), DisableSSL: aws.Bool(true)})
+			if c.ExpectErr != nil {
+				if e, a := c.ExpectErr, err; e != a {
+					t.Fatalf("expect %v error, got %v", e, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			creds, err := s.Config.Credentials.Get()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := "AKID", creds.AccessKeyID; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := "SECRET", creds.SecretAccessKey; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := "SESSION_TOKEN", creds.SessionToken; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := stscreds.WebIdentityProviderName, creds.ProviderName; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
 func TestSessionAssumeRole_WithMFA(t *testing.T) {
 	oldEnv := initSessionTestEnv()
 	defer awstesting.PopEnv(oldEnv)
@@ -666,7 +796,10 @@ func TestSharedConfigCredentialSource(t *testing.T) {
 
 				ec2MetadataCalled := false
 				ec2MetadataServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					if r.URL.Path == "/meta-data/iam/security-credentials/RoleName" {
+					if r.URL.Path == "/api/token" {
+						w.Header().Set("x-aws-ec2-metadata-token-ttl-seconds", "21600")
+						w.Write([]byte("token"))
+					} else if r.URL.Path == "/meta-data/iam/security-credentials/RoleName" {
 						ec2MetadataCalled = true
 						w.Write([]byte(ec2MetadataResponse))
 					} else if r.URL.Path == "/meta-data/iam/security-credentials/" {
@@ -811,6 +944,77 @@ func TestSharedConfigCredentialSource(t *testing.T) {
 	}
 }
 
+func TestSessionRetryMode(t *testing.T) {
+	cases := map[string]struct {
+		Env        map[string]string
+		Config     *aws.Config
+		Expect     aws.RetryMode
+		ExpectCode string
+	}{
+		"default": {},
+		"env": {
+			Env:    map[string]string{"AWS_RETRY_MODE": "adaptive"},
+			Expect: aws.RetryModeAdaptive,
+		},
+		"shared config": {
+			Env: map[string]string{
+				"AWS_SDK_LOAD_CONFIG": "1",
+				"AWS_CONFIG_FILE":     testConfigFilename,
+				"AWS_PROFILE":         "retry_mode",
+			},
+			Expect: aws.RetryModeAdaptive,
+		},
+		"env precedence over shared config": {
+			Env: map[string]string{
+				"AWS_SDK_LOAD_CONFIG": "1",
+				"AWS_CONFIG_FILE":     testConfigFilename,
+				"AWS_PROFILE":         "retry_mode",
+				"AWS_RETRY_MODE":      "legacy",
+			},
+			Expect: aws.RetryModeLegacy,
+		},
+		"config precedence over env": {
+			Env:    map[string]string{"AWS_RETRY_MODE": "legacy"},
+			Config: aws.NewConfig().WithRetryMode(aws.RetryModeAdaptive),
+			Expect: aws.RetryModeAdaptive,
+		},
+		"invalid": {
+			Env:        map[string]string{"AWS_RETRY_MODE": "unknown"},
+			ExpectCode: ErrCodeInvalidRetryMode,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			oldEnv := initSessionTestEnv()
+			defer awstesting.PopEnv(oldEnv)
+
+			for k, v := range c.Env {
+				os.Setenv(k, v)
+			}
+
+			s, err := NewSession(c.Config)
+			if len(c.ExpectCode) != 0 {
+				aerr, ok := err.(awserr.Error)
+				if !ok {
+					t.Fatalf("expect awserr.Error, got %T, %v", err, err)
+				}
+				if e, a := c.ExpectCode, aerr.Code(); e != a {
+					t.Errorf("expect %v error code, got %v", e, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			if e, a := c.Expect, s.Config.RetryMode; e != a {
+				t.Errorf("expect %v retry mode, got %v", e, a)
+			}
+		})
+	}
+}
+
 func initSessionTestEnv() (oldEnv []string) {
 	oldEnv = awstesting.StashEnv()
 	os.Setenv("AWS_CONFIG_FILE", "file_not_exists")
@@ -23,6 +23,9 @@ const (
 	mfaSerialKey        = `mfa_serial`        // optional
 	roleSessionNameKey  = `role_session_name` // optional
 
+	// Web Identity Token File
+	webIdentityTokenFileKey = `web_identity_token_file` // optional
+
 	// Additional Config fields
 	regionKey = `region`
 
@@ -31,6 +34,9 @@ const (
 	// External Credential Process
 	credentialProcessKey = `credential_process`
 
+	// Retry mode of service clients
+	retryModeKey = `retry_mode` // optional
+
 	// DefaultSharedConfigProfile is the default profile to be used when
 	// loading configuration from the config files if another profile name
 	// is not provided.
@@ -44,6 +50,10 @@ type assumeRoleConfig struct {
 	ExternalID       string
 	MFASerial        string
 	RoleSessionName  string
+
+	// WebIdentityTokenFile is the path to an OIDC token file the role will
+	// be assumed with.
+	WebIdentityTokenFile string
 }
 
 // sharedConfig represents the configuration fields of the SDK config files.
@@ -76,6 +86,12 @@ type sharedConfig struct {
 	//
 	//	endpoint_discovery_enabled = true
 	EnableEndpointDiscovery *bool
+
+	// RetryMode specifies the retry mode service clients will use when a
+	// Retryer is not provided. Must be either legacy or adaptive.
+	//
+	//	retry_mode = adaptive
+	RetryMode string
 }
 
 type sharedConfigFile struct {
@@ -216,15 +232,17 @@ func (cfg *sharedConfig) setFromIniFile(profile string, file sharedConfigFile) e
 	roleArn := section.String(roleArnKey)
 	srcProfile := section.String(sourceProfileKey)
 	credentialSource := section.String(credentialSourceKey)
-	hasSource := len(srcProfile) > 0 || len(credentialSource) > 0
+	webIdentityTokenFile := section.String(webIdentityTokenFileKey)
+	hasSource := len(srcProfile) > 0 || len(credentialSource) > 0 || len(webIdentityTokenFile) > 0
 	if len(roleArn) > 0 && hasSource {
 		cfg.AssumeRole = assumeRoleConfig{
-			RoleARN:          roleArn,
-			SourceProfile:    srcProfile,
-			CredentialSource: credentialSource,
-			ExternalID:       section.String(externalIDKey),
-			MFASerial:        section.String(mfaSerialKey),
-			RoleSessionName:  section.String(roleSessionNameKey),
+			RoleARN:              roleArn,
+			SourceProfile:        srcProfile,
+			CredentialSource:     credentialSource,
+			ExternalID:           section.String(externalIDKey),
+			MFASerial:            section.String(mfaSerialKey),
+			RoleSessionName:      section.String(roleSessionNameKey),
+			WebIdentityTokenFile: webIdentityTokenFile,
 		}
 	}
 
@@ -244,6 +262,11 @@ func (cfg *sharedConfig) setFromIniFile(profile string, file sharedConfigFile) e
 		cfg.EnableEndpointDiscovery = &v
 	}
 
+	// Retry mode
+	if v := section.String(retryModeKey); len(v) > 0 {
+		cfg.RetryMode = v
+	}
+
 	return nil
 }
 
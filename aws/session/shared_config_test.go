@@ -229,6 +229,16 @@ func TestLoadSharedConfigFromFile(t *testing.T) {
 				},
 			},
 		},
+		{
+			Profile: "web_identity",
+			Expected: sharedConfig{
+				AssumeRole: assumeRoleConfig{
+					RoleARN:              "web_identity_role_arn",
+					RoleSessionName:      "web_identity_session_name",
+					WebIdentityTokenFile: "testdata/wit.txt",
+				},
+			},
+		},
 		{
 			Profile: "does_not_exists",
 			Err:     SharedConfigProfileNotExistsError{Profile: "does_not_exists"},
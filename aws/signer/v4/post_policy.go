@@ -0,0 +1,98 @@
+package v4
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// postPolicyExpirationFormat is the ISO 8601 time format the expiration of a
+// POST policy document must be formatted with.
+const postPolicyExpirationFormat = "2006-01-02T15:04:05.000Z"
+
+// Form field names of the values included in a signed POST policy.
+const (
+	PostPolicyFieldPolicy        = "policy"
+	PostPolicyFieldAlgorithm     = "x-amz-algorithm"
+	PostPolicyFieldCredential    = "x-amz-credential"
+	PostPolicyFieldDate          = "x-amz-date"
+	PostPolicyFieldSecurityToken = "x-amz-security-token"
+	PostPolicyFieldSignature     = "x-amz-signature"
+)
+
+const logPostPolicyMsg = `DEBUG: POST Policy Signature:
+---[ POLICY DOCUMENT ]-------------------------------
+%s
+---[ STRING TO SIGN ]--------------------------------
+%s
+-----------------------------------------------------`
+
+type postPolicy struct {
+	Expiration string        `json:"expiration"`
+	Conditions []interface{} `json:"conditions"`
+}
+
+// PresignPOSTPolicy signs a POST policy document, such as used for browser
+// based uploads to Amazon S3, with the Signer's credentials. The policy will
+// expire after exp, relative to the signTime.
+//
+// The conditions are marshaled as JSON to form the policy document's
+// conditions. The Signature Version 4 conditions for the algorithm,
+// credential, date, and the security token if the credentials have one, are
+// added to the conditions provided.
+//
+// Returns the form fields that must be included in the POST request's form
+// for the policy to be used. The returned fields include the base64 encoded
+// policy document, the policy's signature, and the Signature Version 4
+// values. Values which the conditions require, other than the Signature
+// Version 4 values, must be added to the form by the caller.
+func (v4 Signer) PresignPOSTPolicy(conditions []interface{}, service, region string, exp time.Duration, signTime time.Time) (map[string]string, error) {
+	credValues, err := v4.Credentials.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	shortTime := signTime.UTC().Format(shortTimeFormat)
+	fields := map[string]string{
+		PostPolicyFieldAlgorithm: authHeaderPrefix,
+		PostPolicyFieldCredential: credValues.AccessKeyID + "/" + shortTime +
+			"/" + region + "/" + service + "/aws4_request",
+		PostPolicyFieldDate: signTime.UTC().Format(timeFormat),
+	}
+	if len(credValues.SessionToken) != 0 {
+		fields[PostPolicyFieldSecurityToken] = credValues.SessionToken
+	}
+
+	policy := postPolicy{
+		Expiration: signTime.Add(exp).UTC().Format(postPolicyExpirationFormat),
+		Conditions: append([]interface{}{}, conditions...),
+	}
+	for _, k := range []string{
+		PostPolicyFieldAlgorithm, PostPolicyFieldCredential,
+		PostPolicyFieldDate, PostPolicyFieldSecurityToken,
+	} {
+		if v, ok := fields[k]; ok {
+			policy.Conditions = append(policy.Conditions, map[string]string{k: v})
+		}
+	}
+
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(b)
+
+	key := deriveSigningKey(credValues.SecretAccessKey, shortTime, region, service)
+	fields[PostPolicyFieldPolicy] = encoded
+	fields[PostPolicyFieldSignature] = hex.EncodeToString(makeHmac(key, []byte(encoded)))
+
+	if v4.Logger != nil && v4.Debug.Matches(aws.LogDebugWithSigning) {
+		v4.Logger.Log(fmt.Sprintf(logPostPolicyMsg, string(b), encoded))
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,98 @@
+package v4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+func TestPresignPOSTPolicy(t *testing.T) {
+	signer := NewSigner(credentials.NewStaticCredentials("AKID", "SECRET", "SESSION"))
+	signTime := time.Date(2015, 12, 29, 0, 0, 0, 0, time.UTC)
+
+	conditions := []interface{}{
+		map[string]string{"bucket": "examplebucket"},
+		[]interface{}{"starts-with", "$key", "user/"},
+	}
+
+	fields, err := signer.PresignPOSTPolicy(conditions, "s3", "us-east-1", time.Hour, signTime)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expectFields := map[string]string{
+		"x-amz-algorithm":      "AWS4-HMAC-SHA256",
+		"x-amz-credential":     "AKID/20151229/us-east-1/s3/aws4_request",
+		"x-amz-date":           "20151229T000000Z",
+		"x-amz-security-token": "SESSION",
+	}
+	for k, v := range expectFields {
+		if e, a := v, fields[k]; e != a {
+			t.Errorf("expect %v %s, got %v", e, k, a)
+		}
+	}
+
+	b, err := base64.StdEncoding.DecodeString(fields["policy"])
+	if err != nil {
+		t.Fatalf("expect no error decoding policy, got %v", err)
+	}
+	var policy struct {
+		Expiration string
+		Conditions []interface{}
+	}
+	if err := json.Unmarshal(b, &policy); err != nil {
+		t.Fatalf("expect no error unmarshaling policy, got %v", err)
+	}
+
+	if e, a := "2015-12-29T01:00:00.000Z", policy.Expiration; e != a {
+		t.Errorf("expect %v expiration, got %v", e, a)
+	}
+	expectConditions := []interface{}{
+		map[string]interface{}{"bucket": "examplebucket"},
+		[]interface{}{"starts-with", "$key", "user/"},
+		map[string]interface{}{"x-amz-algorithm": "AWS4-HMAC-SHA256"},
+		map[string]interface{}{"x-amz-credential": "AKID/20151229/us-east-1/s3/aws4_request"},
+		map[string]interface{}{"x-amz-date": "20151229T000000Z"},
+		map[string]interface{}{"x-amz-security-token": "SESSION"},
+	}
+	if e, a := expectConditions, policy.Conditions; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v conditions, got %v", e, a)
+	}
+
+	key := []byte("AWS4SECRET")
+	for _, v := range []string{"20151229", "us-east-1", "s3", "aws4_request", fields["policy"]} {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(v))
+		key = h.Sum(nil)
+	}
+	if e, a := hex.EncodeToString(key), fields["x-amz-signature"]; e != a {
+		t.Errorf("expect %v signature, got %v", e, a)
+	}
+}
+
+func TestPresignPOSTPolicy_NoSessionToken(t *testing.T) {
+	signer := NewSigner(credentials.NewStaticCredentials("AKID", "SECRET", ""))
+
+	fields, err := signer.PresignPOSTPolicy(nil, "s3", "us-east-1", time.Hour, time.Now())
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if _, ok := fields["x-amz-security-token"]; ok {
+		t.Errorf("expect no security token field")
+	}
+}
+
+func TestPresignPOSTPolicy_CredentialsError(t *testing.T) {
+	signer := NewSigner(credentials.AnonymousCredentials)
+
+	if _, err := signer.PresignPOSTPolicy(nil, "s3", "us-east-1", time.Hour, time.Now()); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+}
@@ -660,15 +660,21 @@ func (ctx *signingCtx) buildStringToSign() {
 }
 
 func (ctx *signingCtx) buildSignature() {
-	secret := ctx.credValues.SecretAccessKey
-	date := makeHmac([]byte("AWS4"+secret), []byte(ctx.formattedShortTime))
-	region := makeHmac(date, []byte(ctx.Region))
-	service := makeHmac(region, []byte(ctx.ServiceName))
-	credentials := makeHmac(service, []byte("aws4_request"))
-	signature := makeHmac(credentials, []byte(ctx.stringToSign))
+	key := deriveSigningKey(ctx.credValues.SecretAccessKey,
+		ctx.formattedShortTime, ctx.Region, ctx.ServiceName)
+	signature := makeHmac(key, []byte(ctx.stringToSign))
 	ctx.signature = hex.EncodeToString(signature)
 }
 
+// deriveSigningKey returns the key used to sign values for the date, region,
+// and service of the credential scope.
+func deriveSigningKey(secret, shortTime, region, service string) []byte {
+	date := makeHmac([]byte("AWS4"+secret), []byte(shortTime))
+	regionKey := makeHmac(date, []byte(region))
+	serviceKey := makeHmac(regionKey, []byte(service))
+	return makeHmac(serviceKey, []byte("aws4_request"))
+}
+
 func (ctx *signingCtx) buildBodyDigest() error {
 	hash := ctx.Request.Header.Get("X-Amz-Content-Sha256")
 	if hash == "" {
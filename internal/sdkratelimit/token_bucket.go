@@ -0,0 +1,95 @@
+package sdkratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// A TokenBucket limits the rate tokens are taken at to the bucket's rate in
+// tokens per second. The bucket holds at most one second of tokens, and
+// starts empty.
+//
+// Tokens are taken before waiting, so the bucket's balance may go negative.
+// Waiters wait for the debt to be repaid, which orders concurrent waiters by
+// the time they took their tokens.
+//
+// It is safe to use a TokenBucket concurrently across goroutines.
+type TokenBucket struct {
+	m      sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+
+	// Now and Sleep are the bucket's clock, and are replaced by tests.
+	Now   func() time.Time
+	Sleep func(aws.Context, time.Duration) error
+}
+
+// NewTokenBucket returns a TokenBucket with the rate in tokens per second.
+// A rate of zero or less disables the limit.
+func NewTokenBucket(rate float64) *TokenBucket {
+	return &TokenBucket{
+		rate:  rate,
+		Now:   time.Now,
+		Sleep: aws.SleepWithContext,
+	}
+}
+
+// Rate returns the bucket's rate in tokens per second.
+func (b *TokenBucket) Rate() float64 {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	return b.rate
+}
+
+// SetRate sets the bucket's rate in tokens per second. The new rate applies
+// to waiters already waiting. A rate of zero or less disables the limit.
+func (b *TokenBucket) SetRate(rate float64) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.refill(b.Now())
+	b.rate = rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+}
+
+// Wait takes n tokens from the bucket, and blocks until the tokens taken are
+// repaid, or the context is canceled.
+func (b *TokenBucket) Wait(ctx aws.Context, n float64) error {
+	b.m.Lock()
+	if b.rate <= 0 {
+		b.m.Unlock()
+		return nil
+	}
+
+	b.refill(b.Now())
+	b.tokens -= n
+
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.m.Unlock()
+
+	if delay > 0 {
+		return b.Sleep(ctx, delay)
+	}
+	return nil
+}
+
+// refill adds the tokens accrued since the last refill, up to one second of
+// tokens at the current rate. Must be called with the lock held.
+func (b *TokenBucket) refill(now time.Time) {
+	if !b.last.IsZero() && b.rate > 0 {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+	}
+	b.last = now
+}
@@ -0,0 +1,133 @@
+package sdkratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/awstesting"
+)
+
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newTestTokenBucket(rate float64) (*TokenBucket, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	b := NewTokenBucket(rate)
+	b.Now = func() time.Time { return clock.now }
+	b.Sleep = func(ctx aws.Context, d time.Duration) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		clock.sleeps = append(clock.sleeps, d)
+		clock.now = clock.now.Add(d)
+		return nil
+	}
+	return b, clock
+}
+
+func TestTokenBucket_Wait(t *testing.T) {
+	cases := map[string]struct {
+		Rate   float64
+		Full   bool
+		Waits  []float64
+		Sleeps []time.Duration
+	}{
+		"empty": {
+			Rate:   100,
+			Waits:  []float64{50},
+			Sleeps: []time.Duration{500 * time.Millisecond},
+		},
+		"under rate": {
+			Rate:  100,
+			Full:  true,
+			Waits: []float64{50},
+		},
+		"over rate": {
+			Rate:   100,
+			Full:   true,
+			Waits:  []float64{50, 100},
+			Sleeps: []time.Duration{500 * time.Millisecond},
+		},
+		"larger than rate": {
+			Rate:   100,
+			Full:   true,
+			Waits:  []float64{350},
+			Sleeps: []time.Duration{2500 * time.Millisecond},
+		},
+		"unlimited": {
+			Rate:  0,
+			Waits: []float64{1000, 1000},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, clock := newTestTokenBucket(c.Rate)
+			if c.Full {
+				b.refill(clock.now)
+				b.tokens = c.Rate
+			}
+
+			for _, n := range c.Waits {
+				if err := b.Wait(aws.BackgroundContext(), n); err != nil {
+					t.Fatalf("expect no error, got %v", err)
+				}
+			}
+
+			if e, a := len(c.Sleeps), len(clock.sleeps); e != a {
+				t.Fatalf("expect %v sleeps, got %v, %v", e, a, clock.sleeps)
+			}
+			for i := range c.Sleeps {
+				if e, a := c.Sleeps[i], clock.sleeps[i]; e != a {
+					t.Errorf("%d, expect %v, got %v", i, e, a)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenBucket_SetRate(t *testing.T) {
+	b, clock := newTestTokenBucket(100)
+	b.refill(clock.now)
+	b.tokens = 100
+
+	// A second of tokens is kept when the rate is lowered.
+	b.SetRate(50)
+	if e, a := float64(50), b.Rate(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := float64(50), b.tokens; e != a {
+		t.Errorf("expect %v tokens, got %v", e, a)
+	}
+
+	if err := b.Wait(aws.BackgroundContext(), 100); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := []time.Duration{time.Second}, clock.sleeps; len(a) != 1 || e[0] != a[0] {
+		t.Fatalf("expect %v, got %v", e, a)
+	}
+
+	b.SetRate(0)
+	if err := b.Wait(aws.BackgroundContext(), 1000); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 1, len(clock.sleeps); e != a {
+		t.Errorf("expect %v sleeps, got %v", e, a)
+	}
+}
+
+func TestTokenBucket_Canceled(t *testing.T) {
+	b, _ := newTestTokenBucket(10)
+
+	ctx := &awstesting.FakeContext{DoneCh: make(chan struct{})}
+	ctx.Error = fmt.Errorf("context canceled")
+	close(ctx.DoneCh)
+
+	if err := b.Wait(ctx, 100); err == nil {
+		t.Errorf("expect error, got none")
+	}
+}
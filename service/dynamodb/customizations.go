@@ -24,6 +24,15 @@ func (d retryer) RetryRules(r *request.Request) time.Duration {
 	return delay * time.Millisecond
 }
 
+// adaptiveRetryer is an AdaptiveRetryer using DynamoDB's backoff.
+type adaptiveRetryer struct {
+	*client.AdaptiveRetryer
+}
+
+func (d adaptiveRetryer) RetryRules(r *request.Request) time.Duration {
+	return retryer{}.RetryRules(r)
+}
+
 func init() {
 	initClient = func(c *client.Client) {
 		if c.Config.Retryer == nil {
@@ -43,6 +52,15 @@ func setCustomRetryer(c *client.Client) {
 		maxRetries = 10
 	}
 
+	if _, ok := c.Retryer.(*client.AdaptiveRetryer); ok {
+		// Replace the adaptive retryer selected by the config's RetryMode
+		// with one using DynamoDB's default max retries and backoff.
+		adaptive := adaptiveRetryer{client.NewAdaptiveRetryer(maxRetries)}
+		adaptive.AddHandlers(&c.Handlers)
+		c.Retryer = adaptive
+		return
+	}
+
 	c.Retryer = retryer{
 		DefaultRetryer: client.DefaultRetryer{
 			NumMaxRetries: maxRetries,
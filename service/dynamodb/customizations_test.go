@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -77,6 +78,53 @@ func TestCustomRetry_FromConfig(t *testing.T) {
 	}
 }
 
+func TestCustomRetry_AdaptiveRetryMode(t *testing.T) {
+	d := dynamodb.New(unit.Session, &aws.Config{
+		RetryMode: aws.RetryModeAdaptive,
+	})
+
+	if _, ok := d.Retryer.(*client.AdaptiveRetryer); ok {
+		t.Errorf("expect retryer not to be the client's AdaptiveRetryer")
+	}
+
+	if e, a := 10, d.MaxRetries(); e != a {
+		t.Errorf("expect %d max retries, got %d", e, a)
+	}
+
+	r := &request.Request{RetryCount: 2}
+	if e, a := 200*time.Millisecond, d.Retryer.RetryRules(r); e != a {
+		t.Errorf("expect %v retry delay, got %v", e, a)
+	}
+}
+
+func TestCustomRetry_AdaptiveRetryModeNotShared(t *testing.T) {
+	cfg := &aws.Config{
+		RetryMode:  aws.RetryModeAdaptive,
+		MaxRetries: aws.Int(2),
+	}
+	d1 := dynamodb.New(unit.Session, cfg)
+	d2 := dynamodb.New(unit.Session, cfg)
+
+	if d1.Retryer == d2.Retryer {
+		t.Errorf("expect each client to have its own retryer")
+	}
+	if e, a := 2, d1.MaxRetries(); e != a {
+		t.Errorf("expect %d max retries, got %d", e, a)
+	}
+}
+
+func TestCustomRetry_SharedAdaptiveRetryer(t *testing.T) {
+	shared := client.NewAdaptiveRetryer(3)
+	d := dynamodb.New(unit.Session, request.WithRetryer(aws.NewConfig(), shared))
+
+	if e, a := request.Retryer(shared), d.Retryer; e != a {
+		t.Errorf("expect the shared retryer to be used, got %T", a)
+	}
+	if e, a := 3, shared.NumMaxRetries; e != a {
+		t.Errorf("expect %d max retries, got %d", e, a)
+	}
+}
+
 func TestValidateCRC32NoHeaderSkip(t *testing.T) {
 	req := mockCRCResponse(db, 200, "{}", "")
 	if req.Error != nil {
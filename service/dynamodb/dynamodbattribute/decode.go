@@ -170,6 +170,9 @@ func (d *Decoder) decode(av *dynamodb.AttributeValue, v reflect.Value, fieldTag
 	if u != nil {
 		return u.UnmarshalDynamoDBAttributeValue(av)
 	}
+	if fn := d.TypeConverters.decoder(v.Type()); fn != nil {
+		return fn(av, v)
+	}
 
 	switch {
 	case len(av.B) != 0:
@@ -419,6 +422,12 @@ func (d *Decoder) decodeNumberSet(ns []*string, v reflect.Value) error {
 		if u != nil {
 			return u.UnmarshalDynamoDBAttributeValue(&dynamodb.AttributeValue{NS: ns})
 		}
+		if fn := d.TypeConverters.decoder(elem.Type()); fn != nil {
+			if err := fn(&dynamodb.AttributeValue{N: ns[i]}, elem); err != nil {
+				return err
+			}
+			continue
+		}
 		if err := d.decodeNumber(ns[i], elem, tag{}); err != nil {
 			return err
 		}
@@ -592,6 +601,12 @@ func (d *Decoder) decodeStringSet(ss []*string, v reflect.Value) error {
 		if u != nil {
 			return u.UnmarshalDynamoDBAttributeValue(&dynamodb.AttributeValue{SS: ss})
 		}
+		if fn := d.TypeConverters.decoder(elem.Type()); fn != nil {
+			if err := fn(&dynamodb.AttributeValue{S: ss[i]}, elem); err != nil {
+				return err
+			}
+			continue
+		}
 		if err := d.decodeString(ss[i], elem, tag{}); err != nil {
 			return err
 		}
@@ -122,6 +122,11 @@ type Marshaler interface {
 //		// January 1, 0001 UTC, and January 1, 0001 UTC.
 //		Field time.Time `dynamodbav:",unixtime"`
 //
+//		// Field is the item's version number, used for optimistic locking
+//		// with dynamodb.WithVersionCheck. Only valid for integer types.
+//		// See VersionAttribute.
+//		Field int64 `dynamodbav:",version"`
+//
 // The omitempty tag is only used during Marshaling and is ignored for
 // Unmarshal. Any zero value or a value when marshaled results in a
 // AttributeValue NULL will be added to AttributeValue Maps during struct
@@ -194,6 +199,12 @@ type MarshalOptions struct {
 	// Note that values provided with a custom TagKey must also be supported
 	// by the (un)marshalers in this package.
 	TagKey string
+
+	// TypeConverters, if set, is consulted for converters to marshal and
+	// unmarshal Go value types which do not implement the Marshaler or
+	// Unmarshaler interfaces. Use NewTypeConverterRegistry to create a
+	// registry with converters for common types.
+	TypeConverters *TypeConverterRegistry
 }
 
 // An Encoder provides marshaling Go value types to AttributeValues.
@@ -267,6 +278,9 @@ func (e *Encoder) encode(av *dynamodb.AttributeValue, v reflect.Value, fieldTag
 		if used, err := tryMarshaler(av, v); used {
 			return err
 		}
+		if fn := e.TypeConverters.encoder(v.Type()); fn != nil {
+			return fn(av, v)
+		}
 	}
 
 	switch v.Kind() {
@@ -316,6 +330,11 @@ func (e *Encoder) encodeStruct(av *dynamodb.AttributeValue, v reflect.Value, fie
 		if !found {
 			continue
 		}
+		if f.Version && !isVersionKind(fv.Kind()) {
+			return &InvalidMarshalError{
+				msg: fmt.Sprintf("version field %s must be an integer, got %s", f.Name, fv.Type()),
+			}
+		}
 		elem := &dynamodb.AttributeValue{}
 		err := e.encode(elem, fv, f.tag)
 		if err != nil {
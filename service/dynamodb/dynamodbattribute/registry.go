@@ -0,0 +1,279 @@
+package dynamodbattribute
+
+import (
+	"encoding"
+	"math/big"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// A TypeConverter provides custom marshaling of a Go value type to and from
+// AttributeValues, for types which cannot implement the Marshaler and
+// Unmarshaler interfaces, such as types defined in other packages.
+//
+// Encode is called with the value to marshal, and the AttributeValue to set.
+// Decode is called with the AttributeValue to unmarshal, and the settable
+// value to set. Either may be nil, in which case the value is marshaled or
+// unmarshaled as if the converter was not registered.
+type TypeConverter struct {
+	Encode func(av *dynamodb.AttributeValue, v reflect.Value) error
+	Decode func(av *dynamodb.AttributeValue, v reflect.Value) error
+}
+
+// A TypeConverterRegistry maps Go value types to the TypeConverters used to
+// marshal and unmarshal them. Set the registry as the TypeConverters of an
+// Encoder or Decoder's MarshalOptions to use it.
+//
+// A converter registered for an interface type is used for all types which
+// implement the interface, either directly or with a pointer receiver.
+// Converters registered for a type take precedence over converters
+// registered for an interface the type implements. Types convertible to
+// time.Time are not matched by interface converters, so time.Time values
+// continue to be marshaled with their existing formats, and the `unixtime`
+// struct tag.
+//
+// The registry is consulted for values which are not Marshalers or
+// Unmarshalers, before the value's kind is marshaled or unmarshaled. Struct
+// tags such as `string` are not applied to values marshaled by a converter.
+//
+// It is safe to use a TypeConverterRegistry concurrently across goroutines.
+//
+// Example:
+//     reg := dynamodbattribute.NewTypeConverterRegistry()
+//     reg.Register(reflect.TypeOf(decimal.Decimal{}), dynamodbattribute.TypeConverter{
+//         Encode: func(av *dynamodb.AttributeValue, v reflect.Value) error {
+//             av.N = aws.String(v.Interface().(decimal.Decimal).String())
+//             return nil
+//         },
+//         Decode: func(av *dynamodb.AttributeValue, v reflect.Value) error {
+//             d, err := decimal.NewFromString(aws.StringValue(av.N))
+//             if err != nil {
+//                 return err
+//             }
+//             v.Set(reflect.ValueOf(d))
+//             return nil
+//         },
+//     })
+//
+//     encoder := dynamodbattribute.NewEncoder(func(e *dynamodbattribute.Encoder) {
+//         e.TypeConverters = reg
+//     })
+type TypeConverterRegistry struct {
+	m          sync.RWMutex
+	types      map[reflect.Type]TypeConverter
+	interfaces []reflect.Type
+}
+
+// NewTypeConverterRegistry returns a TypeConverterRegistry with converters
+// registered for the following types.
+//
+//		time.Duration,            AV Number (N) of nanoseconds. Also
+//		                          unmarshals AV String (S) duration strings
+//		                          such as "1h30m".
+//		big.Int,                  AV Number (N)
+//		big.Float,                AV Number (N)
+//		encoding.TextMarshaler,   AV String (S). Types must also implement
+//		                          encoding.TextUnmarshaler to be unmarshaled.
+//
+// Use Register to add converters to the registry, or replace the converters
+// registered.
+func NewTypeConverterRegistry() *TypeConverterRegistry {
+	r := &TypeConverterRegistry{
+		types: map[reflect.Type]TypeConverter{},
+	}
+
+	r.Register(durationType, TypeConverter{Encode: encodeDuration, Decode: decodeDuration})
+	r.Register(bigIntType, TypeConverter{Encode: encodeBigInt, Decode: decodeBigInt})
+	r.Register(bigFloatType, TypeConverter{Encode: encodeBigFloat, Decode: decodeBigFloat})
+	r.Register(textMarshalerType, TypeConverter{Encode: encodeText, Decode: decodeText})
+
+	return r
+}
+
+// Register registers the converter for the type, replacing any converter
+// already registered for the type. The type must not be a pointer type,
+// values are dereferenced before the registry is consulted.
+func (r *TypeConverterRegistry) Register(t reflect.Type, c TypeConverter) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.types == nil {
+		r.types = map[reflect.Type]TypeConverter{}
+	}
+	if _, ok := r.types[t]; !ok && t.Kind() == reflect.Interface {
+		r.interfaces = append(r.interfaces, t)
+	}
+	r.types[t] = c
+}
+
+// lookup returns the converter registered for the type, or for an interface
+// the type implements.
+func (r *TypeConverterRegistry) lookup(t reflect.Type) (TypeConverter, bool) {
+	if r == nil || t.Kind() == reflect.Interface {
+		return TypeConverter{}, false
+	}
+
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if c, ok := r.types[t]; ok {
+		return c, true
+	}
+
+	if t.ConvertibleTo(timeType) {
+		return TypeConverter{}, false
+	}
+	pt := reflect.PtrTo(t)
+	for _, it := range r.interfaces {
+		if t.Implements(it) || pt.Implements(it) {
+			return r.types[it], true
+		}
+	}
+
+	return TypeConverter{}, false
+}
+
+// encoder returns the encode func of the converter for the type, if any.
+func (r *TypeConverterRegistry) encoder(t reflect.Type) func(*dynamodb.AttributeValue, reflect.Value) error {
+	c, _ := r.lookup(t)
+	return c.Encode
+}
+
+// decoder returns the decode func of the converter for the type, if any.
+func (r *TypeConverterRegistry) decoder(t reflect.Type) func(*dynamodb.AttributeValue, reflect.Value) error {
+	c, _ := r.lookup(t)
+	return c.Decode
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+var bigIntType = reflect.TypeOf(big.Int{})
+var bigFloatType = reflect.TypeOf(big.Float{})
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// addressable returns an addressable copy of the value if it is not
+// addressable, so methods with pointer receivers can be called.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Elem()
+}
+
+func encodeDuration(av *dynamodb.AttributeValue, v reflect.Value) error {
+	s := encodeInt(v.Int())
+	av.N = &s
+	return nil
+}
+
+func decodeDuration(av *dynamodb.AttributeValue, v reflect.Value) error {
+	switch {
+	case av.N != nil:
+		n, err := strconv.ParseInt(*av.N, 10, 64)
+		if err != nil {
+			return &UnmarshalError{Err: err, Value: *av.N, Type: v.Type()}
+		}
+		v.SetInt(n)
+	case av.S != nil:
+		d, err := time.ParseDuration(*av.S)
+		if err != nil {
+			return &UnmarshalError{Err: err, Value: *av.S, Type: v.Type()}
+		}
+		v.SetInt(int64(d))
+	default:
+		return &UnmarshalTypeError{Value: "number", Type: v.Type()}
+	}
+
+	return nil
+}
+
+func encodeBigInt(av *dynamodb.AttributeValue, v reflect.Value) error {
+	s := addressable(v).Addr().Interface().(*big.Int).String()
+	av.N = &s
+	return nil
+}
+
+func decodeBigInt(av *dynamodb.AttributeValue, v reflect.Value) error {
+	s := av.N
+	if s == nil {
+		s = av.S
+	}
+	if s == nil {
+		return &UnmarshalTypeError{Value: "number", Type: v.Type()}
+	}
+
+	i, ok := new(big.Int).SetString(*s, 10)
+	if !ok {
+		return &UnmarshalTypeError{Value: "number " + *s, Type: v.Type()}
+	}
+	v.Set(reflect.ValueOf(i).Elem())
+
+	return nil
+}
+
+func encodeBigFloat(av *dynamodb.AttributeValue, v reflect.Value) error {
+	s := addressable(v).Addr().Interface().(*big.Float).Text('g', -1)
+	av.N = &s
+	return nil
+}
+
+func decodeBigFloat(av *dynamodb.AttributeValue, v reflect.Value) error {
+	s := av.N
+	if s == nil {
+		s = av.S
+	}
+	if s == nil {
+		return &UnmarshalTypeError{Value: "number", Type: v.Type()}
+	}
+
+	// DynamoDB numbers have up to 38 digits of precision, which requires
+	// 127 bits of mantissa.
+	f, _, err := big.ParseFloat(*s, 10, 128, big.ToNearestEven)
+	if err != nil {
+		return &UnmarshalError{Err: err, Value: *s, Type: v.Type()}
+	}
+	v.Set(reflect.ValueOf(f).Elem())
+
+	return nil
+}
+
+func encodeText(av *dynamodb.AttributeValue, v reflect.Value) error {
+	var m encoding.TextMarshaler
+	if v.Type().Implements(textMarshalerType) {
+		m = v.Interface().(encoding.TextMarshaler)
+	} else {
+		m = addressable(v).Addr().Interface().(encoding.TextMarshaler)
+	}
+
+	b, err := m.MarshalText()
+	if err != nil {
+		return err
+	}
+	s := string(b)
+	av.S = &s
+
+	return nil
+}
+
+func decodeText(av *dynamodb.AttributeValue, v reflect.Value) error {
+	if av.S == nil {
+		return &UnmarshalTypeError{Value: "string", Type: v.Type()}
+	}
+	if !reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		return &UnmarshalTypeError{Value: "string", Type: v.Type()}
+	}
+
+	u := v.Addr().Interface().(encoding.TextUnmarshaler)
+	if err := u.UnmarshalText([]byte(*av.S)); err != nil {
+		return &UnmarshalError{Err: err, Value: *av.S, Type: v.Type()}
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package dynamodbattribute
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// registryPoint is a type which does not implement the Marshaler interfaces.
+type registryPoint struct {
+	X, Y int
+}
+
+var registryPointConverter = TypeConverter{
+	Encode: func(av *dynamodb.AttributeValue, v reflect.Value) error {
+		p := v.Interface().(registryPoint)
+		av.S = aws.String(fmt.Sprintf("%d,%d", p.X, p.Y))
+		return nil
+	},
+	Decode: func(av *dynamodb.AttributeValue, v reflect.Value) error {
+		var p registryPoint
+		if _, err := fmt.Sscanf(aws.StringValue(av.S), "%d,%d", &p.X, &p.Y); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(p))
+		return nil
+	},
+}
+
+type registryRecord struct {
+	Duration time.Duration
+	Int      big.Int
+	IntPtr   *big.Int
+	Float    *big.Float
+	IP       net.IP
+	Time     time.Time
+	Unix     time.Time `dynamodbav:",unixtime"`
+	Point    registryPoint
+	Points   []registryPoint
+	Ints     []*big.Int `dynamodbav:",numberset"`
+}
+
+func newRegistryOptions() MarshalOptions {
+	reg := NewTypeConverterRegistry()
+	reg.Register(reflect.TypeOf(registryPoint{}), registryPointConverter)
+
+	return MarshalOptions{SupportJSONTags: true, TypeConverters: reg}
+}
+
+func TestTypeConverterRegistry(t *testing.T) {
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	bigFloat, _, _ := big.ParseFloat("1234567890.0123456789", 10, 128, big.ToNearestEven)
+
+	in := registryRecord{
+		Duration: 90 * time.Minute,
+		Int:      *bigInt,
+		IntPtr:   big.NewInt(-42),
+		Float:    bigFloat,
+		IP:       net.ParseIP("10.0.0.1"),
+		Time:     testDate,
+		Unix:     time.Unix(1500000000, 0),
+		Point:    registryPoint{X: 1, Y: 2},
+		Points:   []registryPoint{{X: 3, Y: 4}},
+		Ints:     []*big.Int{big.NewInt(1), bigInt},
+	}
+
+	expect := map[string]*dynamodb.AttributeValue{
+		"Duration": {N: aws.String("5400000000000")},
+		"Int":      {N: aws.String("123456789012345678901234567890")},
+		"IntPtr":   {N: aws.String("-42")},
+		"Float":    {N: aws.String("1.2345678900123456789e+09")},
+		"IP":       {S: aws.String("10.0.0.1")},
+		"Time":     {S: aws.String("2016-05-03T17:06:26.209072Z")},
+		"Unix":     {N: aws.String("1500000000")},
+		"Point":    {S: aws.String("1,2")},
+		"Points":   {L: []*dynamodb.AttributeValue{{S: aws.String("3,4")}}},
+		"Ints":     {NS: []*string{aws.String("1"), aws.String("123456789012345678901234567890")}},
+	}
+
+	encoder := NewEncoder(func(e *Encoder) {
+		e.MarshalOptions = newRegistryOptions()
+	})
+	av, err := encoder.Encode(in)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := expect, av.M; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	var out registryRecord
+	decoder := NewDecoder(func(d *Decoder) {
+		d.MarshalOptions = newRegistryOptions()
+	})
+	if err := decoder.Decode(av, &out); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := in.Duration, out.Duration; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := in.Int.String(), out.Int.String(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := in.IntPtr.String(), out.IntPtr.String(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := in.Float.Text('g', -1), out.Float.Text('g', -1); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if !in.IP.Equal(out.IP) {
+		t.Errorf("expect %v, got %v", in.IP, out.IP)
+	}
+	if !in.Time.Equal(out.Time) {
+		t.Errorf("expect %v, got %v", in.Time, out.Time)
+	}
+	if !in.Unix.Equal(out.Unix) {
+		t.Errorf("expect %v, got %v", in.Unix, out.Unix)
+	}
+	if e, a := in.Point, out.Point; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := in.Points, out.Points; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := len(in.Ints), len(out.Ints); e != a {
+		t.Fatalf("expect %v ints, got %v", e, a)
+	}
+	for i := range in.Ints {
+		if e, a := in.Ints[i].String(), out.Ints[i].String(); e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+}
+
+func TestTypeConverterRegistry_Decode(t *testing.T) {
+	cases := map[string]struct {
+		In     *dynamodb.AttributeValue
+		Out    interface{}
+		Expect interface{}
+		Err    bool
+	}{
+		"duration string": {
+			In:     &dynamodb.AttributeValue{S: aws.String("1h30m")},
+			Out:    new(time.Duration),
+			Expect: 90 * time.Minute,
+		},
+		"invalid duration": {
+			In:  &dynamodb.AttributeValue{S: aws.String("abc")},
+			Out: new(time.Duration),
+			Err: true,
+		},
+		"invalid big int": {
+			In:  &dynamodb.AttributeValue{N: aws.String("1.5")},
+			Out: new(big.Int),
+			Err: true,
+		},
+		"big int from binary": {
+			In:  &dynamodb.AttributeValue{B: []byte{1}},
+			Out: new(big.Int),
+			Err: true,
+		},
+		"invalid text": {
+			In:  &dynamodb.AttributeValue{S: aws.String("not an ip")},
+			Out: new(net.IP),
+			Err: true,
+		},
+		"interface": {
+			In:     &dynamodb.AttributeValue{N: aws.String("10")},
+			Out:    new(interface{}),
+			Expect: 10.0,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := NewDecoder(func(d *Decoder) {
+				d.TypeConverters = NewTypeConverterRegistry()
+			})
+			err := d.Decode(c.In, c.Out)
+			if c.Err {
+				if err == nil {
+					t.Fatalf("expect error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.Expect, reflect.ValueOf(c.Out).Elem().Interface(); !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestTypeConverterRegistry_Precedence(t *testing.T) {
+	reg := NewTypeConverterRegistry()
+	reg.Register(reflect.TypeOf(net.IP{}), TypeConverter{
+		Encode: func(av *dynamodb.AttributeValue, v reflect.Value) error {
+			av.S = aws.String("ip:" + v.Interface().(net.IP).String())
+			return nil
+		},
+	})
+
+	e := NewEncoder(func(e *Encoder) {
+		e.TypeConverters = reg
+	})
+
+	av, err := e.Encode(net.ParseIP("10.0.0.1"))
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "ip:10.0.0.1", aws.StringValue(av.S); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	// Marshalers take precedence over the registry.
+	reg.Register(reflect.TypeOf(UnixTime{}), registryPointConverter)
+	av, err = e.Encode(UnixTime(time.Unix(10, 0)))
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "10", aws.StringValue(av.N); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestTypeConverterRegistry_Unset(t *testing.T) {
+	// Without a registry values are marshaled by their kind.
+	av, err := Marshal(net.ParseIP("10.0.0.1").To4())
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := []byte{10, 0, 0, 1}, av.B; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
@@ -13,6 +13,7 @@ type tag struct {
 	AsString                     bool
 	AsBinSet, AsNumSet, AsStrSet bool
 	AsUnixTime                   bool
+	Version                      bool
 }
 
 func (t *tag) parseAVTag(structTag reflect.StructTag) {
@@ -63,6 +64,8 @@ func (t *tag) parseTagStr(tagStr string) {
 			t.AsStrSet = true
 		case "unixtime":
 			t.AsUnixTime = true
+		case "version":
+			t.Version = true
 		}
 	}
 }
@@ -0,0 +1,91 @@
+package dynamodbattribute
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// VersionAttribute returns the attribute name and value of the struct's
+// version field, the field tagged with the `version` struct tag option. Use
+// the returned name and version with dynamodb.WithVersionCheck to write the
+// struct's item with optimistic locking.
+//
+// An error is returned if the value is not a struct, or the struct does not
+// have a version field.
+//
+// Example:
+//     type Order struct {
+//         ID      string
+//         Version int64 `dynamodbav:",version"`
+//     }
+//
+//     item, err := dynamodbattribute.MarshalMap(order)
+//     if err != nil {
+//         return err
+//     }
+//     name, version, err := dynamodbattribute.VersionAttribute(order)
+//     if err != nil {
+//         return err
+//     }
+//     _, err = svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+//         TableName: aws.String("orders"),
+//         Item:      item,
+//     }, dynamodb.WithVersionCheck(name, version))
+func VersionAttribute(in interface{}) (name string, version int64, err error) {
+	return NewEncoder().VersionAttribute(in)
+}
+
+// VersionAttribute returns the attribute name and value of the struct's
+// version field, using the Encoder's MarshalOptions to resolve the field's
+// attribute name.
+func (e *Encoder) VersionAttribute(in interface{}) (name string, version int64, err error) {
+	v := reflect.ValueOf(in)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", 0, &InvalidMarshalError{
+			msg: fmt.Sprintf("version attribute requires a struct, got %T", in),
+		}
+	}
+
+	for _, f := range unionStructFields(v.Type(), e.MarshalOptions) {
+		if !f.Version {
+			continue
+		}
+
+		found := true
+		fv := fieldByIndex(v, f.Index, func(v *reflect.Value) bool {
+			found = false
+			return false // to break the loop.
+		})
+		if !found {
+			return f.Name, 0, nil
+		}
+
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return f.Name, fv.Int(), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return f.Name, int64(fv.Uint()), nil
+		default:
+			return "", 0, &InvalidMarshalError{
+				msg: fmt.Sprintf("version field %s must be an integer, got %s", f.Name, fv.Type()),
+			}
+		}
+	}
+
+	return "", 0, &InvalidMarshalError{
+		msg: fmt.Sprintf("%T has no version field", in),
+	}
+}
+
+// isVersionKind returns if the kind is valid for version fields.
+func isVersionKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package dynamodbattribute
+
+import (
+	"testing"
+)
+
+type versionedRecord struct {
+	ID      string
+	Version int64 `dynamodbav:"ver,version"`
+}
+
+type embeddedVersion struct {
+	Revision uint32 `json:",version"`
+}
+
+type embeddedVersionedRecord struct {
+	*embeddedVersion
+	ID string
+}
+
+func TestVersionAttribute(t *testing.T) {
+	cases := map[string]struct {
+		In            interface{}
+		Options       MarshalOptions
+		ExpectName    string
+		ExpectVersion int64
+		Err           bool
+	}{
+		"tagged field": {
+			In:            versionedRecord{ID: "1", Version: 3},
+			ExpectName:    "ver",
+			ExpectVersion: 3,
+		},
+		"pointer": {
+			In:            &versionedRecord{ID: "1", Version: 4},
+			ExpectName:    "ver",
+			ExpectVersion: 4,
+		},
+		"embedded json tag": {
+			In:            embeddedVersionedRecord{embeddedVersion: &embeddedVersion{Revision: 2}},
+			Options:       MarshalOptions{SupportJSONTags: true},
+			ExpectName:    "Revision",
+			ExpectVersion: 2,
+		},
+		"nil embedded": {
+			In:         embeddedVersionedRecord{},
+			Options:    MarshalOptions{SupportJSONTags: true},
+			ExpectName: "Revision",
+		},
+		"no version field": {
+			In:  struct{ ID string }{},
+			Err: true,
+		},
+		"not a struct": {
+			In:  "abc",
+			Err: true,
+		},
+		"not an integer": {
+			In: struct {
+				Version string `dynamodbav:",version"`
+			}{},
+			Err: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := NewEncoder(func(e *Encoder) {
+				e.MarshalOptions = c.Options
+			})
+			name, version, err := e.VersionAttribute(c.In)
+			if c.Err {
+				if err == nil {
+					t.Fatalf("expect error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.ExpectName, name; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := c.ExpectVersion, version; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestMarshalVersionField(t *testing.T) {
+	av, err := Marshal(versionedRecord{ID: "1", Version: 3})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "3", *av.M["ver"].N; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	_, err = Marshal(struct {
+		Version float64 `dynamodbav:",version"`
+	}{})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if _, ok := err.(*InvalidMarshalError); !ok {
+		t.Errorf("expect *InvalidMarshalError, got %T", err)
+	}
+}
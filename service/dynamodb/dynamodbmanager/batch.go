@@ -0,0 +1,175 @@
+package dynamodbmanager
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	// MaxBatchWriteSize is the maximum number of write requests a single
+	// BatchWriteItem call can contain.
+	MaxBatchWriteSize = 25
+
+	// MaxBatchGetSize is the maximum number of keys a single BatchGetItem
+	// call can contain.
+	MaxBatchGetSize = 100
+
+	// DefaultBatchConcurrency is the default number of batches sent
+	// concurrently by the BatchWriter and BatchGetter.
+	DefaultBatchConcurrency = 5
+
+	// ErrCodeUnprocessedItem is the error code of items which were still
+	// unprocessed by the service after the batch was retried the maximum
+	// number of times.
+	ErrCodeUnprocessedItem = "UnprocessedItem"
+
+	// defaultBatchMaxRetries is the number of times unprocessed items are
+	// retried if the request has no Retryer.
+	defaultBatchMaxRetries = 3
+)
+
+// Error will contain the original error, table name, and write request or
+// key of the item which failed during batch operations.
+type Error struct {
+	OrigErr   error
+	TableName *string
+
+	// The write request which failed, for BatchWriter errors.
+	WriteRequest *dynamodb.WriteRequest
+
+	// The key of the item which failed, for BatchGetter errors.
+	Key map[string]*dynamodb.AttributeValue
+}
+
+func (err *Error) Error() string {
+	origErr := ""
+	if err.OrigErr != nil {
+		origErr = ":\n" + err.OrigErr.Error()
+	}
+
+	item := err.Key
+	if r := err.WriteRequest; r != nil {
+		switch {
+		case r.PutRequest != nil:
+			item = r.PutRequest.Item
+		case r.DeleteRequest != nil:
+			item = r.DeleteRequest.Key
+		}
+	}
+
+	return fmt.Sprintf("failed to perform batch operation on item %s in %q%s",
+		awsutil.Prettify(item),
+		aws.StringValue(err.TableName),
+		origErr,
+	)
+}
+
+// newBatchError returns the awserr.BatchedErrors returned by batch operations
+// which failed to process some items, with the *Error of each item which
+// failed as its original errors.
+func newBatchError(code, message string, errs []Error) awserr.BatchedErrors {
+	origErrs := make([]error, 0, len(errs))
+	for i := range errs {
+		origErrs = append(origErrs, &errs[i])
+	}
+	return awserr.NewBatchError(code, message, origErrs)
+}
+
+// batchRunner runs batch funcs concurrently, and collects their errors.
+type batchRunner struct {
+	ctx     aws.Context
+	wg      sync.WaitGroup
+	ch      chan func() []Error
+	m       sync.Mutex
+	errs    []Error
+	ctxDone bool
+}
+
+func newBatchRunner(ctx aws.Context, concurrency int) *batchRunner {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	r := &batchRunner{ctx: ctx, ch: make(chan func() []Error)}
+	for i := 0; i < concurrency; i++ {
+		r.wg.Add(1)
+		go r.run()
+	}
+
+	return r
+}
+
+func (r *batchRunner) run() {
+	defer r.wg.Done()
+	for fn := range r.ch {
+		r.addErrs(fn())
+	}
+}
+
+func (r *batchRunner) addErrs(errs []Error) {
+	if len(errs) == 0 {
+		return
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.errs = append(r.errs, errs...)
+}
+
+// send queues the batch func, returning false if the context is done before
+// the func is queued.
+func (r *batchRunner) send(fn func() []Error) bool {
+	select {
+	case r.ch <- fn:
+		return true
+	case <-r.ctx.Done():
+		if !r.ctxDone {
+			r.ctxDone = true
+			r.addErrs([]Error{{OrigErr: r.ctx.Err()}})
+		}
+		return false
+	}
+}
+
+// wait waits for the queued batches to complete, returning their errors.
+func (r *batchRunner) wait() []Error {
+	close(r.ch)
+	r.wg.Wait()
+	return r.errs
+}
+
+// batchRetryer returns the retryer unprocessed items of the request are
+// retried with.
+func batchRetryer(req *request.Request, retryer request.Retryer) request.Retryer {
+	if retryer != nil {
+		return retryer
+	}
+	if req.Retryer != nil {
+		return req.Retryer
+	}
+	return client.DefaultRetryer{NumMaxRetries: defaultBatchMaxRetries}
+}
+
+// retryUnprocessed waits for the retryer's delay before the request's
+// unprocessed items are retried. False is returned if the items should not be
+// retried, because the maximum number of retries has been reached, or the
+// context is done.
+func retryUnprocessed(ctx aws.Context, req *request.Request, retryer request.Retryer, attempt int) (bool, error) {
+	if attempt >= retryer.MaxRetries() {
+		return false, nil
+	}
+
+	req.RetryCount = attempt
+	if err := aws.SleepWithContext(ctx, retryer.RetryRules(req)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
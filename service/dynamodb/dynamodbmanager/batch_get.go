@@ -0,0 +1,246 @@
+package dynamodbmanager
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// BatchGetIterator is an interface that uses the scanner pattern to iterate
+// through the keys of the items of a batch get.
+type BatchGetIterator interface {
+	Next() bool
+	Err() error
+	GetKey() BatchGetKey
+}
+
+// BatchGetKey is the key of an item in a table.
+type BatchGetKey struct {
+	TableName *string
+	Key       map[string]*dynamodb.AttributeValue
+}
+
+// KeysIterator implements the BatchGetIterator interface and allows for
+// batched gets of a list of keys.
+type KeysIterator struct {
+	Keys  []BatchGetKey
+	index int
+	inc   bool
+}
+
+// Next will increment the default iterator's index and ensure that there
+// is another key to iterate to.
+func (iter *KeysIterator) Next() bool {
+	if iter.inc {
+		iter.index++
+	} else {
+		iter.inc = true
+	}
+	return iter.index < len(iter.Keys)
+}
+
+// Err will return an error. Since this is just used to satisfy the
+// BatchGetIterator interface this will only return nil.
+func (iter *KeysIterator) Err() error {
+	return nil
+}
+
+// GetKey will return the BatchGetKey at the current index.
+func (iter *KeysIterator) GetKey() BatchGetKey {
+	return iter.Keys[iter.index]
+}
+
+// BatchGetter gets items with BatchGetItem, splitting keys into batches of
+// the service's maximum size, and getting batches concurrently. Keys left
+// unprocessed by the service are retried with the backoff of the request's
+// Retryer.
+type BatchGetter struct {
+	Client dynamodbiface.DynamoDBAPI
+
+	// The number of keys per BatchGetItem call. Defaults to, and cannot
+	// exceed, MaxBatchGetSize.
+	BatchSize int
+
+	// The number of batches sent concurrently. Defaults to
+	// DefaultBatchConcurrency.
+	Concurrency int
+
+	// The Retryer used to retry unprocessed keys. The delay between retries
+	// is the Retryer's RetryRules delay, and unprocessed keys are retried up
+	// to the Retryer's MaxRetries times. Defaults to the Retryer of the
+	// BatchGetItem request.
+	Retryer request.Retryer
+
+	// The options, such as ConsistentRead and ProjectionExpression, used to
+	// get the items of each table, by table name. The Keys of the options
+	// are ignored.
+	TableOptions map[string]*dynamodb.KeysAndAttributes
+
+	// Options applied to each BatchGetItem request.
+	RequestOptions []request.Option
+}
+
+// NewBatchGetterWithClient returns a BatchGetter getting items with the
+// client.
+//
+// Example:
+//     getter := dynamodbmanager.NewBatchGetterWithClient(svc)
+//
+//     err := getter.Get(aws.BackgroundContext(), &dynamodbmanager.KeysIterator{
+//         Keys: keys,
+//     }, func(table string, item map[string]*dynamodb.AttributeValue) {
+//         fmt.Println(table, item)
+//     })
+func NewBatchGetterWithClient(client dynamodbiface.DynamoDBAPI, options ...func(*BatchGetter)) *BatchGetter {
+	g := &BatchGetter{
+		Client:      client,
+		BatchSize:   MaxBatchGetSize,
+		Concurrency: DefaultBatchConcurrency,
+	}
+
+	for _, opt := range options {
+		opt(g)
+	}
+
+	return g
+}
+
+// NewBatchGetter returns a BatchGetter getting items with a client created
+// from the ConfigProvider.
+func NewBatchGetter(c client.ConfigProvider, options ...func(*BatchGetter)) *BatchGetter {
+	return NewBatchGetterWithClient(dynamodb.New(c), options...)
+}
+
+// Get gets the items of the iterator's keys in batches, calling fn with each
+// item found and the name of its table. Items are returned in no particular
+// order, and fn is not called for keys without items. Calls to fn are
+// serialized.
+//
+// If the items of any keys could not be got an awserr.BatchedErrors is
+// returned, whose OrigErrs are the *Error of each key which failed. Keys still unprocessed after the
+// maximum number of retries fail with an error with the
+// ErrCodeUnprocessedItem code.
+func (g *BatchGetter) Get(ctx aws.Context, iter BatchGetIterator, fn func(table string, item map[string]*dynamodb.AttributeValue)) error {
+	size := g.BatchSize
+	if size <= 0 || size > MaxBatchGetSize {
+		size = MaxBatchGetSize
+	}
+
+	var m sync.Mutex
+	onItem := func(table string, item map[string]*dynamodb.AttributeValue) {
+		m.Lock()
+		defer m.Unlock()
+		fn(table, item)
+	}
+
+	runner := newBatchRunner(ctx, g.Concurrency)
+
+	var n int
+	keys := map[string]*dynamodb.KeysAndAttributes{}
+	for iter.Next() {
+		k := iter.GetKey()
+		table := aws.StringValue(k.TableName)
+		kas, ok := keys[table]
+		if !ok {
+			kas = g.keysAndAttributes(table)
+			keys[table] = kas
+		}
+		kas.Keys = append(kas.Keys, k.Key)
+		n++
+
+		if n == size {
+			if !runner.send(g.batchFunc(ctx, keys, onItem)) {
+				break
+			}
+			keys = map[string]*dynamodb.KeysAndAttributes{}
+			n = 0
+		}
+	}
+
+	if n > 0 {
+		runner.send(g.batchFunc(ctx, keys, onItem))
+	}
+
+	errs := runner.wait()
+	if err := iter.Err(); err != nil {
+		errs = append(errs, Error{OrigErr: err})
+	}
+
+	if len(errs) > 0 {
+		return newBatchError("BatchedGetIncomplete", "some items have failed to be retrieved.", errs)
+	}
+	return nil
+}
+
+// keysAndAttributes returns the KeysAndAttributes of the table's options,
+// without keys.
+func (g *BatchGetter) keysAndAttributes(table string) *dynamodb.KeysAndAttributes {
+	kas := &dynamodb.KeysAndAttributes{}
+	if opts, ok := g.TableOptions[table]; ok && opts != nil {
+		*kas = *opts
+		kas.Keys = nil
+	}
+	return kas
+}
+
+func (g *BatchGetter) batchFunc(ctx aws.Context, keys map[string]*dynamodb.KeysAndAttributes,
+	fn func(string, map[string]*dynamodb.AttributeValue)) func() []Error {
+	return func() []Error {
+		return g.getBatch(ctx, keys, fn)
+	}
+}
+
+// getBatch gets the batch, retrying unprocessed keys until all keys are
+// processed or the maximum number of retries is reached.
+func (g *BatchGetter) getBatch(ctx aws.Context, keys map[string]*dynamodb.KeysAndAttributes,
+	fn func(string, map[string]*dynamodb.AttributeValue)) []Error {
+	for attempt := 0; ; attempt++ {
+		req, out := g.Client.BatchGetItemRequest(&dynamodb.BatchGetItemInput{
+			RequestItems: keys,
+		})
+		req.SetContext(ctx)
+		req.ApplyOptions(g.RequestOptions...)
+
+		if err := req.Send(); err != nil {
+			return getErrors(keys, err)
+		}
+
+		for table, items := range out.Responses {
+			for _, item := range items {
+				fn(table, item)
+			}
+		}
+
+		keys = out.UnprocessedKeys
+		if len(keys) == 0 {
+			return nil
+		}
+
+		retry, err := retryUnprocessed(ctx, req, batchRetryer(req, g.Retryer), attempt)
+		if err != nil {
+			return getErrors(keys, err)
+		} else if !retry {
+			return getErrors(keys, awserr.New(ErrCodeUnprocessedItem,
+				"item was not processed before the maximum number of retries", nil))
+		}
+	}
+}
+
+func getErrors(keys map[string]*dynamodb.KeysAndAttributes, err error) []Error {
+	var errs []Error
+	for table, kas := range keys {
+		for _, key := range kas.Keys {
+			errs = append(errs, Error{
+				OrigErr:   err,
+				TableName: aws.String(table),
+				Key:       key,
+			})
+		}
+	}
+	return errs
+}
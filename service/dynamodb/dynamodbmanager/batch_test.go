@@ -0,0 +1,358 @@
+package dynamodbmanager_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+)
+
+// batchSvc returns a client whose requests are handled by fn instead of
+// being sent.
+func batchSvc(fn func(r *request.Request)) *dynamodb.DynamoDB {
+	svc := dynamodb.New(unit.Session, &aws.Config{MaxRetries: aws.Int(1)})
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Unmarshal.Clear()
+	svc.Handlers.UnmarshalMeta.Clear()
+	svc.Handlers.ValidateResponse.Clear()
+
+	var m sync.Mutex
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		fn(r)
+	})
+
+	return svc
+}
+
+// testRetryer retries unprocessed items without delay.
+type testRetryer struct {
+	maxRetries  int
+	retryCounts []int
+}
+
+func (r *testRetryer) RetryRules(req *request.Request) time.Duration {
+	r.retryCounts = append(r.retryCounts, req.RetryCount)
+	return 0
+}
+
+func (r *testRetryer) ShouldRetry(*request.Request) bool { return false }
+
+func (r *testRetryer) MaxRetries() int { return r.maxRetries }
+
+func itemID(item map[string]*dynamodb.AttributeValue) string {
+	return aws.StringValue(item["ID"].S)
+}
+
+func putRequests(table string, n int) []dynamodbmanager.BatchWriteRequest {
+	var rs []dynamodbmanager.BatchWriteRequest
+	for i := 0; i < n; i++ {
+		rs = append(rs, dynamodbmanager.BatchWriteRequest{
+			TableName: aws.String(table),
+			Request: &dynamodb.WriteRequest{
+				PutRequest: &dynamodb.PutRequest{
+					Item: map[string]*dynamodb.AttributeValue{
+						"ID": {S: aws.String(fmt.Sprintf("%s-%d", table, i))},
+					},
+				},
+			},
+		})
+	}
+	return rs
+}
+
+func TestBatchWriter(t *testing.T) {
+	var sizes []int
+	var written []string
+	svc := batchSvc(func(r *request.Request) {
+		var n int
+		for _, rs := range r.Params.(*dynamodb.BatchWriteItemInput).RequestItems {
+			for _, w := range rs {
+				written = append(written, itemID(w.PutRequest.Item))
+				n++
+			}
+		}
+		sizes = append(sizes, n)
+	})
+
+	requests := append(putRequests("a", 40), putRequests("b", 20)...)
+	w := dynamodbmanager.NewBatchWriterWithClient(svc)
+	err := w.Write(aws.BackgroundContext(), &dynamodbmanager.WriteRequestsIterator{
+		Requests: requests,
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	sort.Ints(sizes)
+	if e, a := []int{10, 25, 25}, sizes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v batch sizes, got %v", e, a)
+	}
+
+	var expect []string
+	for _, r := range requests {
+		expect = append(expect, itemID(r.Request.PutRequest.Item))
+	}
+	sort.Strings(expect)
+	sort.Strings(written)
+	if e, a := expect, written; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v written, got %v", e, a)
+	}
+}
+
+func TestBatchWriter_Unprocessed(t *testing.T) {
+	cases := map[string]struct {
+		Unprocessed  int
+		MaxRetries   int
+		ExpectCalls  int
+		ExpectFailed int
+	}{
+		"retried": {
+			Unprocessed: 2, MaxRetries: 3,
+			ExpectCalls: 3,
+		},
+		"retries exhausted": {
+			// 20 requests, 10 unprocessed, 5 unprocessed, 3 unprocessed.
+			Unprocessed: 5, MaxRetries: 2,
+			ExpectCalls: 3, ExpectFailed: 3,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var calls int
+			svc := batchSvc(func(r *request.Request) {
+				calls++
+				if calls > c.Unprocessed {
+					return
+				}
+				// Leave the first half of the requests unprocessed.
+				in := r.Params.(*dynamodb.BatchWriteItemInput).RequestItems["a"]
+				r.Data.(*dynamodb.BatchWriteItemOutput).UnprocessedItems = map[string][]*dynamodb.WriteRequest{
+					"a": in[:(len(in)+1)/2],
+				}
+			})
+
+			retryer := &testRetryer{maxRetries: c.MaxRetries}
+			w := dynamodbmanager.NewBatchWriterWithClient(svc, func(w *dynamodbmanager.BatchWriter) {
+				w.Retryer = retryer
+			})
+			err := w.Write(aws.BackgroundContext(), &dynamodbmanager.WriteRequestsIterator{
+				Requests: putRequests("a", 20),
+			})
+
+			if e, a := c.ExpectCalls, calls; e != a {
+				t.Errorf("expect %v calls, got %v", e, a)
+			}
+			var expectCounts []int
+			for i := 0; i < c.ExpectCalls-1; i++ {
+				expectCounts = append(expectCounts, i)
+			}
+			if e, a := expectCounts, retryer.retryCounts; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v retry counts, got %v", e, a)
+			}
+
+			if c.ExpectFailed == 0 {
+				if err != nil {
+					t.Fatalf("expect no error, got %v", err)
+				}
+				return
+			}
+
+			batchErr, ok := err.(awserr.BatchedErrors)
+			if !ok {
+				t.Fatalf("expect awserr.BatchedErrors, got %T, %v", err, err)
+			}
+			if e, a := c.ExpectFailed, len(batchErr.OrigErrs()); e != a {
+				t.Fatalf("expect %v errors, got %v", e, a)
+			}
+			for _, err := range batchErr.OrigErrs() {
+				e := err.(*dynamodbmanager.Error)
+				if e, a := dynamodbmanager.ErrCodeUnprocessedItem, e.OrigErr.(awserr.Error).Code(); e != a {
+					t.Errorf("expect %v, got %v", e, a)
+				}
+				if e, a := "a", aws.StringValue(e.TableName); e != a {
+					t.Errorf("expect %v table, got %v", e, a)
+				}
+				if e.WriteRequest == nil {
+					t.Errorf("expect write request, got none")
+				}
+			}
+		})
+	}
+}
+
+func TestBatchWriter_RequestRetryer(t *testing.T) {
+	var calls int
+	svc := batchSvc(func(r *request.Request) {
+		calls++
+		r.Data.(*dynamodb.BatchWriteItemOutput).UnprocessedItems =
+			r.Params.(*dynamodb.BatchWriteItemInput).RequestItems
+	})
+
+	w := dynamodbmanager.NewBatchWriterWithClient(svc)
+	err := w.Write(aws.BackgroundContext(), &dynamodbmanager.WriteRequestsIterator{
+		Requests: putRequests("a", 1),
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	// The client's MaxRetries is 1.
+	if e, a := 2, calls; e != a {
+		t.Errorf("expect %v calls, got %v", e, a)
+	}
+}
+
+func TestBatchWriter_RequestError(t *testing.T) {
+	svc := batchSvc(func(r *request.Request) {
+		if len(r.Params.(*dynamodb.BatchWriteItemInput).RequestItems["a"]) < 25 {
+			r.Error = awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+		}
+	})
+
+	w := dynamodbmanager.NewBatchWriterWithClient(svc, func(w *dynamodbmanager.BatchWriter) {
+		w.Concurrency = 1
+	})
+	err := w.Write(aws.BackgroundContext(), &dynamodbmanager.WriteRequestsIterator{
+		Requests: putRequests("a", 30),
+	})
+
+	batchErr, ok := err.(awserr.BatchedErrors)
+	if !ok {
+		t.Fatalf("expect awserr.BatchedErrors, got %T, %v", err, err)
+	}
+	if e, a := 5, len(batchErr.OrigErrs()); e != a {
+		t.Fatalf("expect %v errors, got %v", e, a)
+	}
+	for _, err := range batchErr.OrigErrs() {
+		e := err.(*dynamodbmanager.Error)
+		if e, a := dynamodb.ErrCodeResourceNotFoundException, e.OrigErr.(awserr.Error).Code(); e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+	}
+}
+
+func TestBatchGetter(t *testing.T) {
+	var calls int
+	var sizes []int
+	svc := batchSvc(func(r *request.Request) {
+		calls++
+		in := r.Params.(*dynamodb.BatchGetItemInput).RequestItems
+		out := r.Data.(*dynamodb.BatchGetItemOutput)
+		out.Responses = map[string][]map[string]*dynamodb.AttributeValue{}
+
+		var n int
+		for table, kas := range in {
+			if e, a := table == "a", aws.BoolValue(kas.ConsistentRead); e != a {
+				t.Errorf("expect %v consistent read for %v, got %v", e, table, a)
+			}
+
+			keys := kas.Keys
+			if calls == 1 {
+				// Leave the first key unprocessed.
+				out.UnprocessedKeys = map[string]*dynamodb.KeysAndAttributes{
+					table: {Keys: keys[:1], ConsistentRead: kas.ConsistentRead},
+				}
+				keys = keys[1:]
+			}
+			for _, key := range keys {
+				// Items with odd IDs do not exist.
+				if id := itemID(key); id[len(id)-1]%2 == 0 {
+					out.Responses[table] = append(out.Responses[table], key)
+				}
+			}
+			n += len(kas.Keys)
+		}
+		sizes = append(sizes, n)
+	})
+
+	var keys []dynamodbmanager.BatchGetKey
+	for _, table := range []string{"a", "b"} {
+		for i := 0; i < 110; i++ {
+			keys = append(keys, dynamodbmanager.BatchGetKey{
+				TableName: aws.String(table),
+				Key: map[string]*dynamodb.AttributeValue{
+					"ID": {S: aws.String(fmt.Sprintf("%s-%d", table, i))},
+				},
+			})
+		}
+	}
+
+	g := dynamodbmanager.NewBatchGetterWithClient(svc, func(g *dynamodbmanager.BatchGetter) {
+		// Send batches in order so the first batch is the first call.
+		g.Concurrency = 1
+		g.Retryer = &testRetryer{maxRetries: 3}
+		g.TableOptions = map[string]*dynamodb.KeysAndAttributes{
+			"a": {ConsistentRead: aws.Bool(true)},
+		}
+	})
+
+	var got []string
+	err := g.Get(aws.BackgroundContext(), &dynamodbmanager.KeysIterator{Keys: keys},
+		func(table string, item map[string]*dynamodb.AttributeValue) {
+			got = append(got, table+":"+itemID(item))
+		})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := 4, calls; e != a {
+		t.Errorf("expect %v calls, got %v", e, a)
+	}
+	sort.Ints(sizes)
+	if e, a := []int{1, 20, 100, 100}, sizes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v batch sizes, got %v", e, a)
+	}
+
+	var expect []string
+	for _, k := range keys {
+		if id := itemID(k.Key); id[len(id)-1]%2 == 0 {
+			expect = append(expect, aws.StringValue(k.TableName)+":"+id)
+		}
+	}
+	sort.Strings(expect)
+	sort.Strings(got)
+	if e, a := expect, got; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v items, got %v", e, a)
+	}
+}
+
+func TestBatchGetter_ContextCanceled(t *testing.T) {
+	svc := batchSvc(func(r *request.Request) {})
+
+	ctx := &awstesting.FakeContext{DoneCh: make(chan struct{})}
+	ctx.Error = fmt.Errorf("context canceled")
+	close(ctx.DoneCh)
+
+	g := dynamodbmanager.NewBatchGetterWithClient(svc)
+	err := g.Get(ctx, &dynamodbmanager.KeysIterator{
+		Keys: []dynamodbmanager.BatchGetKey{{
+			TableName: aws.String("a"),
+			Key:       map[string]*dynamodb.AttributeValue{"ID": {S: aws.String("1")}},
+		}},
+	}, func(string, map[string]*dynamodb.AttributeValue) {})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+}
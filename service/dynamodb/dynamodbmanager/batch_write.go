@@ -0,0 +1,208 @@
+package dynamodbmanager
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// BatchWriteIterator is an interface that uses the scanner pattern to
+// iterate through the write requests of a batch write.
+type BatchWriteIterator interface {
+	Next() bool
+	Err() error
+	WriteRequest() BatchWriteRequest
+}
+
+// BatchWriteRequest is a put or delete request of an item in a table.
+type BatchWriteRequest struct {
+	TableName *string
+	Request   *dynamodb.WriteRequest
+}
+
+// WriteRequestsIterator implements the BatchWriteIterator interface and
+// allows for batched writes of a list of write requests.
+type WriteRequestsIterator struct {
+	Requests []BatchWriteRequest
+	index    int
+	inc      bool
+}
+
+// Next will increment the default iterator's index and ensure that there
+// is another write request to iterate to.
+func (iter *WriteRequestsIterator) Next() bool {
+	if iter.inc {
+		iter.index++
+	} else {
+		iter.inc = true
+	}
+	return iter.index < len(iter.Requests)
+}
+
+// Err will return an error. Since this is just used to satisfy the
+// BatchWriteIterator interface this will only return nil.
+func (iter *WriteRequestsIterator) Err() error {
+	return nil
+}
+
+// WriteRequest will return the BatchWriteRequest at the current index.
+func (iter *WriteRequestsIterator) WriteRequest() BatchWriteRequest {
+	return iter.Requests[iter.index]
+}
+
+// BatchWriter writes items with BatchWriteItem, splitting write requests
+// into batches of the service's maximum size, and writing batches
+// concurrently. Items left unprocessed by the service are retried with the
+// backoff of the request's Retryer.
+type BatchWriter struct {
+	Client dynamodbiface.DynamoDBAPI
+
+	// The number of write requests per BatchWriteItem call. Defaults to,
+	// and cannot exceed, MaxBatchWriteSize.
+	BatchSize int
+
+	// The number of batches written concurrently. Defaults to
+	// DefaultBatchConcurrency.
+	Concurrency int
+
+	// The Retryer used to retry unprocessed items. The delay between retries
+	// is the Retryer's RetryRules delay, and unprocessed items are retried
+	// up to the Retryer's MaxRetries times. Defaults to the Retryer of the
+	// BatchWriteItem request.
+	Retryer request.Retryer
+
+	// Options applied to each BatchWriteItem request.
+	RequestOptions []request.Option
+}
+
+// NewBatchWriterWithClient returns a BatchWriter writing items with the
+// client.
+//
+// Example:
+//     writer := dynamodbmanager.NewBatchWriterWithClient(svc)
+//
+//     err := writer.Write(aws.BackgroundContext(), &dynamodbmanager.WriteRequestsIterator{
+//         Requests: []dynamodbmanager.BatchWriteRequest{
+//             {
+//                 TableName: aws.String("orders"),
+//                 Request: &dynamodb.WriteRequest{
+//                     PutRequest: &dynamodb.PutRequest{Item: item},
+//                 },
+//             },
+//         },
+//     })
+func NewBatchWriterWithClient(client dynamodbiface.DynamoDBAPI, options ...func(*BatchWriter)) *BatchWriter {
+	w := &BatchWriter{
+		Client:      client,
+		BatchSize:   MaxBatchWriteSize,
+		Concurrency: DefaultBatchConcurrency,
+	}
+
+	for _, opt := range options {
+		opt(w)
+	}
+
+	return w
+}
+
+// NewBatchWriter returns a BatchWriter writing items with a client created
+// from the ConfigProvider.
+func NewBatchWriter(c client.ConfigProvider, options ...func(*BatchWriter)) *BatchWriter {
+	return NewBatchWriterWithClient(dynamodb.New(c), options...)
+}
+
+// Write writes the iterator's write requests in batches. If any write
+// request fails an awserr.BatchedErrors is returned, whose OrigErrs are the
+// *Error of each write request which failed. Write requests still unprocessed after the maximum
+// number of retries fail with an error with the ErrCodeUnprocessedItem code.
+func (w *BatchWriter) Write(ctx aws.Context, iter BatchWriteIterator) error {
+	size := w.BatchSize
+	if size <= 0 || size > MaxBatchWriteSize {
+		size = MaxBatchWriteSize
+	}
+
+	runner := newBatchRunner(ctx, w.Concurrency)
+
+	var n int
+	items := map[string][]*dynamodb.WriteRequest{}
+	for iter.Next() {
+		r := iter.WriteRequest()
+		table := aws.StringValue(r.TableName)
+		items[table] = append(items[table], r.Request)
+		n++
+
+		if n == size {
+			if !runner.send(w.batchFunc(ctx, items)) {
+				break
+			}
+			items = map[string][]*dynamodb.WriteRequest{}
+			n = 0
+		}
+	}
+
+	if n > 0 {
+		runner.send(w.batchFunc(ctx, items))
+	}
+
+	errs := runner.wait()
+	if err := iter.Err(); err != nil {
+		errs = append(errs, Error{OrigErr: err})
+	}
+
+	if len(errs) > 0 {
+		return newBatchError("BatchedWriteIncomplete", "some items have failed to be written.", errs)
+	}
+	return nil
+}
+
+func (w *BatchWriter) batchFunc(ctx aws.Context, items map[string][]*dynamodb.WriteRequest) func() []Error {
+	return func() []Error {
+		return w.writeBatch(ctx, items)
+	}
+}
+
+// writeBatch writes the batch, retrying unprocessed items until all items are
+// processed or the maximum number of retries is reached.
+func (w *BatchWriter) writeBatch(ctx aws.Context, items map[string][]*dynamodb.WriteRequest) []Error {
+	for attempt := 0; ; attempt++ {
+		req, out := w.Client.BatchWriteItemRequest(&dynamodb.BatchWriteItemInput{
+			RequestItems: items,
+		})
+		req.SetContext(ctx)
+		req.ApplyOptions(w.RequestOptions...)
+
+		if err := req.Send(); err != nil {
+			return writeErrors(items, err)
+		}
+
+		items = out.UnprocessedItems
+		if len(items) == 0 {
+			return nil
+		}
+
+		retry, err := retryUnprocessed(ctx, req, batchRetryer(req, w.Retryer), attempt)
+		if err != nil {
+			return writeErrors(items, err)
+		} else if !retry {
+			return writeErrors(items, awserr.New(ErrCodeUnprocessedItem,
+				"item was not processed before the maximum number of retries", nil))
+		}
+	}
+}
+
+func writeErrors(items map[string][]*dynamodb.WriteRequest, err error) []Error {
+	var errs []Error
+	for table, requests := range items {
+		for _, r := range requests {
+			errs = append(errs, Error{
+				OrigErr:      err,
+				TableName:    aws.String(table),
+				WriteRequest: r,
+			})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,5 @@
+// Package dynamodbmanager provides utilities to read and write many Amazon
+// DynamoDB items concurrently, retrying items left unprocessed by the
+// service's batch operations, and to build transactional writes from Go
+// values.
+package dynamodbmanager
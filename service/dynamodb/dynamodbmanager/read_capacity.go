@@ -0,0 +1,55 @@
+package dynamodbmanager
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/internal/sdkratelimit"
+)
+
+// A ReadCapacityLimiter limits the rate, in read capacity units per second,
+// ParallelScanners consume a table's read capacity. A ReadCapacityLimiter can
+// be shared by multiple ParallelScanners to limit their combined rate.
+//
+// Capacity is consumed after each page is read, using the page's
+// ConsumedCapacity. Segments wait before reading their next page until the
+// capacity consumed is within the limit.
+//
+// The limit can be adjusted while scans are in progress with SetLimit. A
+// limit of zero or less disables the limit.
+//
+// It is safe to use a ReadCapacityLimiter concurrently across goroutines.
+//
+// Example:
+//     // Consume at most 500 read capacity units per second.
+//     scanner := dynamodbmanager.NewParallelScanner(sess, func(s *dynamodbmanager.ParallelScanner) {
+//         s.ReadCapacityLimiter = dynamodbmanager.NewReadCapacityLimiter(500)
+//     })
+type ReadCapacityLimiter struct {
+	bucket *sdkratelimit.TokenBucket
+}
+
+// NewReadCapacityLimiter returns a ReadCapacityLimiter limiting scans to the
+// number of read capacity units per second.
+func NewReadCapacityLimiter(unitsPerSecond float64) *ReadCapacityLimiter {
+	return &ReadCapacityLimiter{
+		bucket: sdkratelimit.NewTokenBucket(unitsPerSecond),
+	}
+}
+
+// Limit returns the limiter's current limit in read capacity units per
+// second.
+func (l *ReadCapacityLimiter) Limit() float64 {
+	return l.bucket.Rate()
+}
+
+// SetLimit sets the limiter's limit in read capacity units per second. The
+// new limit applies to scans already in progress. A limit of zero or less
+// disables the limit.
+func (l *ReadCapacityLimiter) SetLimit(unitsPerSecond float64) {
+	l.bucket.SetRate(unitsPerSecond)
+}
+
+// Consume records the units of read capacity consumed, and blocks until the
+// capacity consumed is within the limit, or the context is canceled.
+func (l *ReadCapacityLimiter) Consume(ctx aws.Context, units float64) error {
+	return l.bucket.Wait(ctx, units)
+}
@@ -0,0 +1,63 @@
+package dynamodbmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// stopReadCapacityClock stops the limiter's clock, returning the delays the
+// limiter sleeps for. The timing of the limiter's token bucket is tested by
+// the sdkratelimit package.
+func stopReadCapacityClock(l *ReadCapacityLimiter) *[]time.Duration {
+	var sleeps []time.Duration
+	now := time.Unix(0, 0)
+	l.bucket.Now = func() time.Time { return now }
+	l.bucket.Sleep = func(_ aws.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return &sleeps
+}
+
+func TestReadCapacityLimiter_Consume(t *testing.T) {
+	l := NewReadCapacityLimiter(2)
+	sleeps := stopReadCapacityClock(l)
+
+	// The units of a page's ConsumedCapacity are consumed as is, including
+	// fractional units.
+	for _, units := range []float64{0.5, 1.5} {
+		if err := l.Consume(aws.BackgroundContext(), units); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+	}
+
+	expect := []time.Duration{250 * time.Millisecond, time.Second}
+	if e, a := len(expect), len(*sleeps); e != a {
+		t.Fatalf("expect %v sleeps, got %v", e, a)
+	}
+	for i := range expect {
+		if e, a := expect[i], (*sleeps)[i]; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+}
+
+func TestReadCapacityLimiter_SetLimit(t *testing.T) {
+	l := NewReadCapacityLimiter(100)
+	sleeps := stopReadCapacityClock(l)
+
+	l.SetLimit(0.5)
+	if e, a := 0.5, l.Limit(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	l.SetLimit(0)
+	if err := l.Consume(aws.BackgroundContext(), 1000); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 0, len(*sleeps); e != a {
+		t.Errorf("expect %v sleeps, got %v", e, a)
+	}
+}
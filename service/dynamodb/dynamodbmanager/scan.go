@@ -0,0 +1,250 @@
+package dynamodbmanager
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DefaultScanSegments is the default number of segments a ParallelScanner
+// scans concurrently.
+const DefaultScanSegments = 4
+
+// A ScanPage is a page of items read by a segment of a parallel scan.
+type ScanPage struct {
+	// The segment the page was read by.
+	Segment int64
+
+	// The items of the page.
+	Items []map[string]*dynamodb.AttributeValue
+
+	// The LastEvaluatedKey of the page. Nil if the page is the segment's
+	// last page.
+	LastEvaluatedKey map[string]*dynamodb.AttributeValue
+
+	// The capacity consumed reading the page, if the scan's input requested
+	// it, or the ParallelScanner has a ReadCapacityLimiter.
+	ConsumedCapacity *dynamodb.ConsumedCapacity
+}
+
+// UnmarshalItems unmarshals the page's items into the slice pointed to by
+// out, using dynamodbattribute.UnmarshalListOfMaps.
+func (p *ScanPage) UnmarshalItems(out interface{}) error {
+	return dynamodbattribute.UnmarshalListOfMaps(p.Items, out)
+}
+
+// ParallelScanner scans a table, or index, with a parallel Scan, reading
+// each of the scan's segments concurrently.
+//
+// If the ParallelScanner has a CheckpointStore the progress of each segment
+// is saved after each page is processed, and an interrupted scan resumes
+// each segment following the last page processed. The checkpoints are
+// deleted once all segments are complete.
+type ParallelScanner struct {
+	Client dynamodbiface.DynamoDBAPI
+
+	// The number of segments the scan is divided into, each read
+	// concurrently. Defaults to DefaultScanSegments.
+	TotalSegments int64
+
+	// The limiter of the read capacity consumed by the scan. Nil for no
+	// limit.
+	ReadCapacityLimiter *ReadCapacityLimiter
+
+	// The store checkpoints of the scan's segments are saved to. Nil to
+	// not checkpoint scans.
+	CheckpointStore ScanCheckpointStore
+
+	// Options applied to each Scan request.
+	RequestOptions []request.Option
+}
+
+// NewParallelScannerWithClient returns a ParallelScanner scanning with the
+// client.
+//
+// Example:
+//     scanner := dynamodbmanager.NewParallelScannerWithClient(svc, func(s *dynamodbmanager.ParallelScanner) {
+//         s.TotalSegments = 16
+//     })
+//
+//     var m sync.Mutex
+//     var count int
+//     err := scanner.Scan(aws.BackgroundContext(), &dynamodb.ScanInput{
+//         TableName: aws.String("orders"),
+//     }, func(page *dynamodbmanager.ScanPage) error {
+//         var orders []Order
+//         if err := page.UnmarshalItems(&orders); err != nil {
+//             return err
+//         }
+//
+//         m.Lock()
+//         defer m.Unlock()
+//         count += len(orders)
+//         return nil
+//     })
+func NewParallelScannerWithClient(client dynamodbiface.DynamoDBAPI, options ...func(*ParallelScanner)) *ParallelScanner {
+	s := &ParallelScanner{
+		Client:        client,
+		TotalSegments: DefaultScanSegments,
+	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	return s
+}
+
+// NewParallelScanner returns a ParallelScanner scanning with a client
+// created from the ConfigProvider.
+func NewParallelScanner(c client.ConfigProvider, options ...func(*ParallelScanner)) *ParallelScanner {
+	return NewParallelScannerWithClient(dynamodb.New(c), options...)
+}
+
+// Scan scans the table with the input, calling fn with each page of items
+// read. The input's Segment, TotalSegments, and ExclusiveStartKey are set by
+// the ParallelScanner for each segment.
+//
+// fn is called concurrently by the goroutines of each segment, and must be
+// safe to call concurrently. The scan stops if fn returns an error, or a
+// segment fails, and the first error is returned. Segments already reading
+// a page when the scan stops will complete the page without calling fn.
+func (s *ParallelScanner) Scan(ctx aws.Context, input *dynamodb.ScanInput, fn func(*ScanPage) error) error {
+	total := s.TotalSegments
+	if total <= 0 {
+		total = DefaultScanSegments
+	}
+
+	st := &scanState{}
+
+	var wg sync.WaitGroup
+	for seg := int64(0); seg < total; seg++ {
+		wg.Add(1)
+		go func(seg int64) {
+			defer wg.Done()
+			if err := s.scanSegment(ctx, input, seg, total, fn, st); err != nil {
+				st.stop(err)
+			}
+		}(seg)
+	}
+	wg.Wait()
+
+	if st.err != nil {
+		return st.err
+	}
+
+	if s.CheckpointStore != nil {
+		table, index := aws.StringValue(input.TableName), aws.StringValue(input.IndexName)
+		for seg := int64(0); seg < total; seg++ {
+			if err := s.CheckpointStore.DeleteScanCheckpoint(table, index, seg, total); err != nil {
+				return awserr.New(ErrCodeScanCheckpoint, "failed to delete scan checkpoint", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// scanState is the state shared by the segments of a scan.
+type scanState struct {
+	m       sync.Mutex
+	err     error
+	stopped bool
+}
+
+// stop stops the scan, recording the first error the scan stopped with.
+func (s *scanState) stop(err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+	s.stopped = true
+}
+
+func (s *scanState) isStopped() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.stopped
+}
+
+// scanSegment scans the segment, resuming from the segment's checkpoint.
+func (s *ParallelScanner) scanSegment(ctx aws.Context, input *dynamodb.ScanInput, seg, total int64,
+	fn func(*ScanPage) error, st *scanState) error {
+
+	in := *input
+	in.Segment = aws.Int64(seg)
+	in.TotalSegments = aws.Int64(total)
+	in.ExclusiveStartKey = nil
+	if s.ReadCapacityLimiter != nil {
+		if v := aws.StringValue(in.ReturnConsumedCapacity); len(v) == 0 || v == dynamodb.ReturnConsumedCapacityNone {
+			in.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+		}
+	}
+
+	table, index := aws.StringValue(in.TableName), aws.StringValue(in.IndexName)
+	if s.CheckpointStore != nil {
+		cp, err := s.CheckpointStore.GetScanCheckpoint(table, index, seg, total)
+		if err != nil {
+			return awserr.New(ErrCodeScanCheckpoint, "failed to load scan checkpoint", err)
+		}
+		if cp != nil {
+			if cp.Done {
+				return nil
+			}
+			in.ExclusiveStartKey = cp.LastEvaluatedKey
+		}
+	}
+
+	var pageErr error
+	err := s.Client.ScanPagesWithContext(ctx, &in, func(out *dynamodb.ScanOutput, last bool) bool {
+		if st.isStopped() {
+			return false
+		}
+
+		page := &ScanPage{
+			Segment:          seg,
+			Items:            out.Items,
+			LastEvaluatedKey: out.LastEvaluatedKey,
+			ConsumedCapacity: out.ConsumedCapacity,
+		}
+		if pageErr = fn(page); pageErr != nil {
+			return false
+		}
+
+		if s.CheckpointStore != nil {
+			if err := s.CheckpointStore.PutScanCheckpoint(&ScanCheckpoint{
+				TableName:        table,
+				IndexName:        index,
+				Segment:          seg,
+				TotalSegments:    total,
+				LastEvaluatedKey: out.LastEvaluatedKey,
+				Done:             last,
+			}); err != nil {
+				pageErr = awserr.New(ErrCodeScanCheckpoint, "failed to save scan checkpoint", err)
+				return false
+			}
+		}
+
+		if s.ReadCapacityLimiter != nil && out.ConsumedCapacity != nil {
+			units := aws.Float64Value(out.ConsumedCapacity.CapacityUnits)
+			if pageErr = s.ReadCapacityLimiter.Consume(ctx, units); pageErr != nil {
+				return false
+			}
+		}
+
+		return true
+	}, s.RequestOptions...)
+
+	if pageErr != nil {
+		return pageErr
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package dynamodbmanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrCodeScanCheckpoint is the error code returned when the ParallelScanner
+// is unable to load or save a segment's checkpoint.
+const ErrCodeScanCheckpoint = "ScanCheckpointError"
+
+// A ScanCheckpoint records the progress of a segment of a parallel scan
+// needed to resume the segment.
+type ScanCheckpoint struct {
+	// The table, and index if any, being scanned.
+	TableName string
+	IndexName string
+
+	// The segment, and the total number of segments of the scan. A segment
+	// can only be resumed by a scan with the same number of segments.
+	Segment       int64
+	TotalSegments int64
+
+	// The LastEvaluatedKey of the last page of the segment processed. The
+	// segment resumes with the page following the key.
+	LastEvaluatedKey map[string]*dynamodb.AttributeValue
+
+	// If all pages of the segment have been processed.
+	Done bool
+}
+
+// ScanCheckpointStore provides the interface the ParallelScanner uses to
+// persist the checkpoints of the segments of in-progress scans, so that an
+// interrupted scan can be resumed.
+//
+// A ScanCheckpointStore must be safe to use across multiple goroutines.
+type ScanCheckpointStore interface {
+	// GetScanCheckpoint returns the checkpoint of the segment of the scan of
+	// the table and index. A nil checkpoint is returned if there is no
+	// checkpoint for the segment.
+	GetScanCheckpoint(table, index string, segment, totalSegments int64) (*ScanCheckpoint, error)
+
+	// PutScanCheckpoint saves the checkpoint, replacing any existing
+	// checkpoint for the checkpoint's segment.
+	PutScanCheckpoint(*ScanCheckpoint) error
+
+	// DeleteScanCheckpoint removes the checkpoint of the segment. Deleting
+	// a checkpoint which does not exist is not an error.
+	DeleteScanCheckpoint(table, index string, segment, totalSegments int64) error
+}
+
+// FileScanCheckpointStore is a ScanCheckpointStore which saves each
+// checkpoint as a JSON file within a directory. Use a separate directory for
+// each scan of the same table and index, such as scans with different
+// filters.
+type FileScanCheckpointStore struct {
+	// The directory checkpoint files are written to. The directory will be
+	// created if it does not exist.
+	Dir string
+}
+
+// NewFileScanCheckpointStore returns a FileScanCheckpointStore which saves
+// checkpoints in the directory provided.
+//
+// Example:
+//     scanner := dynamodbmanager.NewParallelScanner(sess, func(s *dynamodbmanager.ParallelScanner) {
+//         s.CheckpointStore = dynamodbmanager.NewFileScanCheckpointStore("/var/lib/myapp/export")
+//     })
+func NewFileScanCheckpointStore(dir string) *FileScanCheckpointStore {
+	return &FileScanCheckpointStore{Dir: dir}
+}
+
+// GetScanCheckpoint reads the checkpoint of the segment from the store's
+// directory.
+func (s *FileScanCheckpointStore) GetScanCheckpoint(table, index string, segment, totalSegments int64) (*ScanCheckpoint, error) {
+	b, err := ioutil.ReadFile(s.filename(table, index, segment, totalSegments))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	cp := &ScanCheckpoint{}
+	if err := json.Unmarshal(b, cp); err != nil {
+		return nil, err
+	}
+	if cp.TableName != table || cp.IndexName != index ||
+		cp.Segment != segment || cp.TotalSegments != totalSegments {
+		return nil, nil
+	}
+
+	return cp, nil
+}
+
+// PutScanCheckpoint writes the checkpoint to the store's directory.
+func (s *FileScanCheckpointStore) PutScanCheckpoint(cp *ScanCheckpoint) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a partially written checkpoint is
+	// never read.
+	f, err := ioutil.TempFile(s.Dir, ".checkpoint")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), s.filename(cp.TableName, cp.IndexName, cp.Segment, cp.TotalSegments))
+}
+
+// DeleteScanCheckpoint removes the checkpoint of the segment from the
+// store's directory.
+func (s *FileScanCheckpointStore) DeleteScanCheckpoint(table, index string, segment, totalSegments int64) error {
+	err := os.Remove(s.filename(table, index, segment, totalSegments))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileScanCheckpointStore) filename(table, index string, segment, totalSegments int64) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d/%d", table, index, segment, totalSegments)))
+	return filepath.Join(s.Dir, hex.EncodeToString(h[:])+".json")
+}
@@ -0,0 +1,228 @@
+package dynamodbmanager_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+)
+
+const scanPagesPerSegment = 3
+
+// scanSvc returns a client serving scans of a table with segments of
+// scanPagesPerSegment pages of two items each.
+func scanSvc(inputs *[]dynamodb.ScanInput) *dynamodb.DynamoDB {
+	return batchSvc(func(r *request.Request) {
+		in := r.Params.(*dynamodb.ScanInput)
+		if inputs != nil {
+			*inputs = append(*inputs, *in)
+		}
+
+		seg := aws.Int64Value(in.Segment)
+		var page int
+		if k, ok := in.ExclusiveStartKey["Page"]; ok {
+			page, _ = strconv.Atoi(aws.StringValue(k.N))
+			page++
+		}
+
+		out := r.Data.(*dynamodb.ScanOutput)
+		for i := 0; i < 2; i++ {
+			out.Items = append(out.Items, map[string]*dynamodb.AttributeValue{
+				"ID": {S: aws.String(fmt.Sprintf("%d-%d-%d", seg, page, i))},
+			})
+		}
+		if page+1 < scanPagesPerSegment {
+			out.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{
+				"Page": {N: aws.String(strconv.Itoa(page))},
+			}
+		}
+		if aws.StringValue(in.ReturnConsumedCapacity) == dynamodb.ReturnConsumedCapacityTotal {
+			out.ConsumedCapacity = &dynamodb.ConsumedCapacity{CapacityUnits: aws.Float64(0.5)}
+		}
+	})
+}
+
+func scanItemIDs(segments int64) []string {
+	var ids []string
+	for seg := int64(0); seg < segments; seg++ {
+		for page := 0; page < scanPagesPerSegment; page++ {
+			for i := 0; i < 2; i++ {
+				ids = append(ids, fmt.Sprintf("%d-%d-%d", seg, page, i))
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+type scanRecord struct {
+	ID string
+}
+
+func TestParallelScanner(t *testing.T) {
+	var inputs []dynamodb.ScanInput
+	scanner := dynamodbmanager.NewParallelScannerWithClient(scanSvc(&inputs), func(s *dynamodbmanager.ParallelScanner) {
+		s.TotalSegments = 3
+	})
+
+	var m sync.Mutex
+	var ids []string
+	err := scanner.Scan(aws.BackgroundContext(), &dynamodb.ScanInput{
+		TableName: aws.String("table"),
+	}, func(page *dynamodbmanager.ScanPage) error {
+		var records []scanRecord
+		if err := page.UnmarshalItems(&records); err != nil {
+			return err
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		for _, r := range records {
+			ids = append(ids, r.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	sort.Strings(ids)
+	if e, a := scanItemIDs(3), ids; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	if e, a := 3*scanPagesPerSegment, len(inputs); e != a {
+		t.Fatalf("expect %v requests, got %v", e, a)
+	}
+	for _, in := range inputs {
+		if e, a := int64(3), aws.Int64Value(in.TotalSegments); e != a {
+			t.Errorf("expect %v total segments, got %v", e, a)
+		}
+		if in.ReturnConsumedCapacity != nil {
+			t.Errorf("expect no consumed capacity, got %v", *in.ReturnConsumedCapacity)
+		}
+	}
+}
+
+func TestParallelScanner_ResumeFromCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scancheckpoint")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := &dynamodb.ScanInput{TableName: aws.String("table")}
+	newScanner := func(inputs *[]dynamodb.ScanInput) *dynamodbmanager.ParallelScanner {
+		return dynamodbmanager.NewParallelScannerWithClient(scanSvc(inputs), func(s *dynamodbmanager.ParallelScanner) {
+			s.TotalSegments = 2
+			s.CheckpointStore = dynamodbmanager.NewFileScanCheckpointStore(dir)
+		})
+	}
+
+	var m sync.Mutex
+	var ids []string
+	collect := func(fail string) func(*dynamodbmanager.ScanPage) error {
+		return func(page *dynamodbmanager.ScanPage) error {
+			m.Lock()
+			defer m.Unlock()
+
+			var pageIDs []string
+			for _, item := range page.Items {
+				id := itemID(item)
+				if id == fail {
+					return awserr.New("ProcessingError", "failed to process "+id, nil)
+				}
+				pageIDs = append(pageIDs, id)
+			}
+			ids = append(ids, pageIDs...)
+			return nil
+		}
+	}
+
+	// Fail processing the second page of segment 1.
+	err = newScanner(nil).Scan(aws.BackgroundContext(), input, collect("1-1-0"))
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := "ProcessingError", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	cp, err := dynamodbmanager.NewFileScanCheckpointStore(dir).GetScanCheckpoint("table", "", 1, 2)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if cp == nil || cp.Done {
+		t.Fatalf("expect incomplete checkpoint for segment 1, got %v", cp)
+	}
+	if e, a := "0", aws.StringValue(cp.LastEvaluatedKey["Page"].N); e != a {
+		t.Errorf("expect checkpoint after page %v, got %v", e, a)
+	}
+
+	var inputs []dynamodb.ScanInput
+	if err := newScanner(&inputs).Scan(aws.BackgroundContext(), input, collect("")); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	// Each item is processed exactly once across both scans.
+	sort.Strings(ids)
+	if e, a := scanItemIDs(2), ids; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	for _, in := range inputs {
+		if aws.Int64Value(in.Segment) == 1 && in.ExclusiveStartKey == nil {
+			t.Errorf("expect segment 1 to resume from its checkpoint")
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 0, len(files); e != a {
+		t.Errorf("expect %v checkpoints after scan completed, got %v", e, a)
+	}
+}
+
+func TestParallelScanner_ReadCapacityLimiter(t *testing.T) {
+	var inputs []dynamodb.ScanInput
+	limiter := dynamodbmanager.NewReadCapacityLimiter(1000)
+	scanner := dynamodbmanager.NewParallelScannerWithClient(scanSvc(&inputs), func(s *dynamodbmanager.ParallelScanner) {
+		s.TotalSegments = 2
+		s.ReadCapacityLimiter = limiter
+	})
+
+	var m sync.Mutex
+	var units float64
+	err := scanner.Scan(aws.BackgroundContext(), &dynamodb.ScanInput{
+		TableName: aws.String("table"),
+	}, func(page *dynamodbmanager.ScanPage) error {
+		m.Lock()
+		defer m.Unlock()
+		units += aws.Float64Value(page.ConsumedCapacity.CapacityUnits)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := float64(2*scanPagesPerSegment)*0.5, units; e != a {
+		t.Errorf("expect %v units consumed, got %v", e, a)
+	}
+	for _, in := range inputs {
+		if e, a := dynamodb.ReturnConsumedCapacityTotal, aws.StringValue(in.ReturnConsumedCapacity); e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+	}
+}
@@ -0,0 +1,450 @@
+package dynamodbmanager
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+const (
+	// DefaultMaxTransactWriteItems is the default maximum number of items a
+	// TransactWriteBuilder's transaction can contain.
+	DefaultMaxTransactWriteItems = 10
+
+	// ErrCodeInvalidTransaction is the error code returned when a
+	// TransactWriteBuilder's transaction cannot be built.
+	ErrCodeInvalidTransaction = "InvalidTransaction"
+)
+
+// Operations of the items of a transaction.
+const (
+	TransactPut            = "Put"
+	TransactUpdate         = "Update"
+	TransactDelete         = "Delete"
+	TransactConditionCheck = "ConditionCheck"
+)
+
+// A TransactWriteBuilder builds the input of a TransactWriteItems call from
+// Go values, marshaled with dynamodbattribute, and expression builders.
+//
+// Each of the builder's transaction items is identified by the index it was
+// added at, which is used to map the cancellation reasons of a canceled
+// transaction back to the item.
+//
+// The builder generates a ClientRequestToken when created, so the
+// transaction can be retried idempotently by executing the same builder
+// again.
+//
+// Example:
+//     b := dynamodbmanager.NewTransactWriteBuilder()
+//     b.PutWithCondition("orders", order,
+//         expression.AttributeNotExists(expression.Name("ID")))
+//     b.Update("customers", customerKey,
+//         expression.Add(expression.Name("OrderCount"), expression.Value(1)))
+//
+//     _, err := b.Execute(aws.BackgroundContext(), svc)
+//     if canceled, ok := err.(*dynamodbmanager.TransactionCanceledError); ok {
+//         for _, reason := range canceled.Reasons {
+//             fmt.Println(reason.Index, reason.Operation, reason.Code)
+//         }
+//     }
+type TransactWriteBuilder struct {
+	// The Encoder used to marshal items and keys. Defaults to the
+	// dynamodbattribute package's default Encoder.
+	Encoder *dynamodbattribute.Encoder
+
+	// The maximum number of items the transaction can contain. Defaults to
+	// DefaultMaxTransactWriteItems.
+	MaxItems int
+
+	// The token making the transaction idempotent. Defaults to a randomly
+	// generated token.
+	ClientRequestToken string
+
+	// The ReturnValuesOnConditionCheckFailure of the transaction's items with
+	// conditions, such as dynamodb.ReturnValuesOnConditionCheckFailureAllOld
+	// to return the items whose conditions failed in the cancellation
+	// reasons. Defaults to not returning items.
+	ReturnValuesOnConditionCheckFailure string
+
+	entries []transactEntry
+	err     error
+}
+
+// transactEntry is an item of the transaction.
+type transactEntry struct {
+	operation string
+	table     string
+	value     interface{}
+	item      *dynamodb.TransactWriteItem
+}
+
+// NewTransactWriteBuilder returns a TransactWriteBuilder with the options
+// applied.
+func NewTransactWriteBuilder(opts ...func(*TransactWriteBuilder)) *TransactWriteBuilder {
+	b := &TransactWriteBuilder{
+		Encoder:            dynamodbattribute.NewEncoder(),
+		MaxItems:           DefaultMaxTransactWriteItems,
+		ClientRequestToken: protocol.GetIdempotencyToken(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Put adds a put of the item, marshaled to an attribute value map, to the
+// table.
+func (b *TransactWriteBuilder) Put(table string, item interface{}) *TransactWriteBuilder {
+	return b.put(table, item, nil)
+}
+
+// PutWithCondition adds a put of the item to the table, if the condition
+// is met.
+func (b *TransactWriteBuilder) PutWithCondition(table string, item interface{}, cond expression.ConditionBuilder) *TransactWriteBuilder {
+	return b.put(table, item, &cond)
+}
+
+func (b *TransactWriteBuilder) put(table string, item interface{}, cond *expression.ConditionBuilder) *TransactWriteBuilder {
+	av, err := b.marshalMap(item)
+	if err != nil {
+		return b.fail(TransactPut, err)
+	}
+
+	expr, err := buildTransactExpression(nil, cond)
+	if err != nil {
+		return b.fail(TransactPut, err)
+	}
+
+	put := &dynamodb.Put{
+		TableName: aws.String(table),
+		Item:      av,
+	}
+	if cond != nil {
+		put.ConditionExpression = expr.Condition()
+		put.ExpressionAttributeNames = expr.Names()
+		put.ExpressionAttributeValues = expr.Values()
+		put.ReturnValuesOnConditionCheckFailure = b.returnValues()
+	}
+
+	return b.add(TransactPut, table, item, &dynamodb.TransactWriteItem{Put: put})
+}
+
+// Update adds an update of the item with the key in the table. The key is
+// marshaled to an attribute value map.
+func (b *TransactWriteBuilder) Update(table string, key interface{}, update expression.UpdateBuilder) *TransactWriteBuilder {
+	return b.update(table, key, update, nil)
+}
+
+// UpdateWithCondition adds an update of the item with the key in the
+// table, if the condition is met.
+func (b *TransactWriteBuilder) UpdateWithCondition(table string, key interface{}, update expression.UpdateBuilder, cond expression.ConditionBuilder) *TransactWriteBuilder {
+	return b.update(table, key, update, &cond)
+}
+
+func (b *TransactWriteBuilder) update(table string, key interface{}, update expression.UpdateBuilder, cond *expression.ConditionBuilder) *TransactWriteBuilder {
+	av, err := b.marshalMap(key)
+	if err != nil {
+		return b.fail(TransactUpdate, err)
+	}
+
+	expr, err := buildTransactExpression(&update, cond)
+	if err != nil {
+		return b.fail(TransactUpdate, err)
+	}
+
+	u := &dynamodb.Update{
+		TableName:                 aws.String(table),
+		Key:                       av,
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+	if cond != nil {
+		u.ConditionExpression = expr.Condition()
+		u.ReturnValuesOnConditionCheckFailure = b.returnValues()
+	}
+
+	return b.add(TransactUpdate, table, key, &dynamodb.TransactWriteItem{Update: u})
+}
+
+// Delete adds a delete of the item with the key from the table.
+func (b *TransactWriteBuilder) Delete(table string, key interface{}) *TransactWriteBuilder {
+	return b.delete(table, key, nil)
+}
+
+// DeleteWithCondition adds a delete of the item with the key from the
+// table, if the condition is met.
+func (b *TransactWriteBuilder) DeleteWithCondition(table string, key interface{}, cond expression.ConditionBuilder) *TransactWriteBuilder {
+	return b.delete(table, key, &cond)
+}
+
+func (b *TransactWriteBuilder) delete(table string, key interface{}, cond *expression.ConditionBuilder) *TransactWriteBuilder {
+	av, err := b.marshalMap(key)
+	if err != nil {
+		return b.fail(TransactDelete, err)
+	}
+
+	expr, err := buildTransactExpression(nil, cond)
+	if err != nil {
+		return b.fail(TransactDelete, err)
+	}
+
+	d := &dynamodb.Delete{
+		TableName: aws.String(table),
+		Key:       av,
+	}
+	if cond != nil {
+		d.ConditionExpression = expr.Condition()
+		d.ExpressionAttributeNames = expr.Names()
+		d.ExpressionAttributeValues = expr.Values()
+		d.ReturnValuesOnConditionCheckFailure = b.returnValues()
+	}
+
+	return b.add(TransactDelete, table, key, &dynamodb.TransactWriteItem{Delete: d})
+}
+
+// ConditionCheck adds a check of the condition on the item with the key in
+// the table. The transaction is canceled if the condition is not met.
+func (b *TransactWriteBuilder) ConditionCheck(table string, key interface{}, cond expression.ConditionBuilder) *TransactWriteBuilder {
+	av, err := b.marshalMap(key)
+	if err != nil {
+		return b.fail(TransactConditionCheck, err)
+	}
+
+	expr, err := buildTransactExpression(nil, &cond)
+	if err != nil {
+		return b.fail(TransactConditionCheck, err)
+	}
+
+	return b.add(TransactConditionCheck, table, key, &dynamodb.TransactWriteItem{
+		ConditionCheck: &dynamodb.ConditionCheck{
+			TableName:                           aws.String(table),
+			Key:                                 av,
+			ConditionExpression:                 expr.Condition(),
+			ExpressionAttributeNames:            expr.Names(),
+			ExpressionAttributeValues:           expr.Values(),
+			ReturnValuesOnConditionCheckFailure: b.returnValues(),
+		},
+	})
+}
+
+// Len returns the number of items in the transaction.
+func (b *TransactWriteBuilder) Len() int {
+	return len(b.entries)
+}
+
+// Build returns the TransactWriteItemsInput of the transaction. An error is
+// returned if an item could not be marshaled, an expression could not be
+// built, or the transaction has no items or more than MaxItems items.
+func (b *TransactWriteBuilder) Build() (*dynamodb.TransactWriteItemsInput, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.entries) == 0 {
+		return nil, awserr.New(ErrCodeInvalidTransaction, "transaction has no items", nil)
+	}
+
+	input := &dynamodb.TransactWriteItemsInput{}
+	if len(b.ClientRequestToken) != 0 {
+		input.ClientRequestToken = aws.String(b.ClientRequestToken)
+	}
+	for _, e := range b.entries {
+		input.TransactItems = append(input.TransactItems, e.item)
+	}
+
+	return input, nil
+}
+
+// Execute builds the transaction, and writes it with the client's
+// TransactWriteItems. If the transaction is canceled the error returned is a
+// *TransactionCanceledError, with the cancellation reasons of the items
+// which caused the transaction to be canceled.
+func (b *TransactWriteBuilder) Execute(ctx aws.Context, client dynamodbiface.DynamoDBAPI, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	input, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	req, out := client.TransactWriteItemsRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+
+	var reasons []*dynamodb.CancellationReason
+	req.Handlers.UnmarshalError.PushFrontNamed(request.NamedHandler{
+		Name: "dynamodbmanager.ReadCancellationReasons",
+		Fn: func(r *request.Request) {
+			reasons = readCancellationReasons(r)
+		},
+	})
+
+	if err := req.Send(); err != nil {
+		reqErr, ok := err.(awserr.RequestFailure)
+		if !ok || reqErr.Code() != dynamodb.ErrCodeTransactionCanceledException {
+			return out, err
+		}
+		return out, b.canceledError(reqErr, reasons)
+	}
+
+	return out, nil
+}
+
+func (b *TransactWriteBuilder) marshalMap(v interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	av, err := b.Encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	if av == nil || av.M == nil {
+		return nil, fmt.Errorf("%T must marshal to a map", v)
+	}
+	return av.M, nil
+}
+
+func (b *TransactWriteBuilder) returnValues() *string {
+	if len(b.ReturnValuesOnConditionCheckFailure) == 0 {
+		return nil
+	}
+	return aws.String(b.ReturnValuesOnConditionCheckFailure)
+}
+
+// add adds the item to the transaction, failing if the transaction is full.
+func (b *TransactWriteBuilder) add(op, table string, value interface{}, item *dynamodb.TransactWriteItem) *TransactWriteBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	max := b.MaxItems
+	if max <= 0 {
+		max = DefaultMaxTransactWriteItems
+	}
+	if len(b.entries) >= max {
+		b.err = awserr.New(ErrCodeInvalidTransaction,
+			fmt.Sprintf("transaction cannot contain more than %d items", max), nil)
+		return b
+	}
+
+	b.entries = append(b.entries, transactEntry{
+		operation: op,
+		table:     table,
+		value:     value,
+		item:      item,
+	})
+	return b
+}
+
+// fail records the first error adding an item to the transaction.
+func (b *TransactWriteBuilder) fail(op string, err error) *TransactWriteBuilder {
+	if b.err == nil {
+		b.err = awserr.New(ErrCodeInvalidTransaction,
+			fmt.Sprintf("failed to add %s item %d to transaction", op, len(b.entries)), err)
+	}
+	return b
+}
+
+func buildTransactExpression(update *expression.UpdateBuilder, cond *expression.ConditionBuilder) (expression.Expression, error) {
+	builder := expression.NewBuilder()
+	if update != nil {
+		builder = builder.WithUpdate(*update)
+	}
+	if cond != nil {
+		builder = builder.WithCondition(*cond)
+	}
+	if update == nil && cond == nil {
+		return expression.Expression{}, nil
+	}
+	return builder.Build()
+}
+
+// TransactionCanceledError is the error returned by a TransactWriteBuilder's
+// Execute when the transaction is canceled. It wraps the
+// TransactionCanceledException returned by the service.
+type TransactionCanceledError struct {
+	awserr.RequestFailure
+
+	// The cancellation reasons of the transaction's items which caused the
+	// transaction to be canceled, if the service returned them.
+	Reasons []TransactCancellationReason
+}
+
+// A TransactCancellationReason is the reason an item of a transaction
+// caused the transaction to be canceled.
+type TransactCancellationReason struct {
+	// The index the item was added to the TransactWriteBuilder at.
+	Index int
+
+	// The operation of the item, such as TransactPut.
+	Operation string
+
+	// The table of the item.
+	TableName string
+
+	// The item or key passed to the TransactWriteBuilder.
+	Value interface{}
+
+	// The code and message of the reason, such as
+	// "ConditionalCheckFailed".
+	Code    string
+	Message string
+
+	// The item's attributes, if the builder's
+	// ReturnValuesOnConditionCheckFailure requested them.
+	Item map[string]*dynamodb.AttributeValue
+}
+
+func (b *TransactWriteBuilder) canceledError(err awserr.RequestFailure, reasons []*dynamodb.CancellationReason) *TransactionCanceledError {
+	canceled := &TransactionCanceledError{RequestFailure: err}
+
+	for i, r := range reasons {
+		code := aws.StringValue(r.Code)
+		if i >= len(b.entries) || len(code) == 0 || code == "None" {
+			continue
+		}
+
+		e := b.entries[i]
+		canceled.Reasons = append(canceled.Reasons, TransactCancellationReason{
+			Index:     i,
+			Operation: e.operation,
+			TableName: e.table,
+			Value:     e.value,
+			Code:      code,
+			Message:   aws.StringValue(r.Message),
+			Item:      r.Item,
+		})
+	}
+
+	return canceled
+}
+
+// readCancellationReasons reads the cancellation reasons from the error
+// response's body, restoring the body for the protocol's error unmarshaler.
+func readCancellationReasons(r *request.Request) []*dynamodb.CancellationReason {
+	if r.HTTPResponse == nil || r.HTTPResponse.Body == nil {
+		return nil
+	}
+
+	b, err := ioutil.ReadAll(r.HTTPResponse.Body)
+	r.HTTPResponse.Body.Close()
+	r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+
+	var resp struct {
+		CancellationReasons []*dynamodb.CancellationReason `type:"list"`
+	}
+	if err := jsonutil.UnmarshalJSON(&resp, bytes.NewReader(b)); err != nil {
+		return nil
+	}
+	return resp.CancellationReasons
+}
@@ -0,0 +1,245 @@
+package dynamodbmanager_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+type transactKey struct {
+	ID string
+}
+
+type transactOrder struct {
+	ID    string
+	Total int
+}
+
+func TestTransactWriteBuilder_Build(t *testing.T) {
+	b := dynamodbmanager.NewTransactWriteBuilder(func(b *dynamodbmanager.TransactWriteBuilder) {
+		b.ClientRequestToken = "token"
+	})
+	b.PutWithCondition("orders", transactOrder{ID: "o1", Total: 10},
+		expression.AttributeNotExists(expression.Name("ID"))).
+		Update("customers", transactKey{ID: "c1"},
+			expression.Add(expression.Name("OrderCount"), expression.Value(1))).
+		Delete("carts", transactKey{ID: "c1"}).
+		ConditionCheck("customers", transactKey{ID: "c2"},
+			expression.AttributeExists(expression.Name("ID")))
+
+	if e, a := 4, b.Len(); e != a {
+		t.Errorf("expect %v items, got %v", e, a)
+	}
+
+	input, err := b.Build()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "token", aws.StringValue(input.ClientRequestToken); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := 4, len(input.TransactItems); e != a {
+		t.Fatalf("expect %v items, got %v", e, a)
+	}
+
+	put := input.TransactItems[0].Put
+	if e, a := "orders", aws.StringValue(put.TableName); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "10", aws.StringValue(put.Item["Total"].N); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "attribute_not_exists (#0)", aws.StringValue(put.ConditionExpression); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "ID", aws.StringValue(put.ExpressionAttributeNames["#0"]); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	update := input.TransactItems[1].Update
+	if e, a := "c1", aws.StringValue(update.Key["ID"].S); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "ADD #0 :0\n", aws.StringValue(update.UpdateExpression); e != a {
+		t.Errorf("expect %q, got %q", e, a)
+	}
+	if update.ConditionExpression != nil {
+		t.Errorf("expect no condition, got %v", *update.ConditionExpression)
+	}
+
+	del := input.TransactItems[2].Delete
+	if e, a := "carts", aws.StringValue(del.TableName); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if del.ConditionExpression != nil || del.ExpressionAttributeNames != nil {
+		t.Errorf("expect no condition, got %v", del)
+	}
+
+	check := input.TransactItems[3].ConditionCheck
+	if e, a := "attribute_exists (#0)", aws.StringValue(check.ConditionExpression); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestTransactWriteBuilder_BuildErrors(t *testing.T) {
+	cases := map[string]struct {
+		Build func(*dynamodbmanager.TransactWriteBuilder)
+	}{
+		"no items": {
+			Build: func(b *dynamodbmanager.TransactWriteBuilder) {},
+		},
+		"too many items": {
+			Build: func(b *dynamodbmanager.TransactWriteBuilder) {
+				for i := 0; i <= dynamodbmanager.DefaultMaxTransactWriteItems; i++ {
+					b.Delete("table", transactKey{ID: "id"})
+				}
+			},
+		},
+		"not a map": {
+			Build: func(b *dynamodbmanager.TransactWriteBuilder) {
+				b.Put("table", "item")
+			},
+		},
+		"invalid condition": {
+			Build: func(b *dynamodbmanager.TransactWriteBuilder) {
+				b.ConditionCheck("table", transactKey{ID: "id"}, expression.ConditionBuilder{})
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := dynamodbmanager.NewTransactWriteBuilder()
+			c.Build(b)
+
+			_, err := b.Build()
+			if err == nil {
+				t.Fatalf("expect error, got none")
+			}
+			if e, a := dynamodbmanager.ErrCodeInvalidTransaction, err.(awserr.Error).Code(); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestTransactWriteBuilder_MaxItems(t *testing.T) {
+	b := dynamodbmanager.NewTransactWriteBuilder(func(b *dynamodbmanager.TransactWriteBuilder) {
+		b.MaxItems = 2
+	})
+	b.Delete("table", transactKey{ID: "1"}).Delete("table", transactKey{ID: "2"})
+	if _, err := b.Build(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	b.Delete("table", transactKey{ID: "3"})
+	if _, err := b.Build(); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+}
+
+func TestTransactWriteBuilder_ClientRequestToken(t *testing.T) {
+	b1 := dynamodbmanager.NewTransactWriteBuilder()
+	b2 := dynamodbmanager.NewTransactWriteBuilder()
+
+	if len(b1.ClientRequestToken) == 0 {
+		t.Fatalf("expect token to be generated")
+	}
+	if b1.ClientRequestToken == b2.ClientRequestToken {
+		t.Errorf("expect unique tokens, got %v", b1.ClientRequestToken)
+	}
+}
+
+func TestTransactWriteBuilder_Execute(t *testing.T) {
+	var input *dynamodb.TransactWriteItemsInput
+	svc := batchSvc(func(r *request.Request) {
+		input = r.Params.(*dynamodb.TransactWriteItemsInput)
+	})
+
+	b := dynamodbmanager.NewTransactWriteBuilder().
+		Put("orders", transactOrder{ID: "o1"})
+	if _, err := b.Execute(aws.BackgroundContext(), svc); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := b.ClientRequestToken, aws.StringValue(input.ClientRequestToken); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := 1, len(input.TransactItems); e != a {
+		t.Errorf("expect %v items, got %v", e, a)
+	}
+}
+
+func TestTransactWriteBuilder_ExecuteCanceled(t *testing.T) {
+	svc := dynamodb.New(unit.Session, &aws.Config{MaxRetries: aws.Int(0)})
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		body := `{"__type":"com.amazonaws.dynamodb.v20120810#TransactionCanceledException",` +
+			`"message":"Transaction cancelled",` +
+			`"CancellationReasons":[{"Code":"None"},` +
+			`{"Code":"ConditionalCheckFailed","Message":"The conditional request failed",` +
+			`"Item":{"ID":{"S":"c2"},"Balance":{"N":"5"}}}]}`
+		r.HTTPResponse = &http.Response{
+			StatusCode: 400,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	})
+
+	key := transactKey{ID: "c2"}
+	b := dynamodbmanager.NewTransactWriteBuilder(func(b *dynamodbmanager.TransactWriteBuilder) {
+		b.ReturnValuesOnConditionCheckFailure = dynamodb.ReturnValuesOnConditionCheckFailureAllOld
+	})
+	b.Put("orders", transactOrder{ID: "o1"}).
+		ConditionCheck("customers", key,
+			expression.Name("Balance").GreaterThanEqual(expression.Value(10)))
+
+	_, err := b.Execute(aws.BackgroundContext(), svc)
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	canceled, ok := err.(*dynamodbmanager.TransactionCanceledError)
+	if !ok {
+		t.Fatalf("expect *TransactionCanceledError, got %T, %v", err, err)
+	}
+	if e, a := dynamodb.ErrCodeTransactionCanceledException, canceled.Code(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := 400, canceled.StatusCode(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	if e, a := 1, len(canceled.Reasons); e != a {
+		t.Fatalf("expect %v reasons, got %v", e, a)
+	}
+	reason := canceled.Reasons[0]
+	if e, a := 1, reason.Index; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := dynamodbmanager.TransactConditionCheck, reason.Operation; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "customers", reason.TableName; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := interface{}(key), reason.Value; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "ConditionalCheckFailed", reason.Code; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "5", aws.StringValue(reason.Item["Balance"].N); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
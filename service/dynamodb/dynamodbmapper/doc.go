@@ -0,0 +1,69 @@
+/*
+Package dynamodbmapper provides a typed mapper for Amazon DynamoDB
+single-table designs, where items of multiple entity types are stored in the
+same table.
+
+Declaring Entities
+
+Entities are Go struct types whose key attributes are declared with
+`dynamodbmapper` struct tags. The tag names the key, pk or sk, optionally
+prefixed by the name of a global secondary index, followed by the template
+the key's value is rendered from. Templates reference the entity's fields in
+braces.
+
+  type Customer struct {
+    PK string `dynamodbmapper:"pk,CUSTOMER#{ID}"`
+    SK string `dynamodbmapper:"sk,PROFILE"`
+
+    ID    string
+    Email string
+  }
+
+  type Order struct {
+    PK     string `dynamodbmapper:"pk,CUSTOMER#{CustomerID}"`
+    SK     string `dynamodbmapper:"sk,ORDER#{OrderID}"`
+    GSI1PK string `dynamodbmapper:"GSI1.pk,ORDER#{OrderID}"`
+    GSI1SK string `dynamodbmapper:"GSI1.sk,ORDER#{OrderID}"`
+
+    CustomerID string
+    OrderID    string
+  }
+
+  mapper := dynamodbmapper.New(func(m *dynamodbmapper.Mapper) {
+    m.TableName = "app"
+  })
+  mapper.Register("Customer", Customer{})
+  mapper.Register("Order", Order{})
+
+Marshaling and Querying
+
+Marshal renders the keys of an entity's item, and stores the entity's
+registered name in the item's type attribute. Query and KeyCondition build the
+key condition for the items of an entity's partition, matching sort keys by
+the prefix rendered from the entity's fields which are set. The items of a
+Query result page are unmarshaled to their entity types by UnmarshalItems.
+
+  // PK = "CUSTOMER#1"
+  input, err := mapper.Query(Customer{ID: "1"}, "")
+  if err != nil {
+    return err
+  }
+  out, err := svc.Query(input)
+  if err != nil {
+    return err
+  }
+
+  entities, err := mapper.UnmarshalItems(out.Items)
+  if err != nil {
+    return err
+  }
+  for _, e := range entities {
+    switch e := e.(type) {
+    case *Customer:
+      fmt.Println("customer", e.Email)
+    case *Order:
+      fmt.Println("order", e.OrderID)
+    }
+  }
+*/
+package dynamodbmapper
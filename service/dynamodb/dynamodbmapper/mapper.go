@@ -0,0 +1,505 @@
+package dynamodbmapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+const (
+	// DefaultTypeAttributeName is the default name of the attribute the
+	// entity type of an item is stored in.
+	DefaultTypeAttributeName = "Type"
+
+	// ErrCodeInvalidEntity is the error code returned when a type registered
+	// with the Mapper has invalid key tags.
+	ErrCodeInvalidEntity = "InvalidEntity"
+
+	// ErrCodeUnknownEntity is the error code returned when a value or item is
+	// not of an entity type registered with the Mapper.
+	ErrCodeUnknownEntity = "UnknownEntity"
+
+	// ErrCodeMissingKeyValue is the error code returned when the primary key
+	// of an item, or the partition key of a key condition, cannot be rendered
+	// because a field it references is not set.
+	ErrCodeMissingKeyValue = "MissingKeyValue"
+)
+
+// tagKey is the struct tag key the Mapper reads key templates from.
+const tagKey = "dynamodbmapper"
+
+// A Mapper maps Go struct types, entities, to and from the items of a
+// single-table design, where items of multiple entity types are stored in
+// the same table, and distinguished by the values of their keys, and a type
+// attribute.
+//
+// The partition and sort keys of an entity, and the keys of the global
+// secondary indexes it is stored in, are declared with `dynamodbmapper`
+// struct tags on string fields. The tag's value is the key, followed by the
+// template the key's value is composed from. Templates reference the
+// entity's fields in braces.
+//
+//     type Order struct {
+//         PK     string `dynamodbmapper:"pk,CUSTOMER#{CustomerID}"`
+//         SK     string `dynamodbmapper:"sk,ORDER#{OrderID}"`
+//         GSI1PK string `dynamodbmapper:"GSI1.pk,ORDER#{OrderID}"`
+//         GSI1SK string `dynamodbmapper:"GSI1.sk,{Created}"`
+//
+//         CustomerID string
+//         OrderID    string
+//         Created    time.Time
+//     }
+//
+// The name of the key's attribute is the name of the field, or the name set
+// by its `dynamodbav` struct tag. Keys of global secondary indexes are
+// prefixed with the index name. Items are only stored with the keys of an
+// index if all fields referenced by the index's key templates are set,
+// keeping the index sparse.
+//
+// Entity types must be registered with Register before they are used. It is
+// safe to use a Mapper concurrently across goroutines.
+type Mapper struct {
+	// The name of the table the entities are stored in, used by Query.
+	TableName string
+
+	// The name of the attribute the entity type of an item is stored in.
+	// Defaults to DefaultTypeAttributeName.
+	TypeAttributeName string
+
+	// The Encoder and Decoder used to marshal and unmarshal entities.
+	// Defaults to the dynamodbattribute package's default Encoder and
+	// Decoder.
+	Encoder *dynamodbattribute.Encoder
+	Decoder *dynamodbattribute.Decoder
+
+	m      sync.RWMutex
+	byName map[string]*entity
+	byType map[reflect.Type]*entity
+}
+
+// New returns a Mapper with the options applied.
+//
+// Example:
+//     mapper := dynamodbmapper.New(func(m *dynamodbmapper.Mapper) {
+//         m.TableName = "app"
+//     })
+//     if err := mapper.Register("Order", Order{}); err != nil {
+//         return err
+//     }
+func New(opts ...func(*Mapper)) *Mapper {
+	m := &Mapper{
+		TypeAttributeName: DefaultTypeAttributeName,
+		Encoder:           dynamodbattribute.NewEncoder(),
+		Decoder:           dynamodbattribute.NewDecoder(),
+		byName:            map[string]*entity{},
+		byType:            map[reflect.Type]*entity{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// entity is a struct type registered with the Mapper.
+type entity struct {
+	name string
+	typ  reflect.Type
+
+	// keys of the table, and of each index, by index name. The table's key
+	// has an empty index name.
+	keys map[string]*entityKey
+}
+
+type entityKey struct {
+	pk, sk *keyAttribute
+}
+
+type keyAttribute struct {
+	name string
+	tmpl keyTemplate
+}
+
+// Register registers the struct type of the value as an entity named name.
+// The name is stored in the type attribute of the entity's items, and used to
+// unmarshal items to the entity's type. An error is returned if the type's
+// key tags are invalid, or the name or type is already registered.
+func (m *Mapper) Register(name string, v interface{}) error {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return awserr.New(ErrCodeInvalidEntity,
+			fmt.Sprintf("entity %s must be a struct, got %v", name, t), nil)
+	}
+
+	e, err := newEntity(name, t)
+	if err != nil {
+		return err
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if _, ok := m.byName[name]; ok {
+		return awserr.New(ErrCodeInvalidEntity,
+			fmt.Sprintf("entity %s is already registered", name), nil)
+	}
+	if o, ok := m.byType[t]; ok {
+		return awserr.New(ErrCodeInvalidEntity,
+			fmt.Sprintf("type %v is already registered as entity %s", t, o.name), nil)
+	}
+	m.byName[name] = e
+	m.byType[t] = e
+
+	return nil
+}
+
+// newEntity parses the key tags of the struct type.
+func newEntity(name string, t reflect.Type) (*entity, error) {
+	e := &entity{name: name, typ: t, keys: map[string]*entityKey{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(tagKey)
+		if len(tag) == 0 {
+			continue
+		}
+
+		invalid := func(msg string) error {
+			return awserr.New(ErrCodeInvalidEntity,
+				fmt.Sprintf("entity %s field %s %s", name, f.Name, msg), nil)
+		}
+
+		if len(f.PkgPath) != 0 || f.Type.Kind() != reflect.String {
+			return nil, invalid("key must be an exported string field")
+		}
+
+		parts := strings.SplitN(tag, ",", 2)
+		if len(parts) != 2 || len(parts[1]) == 0 {
+			return nil, invalid(fmt.Sprintf("tag %q must have a key template", tag))
+		}
+
+		var index string
+		role := parts[0]
+		if i := strings.LastIndex(role, "."); i >= 0 {
+			index, role = role[:i], role[i+1:]
+		}
+
+		tmpl, err := parseKeyTemplate(t, parts[1])
+		if err != nil {
+			return nil, invalid(err.Error())
+		}
+		attr := &keyAttribute{name: attributeName(f), tmpl: tmpl}
+		if attr.name == "-" {
+			return nil, invalid("key must not be ignored by its dynamodbav tag")
+		}
+
+		key, ok := e.keys[index]
+		if !ok {
+			key = &entityKey{}
+			e.keys[index] = key
+		}
+
+		switch role {
+		case "pk":
+			if key.pk != nil {
+				return nil, invalid("duplicates the partition key of index " + index)
+			}
+			key.pk = attr
+		case "sk":
+			if key.sk != nil {
+				return nil, invalid("duplicates the sort key of index " + index)
+			}
+			key.sk = attr
+		default:
+			return nil, invalid(fmt.Sprintf("tag %q has unknown key %q", tag, role))
+		}
+	}
+
+	for index, key := range e.keys {
+		if key.pk == nil {
+			return nil, awserr.New(ErrCodeInvalidEntity,
+				fmt.Sprintf("entity %s index %q has no partition key", name, index), nil)
+		}
+	}
+	if _, ok := e.keys[""]; !ok {
+		return nil, awserr.New(ErrCodeInvalidEntity,
+			fmt.Sprintf("entity %s has no partition key", name), nil)
+	}
+
+	return e, nil
+}
+
+// attributeName returns the name of the field's attribute.
+func attributeName(f reflect.StructField) string {
+	if tag := f.Tag.Get("dynamodbav"); len(tag) != 0 {
+		if name := strings.Split(tag, ",")[0]; len(name) != 0 {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// entityOf returns the entity of the value's type, and the dereferenced
+// struct value.
+func (m *Mapper) entityOf(v interface{}) (*entity, reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	var e *entity
+	if rv.IsValid() {
+		m.m.RLock()
+		e = m.byType[rv.Type()]
+		m.m.RUnlock()
+	}
+	if e == nil {
+		return nil, rv, awserr.New(ErrCodeUnknownEntity,
+			fmt.Sprintf("type %T is not a registered entity", v), nil)
+	}
+
+	return e, rv, nil
+}
+
+// Marshal marshals the entity to an item, setting the item's keys from their
+// templates, and its type attribute to the entity's name. The entity's fields
+// referenced by the table's key templates must be set.
+//
+// Example:
+//     item, err := mapper.Marshal(Order{CustomerID: "1", OrderID: "42"})
+//     if err != nil {
+//         return err
+//     }
+//     // item["PK"] is "CUSTOMER#1", and item["SK"] is "ORDER#42"
+//     _, err = svc.PutItem(&dynamodb.PutItemInput{
+//         TableName: aws.String("app"),
+//         Item:      item,
+//     })
+func (m *Mapper) Marshal(v interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	e, rv, err := m.entityOf(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkPrimaryKey(rv); err != nil {
+		return nil, err
+	}
+
+	av, err := m.Encoder.Encode(rv.Interface())
+	if err != nil {
+		return nil, err
+	}
+	item := av.M
+	if item == nil {
+		item = map[string]*dynamodb.AttributeValue{}
+	}
+
+	for index, key := range e.keys {
+		for _, attr := range []*keyAttribute{key.pk, key.sk} {
+			if attr == nil {
+				continue
+			}
+			// Items are only added to an index if its keys can be rendered.
+			if len(index) != 0 && key.hasZero(rv) {
+				delete(item, attr.name)
+				continue
+			}
+			item[attr.name] = &dynamodb.AttributeValue{S: aws.String(attr.tmpl.render(rv))}
+		}
+	}
+
+	item[m.TypeAttributeName] = &dynamodb.AttributeValue{S: aws.String(e.name)}
+
+	return item, nil
+}
+
+// hasZero returns if any field referenced by the key's templates is not set.
+func (k *entityKey) hasZero(v reflect.Value) bool {
+	return k.pk.tmpl.hasZero(v) || k.sk != nil && k.sk.tmpl.hasZero(v)
+}
+
+// checkPrimaryKey returns an error if any field referenced by the table's
+// key templates is not set, as the item's key would be shared by all
+// entities without the field.
+func (e *entity) checkPrimaryKey(v reflect.Value) error {
+	key := e.keys[""]
+	if !key.hasZero(v) {
+		return nil
+	}
+
+	tmpl := key.pk.tmpl.raw
+	if key.sk != nil {
+		tmpl += ", " + key.sk.tmpl.raw
+	}
+	return awserr.New(ErrCodeMissingKeyValue,
+		fmt.Sprintf("entity %s primary key requires fields %s to be set", e.name, tmpl), nil)
+}
+
+// Key returns the primary key of the entity's item, for use with GetItem,
+// DeleteItem, and UpdateItem. The entity's fields referenced by the table's
+// key templates must be set.
+func (m *Mapper) Key(v interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	e, rv, err := m.entityOf(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkPrimaryKey(rv); err != nil {
+		return nil, err
+	}
+
+	key := e.keys[""]
+	out := map[string]*dynamodb.AttributeValue{
+		key.pk.name: {S: aws.String(key.pk.tmpl.render(rv))},
+	}
+	if key.sk != nil {
+		out[key.sk.name] = &dynamodb.AttributeValue{S: aws.String(key.sk.tmpl.render(rv))}
+	}
+
+	return out, nil
+}
+
+// KeyCondition returns the key condition querying the table, or the index if
+// index is not empty, for the items of the entity's partition. The entity's
+// fields referenced by the partition key's template must be set.
+//
+// The sort key's template is rendered up to the first field which is not
+// set. If all of the fields are set, the condition matches the sort key
+// exactly, otherwise it matches sort keys beginning with the rendered
+// prefix. Use the condition with expression.Builder's WithKeyCondition.
+//
+// Example:
+//     // PK = "CUSTOMER#1" AND begins_with(SK, "ORDER#")
+//     keyCond, err := mapper.KeyCondition(Order{CustomerID: "1"}, "")
+func (m *Mapper) KeyCondition(v interface{}, index string) (expression.KeyConditionBuilder, error) {
+	e, rv, err := m.entityOf(v)
+	if err != nil {
+		return expression.KeyConditionBuilder{}, err
+	}
+
+	key, ok := e.keys[index]
+	if !ok {
+		return expression.KeyConditionBuilder{}, awserr.New(ErrCodeInvalidEntity,
+			fmt.Sprintf("entity %s has no keys for index %q", e.name, index), nil)
+	}
+
+	pk, ok := key.pk.tmpl.renderPrefix(rv, true)
+	if !ok {
+		return expression.KeyConditionBuilder{}, awserr.New(ErrCodeMissingKeyValue,
+			fmt.Sprintf("entity %s partition key %s requires fields %s to be set",
+				e.name, key.pk.name, key.pk.tmpl.raw), nil)
+	}
+	cond := expression.Key(key.pk.name).Equal(expression.Value(pk))
+
+	if key.sk == nil {
+		return cond, nil
+	}
+
+	sk, full := key.sk.tmpl.renderPrefix(rv, true)
+	switch {
+	case full:
+		cond = cond.And(expression.Key(key.sk.name).Equal(expression.Value(sk)))
+	case len(sk) != 0:
+		cond = cond.And(expression.Key(key.sk.name).BeginsWith(sk))
+	}
+
+	return cond, nil
+}
+
+// Query returns the QueryInput querying the Mapper's table, or the index if
+// index is not empty, with the entity's KeyCondition.
+//
+// Example:
+//     input, err := mapper.Query(Customer{ID: "1"}, "")
+//     if err != nil {
+//         return err
+//     }
+//     err = svc.QueryPages(input, func(page *dynamodb.QueryOutput, last bool) bool {
+//         entities, err := mapper.UnmarshalItems(page.Items)
+//         // ...
+//         return true
+//     })
+func (m *Mapper) Query(v interface{}, index string) (*dynamodb.QueryInput, error) {
+	cond, err := m.KeyCondition(v, index)
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := expression.NewBuilder().WithKeyCondition(cond).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(m.TableName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+	if len(index) != 0 {
+		input.IndexName = aws.String(index)
+	}
+
+	return input, nil
+}
+
+// Unmarshal unmarshals the item to a new value of the entity type named by
+// the item's type attribute, returning a pointer to the value.
+func (m *Mapper) Unmarshal(item map[string]*dynamodb.AttributeValue) (interface{}, error) {
+	var name string
+	if av, ok := item[m.TypeAttributeName]; ok {
+		name = aws.StringValue(av.S)
+	}
+
+	m.m.RLock()
+	e := m.byName[name]
+	m.m.RUnlock()
+	if e == nil {
+		return nil, awserr.New(ErrCodeUnknownEntity,
+			fmt.Sprintf("item type %q is not a registered entity", name), nil)
+	}
+
+	v := reflect.New(e.typ)
+	if err := m.Decoder.Decode(&dynamodb.AttributeValue{M: item}, v.Interface()); err != nil {
+		return nil, err
+	}
+
+	return v.Interface(), nil
+}
+
+// UnmarshalItems unmarshals the items, such as a Query result page of items
+// of multiple entity types, to pointers to values of their entity types.
+//
+// Example:
+//     entities, err := mapper.UnmarshalItems(out.Items)
+//     if err != nil {
+//         return err
+//     }
+//     for _, e := range entities {
+//         switch e := e.(type) {
+//         case *Customer:
+//             // ...
+//         case *Order:
+//             // ...
+//         }
+//     }
+func (m *Mapper) UnmarshalItems(items []map[string]*dynamodb.AttributeValue) ([]interface{}, error) {
+	out := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		v, err := m.Unmarshal(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
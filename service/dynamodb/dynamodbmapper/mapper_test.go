@@ -0,0 +1,480 @@
+package dynamodbmapper
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+type testCustomer struct {
+	PK string `dynamodbmapper:"pk,CUSTOMER#{ID}"`
+	SK string `dynamodbmapper:"sk,PROFILE"`
+
+	ID    string
+	Email string
+}
+
+type testOrder struct {
+	PK     string `dynamodbmapper:"pk,CUSTOMER#{CustomerID}" dynamodbav:"pk"`
+	SK     string `dynamodbmapper:"sk,ORDER#{OrderID}" dynamodbav:"sk"`
+	GSI1PK string `dynamodbmapper:"GSI1.pk,STATUS#{Status}"`
+	GSI1SK string `dynamodbmapper:"GSI1.sk,{Created}#{OrderID}"`
+
+	CustomerID string
+	OrderID    string
+	Status     string
+	Created    time.Time
+}
+
+func newTestMapper(t *testing.T) *Mapper {
+	m := New(func(m *Mapper) {
+		m.TableName = "app"
+	})
+	if err := m.Register("Customer", testCustomer{}); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if err := m.Register("Order", &testOrder{}); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	return m
+}
+
+func TestParseKeyTemplate(t *testing.T) {
+	typ := reflect.TypeOf(testOrder{})
+	v := reflect.ValueOf(testOrder{
+		CustomerID: "1",
+		Created:    time.Date(2018, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600)),
+	})
+
+	cases := map[string]struct {
+		Template string
+		Render   string
+		Prefix   string
+		Full     bool
+		Err      bool
+	}{
+		"literal": {
+			Template: "PROFILE",
+			Render:   "PROFILE", Prefix: "PROFILE", Full: true,
+		},
+		"fields": {
+			Template: "CUSTOMER#{CustomerID}#{Created}",
+			Render:   "CUSTOMER#1#2018-01-02T02:04:05.000000000Z",
+			Prefix:   "CUSTOMER#1#2018-01-02T02:04:05.000000000Z", Full: true,
+		},
+		"zero field": {
+			Template: "CUSTOMER#{CustomerID}#ORDER#{OrderID}#",
+			Render:   "CUSTOMER#1#ORDER##", Prefix: "CUSTOMER#1#ORDER#",
+		},
+		"unknown field": {
+			Template: "CUSTOMER#{Name}",
+			Err:      true,
+		},
+		"unterminated": {
+			Template: "CUSTOMER#{CustomerID",
+			Err:      true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := parseKeyTemplate(typ, c.Template)
+			if c.Err {
+				if err == nil {
+					t.Fatalf("expect error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			if e, a := c.Render, tmpl.render(v); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			prefix, full := tmpl.renderPrefix(v, true)
+			if e, a := c.Prefix, prefix; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := c.Full, full; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestFormatKeyValue_TimeOrder(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		base,
+		base.Add(100 * time.Millisecond),
+		base.Add(150 * time.Millisecond),
+		base.Add(500 * time.Millisecond),
+		base.Add(time.Second).In(time.FixedZone("", -3600)),
+		base.Add(time.Second + time.Nanosecond),
+		base.Add(time.Hour),
+	}
+
+	var keys []string
+	for _, v := range times {
+		keys = append(keys, formatKeyValue(reflect.ValueOf(v)))
+	}
+	if !sort.StringsAreSorted(keys) {
+		t.Errorf("expect keys to sort in time order, got %v", keys)
+	}
+}
+
+func TestMapperRegister_Invalid(t *testing.T) {
+	cases := map[string]interface{}{
+		"not a struct": "abc",
+		"no partition key": struct {
+			SK string `dynamodbmapper:"sk,X"`
+		}{},
+		"index without partition key": struct {
+			PK string `dynamodbmapper:"pk,X"`
+			SK string `dynamodbmapper:"GSI1.sk,X"`
+		}{},
+		"non string key": struct {
+			PK int `dynamodbmapper:"pk,X"`
+		}{},
+		"missing template": struct {
+			PK string `dynamodbmapper:"pk"`
+		}{},
+		"unknown key": struct {
+			PK string `dynamodbmapper:"hash,X"`
+		}{},
+		"duplicate key": struct {
+			PK  string `dynamodbmapper:"pk,X"`
+			PK2 string `dynamodbmapper:"pk,Y"`
+		}{},
+		"ignored key": struct {
+			PK string `dynamodbmapper:"pk,X" dynamodbav:"-"`
+		}{},
+	}
+
+	for name, v := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := New().Register("Entity", v)
+			if err == nil {
+				t.Fatalf("expect error, got none")
+			}
+			if e, a := ErrCodeInvalidEntity, err.(awserr.Error).Code(); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestMapperRegister_Duplicate(t *testing.T) {
+	m := newTestMapper(t)
+
+	if err := m.Register("Customer", testOrder{}); err == nil {
+		t.Errorf("expect duplicate name error, got none")
+	}
+	if err := m.Register("Other", testCustomer{}); err == nil {
+		t.Errorf("expect duplicate type error, got none")
+	}
+}
+
+func TestMapperMarshal(t *testing.T) {
+	m := newTestMapper(t)
+	created := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := map[string]struct {
+		In     interface{}
+		Expect map[string]*dynamodb.AttributeValue
+	}{
+		"constant sort key": {
+			In: testCustomer{ID: "1", Email: "a@example.com"},
+			Expect: map[string]*dynamodb.AttributeValue{
+				"PK":    {S: aws.String("CUSTOMER#1")},
+				"SK":    {S: aws.String("PROFILE")},
+				"ID":    {S: aws.String("1")},
+				"Email": {S: aws.String("a@example.com")},
+				"Type":  {S: aws.String("Customer")},
+			},
+		},
+		"index keys": {
+			In: &testOrder{CustomerID: "1", OrderID: "42", Status: "OPEN", Created: created},
+			Expect: map[string]*dynamodb.AttributeValue{
+				"pk":         {S: aws.String("CUSTOMER#1")},
+				"sk":         {S: aws.String("ORDER#42")},
+				"GSI1PK":     {S: aws.String("STATUS#OPEN")},
+				"GSI1SK":     {S: aws.String("2018-01-02T03:04:05.000000000Z#42")},
+				"CustomerID": {S: aws.String("1")},
+				"OrderID":    {S: aws.String("42")},
+				"Status":     {S: aws.String("OPEN")},
+				"Created":    {S: aws.String("2018-01-02T03:04:05Z")},
+				"Type":       {S: aws.String("Order")},
+			},
+		},
+		"sparse index": {
+			In: testOrder{CustomerID: "1", OrderID: "42", Created: created},
+			Expect: map[string]*dynamodb.AttributeValue{
+				"pk":         {S: aws.String("CUSTOMER#1")},
+				"sk":         {S: aws.String("ORDER#42")},
+				"CustomerID": {S: aws.String("1")},
+				"OrderID":    {S: aws.String("42")},
+				"Status":     {NULL: aws.Bool(true)},
+				"Created":    {S: aws.String("2018-01-02T03:04:05Z")},
+				"Type":       {S: aws.String("Order")},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			item, err := m.Marshal(c.In)
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.Expect, item; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestMapperMarshal_UnknownEntity(t *testing.T) {
+	m := newTestMapper(t)
+
+	for _, v := range []interface{}{nil, struct{}{}, (*testOrder)(nil)} {
+		_, err := m.Marshal(v)
+		if err == nil {
+			t.Fatalf("%T, expect error, got none", v)
+		}
+		if e, a := ErrCodeUnknownEntity, err.(awserr.Error).Code(); e != a {
+			t.Errorf("%T, expect %v, got %v", v, e, a)
+		}
+	}
+}
+
+func TestMapperKey(t *testing.T) {
+	m := newTestMapper(t)
+
+	key, err := m.Key(testOrder{CustomerID: "1", OrderID: "42", Status: "OPEN"})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expect := map[string]*dynamodb.AttributeValue{
+		"pk": {S: aws.String("CUSTOMER#1")},
+		"sk": {S: aws.String("ORDER#42")},
+	}
+	if e, a := expect, key; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+// missingKeyEntities are entities without a field referenced by the table's
+// key templates set.
+var missingKeyEntities = []interface{}{
+	testCustomer{Email: "a@example.com"},
+	testOrder{OrderID: "42", Status: "OPEN"},
+	&testOrder{CustomerID: "1", Status: "OPEN"},
+}
+
+func TestMapperMarshal_MissingKeyValue(t *testing.T) {
+	m := newTestMapper(t)
+
+	for _, v := range missingKeyEntities {
+		_, err := m.Marshal(v)
+		if err == nil {
+			t.Fatalf("%#v, expect error, got none", v)
+		}
+		if e, a := ErrCodeMissingKeyValue, err.(awserr.Error).Code(); e != a {
+			t.Errorf("%#v, expect %v, got %v", v, e, a)
+		}
+	}
+}
+
+func TestMapperKey_MissingKeyValue(t *testing.T) {
+	m := newTestMapper(t)
+
+	for _, v := range missingKeyEntities {
+		_, err := m.Key(v)
+		if err == nil {
+			t.Fatalf("%#v, expect error, got none", v)
+		}
+		if e, a := ErrCodeMissingKeyValue, err.(awserr.Error).Code(); e != a {
+			t.Errorf("%#v, expect %v, got %v", v, e, a)
+		}
+	}
+}
+
+func TestMapperKeyCondition(t *testing.T) {
+	m := newTestMapper(t)
+
+	cases := map[string]struct {
+		In     interface{}
+		Index  string
+		Expect expression.KeyConditionBuilder
+		Err    string
+	}{
+		"sort key prefix": {
+			In: testOrder{CustomerID: "1"},
+			Expect: expression.Key("pk").Equal(expression.Value("CUSTOMER#1")).
+				And(expression.Key("sk").BeginsWith("ORDER#")),
+		},
+		"sort key equal": {
+			In: testOrder{CustomerID: "1", OrderID: "42"},
+			Expect: expression.Key("pk").Equal(expression.Value("CUSTOMER#1")).
+				And(expression.Key("sk").Equal(expression.Value("ORDER#42"))),
+		},
+		"constant sort key": {
+			In: testCustomer{ID: "1"},
+			Expect: expression.Key("PK").Equal(expression.Value("CUSTOMER#1")).
+				And(expression.Key("SK").Equal(expression.Value("PROFILE"))),
+		},
+		"index partition only": {
+			In:     testOrder{Status: "OPEN"},
+			Index:  "GSI1",
+			Expect: expression.Key("GSI1PK").Equal(expression.Value("STATUS#OPEN")),
+		},
+		"missing partition key field": {
+			In:  testOrder{OrderID: "42"},
+			Err: ErrCodeMissingKeyValue,
+		},
+		"unknown index": {
+			In:    testOrder{CustomerID: "1"},
+			Index: "GSI2",
+			Err:   ErrCodeInvalidEntity,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cond, err := m.KeyCondition(c.In, c.Index)
+			if len(c.Err) != 0 {
+				if err == nil {
+					t.Fatalf("expect error, got none")
+				}
+				if e, a := c.Err, err.(awserr.Error).Code(); e != a {
+					t.Errorf("expect %v, got %v", e, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			expect, err := expression.NewBuilder().WithKeyCondition(c.Expect).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			actual, err := expression.NewBuilder().WithKeyCondition(cond).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := aws.StringValue(expect.KeyCondition()), aws.StringValue(actual.KeyCondition()); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := expect.Names(), actual.Names(); !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := expect.Values(), actual.Values(); !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestMapperQuery(t *testing.T) {
+	m := newTestMapper(t)
+
+	input, err := m.Query(testOrder{Status: "OPEN"}, "GSI1")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "app", aws.StringValue(input.TableName); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "GSI1", aws.StringValue(input.IndexName); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "#0 = :0", aws.StringValue(input.KeyConditionExpression); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "GSI1PK", aws.StringValue(input.ExpressionAttributeNames["#0"]); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "STATUS#OPEN", aws.StringValue(input.ExpressionAttributeValues[":0"].S); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	input, err = m.Query(testCustomer{ID: "1"}, "")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if input.IndexName != nil {
+		t.Errorf("expect no index name, got %v", *input.IndexName)
+	}
+}
+
+func TestMapperUnmarshalItems(t *testing.T) {
+	m := newTestMapper(t)
+	created := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	in := []interface{}{
+		&testCustomer{ID: "1", Email: "a@example.com"},
+		&testOrder{CustomerID: "1", OrderID: "42", Status: "OPEN", Created: created},
+		&testOrder{CustomerID: "1", OrderID: "43", Created: created},
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	for _, v := range in {
+		item, err := m.Marshal(v)
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		items = append(items, item)
+	}
+
+	out, err := m.UnmarshalItems(items)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expect := []interface{}{
+		&testCustomer{PK: "CUSTOMER#1", SK: "PROFILE", ID: "1", Email: "a@example.com"},
+		&testOrder{PK: "CUSTOMER#1", SK: "ORDER#42", GSI1PK: "STATUS#OPEN",
+			GSI1SK: "2018-01-02T03:04:05.000000000Z#42", CustomerID: "1", OrderID: "42",
+			Status: "OPEN", Created: created},
+		&testOrder{PK: "CUSTOMER#1", SK: "ORDER#43", CustomerID: "1", OrderID: "43",
+			Created: created},
+	}
+	if e, a := expect, out; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestMapperUnmarshal_UnknownType(t *testing.T) {
+	m := newTestMapper(t)
+
+	cases := map[string]map[string]*dynamodb.AttributeValue{
+		"missing type": {
+			"PK": {S: aws.String("CUSTOMER#1")},
+		},
+		"unknown type": {
+			"PK":   {S: aws.String("CUSTOMER#1")},
+			"Type": {S: aws.String("Invoice")},
+		},
+	}
+
+	for name, item := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := m.Unmarshal(item)
+			if err == nil {
+				t.Fatalf("expect error, got none")
+			}
+			if e, a := ErrCodeUnknownEntity, err.(awserr.Error).Code(); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package dynamodbmapper
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// keyTemplate is a parsed key template, such as "USER#{ID}", composed of
+// literal strings and references to the struct's fields.
+type keyTemplate struct {
+	raw      string
+	segments []templateSegment
+}
+
+type templateSegment struct {
+	literal string
+
+	// The index of the referenced field, nil for literal segments.
+	field []int
+	name  string
+}
+
+// parseKeyTemplate parses the template, resolving the fields it references
+// in the struct type.
+func parseKeyTemplate(t reflect.Type, raw string) (keyTemplate, error) {
+	tmpl := keyTemplate{raw: raw}
+
+	s := raw
+	for len(s) > 0 {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			tmpl.segments = append(tmpl.segments, templateSegment{literal: s})
+			break
+		}
+		if start > 0 {
+			tmpl.segments = append(tmpl.segments, templateSegment{literal: s[:start]})
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return keyTemplate{}, fmt.Errorf("key template %q has unterminated field reference", raw)
+		}
+		name := s[start+1 : start+end]
+
+		f, ok := t.FieldByName(name)
+		if !ok || len(f.PkgPath) != 0 {
+			return keyTemplate{}, fmt.Errorf("key template %q references unknown field %s", raw, name)
+		}
+		tmpl.segments = append(tmpl.segments, templateSegment{field: f.Index, name: name})
+
+		s = s[start+end+1:]
+	}
+
+	return tmpl, nil
+}
+
+// render renders the template with the field values of the struct value.
+func (t keyTemplate) render(v reflect.Value) string {
+	s, _ := t.renderPrefix(v, false)
+	return s
+}
+
+// renderPrefix renders the template with the field values of the struct
+// value. If stopAtZero is set, the template is only rendered up to the
+// first field with a zero value, and false is returned if the template was
+// not fully rendered.
+func (t keyTemplate) renderPrefix(v reflect.Value, stopAtZero bool) (string, bool) {
+	var b bytes.Buffer
+	for _, seg := range t.segments {
+		if seg.field == nil {
+			b.WriteString(seg.literal)
+			continue
+		}
+
+		fv, ok := fieldByIndex(v, seg.field)
+		if !ok || stopAtZero && isZero(fv) {
+			return b.String(), false
+		}
+		b.WriteString(formatKeyValue(fv))
+	}
+
+	return b.String(), true
+}
+
+// hasZero returns if any field referenced by the template has a zero value.
+func (t keyTemplate) hasZero(v reflect.Value) bool {
+	for _, seg := range t.segments {
+		if seg.field == nil {
+			continue
+		}
+		if fv, ok := fieldByIndex(v, seg.field); !ok || isZero(fv) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByIndex returns the field of the struct value, or false if the field
+// is in a nil embedded struct pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// keyTimeLayout is the layout of times within keys. Times are formatted in
+// UTC with a fixed number of fractional digits, so that they sort
+// chronologically.
+const keyTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// formatKeyValue returns the string representation of the value within a
+// key. Times are formatted with keyTimeLayout, and other values with fmt, so
+// integers do not sort numerically unless they have the same number of
+// digits.
+func formatKeyValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.Type().ConvertibleTo(timeType) {
+		return v.Convert(timeType).Interface().(time.Time).UTC().Format(keyTimeLayout)
+	}
+
+	return fmt.Sprint(v.Interface())
+}
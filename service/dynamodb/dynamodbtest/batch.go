@@ -0,0 +1,226 @@
+package dynamodbtest
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Limits of the number of items of batch and transactional requests.
+const (
+	maxBatchGetItems      = 100
+	maxBatchWriteItems    = 25
+	maxTransactWriteItems = 10
+)
+
+func (db *DB) batchGetItem(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	n := 0
+	for _, kaa := range in.RequestItems {
+		n += len(kaa.Keys)
+	}
+	if n > maxBatchGetItems {
+		return nil, newValidationError("Too many items requested for the BatchGetItem call")
+	}
+
+	out := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]*dynamodb.AttributeValue{},
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+	for name, kaa := range in.RequestItems {
+		if err := legacyParameters(map[string]interface{}{
+			"AttributesToGet": kaa.AttributesToGet,
+		}); err != nil {
+			return nil, err
+		}
+
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPlaceholders(kaa.ExpressionAttributeNames, nil, kaa.ProjectionExpression); err != nil {
+			return nil, err
+		}
+		paths, err := parseProjection(kaa.ProjectionExpression, kaa.ExpressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+
+		items := []map[string]*dynamodb.AttributeValue{}
+		seen := map[string]bool{}
+		for _, key := range kaa.Keys {
+			if err := t.validateKey(key); err != nil {
+				return nil, err
+			}
+			k := t.itemKey(key)
+			if seen[k] {
+				return nil, newValidationError("Provided list of item keys contains duplicates")
+			}
+			seen[k] = true
+
+			if it, ok := t.items[k]; ok {
+				items = append(items, project(it, paths))
+			}
+		}
+		out.Responses[name] = items
+	}
+
+	return out, nil
+}
+
+func (db *DB) batchWriteItem(in *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	n := 0
+	for _, reqs := range in.RequestItems {
+		n += len(reqs)
+	}
+	if n > maxBatchWriteItems {
+		return nil, newValidationError("Too many items requested for the BatchWriteItem call")
+	}
+
+	// All of the writes are validated before any is made, as DynamoDB
+	// rejects the whole request if any write is invalid.
+	var writes []write
+	seen := map[string]bool{}
+	for name, reqs := range in.RequestItems {
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, req := range reqs {
+			var w write
+			switch {
+			case req.PutRequest != nil && req.DeleteRequest == nil:
+				w, err = t.preparePut(req.PutRequest.Item, nil, nil, nil)
+			case req.DeleteRequest != nil && req.PutRequest == nil:
+				w, err = t.prepareDelete(req.DeleteRequest.Key, nil, nil, nil)
+			default:
+				err = newValidationError("Supplied AttributeValue has more than one datatypes set, must contain exactly one of the supported datatypes")
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			k := name + "\x00" + w.key
+			if seen[k] {
+				return nil, newValidationError("Provided list of item keys contains duplicates")
+			}
+			seen[k] = true
+			writes = append(writes, w)
+		}
+	}
+
+	for _, w := range writes {
+		w.commit()
+	}
+
+	return &dynamodb.BatchWriteItemOutput{
+		UnprocessedItems: map[string][]*dynamodb.WriteRequest{},
+	}, nil
+}
+
+// transactionCanceledBody is the additional members of the response body of a
+// TransactionCanceledException.
+type transactionCanceledBody struct {
+	_ struct{} `type:"structure"`
+
+	CancellationReasons []*dynamodb.CancellationReason `min:"1" type:"list"`
+}
+
+func (db *DB) transactWriteItems(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	if n := len(in.TransactItems); n == 0 || n > maxTransactWriteItems {
+		return nil, newValidationError("1 validation error detected: Value at 'transactItems' failed to satisfy constraint: Member must have length less than or equal to %d", maxTransactWriteItems)
+	}
+
+	// A transaction repeating the client request token of a previous
+	// transaction is idempotent, and does not write the items again.
+	token := aws.StringValue(in.ClientRequestToken)
+	if len(token) != 0 && db.tokens[token] {
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	}
+
+	writes := make([]write, 0, len(in.TransactItems))
+	reasons := make([]*dynamodb.CancellationReason, 0, len(in.TransactItems))
+	canceled := false
+	seen := map[string]bool{}
+	for _, ti := range in.TransactItems {
+		var (
+			name         *string
+			returnValues *string
+			w            write
+			err          error
+			t            *table
+		)
+		switch {
+		case ti.Put != nil:
+			name, returnValues = ti.Put.TableName, ti.Put.ReturnValuesOnConditionCheckFailure
+			if t, err = db.table(name); err == nil {
+				w, err = t.preparePut(ti.Put.Item, ti.Put.ConditionExpression,
+					ti.Put.ExpressionAttributeNames, ti.Put.ExpressionAttributeValues)
+			}
+		case ti.Update != nil:
+			name, returnValues = ti.Update.TableName, ti.Update.ReturnValuesOnConditionCheckFailure
+			if t, err = db.table(name); err == nil {
+				w, err = t.prepareUpdate(ti.Update.Key, ti.Update.UpdateExpression, ti.Update.ConditionExpression,
+					ti.Update.ExpressionAttributeNames, ti.Update.ExpressionAttributeValues)
+			}
+		case ti.Delete != nil:
+			name, returnValues = ti.Delete.TableName, ti.Delete.ReturnValuesOnConditionCheckFailure
+			if t, err = db.table(name); err == nil {
+				w, err = t.prepareDelete(ti.Delete.Key, ti.Delete.ConditionExpression,
+					ti.Delete.ExpressionAttributeNames, ti.Delete.ExpressionAttributeValues)
+			}
+		case ti.ConditionCheck != nil:
+			name, returnValues = ti.ConditionCheck.TableName, ti.ConditionCheck.ReturnValuesOnConditionCheckFailure
+			if t, err = db.table(name); err == nil {
+				w, err = t.prepareConditionCheck(ti.ConditionCheck.Key, ti.ConditionCheck.ConditionExpression,
+					ti.ConditionCheck.ExpressionAttributeNames, ti.ConditionCheck.ExpressionAttributeValues)
+			}
+		default:
+			err = newValidationError("TransactItems can only contain one of Check, Put, Update or Delete")
+		}
+
+		reason := &dynamodb.CancellationReason{Code: aws.String("None")}
+		switch {
+		case isConditionalCheckFailed(err):
+			canceled = true
+			reason.Code = aws.String("ConditionalCheckFailed")
+			reason.Message = aws.String("The conditional request failed")
+			if aws.StringValue(returnValues) == dynamodb.ReturnValuesOnConditionCheckFailureAllOld {
+				reason.Item = w.old
+			}
+		case err != nil:
+			return nil, err
+		}
+		reasons = append(reasons, reason)
+
+		k := aws.StringValue(name) + "\x00" + w.key
+		if seen[k] {
+			return nil, newValidationError("Transaction request cannot include multiple operations on one item")
+		}
+		seen[k] = true
+		writes = append(writes, w)
+	}
+
+	if canceled {
+		codes := make([]string, 0, len(reasons))
+		for _, reason := range reasons {
+			codes = append(codes, aws.StringValue(reason.Code))
+		}
+		return nil, &serviceError{
+			code: dynamodb.ErrCodeTransactionCanceledException,
+			message: "Transaction cancelled, please refer cancellation reasons for specific reasons [" +
+				strings.Join(codes, ", ") + "]",
+			body: &transactionCanceledBody{CancellationReasons: reasons},
+		}
+	}
+
+	for _, w := range writes {
+		w.commit()
+	}
+	if len(token) != 0 {
+		db.tokens[token] = true
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
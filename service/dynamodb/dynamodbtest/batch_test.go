@@ -0,0 +1,204 @@
+package dynamodbtest_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbtest"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func TestDB_BatchWriteGetItem(t *testing.T) {
+	svc := newOrdersClient(t)
+	putOrders(t, svc, order("c1", "1", "OPEN"))
+
+	_, err := svc.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{
+			"orders": {
+				{PutRequest: &dynamodb.PutRequest{Item: order("c1", "2", "OPEN")}},
+				{PutRequest: &dynamodb.PutRequest{Item: order("c2", "1", "CLOSED")}},
+				{DeleteRequest: &dynamodb.DeleteRequest{Key: orderKey("c1", "1")}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	out, err := svc.BatchGetItem(&dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			"orders": {
+				Keys: []map[string]*dynamodb.AttributeValue{
+					orderKey("c1", "1"),
+					orderKey("c1", "2"),
+					orderKey("c2", "1"),
+				},
+				ProjectionExpression: aws.String("Customer, #n"),
+				ExpressionAttributeNames: map[string]*string{
+					"#n": aws.String("Number"),
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expect := []map[string]*dynamodb.AttributeValue{
+		orderKey("c1", "2"),
+		orderKey("c2", "1"),
+	}
+	if e, a := expect, out.Responses["orders"]; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := 0, len(out.UnprocessedKeys); e != a {
+		t.Errorf("expect %v unprocessed keys, got %v", e, a)
+	}
+}
+
+func TestDB_BatchWriteItemInvalid(t *testing.T) {
+	svc := newOrdersClient(t)
+
+	_, err := svc.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{
+			"orders": {
+				{PutRequest: &dynamodb.PutRequest{Item: order("c1", "1", "OPEN")}},
+				{PutRequest: &dynamodb.PutRequest{Item: map[string]*dynamodb.AttributeValue{
+					"Customer": {S: aws.String("c1")},
+				}}},
+			},
+		},
+	})
+	expectErrorCode(t, dynamodbtest.ErrCodeValidationException, err)
+
+	out, err := svc.Scan(&dynamodb.ScanInput{TableName: aws.String("orders")})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := int64(0), aws.Int64Value(out.Count); e != a {
+		t.Errorf("expect %v items, got %v", e, a)
+	}
+}
+
+func TestDB_TransactWriteItems(t *testing.T) {
+	svc := newOrdersClient(t)
+	putOrders(t, svc, order("c1", "1", "OPEN"), order("c1", "2", "OPEN"))
+
+	in := &dynamodb.TransactWriteItemsInput{
+		ClientRequestToken: aws.String("token"),
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				TableName:           aws.String("orders"),
+				Item:                order("c1", "3", "OPEN"),
+				ConditionExpression: aws.String("attribute_not_exists(Customer)"),
+			}},
+			{Update: &dynamodb.Update{
+				TableName:                 aws.String("orders"),
+				Key:                       orderKey("c1", "1"),
+				UpdateExpression:          aws.String("SET #s = :s"),
+				ExpressionAttributeNames:  map[string]*string{"#s": aws.String("Status")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":s": {S: aws.String("CLOSED")}},
+			}},
+			{Delete: &dynamodb.Delete{
+				TableName: aws.String("orders"),
+				Key:       orderKey("c1", "2"),
+			}},
+		},
+	}
+	if _, err := svc.TransactWriteItems(in); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	// Repeating the transaction with the same token does not write the items
+	// again.
+	putOrders(t, svc, order("c1", "2", "OPEN"))
+	if _, err := svc.TransactWriteItems(in); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	out, err := svc.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("orders"),
+		KeyConditionExpression:    aws.String("Customer = :c"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": {S: aws.String("c1")}},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expect := []map[string]*dynamodb.AttributeValue{
+		order("c1", "1", "CLOSED"),
+		order("c1", "2", "OPEN"),
+		order("c1", "3", "OPEN"),
+	}
+	if e, a := expect, out.Items; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestDB_TransactWriteItemsDuplicate(t *testing.T) {
+	svc := newOrdersClient(t)
+
+	_, err := svc.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{TableName: aws.String("orders"), Item: order("c1", "1", "OPEN")}},
+			{Delete: &dynamodb.Delete{TableName: aws.String("orders"), Key: orderKey("c1", "1")}},
+		},
+	})
+	expectErrorCode(t, dynamodbtest.ErrCodeValidationException, err)
+}
+
+type transactOrder struct {
+	Customer string
+	Number   int
+	Status   string `dynamodbav:",omitempty"`
+}
+
+type transactOrderKey struct {
+	Customer string
+	Number   int
+}
+
+func TestDB_TransactWriteBuilderCanceled(t *testing.T) {
+	svc := newOrdersClient(t)
+	putOrders(t, svc, order("c1", "1", "OPEN"))
+
+	b := dynamodbmanager.NewTransactWriteBuilder(func(b *dynamodbmanager.TransactWriteBuilder) {
+		b.ReturnValuesOnConditionCheckFailure = dynamodb.ReturnValuesOnConditionCheckFailureAllOld
+	})
+	b.Put("orders", transactOrder{Customer: "c1", Number: 2, Status: "OPEN"}).
+		ConditionCheck("orders", transactOrderKey{Customer: "c1", Number: 1},
+			expression.Name("Status").Equal(expression.Value("CLOSED")))
+
+	_, err := b.Execute(aws.BackgroundContext(), svc)
+	expectErrorCode(t, dynamodb.ErrCodeTransactionCanceledException, err)
+
+	terr, ok := err.(*dynamodbmanager.TransactionCanceledError)
+	if !ok {
+		t.Fatalf("expect transaction canceled error, got %T", err)
+	}
+	if e, a := 1, len(terr.Reasons); e != a {
+		t.Fatalf("expect %v reasons, got %v", e, a)
+	}
+	reason := terr.Reasons[0]
+	if e, a := 1, reason.Index; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "ConditionalCheckFailed", reason.Code; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := order("c1", "1", "OPEN"), reason.Item; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	get, err := svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("orders"),
+		Key:       orderKey("c1", "2"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if get.Item != nil {
+		t.Errorf("expect canceled transaction not to write items, got %v", get.Item)
+	}
+}
@@ -0,0 +1,243 @@
+package dynamodbtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/corehandlers"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Error codes returned by the DB, in addition to the error codes of the
+// dynamodb package.
+const (
+	// ErrCodeValidationException is returned when a request is invalid, such
+	// as a key which does not match the table's key schema.
+	ErrCodeValidationException = "ValidationException"
+
+	// ErrCodeUnknownOperationException is returned for operations the DB does
+	// not support.
+	ErrCodeUnknownOperationException = "UnknownOperationException"
+)
+
+// DB is an in-memory Amazon DynamoDB. Clients created by the DB's NewClient
+// serve their requests from the DB's tables instead of sending them to
+// DynamoDB, allowing code using DynamoDB to be tested without network access.
+//
+// A DB is safe to use concurrently by multiple clients.
+type DB struct {
+	m       sync.Mutex
+	tables  map[string]*table
+	tokens  map[string]bool
+	request int64
+}
+
+// NewDB returns an empty DB.
+func NewDB() *DB {
+	return &DB{
+		tables: map[string]*table{},
+		tokens: map[string]bool{},
+	}
+}
+
+// New returns a DynamoDB client whose requests are served by a new, empty,
+// DB. The client is created from the ConfigProvider, and optional configs,
+// as with dynamodb.New.
+//
+// Example:
+//     svc := dynamodbtest.New(unit.Session)
+//
+//     _, err := svc.CreateTable(&dynamodb.CreateTableInput{
+//         TableName: aws.String("orders"),
+//         KeySchema: []*dynamodb.KeySchemaElement{
+//             {AttributeName: aws.String("ID"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+//         },
+//         AttributeDefinitions: []*dynamodb.AttributeDefinition{
+//             {AttributeName: aws.String("ID"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+//         },
+//     })
+func New(p client.ConfigProvider, cfgs ...*aws.Config) *dynamodb.DynamoDB {
+	return NewDB().NewClient(p, cfgs...)
+}
+
+// NewClient returns a DynamoDB client whose requests are served by the DB.
+// Multiple clients created from the same DB share its tables.
+//
+// The client's requests are built and their responses unmarshaled as with
+// any DynamoDB client, so request options, handlers, paginators, and waiters
+// behave as they would with DynamoDB. Requests are not signed.
+func (db *DB) NewClient(p client.ConfigProvider, cfgs ...*aws.Config) *dynamodb.DynamoDB {
+	svc := dynamodb.New(p, cfgs...)
+	svc.Handlers.Validate.Remove(corehandlers.ValidateEndpointHandler)
+	svc.Handlers.Sign.Clear()
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBackNamed(request.NamedHandler{
+		Name: "dynamodbtest.Send",
+		Fn:   db.send,
+	})
+
+	return svc
+}
+
+// send serves the request, setting the request's HTTP response to the
+// response DynamoDB would return.
+func (db *DB) send(r *request.Request) {
+	// Decode the input from the request's body so the DB never shares values
+	// with the caller's input.
+	in := reflect.New(reflect.TypeOf(r.Params).Elem()).Interface()
+	b, err := ioutil.ReadAll(r.GetBody())
+	if err == nil {
+		err = jsonutil.UnmarshalJSON(in, bytes.NewReader(b))
+	}
+	if err != nil {
+		r.Error = err
+		return
+	}
+
+	db.m.Lock()
+	db.request++
+	requestID := strconv.FormatInt(db.request, 10)
+	out, err := db.handle(aws.StringValue(r.Config.Region), in)
+	db.m.Unlock()
+
+	status := http.StatusOK
+	var body []byte
+	if err != nil {
+		status, body, err = errorResponse(err)
+	} else {
+		body, err = jsonutil.BuildJSON(out)
+	}
+	if err != nil {
+		r.Error = err
+		return
+	}
+
+	r.HTTPResponse = &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Header: http.Header{
+			"Content-Type":     []string{"application/x-amz-json-1.0"},
+			"X-Amzn-Requestid": []string{requestID},
+		},
+		ContentLength: int64(len(body)),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+	}
+}
+
+// handle serves the operation of the input, returning its output.
+func (db *DB) handle(region string, in interface{}) (interface{}, error) {
+	switch in := in.(type) {
+	case *dynamodb.CreateTableInput:
+		return db.createTable(region, in)
+	case *dynamodb.DescribeTableInput:
+		return db.describeTable(in)
+	case *dynamodb.DeleteTableInput:
+		return db.deleteTable(in)
+	case *dynamodb.PutItemInput:
+		return db.putItem(in)
+	case *dynamodb.GetItemInput:
+		return db.getItem(in)
+	case *dynamodb.UpdateItemInput:
+		return db.updateItem(in)
+	case *dynamodb.DeleteItemInput:
+		return db.deleteItem(in)
+	case *dynamodb.QueryInput:
+		return db.query(in)
+	case *dynamodb.ScanInput:
+		return db.scan(in)
+	case *dynamodb.BatchGetItemInput:
+		return db.batchGetItem(in)
+	case *dynamodb.BatchWriteItemInput:
+		return db.batchWriteItem(in)
+	case *dynamodb.TransactWriteItemsInput:
+		return db.transactWriteItems(in)
+	}
+
+	name := reflect.TypeOf(in).Elem().Name()
+	return nil, &serviceError{
+		code:    ErrCodeUnknownOperationException,
+		message: fmt.Sprintf("%s is not supported by dynamodbtest", name[:len(name)-len("Input")]),
+	}
+}
+
+// serviceError is an error returned by DynamoDB.
+type serviceError struct {
+	code    string
+	message string
+
+	// Additional members of the error's response body.
+	body interface{}
+}
+
+func (e *serviceError) Error() string {
+	return e.code + ": " + e.message
+}
+
+func newValidationError(format string, args ...interface{}) *serviceError {
+	return &serviceError{
+		code:    ErrCodeValidationException,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+func newResourceNotFoundError() *serviceError {
+	return &serviceError{
+		code:    dynamodb.ErrCodeResourceNotFoundException,
+		message: "Requested resource not found",
+	}
+}
+
+func newConditionalCheckFailedError() *serviceError {
+	return &serviceError{
+		code:    dynamodb.ErrCodeConditionalCheckFailedException,
+		message: "The conditional request failed",
+	}
+}
+
+// errorResponse returns the status code and body of the error's response.
+func errorResponse(err error) (int, []byte, error) {
+	e, ok := err.(*serviceError)
+	if !ok {
+		e = &serviceError{
+			code:    dynamodb.ErrCodeInternalServerError,
+			message: err.Error(),
+		}
+	}
+
+	fields := map[string]json.RawMessage{}
+	if e.body != nil {
+		b, err := jsonutil.BuildJSON(e.body)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := json.Unmarshal(b, &fields); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	code, _ := json.Marshal("com.amazonaws.dynamodb.v20120810#" + e.code)
+	message, _ := json.Marshal(e.message)
+	fields["__type"] = code
+	fields["message"] = message
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	status := http.StatusBadRequest
+	if e.code == dynamodb.ErrCodeInternalServerError {
+		status = http.StatusInternalServerError
+	}
+	return status, body, nil
+}
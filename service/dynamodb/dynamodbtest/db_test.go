@@ -0,0 +1,207 @@
+package dynamodbtest_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbtest"
+)
+
+var _ dynamodbiface.DynamoDBAPI = dynamodbtest.New(unit.Session)
+
+// newOrdersClient returns a client of a new DB with an orders table, keyed by
+// customer and order number, with an index of orders by status.
+func newOrdersClient(t *testing.T) *dynamodb.DynamoDB {
+	svc := dynamodbtest.New(unit.Session)
+	_, err := svc.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("orders"),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("Customer"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String("Number"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("Customer"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			{AttributeName: aws.String("Number"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+			{AttributeName: aws.String("Status"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("status"),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{AttributeName: aws.String("Status"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				},
+				Projection: &dynamodb.Projection{
+					ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly),
+				},
+			},
+		},
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	return svc
+}
+
+func order(customer, number, status string) map[string]*dynamodb.AttributeValue {
+	it := map[string]*dynamodb.AttributeValue{
+		"Customer": {S: aws.String(customer)},
+		"Number":   {N: aws.String(number)},
+	}
+	if len(status) != 0 {
+		it["Status"] = &dynamodb.AttributeValue{S: aws.String(status)}
+	}
+	return it
+}
+
+func orderKey(customer, number string) map[string]*dynamodb.AttributeValue {
+	return order(customer, number, "")
+}
+
+func putOrders(t *testing.T, svc *dynamodb.DynamoDB, items ...map[string]*dynamodb.AttributeValue) {
+	for _, it := range items {
+		if _, err := svc.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String("orders"),
+			Item:      it,
+		}); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+	}
+}
+
+func expectErrorCode(t *testing.T, code string, err error) {
+	aerr, ok := err.(awserr.RequestFailure)
+	if !ok {
+		t.Fatalf("expect %v request failure, got %v", code, err)
+	}
+	if e, a := code, aerr.Code(); e != a {
+		t.Errorf("expect %v error code, got %v, %v", e, a, aerr.Message())
+	}
+	if e, a := 400, aerr.StatusCode(); e != a {
+		t.Errorf("expect %v status code, got %v", e, a)
+	}
+	if len(aerr.RequestID()) == 0 {
+		t.Errorf("expect request ID")
+	}
+}
+
+func TestDB_Tables(t *testing.T) {
+	svc := newOrdersClient(t)
+	putOrders(t, svc, order("c1", "1", "OPEN"))
+
+	out, err := svc.DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String("orders"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	desc := out.Table
+	if e, a := dynamodb.TableStatusActive, aws.StringValue(desc.TableStatus); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "arn:aws:dynamodb:mock-region:123456789012:table/orders", aws.StringValue(desc.TableArn); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := int64(1), aws.Int64Value(desc.ItemCount); e != a {
+		t.Errorf("expect %v items, got %v", e, a)
+	}
+	if e, a := 1, len(desc.GlobalSecondaryIndexes); e != a {
+		t.Fatalf("expect %v indexes, got %v", e, a)
+	}
+	if e, a := "status", aws.StringValue(desc.GlobalSecondaryIndexes[0].IndexName); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	_, err = svc.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("orders"),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("ID"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("ID"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+	})
+	expectErrorCode(t, dynamodb.ErrCodeResourceInUseException, err)
+
+	if err := svc.WaitUntilTableExists(&dynamodb.DescribeTableInput{
+		TableName: aws.String("orders"),
+	}); err != nil {
+		t.Errorf("expect no error, got %v", err)
+	}
+
+	if _, err := svc.DeleteTable(&dynamodb.DeleteTableInput{
+		TableName: aws.String("orders"),
+	}); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	_, err = svc.DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String("orders"),
+	})
+	expectErrorCode(t, dynamodb.ErrCodeResourceNotFoundException, err)
+}
+
+func TestDB_CreateTableInvalid(t *testing.T) {
+	cases := map[string]*dynamodb.CreateTableInput{
+		"no hash key": {
+			TableName: aws.String("items"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("ID"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+			},
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{AttributeName: aws.String("ID"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			},
+		},
+		"undefined key attribute": {
+			TableName: aws.String("items"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("ID"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			},
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{AttributeName: aws.String("Other"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := dynamodbtest.New(unit.Session)
+			_, err := svc.CreateTable(c)
+			expectErrorCode(t, dynamodbtest.ErrCodeValidationException, err)
+		})
+	}
+}
+
+func TestDB_SharedTables(t *testing.T) {
+	db := dynamodbtest.NewDB()
+	svc1, svc2 := db.NewClient(unit.Session), db.NewClient(unit.Session)
+
+	_, err := svc1.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("items"),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("ID"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("ID"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if _, err := svc2.DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String("items"),
+	}); err != nil {
+		t.Errorf("expect no error, got %v", err)
+	}
+}
+
+func TestDB_UnknownOperation(t *testing.T) {
+	svc := dynamodbtest.New(unit.Session)
+
+	_, err := svc.ListTables(&dynamodb.ListTablesInput{})
+	expectErrorCode(t, dynamodbtest.ErrCodeUnknownOperationException, err)
+}
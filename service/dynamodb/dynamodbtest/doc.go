@@ -0,0 +1,36 @@
+// Package dynamodbtest provides an in-memory Amazon DynamoDB for testing code
+// which uses the dynamodb package's client, or dynamodbiface.DynamoDBAPI,
+// without network access or a DynamoDB Local server.
+//
+// Clients created by a DB are regular dynamodb.DynamoDB clients whose requests
+// are served by the DB's tables. Requests are marshaled, and responses and
+// errors unmarshaled, as they are for DynamoDB, so errors are returned as
+// awserr.RequestFailure errors with DynamoDB's error codes, such as
+// dynamodb.ErrCodeConditionalCheckFailedException.
+//
+// Supported Operations
+//
+// The DB supports CreateTable, DescribeTable, DeleteTable, PutItem, GetItem,
+// UpdateItem, DeleteItem, Query, Scan, BatchGetItem, BatchWriteItem, and
+// TransactWriteItems. Other operations fail with
+// ErrCodeUnknownOperationException.
+//
+// Items are validated against the table's key schema, and condition, update,
+// key condition, filter, and projection expressions are evaluated as DynamoDB
+// evaluates them. Queries and scans are paginated by Limit and
+// ExclusiveStartKey, and may read global and local secondary indexes.
+//
+// The legacy parameters, such as Expected, AttributeUpdates, KeyConditions, and
+// AttributesToGet, are not supported, and fail with ErrCodeValidationException.
+// Capacity, throughput, and item size limits are not enforced, and batch
+// operations never return unprocessed items.
+//
+// Example
+//
+//  db := dynamodbtest.NewDB()
+//  svc := db.NewClient(unit.Session)
+//
+//  _, err := svc.CreateTable(&dynamodb.CreateTableInput{...})
+//  ...
+//  err = codeUnderTest(svc)
+package dynamodbtest
@@ -0,0 +1,513 @@
+package dynamodbtest
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// write is a prepared change to an item of a table, committed once all of the
+// changes of a request have been validated.
+type write struct {
+	table *table
+	key   string
+
+	// The item before, and after, the change. A nil new item deletes the
+	// item, and a nil old item is an item which did not exist.
+	old, new item
+
+	// If the change only checks a condition, and does not change the item.
+	check bool
+}
+
+func (w write) commit() {
+	switch {
+	case w.check:
+	case w.new == nil:
+		delete(w.table.items, w.key)
+	default:
+		w.table.items[w.key] = w.new
+	}
+}
+
+// legacyParameters returns an error if any of the legacy parameters, which
+// are not supported, are set.
+func legacyParameters(params map[string]interface{}) error {
+	var names []string
+	for name, v := range params {
+		if !reflect.ValueOf(v).IsNil() {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+	return newValidationError("dynamodbtest does not support legacy parameters, use expressions instead: %s",
+		strings.Join(names, ", "))
+}
+
+// checkPlaceholders returns an error if any of the names or values are not
+// used by the expressions, as DynamoDB does.
+func checkPlaceholders(names map[string]*string, values map[string]*dynamodb.AttributeValue, exprs ...*string) error {
+	used := map[string]bool{}
+	for _, expr := range exprs {
+		s := aws.StringValue(expr)
+		for i := 0; i < len(s); i++ {
+			if s[i] != '#' && s[i] != ':' {
+				continue
+			}
+			j := i + 1
+			for j < len(s) && isIdentChar(s[j]) {
+				j++
+			}
+			used[s[i:j]] = true
+			i = j - 1
+		}
+	}
+
+	var unused []string
+	for name := range names {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) != 0 {
+		sort.Strings(unused)
+		return newValidationError("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}",
+			strings.Join(unused, ", "))
+	}
+
+	for name := range values {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) != 0 {
+		sort.Strings(unused)
+		return newValidationError("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}",
+			strings.Join(unused, ", "))
+	}
+
+	return nil
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// parseCondition parses the condition expression, returning nil if the
+// expression is not set.
+func parseCondition(param string, expr *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*expression.ConditionBuilder, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	cond, err := expression.ParseCondition(*expr, names, values)
+	if err != nil {
+		return nil, newValidationError("Invalid %s: %v", param, err)
+	}
+	return &cond, nil
+}
+
+// evaluateCondition returns if the item, which is nil if it does not exist,
+// satisfies the condition.
+func evaluateCondition(param string, cond *expression.ConditionBuilder, it item) (bool, error) {
+	if cond == nil {
+		return true, nil
+	}
+	if it == nil {
+		it = item{}
+	}
+	ok, err := cond.Evaluate(it)
+	if err != nil {
+		return false, newValidationError("Invalid %s: %v", param, err)
+	}
+	return ok, nil
+}
+
+func isConditionalCheckFailed(err error) bool {
+	e, ok := err.(*serviceError)
+	return ok && e.code == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+// preparePut prepares the put of the item, if the condition is met.
+func (t *table) preparePut(it item, condExpr *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (write, error) {
+	if err := t.validateItem(it); err != nil {
+		return write{}, err
+	}
+	if err := checkPlaceholders(names, values, condExpr); err != nil {
+		return write{}, err
+	}
+	cond, err := parseCondition("ConditionExpression", condExpr, names, values)
+	if err != nil {
+		return write{}, err
+	}
+
+	w := write{table: t, key: t.itemKey(it), new: it}
+	w.old = t.items[w.key]
+	return w, t.checkCondition(w, cond)
+}
+
+// prepareUpdate prepares the update of the item with the key, if the
+// condition is met. The item is created if it does not exist.
+func (t *table) prepareUpdate(key item, updateExpr, condExpr *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (write, error) {
+	if err := t.validateKey(key); err != nil {
+		return write{}, err
+	}
+	if err := checkPlaceholders(names, values, updateExpr, condExpr); err != nil {
+		return write{}, err
+	}
+	cond, err := parseCondition("ConditionExpression", condExpr, names, values)
+	if err != nil {
+		return write{}, err
+	}
+
+	var update *expression.UpdateBuilder
+	if updateExpr != nil {
+		ub, err := expression.ParseUpdate(*updateExpr, names, values)
+		if err != nil {
+			return write{}, newValidationError("Invalid UpdateExpression: %v", err)
+		}
+		update = &ub
+	}
+
+	w := write{table: t, key: t.itemKey(key)}
+	w.old = t.items[w.key]
+	if err := t.checkCondition(w, cond); err != nil {
+		return w, err
+	}
+
+	base := w.old
+	if base == nil {
+		base = key
+	}
+	if update != nil {
+		if w.new, err = update.Apply(base); err != nil {
+			return write{}, newValidationError("Invalid UpdateExpression: %v", err)
+		}
+	} else {
+		w.new = item{}
+		for k, v := range base {
+			w.new[k] = v
+		}
+	}
+
+	for _, name := range t.key.names {
+		if keyValueString(w.new[name]) != keyValueString(key[name]) {
+			return write{}, newValidationError("One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", name)
+		}
+	}
+	if err := t.validateItem(w.new); err != nil {
+		return write{}, err
+	}
+
+	return w, nil
+}
+
+// prepareDelete prepares the delete of the item with the key, if the
+// condition is met.
+func (t *table) prepareDelete(key item, condExpr *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (write, error) {
+	if err := t.validateKey(key); err != nil {
+		return write{}, err
+	}
+	if err := checkPlaceholders(names, values, condExpr); err != nil {
+		return write{}, err
+	}
+	cond, err := parseCondition("ConditionExpression", condExpr, names, values)
+	if err != nil {
+		return write{}, err
+	}
+
+	w := write{table: t, key: t.itemKey(key)}
+	w.old = t.items[w.key]
+	return w, t.checkCondition(w, cond)
+}
+
+// prepareConditionCheck prepares the check of the condition on the item with
+// the key.
+func (t *table) prepareConditionCheck(key item, condExpr *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (write, error) {
+	w, err := t.prepareDelete(key, condExpr, names, values)
+	w.check = true
+	return w, err
+}
+
+func (t *table) checkCondition(w write, cond *expression.ConditionBuilder) error {
+	ok, err := evaluateCondition("ConditionExpression", cond, w.old)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newConditionalCheckFailedError()
+	}
+	return nil
+}
+
+func (db *DB) putItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if err := legacyParameters(map[string]interface{}{
+		"Expected": in.Expected,
+	}); err != nil {
+		return nil, err
+	}
+	switch aws.StringValue(in.ReturnValues) {
+	case "", dynamodb.ReturnValueNone, dynamodb.ReturnValueAllOld:
+	default:
+		return nil, newValidationError("ReturnValues can only be ALL_OLD or NONE")
+	}
+
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	w, err := t.preparePut(in.Item, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+
+	out := &dynamodb.PutItemOutput{}
+	if aws.StringValue(in.ReturnValues) == dynamodb.ReturnValueAllOld {
+		out.Attributes = w.old
+	}
+	return out, nil
+}
+
+func (db *DB) getItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	if err := legacyParameters(map[string]interface{}{
+		"AttributesToGet": in.AttributesToGet,
+	}); err != nil {
+		return nil, err
+	}
+
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateKey(in.Key); err != nil {
+		return nil, err
+	}
+	if err := checkPlaceholders(in.ExpressionAttributeNames, nil, in.ProjectionExpression); err != nil {
+		return nil, err
+	}
+	paths, err := parseProjection(in.ProjectionExpression, in.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dynamodb.GetItemOutput{}
+	if it, ok := t.items[t.itemKey(in.Key)]; ok {
+		out.Item = project(it, paths)
+	}
+	return out, nil
+}
+
+func (db *DB) updateItem(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	if err := legacyParameters(map[string]interface{}{
+		"AttributeUpdates": in.AttributeUpdates,
+		"Expected":         in.Expected,
+	}); err != nil {
+		return nil, err
+	}
+
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	w, err := t.prepareUpdate(in.Key, in.UpdateExpression, in.ConditionExpression,
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+
+	out := &dynamodb.UpdateItemOutput{}
+	switch aws.StringValue(in.ReturnValues) {
+	case dynamodb.ReturnValueAllOld:
+		out.Attributes = w.old
+	case dynamodb.ReturnValueAllNew:
+		out.Attributes = w.new
+	case dynamodb.ReturnValueUpdatedOld:
+		out.Attributes = updatedAttributes(w.old, w.new)
+	case dynamodb.ReturnValueUpdatedNew:
+		out.Attributes = updatedAttributes(w.new, w.old)
+	}
+	return out, nil
+}
+
+// updatedAttributes returns the attributes of the item which differ in the
+// other item.
+func updatedAttributes(it, other item) item {
+	var updated item
+	for name, av := range it {
+		if !reflect.DeepEqual(av, other[name]) {
+			if updated == nil {
+				updated = item{}
+			}
+			updated[name] = av
+		}
+	}
+	return updated
+}
+
+func (db *DB) deleteItem(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	if err := legacyParameters(map[string]interface{}{
+		"Expected": in.Expected,
+	}); err != nil {
+		return nil, err
+	}
+	switch aws.StringValue(in.ReturnValues) {
+	case "", dynamodb.ReturnValueNone, dynamodb.ReturnValueAllOld:
+	default:
+		return nil, newValidationError("ReturnValues can only be ALL_OLD or NONE")
+	}
+
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	w, err := t.prepareDelete(in.Key, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+
+	out := &dynamodb.DeleteItemOutput{}
+	if aws.StringValue(in.ReturnValues) == dynamodb.ReturnValueAllOld {
+		out.Attributes = w.old
+	}
+	return out, nil
+}
+
+// pathElem is an element of a projected document path.
+type pathElem struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// parseProjection parses the document paths of the projection expression,
+// returning nil if the expression is not set.
+func parseProjection(expr *string, names map[string]*string) ([][]pathElem, error) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	var paths [][]pathElem
+	for _, s := range strings.Split(*expr, ",") {
+		s = strings.TrimSpace(s)
+		var path []pathElem
+		for len(s) > 0 {
+			switch {
+			case s[0] == '[':
+				end := strings.IndexByte(s, ']')
+				if end < 0 || len(path) == 0 {
+					return nil, newValidationError("Invalid ProjectionExpression: %s", *expr)
+				}
+				i, err := strconv.Atoi(strings.TrimSpace(s[1:end]))
+				if err != nil {
+					return nil, newValidationError("Invalid ProjectionExpression: %s", *expr)
+				}
+				path = append(path, pathElem{index: i, isIndex: true})
+				s = s[end+1:]
+				continue
+			case s[0] == '.' && len(path) != 0:
+				s = s[1:]
+			case len(path) != 0:
+				return nil, newValidationError("Invalid ProjectionExpression: %s", *expr)
+			}
+
+			j := 0
+			if j < len(s) && s[j] == '#' {
+				j++
+			}
+			for j < len(s) && isIdentChar(s[j]) {
+				j++
+			}
+			name := s[:j]
+			if len(name) == 0 || name == "#" {
+				return nil, newValidationError("Invalid ProjectionExpression: %s", *expr)
+			}
+			if name[0] == '#' {
+				v, ok := names[name]
+				if !ok {
+					return nil, newValidationError("Invalid ProjectionExpression: An expression attribute name used in the document path is not defined; attribute name: %s", name)
+				}
+				name = aws.StringValue(v)
+			}
+			path = append(path, pathElem{name: name})
+			s = s[j:]
+		}
+		if len(path) == 0 {
+			return nil, newValidationError("Invalid ProjectionExpression: %s", *expr)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// project returns the attributes of the item at the paths. The item is
+// returned if there are no paths.
+func project(it item, paths [][]pathElem) item {
+	if paths == nil {
+		return it
+	}
+
+	projected := item{}
+	for _, path := range paths {
+		src, ok := it[path[0].name]
+		if !ok {
+			continue
+		}
+		if len(path) == 1 {
+			projected[path[0].name] = src
+			continue
+		}
+		if av := projectValue(projected[path[0].name], src, path[1:]); av != nil {
+			projected[path[0].name] = av
+		}
+	}
+	return projected
+}
+
+// projectValue adds the value at the path of src to dst, returning dst.
+func projectValue(dst, src *dynamodb.AttributeValue, path []pathElem) *dynamodb.AttributeValue {
+	elem := path[0]
+
+	var child *dynamodb.AttributeValue
+	if elem.isIndex {
+		if elem.index >= len(src.L) {
+			return dst
+		}
+		child = src.L[elem.index]
+	} else {
+		var ok bool
+		if child, ok = src.M[elem.name]; !ok {
+			return dst
+		}
+	}
+	if len(path) > 1 {
+		if child = projectValue(nil, child, path[1:]); child == nil {
+			return dst
+		}
+	}
+
+	if elem.isIndex {
+		if dst == nil {
+			dst = &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}
+		}
+		dst.L = append(dst.L, child)
+		return dst
+	}
+
+	if dst == nil {
+		dst = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{}}
+	}
+	dst.M[elem.name] = child
+	return dst
+}
@@ -0,0 +1,304 @@
+package dynamodbtest_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbtest"
+)
+
+func TestDB_PutGetDeleteItem(t *testing.T) {
+	svc := newOrdersClient(t)
+
+	it := order("c1", "1", "OPEN")
+	it["Lines"] = &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{
+		{M: map[string]*dynamodb.AttributeValue{"SKU": {S: aws.String("a")}, "Qty": {N: aws.String("2")}}},
+		{M: map[string]*dynamodb.AttributeValue{"SKU": {S: aws.String("b")}, "Qty": {N: aws.String("1")}}},
+	}}
+	putOrders(t, svc, it)
+
+	out, err := svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("orders"),
+		Key:       orderKey("c1", "1.0"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := it, out.Item; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	out, err = svc.GetItem(&dynamodb.GetItemInput{
+		TableName:                aws.String("orders"),
+		Key:                      orderKey("c1", "1"),
+		ProjectionExpression:     aws.String("#s, Lines[1].SKU"),
+		ExpressionAttributeNames: map[string]*string{"#s": aws.String("Status")},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expect := map[string]*dynamodb.AttributeValue{
+		"Status": {S: aws.String("OPEN")},
+		"Lines": {L: []*dynamodb.AttributeValue{
+			{M: map[string]*dynamodb.AttributeValue{"SKU": {S: aws.String("b")}}},
+		}},
+	}
+	if e, a := expect, out.Item; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	del, err := svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:    aws.String("orders"),
+		Key:          orderKey("c1", "1"),
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := it, del.Attributes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	out, err = svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("orders"),
+		Key:       orderKey("c1", "1"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if out.Item != nil {
+		t.Errorf("expect no item, got %v", out.Item)
+	}
+}
+
+func TestDB_PutItemCondition(t *testing.T) {
+	svc := newOrdersClient(t)
+
+	put := &dynamodb.PutItemInput{
+		TableName:           aws.String("orders"),
+		Item:                order("c1", "1", "OPEN"),
+		ConditionExpression: aws.String("attribute_not_exists(Customer)"),
+	}
+	if _, err := svc.PutItem(put); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	put.Item = order("c1", "1", "CLOSED")
+	put.ReturnValues = aws.String(dynamodb.ReturnValueAllOld)
+	out, err := svc.PutItem(put)
+	expectErrorCode(t, dynamodb.ErrCodeConditionalCheckFailedException, err)
+	if out.Attributes != nil {
+		t.Errorf("expect no attributes, got %v", out.Attributes)
+	}
+
+	put.ConditionExpression = aws.String("#s = :s")
+	put.ExpressionAttributeNames = map[string]*string{"#s": aws.String("Status")}
+	put.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":s": {S: aws.String("OPEN")}}
+	out, err = svc.PutItem(put)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := order("c1", "1", "OPEN"), out.Attributes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestDB_UpdateItem(t *testing.T) {
+	cases := map[string]struct {
+		ReturnValues string
+		Expect       map[string]*dynamodb.AttributeValue
+	}{
+		"none": {
+			ReturnValues: dynamodb.ReturnValueNone,
+		},
+		"all old": {
+			ReturnValues: dynamodb.ReturnValueAllOld,
+			Expect: map[string]*dynamodb.AttributeValue{
+				"Customer": {S: aws.String("c1")},
+				"Number":   {N: aws.String("1")},
+				"Status":   {S: aws.String("OPEN")},
+				"Total":    {N: aws.String("10")},
+			},
+		},
+		"updated old": {
+			ReturnValues: dynamodb.ReturnValueUpdatedOld,
+			Expect: map[string]*dynamodb.AttributeValue{
+				"Status": {S: aws.String("OPEN")},
+				"Total":  {N: aws.String("10")},
+			},
+		},
+		"updated new": {
+			ReturnValues: dynamodb.ReturnValueUpdatedNew,
+			Expect: map[string]*dynamodb.AttributeValue{
+				"Total": {N: aws.String("15")},
+			},
+		},
+		"all new": {
+			ReturnValues: dynamodb.ReturnValueAllNew,
+			Expect: map[string]*dynamodb.AttributeValue{
+				"Customer": {S: aws.String("c1")},
+				"Number":   {N: aws.String("1")},
+				"Total":    {N: aws.String("15")},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newOrdersClient(t)
+			it := order("c1", "1", "OPEN")
+			it["Total"] = &dynamodb.AttributeValue{N: aws.String("10")}
+			putOrders(t, svc, it)
+
+			out, err := svc.UpdateItem(&dynamodb.UpdateItemInput{
+				TableName:                aws.String("orders"),
+				Key:                      orderKey("c1", "1"),
+				UpdateExpression:         aws.String("ADD Total :n REMOVE #s"),
+				ConditionExpression:      aws.String("Total < :max"),
+				ExpressionAttributeNames: map[string]*string{"#s": aws.String("Status")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":n":   {N: aws.String("5")},
+					":max": {N: aws.String("100")},
+				},
+				ReturnValues: aws.String(c.ReturnValues),
+			})
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.Expect, out.Attributes; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestDB_UpdateItemCreates(t *testing.T) {
+	svc := newOrdersClient(t)
+
+	out, err := svc.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("orders"),
+		Key:                       orderKey("c1", "1"),
+		UpdateExpression:          aws.String("SET Total = if_not_exists(Total, :zero) + :n"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":zero": {N: aws.String("0")}, ":n": {N: aws.String("3")}},
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expect := orderKey("c1", "1")
+	expect["Total"] = &dynamodb.AttributeValue{N: aws.String("3")}
+	if e, a := expect, out.Attributes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestDB_ItemErrors(t *testing.T) {
+	cases := map[string]struct {
+		Call func(*dynamodb.DynamoDB) error
+		Code string
+	}{
+		"unknown table": {
+			Call: func(svc *dynamodb.DynamoDB) error {
+				_, err := svc.GetItem(&dynamodb.GetItemInput{
+					TableName: aws.String("unknown"),
+					Key:       orderKey("c1", "1"),
+				})
+				return err
+			},
+			Code: dynamodb.ErrCodeResourceNotFoundException,
+		},
+		"key not matching schema": {
+			Call: func(svc *dynamodb.DynamoDB) error {
+				_, err := svc.GetItem(&dynamodb.GetItemInput{
+					TableName: aws.String("orders"),
+					Key: map[string]*dynamodb.AttributeValue{
+						"Customer": {S: aws.String("c1")},
+					},
+				})
+				return err
+			},
+			Code: dynamodbtest.ErrCodeValidationException,
+		},
+		"key of wrong type": {
+			Call: func(svc *dynamodb.DynamoDB) error {
+				_, err := svc.PutItem(&dynamodb.PutItemInput{
+					TableName: aws.String("orders"),
+					Item: map[string]*dynamodb.AttributeValue{
+						"Customer": {S: aws.String("c1")},
+						"Number":   {S: aws.String("1")},
+					},
+				})
+				return err
+			},
+			Code: dynamodbtest.ErrCodeValidationException,
+		},
+		"update of key": {
+			Call: func(svc *dynamodb.DynamoDB) error {
+				_, err := svc.UpdateItem(&dynamodb.UpdateItemInput{
+					TableName:                 aws.String("orders"),
+					Key:                       orderKey("c1", "1"),
+					UpdateExpression:          aws.String("SET Customer = :c"),
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": {S: aws.String("c2")}},
+				})
+				return err
+			},
+			Code: dynamodbtest.ErrCodeValidationException,
+		},
+		"unused placeholder": {
+			Call: func(svc *dynamodb.DynamoDB) error {
+				_, err := svc.DeleteItem(&dynamodb.DeleteItemInput{
+					TableName:                aws.String("orders"),
+					Key:                      orderKey("c1", "1"),
+					ConditionExpression:      aws.String("attribute_exists(Customer)"),
+					ExpressionAttributeNames: map[string]*string{"#s": aws.String("Status")},
+				})
+				return err
+			},
+			Code: dynamodbtest.ErrCodeValidationException,
+		},
+		"invalid expression": {
+			Call: func(svc *dynamodb.DynamoDB) error {
+				_, err := svc.DeleteItem(&dynamodb.DeleteItemInput{
+					TableName:           aws.String("orders"),
+					Key:                 orderKey("c1", "1"),
+					ConditionExpression: aws.String("attribute_exists("),
+				})
+				return err
+			},
+			Code: dynamodbtest.ErrCodeValidationException,
+		},
+		"legacy parameter": {
+			Call: func(svc *dynamodb.DynamoDB) error {
+				_, err := svc.PutItem(&dynamodb.PutItemInput{
+					TableName: aws.String("orders"),
+					Item:      order("c1", "1", ""),
+					Expected: map[string]*dynamodb.ExpectedAttributeValue{
+						"Customer": {Exists: aws.Bool(false)},
+					},
+				})
+				return err
+			},
+			Code: dynamodbtest.ErrCodeValidationException,
+		},
+		"invalid return values": {
+			Call: func(svc *dynamodb.DynamoDB) error {
+				_, err := svc.PutItem(&dynamodb.PutItemInput{
+					TableName:    aws.String("orders"),
+					Item:         order("c1", "1", ""),
+					ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+				})
+				return err
+			},
+			Code: dynamodbtest.ErrCodeValidationException,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newOrdersClient(t)
+			expectErrorCode(t, c.Code, c.Call(svc))
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package dynamodbtest
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// index returns the index with the name, or nil if the name is not set.
+func (t *table) index(name *string) (*index, error) {
+	if name == nil {
+		return nil, nil
+	}
+	idx, ok := t.indexes[*name]
+	if !ok {
+		return nil, newValidationError("The table does not have the specified index: %s", *name)
+	}
+	return idx, nil
+}
+
+// page is a page of the items of a Query or Scan.
+type page struct {
+	items        []item
+	count        int64
+	scannedCount int64
+	lastKey      item
+}
+
+// pageInput is the parameters common to Query and Scan.
+type pageInput struct {
+	index      *index
+	startKey   item
+	limit      int64
+	filter     *expression.ConditionBuilder
+	projection [][]pathElem
+	count      bool
+}
+
+// page returns the page of the items, which are in the order they are read,
+// starting after the ExclusiveStartKey. The attributes are the attributes
+// which order the items.
+func (t *table) page(items []item, attrs []string, forward bool, in pageInput) (page, error) {
+	keyNames := t.key.names
+	if in.index != nil {
+		keyNames = appendNames(append([]string{}, in.index.key.names...), keyNames...)
+	}
+
+	if in.startKey != nil {
+		if !hasAttributes(in.startKey, keyNames) || len(in.startKey) != len(keyNames) {
+			return page{}, newValidationError("The provided starting key is invalid")
+		}
+		start := 0
+		for start < len(items) {
+			c := compareItems(items[start], in.startKey, attrs)
+			if (forward && c > 0) || (!forward && c < 0) {
+				break
+			}
+			start++
+		}
+		items = items[start:]
+	}
+
+	var p page
+	for i, it := range items {
+		if in.limit > 0 && int64(i) == in.limit {
+			last := items[i-1]
+			p.lastKey = item{}
+			for _, name := range keyNames {
+				p.lastKey[name] = last[name]
+			}
+			break
+		}
+		p.scannedCount++
+
+		ok, err := evaluateCondition("FilterExpression", in.filter, it)
+		if err != nil {
+			return page{}, err
+		}
+		if !ok {
+			continue
+		}
+		p.count++
+		if !in.count {
+			p.items = append(p.items, project(t.projectIndex(in.index, it), in.projection))
+		}
+	}
+
+	return p, nil
+}
+
+// appendNames appends the names not already in names.
+func appendNames(names []string, others ...string) []string {
+	for _, other := range others {
+		found := false
+		for _, name := range names {
+			if name == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			names = append(names, other)
+		}
+	}
+	return names
+}
+
+func (db *DB) query(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	if err := legacyParameters(map[string]interface{}{
+		"AttributesToGet":     in.AttributesToGet,
+		"ConditionalOperator": in.ConditionalOperator,
+		"KeyConditions":       in.KeyConditions,
+		"QueryFilter":         in.QueryFilter,
+	}); err != nil {
+		return nil, err
+	}
+
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := t.index(in.IndexName)
+	if err != nil {
+		return nil, err
+	}
+	if in.KeyConditionExpression == nil {
+		return nil, newValidationError("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+	if err := checkPlaceholders(in.ExpressionAttributeNames, in.ExpressionAttributeValues,
+		in.KeyConditionExpression, in.FilterExpression, in.ProjectionExpression); err != nil {
+		return nil, err
+	}
+	keyCond, err := parseCondition("KeyConditionExpression", in.KeyConditionExpression,
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	ks := t.key
+	if idx != nil {
+		ks = idx.key
+	}
+	if err := checkKeyCondition(keyCond, ks); err != nil {
+		return nil, err
+	}
+	pin, err := newPageInput(idx, in.ExclusiveStartKey, in.Limit, in.FilterExpression,
+		in.ProjectionExpression, in.Select, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []item
+	for _, it := range t.indexItems(idx) {
+		ok, err := evaluateCondition("KeyConditionExpression", keyCond, it)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			items = append(items, it)
+		}
+	}
+
+	// Items of a query share the partition key, and are ordered by the sort
+	// key. Items of an index may share the sort key, so are also ordered by
+	// the table's key.
+	var attrs []string
+	if len(ks.rng) != 0 {
+		attrs = append(attrs, ks.rng)
+	}
+	if idx != nil {
+		attrs = appendNames(attrs, t.key.names...)
+	}
+	forward := aws.BoolValue(in.ScanIndexForward) || in.ScanIndexForward == nil
+	sortItems(items, attrs)
+	if !forward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	p, err := t.page(items, attrs, forward, pin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.QueryOutput{
+		Items:            itemsOutput(p.items, pin.count),
+		Count:            aws.Int64(p.count),
+		ScannedCount:     aws.Int64(p.scannedCount),
+		LastEvaluatedKey: p.lastKey,
+	}, nil
+}
+
+// The conditions a key condition expression may be made of, as formatted by
+// expression.Builder.
+var (
+	keyEqualPattern = regexp.MustCompile(`^(#\d+) = :\d+$`)
+	keySortPattern  = regexp.MustCompile(`^(?:(#\d+) (?:=|<|<=|>|>=) :\d+|(#\d+) BETWEEN :\d+ AND :\d+|begins_with \((#\d+), :\d+\))$`)
+)
+
+// checkKeyCondition validates the key condition is an equality condition on
+// the partition key, optionally ANDed with one comparison, BETWEEN or
+// begins_with condition on the sort key.
+func checkKeyCondition(cond *expression.ConditionBuilder, ks keySchema) error {
+	expr, err := expression.NewBuilder().WithCondition(*cond).Build()
+	if err != nil {
+		return newValidationError("Invalid KeyConditionExpression: %v", err)
+	}
+	names := expr.Names()
+	nameOf := func(m []string) string {
+		for _, placeholder := range m[1:] {
+			if len(placeholder) != 0 {
+				return aws.StringValue(names[placeholder])
+			}
+		}
+		return ""
+	}
+
+	s := aws.StringValue(expr.Condition())
+	conds := []string{s}
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		conds = strings.Split(s[1:len(s)-1], ") AND (")
+	}
+	if len(conds) > 2 {
+		return newValidationError("Query key condition not supported")
+	}
+
+	var hasHash, hasRange bool
+	for _, c := range conds {
+		if m := keyEqualPattern.FindStringSubmatch(c); m != nil && !hasHash && nameOf(m) == ks.hash {
+			hasHash = true
+			continue
+		}
+		if m := keySortPattern.FindStringSubmatch(c); m != nil && !hasRange && len(ks.rng) != 0 && nameOf(m) == ks.rng {
+			hasRange = true
+			continue
+		}
+		return newValidationError("Query key condition not supported")
+	}
+	if !hasHash {
+		return newValidationError("Query condition missed key schema element: %s", ks.hash)
+	}
+
+	return nil
+}
+
+func (db *DB) scan(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	if err := legacyParameters(map[string]interface{}{
+		"AttributesToGet":     in.AttributesToGet,
+		"ConditionalOperator": in.ConditionalOperator,
+		"ScanFilter":          in.ScanFilter,
+	}); err != nil {
+		return nil, err
+	}
+
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := t.index(in.IndexName)
+	if err != nil {
+		return nil, err
+	}
+	if (in.Segment == nil) != (in.TotalSegments == nil) {
+		return nil, newValidationError("The Segment parameter is required but was not present in the request when parameter TotalSegments is present")
+	}
+	if in.TotalSegments != nil && (*in.Segment < 0 || *in.Segment >= *in.TotalSegments) {
+		return nil, newValidationError("The Segment parameter is zero-based and must be less than parameter TotalSegments: Segment: %d is not less than TotalSegments: %d",
+			*in.Segment, *in.TotalSegments)
+	}
+	if err := checkPlaceholders(in.ExpressionAttributeNames, in.ExpressionAttributeValues,
+		in.FilterExpression, in.ProjectionExpression); err != nil {
+		return nil, err
+	}
+	pin, err := newPageInput(idx, in.ExclusiveStartKey, in.Limit, in.FilterExpression,
+		in.ProjectionExpression, in.Select, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []item
+	for _, it := range t.indexItems(idx) {
+		if in.TotalSegments != nil && segmentOf(it[t.key.hash], *in.TotalSegments) != *in.Segment {
+			continue
+		}
+		items = append(items, it)
+	}
+
+	// Scans read items in an unspecified order, which is made stable by
+	// ordering the items by their keys.
+	var attrs []string
+	if idx != nil {
+		attrs = append(attrs, idx.key.names...)
+	}
+	attrs = appendNames(attrs, t.key.names...)
+	sortItems(items, attrs)
+
+	p, err := t.page(items, attrs, true, pin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.ScanOutput{
+		Items:            itemsOutput(p.items, pin.count),
+		Count:            aws.Int64(p.count),
+		ScannedCount:     aws.Int64(p.scannedCount),
+		LastEvaluatedKey: p.lastKey,
+	}, nil
+}
+
+func newPageInput(idx *index, startKey item, limit *int64, filterExpr, projectionExpr, sel *string,
+	names map[string]*string, values map[string]*dynamodb.AttributeValue) (pageInput, error) {
+
+	if limit != nil && *limit < 1 {
+		return pageInput{}, newValidationError("1 validation error detected: Value '%d' at 'limit' failed to satisfy constraint: Member must have value greater than or equal to 1", *limit)
+	}
+
+	filter, err := parseCondition("FilterExpression", filterExpr, names, values)
+	if err != nil {
+		return pageInput{}, err
+	}
+	projection, err := parseProjection(projectionExpr, names)
+	if err != nil {
+		return pageInput{}, err
+	}
+
+	return pageInput{
+		index:      idx,
+		startKey:   startKey,
+		limit:      aws.Int64Value(limit),
+		filter:     filter,
+		projection: projection,
+		count:      aws.StringValue(sel) == dynamodb.SelectCount,
+	}, nil
+}
+
+// itemsOutput returns the items of the output, which are not returned when
+// only counting items.
+func itemsOutput(items []item, count bool) []map[string]*dynamodb.AttributeValue {
+	if count {
+		return nil
+	}
+	out := make([]map[string]*dynamodb.AttributeValue, 0, len(items))
+	for _, it := range items {
+		out = append(out, it)
+	}
+	return out
+}
+
+// segmentOf returns the segment of the partition key value.
+func segmentOf(av *dynamodb.AttributeValue, totalSegments int64) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(keyValueString(av)))
+	return int64(h.Sum32() % uint32(totalSegments))
+}
@@ -0,0 +1,352 @@
+package dynamodbtest_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbtest"
+)
+
+func orderNumbers(items []map[string]*dynamodb.AttributeValue) []string {
+	numbers := []string{}
+	for _, it := range items {
+		numbers = append(numbers, aws.StringValue(it["Customer"].S)+"/"+aws.StringValue(it["Number"].N))
+	}
+	return numbers
+}
+
+func TestDB_Query(t *testing.T) {
+	cases := map[string]struct {
+		Input        dynamodb.QueryInput
+		Expect       []string
+		Count        int64
+		ScannedCount int64
+	}{
+		"all": {
+			Input: dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("Customer = :c"),
+			},
+			Expect:       []string{"c1/2", "c1/10", "c1/11"},
+			Count:        3,
+			ScannedCount: 3,
+		},
+		"sort key condition": {
+			Input: dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("Customer = :c AND #n BETWEEN :lo AND :hi"),
+				ExpressionAttributeNames: map[string]*string{
+					"#n": aws.String("Number"),
+				},
+			},
+			Expect:       []string{"c1/10", "c1/11"},
+			Count:        2,
+			ScannedCount: 2,
+		},
+		"sort key condition first": {
+			Input: dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("#n > :lo AND Customer = :c"),
+				ExpressionAttributeNames: map[string]*string{
+					"#n": aws.String("Number"),
+				},
+			},
+			Expect:       []string{"c1/10", "c1/11"},
+			Count:        2,
+			ScannedCount: 2,
+		},
+		"backward": {
+			Input: dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("Customer = :c"),
+				ScanIndexForward:       aws.Bool(false),
+			},
+			Expect:       []string{"c1/11", "c1/10", "c1/2"},
+			Count:        3,
+			ScannedCount: 3,
+		},
+		"filter": {
+			Input: dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("Customer = :c"),
+				FilterExpression:       aws.String("#s = :open"),
+				ExpressionAttributeNames: map[string]*string{
+					"#s": aws.String("Status"),
+				},
+			},
+			Expect:       []string{"c1/2", "c1/11"},
+			Count:        2,
+			ScannedCount: 3,
+		},
+		"count": {
+			Input: dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("Customer = :c"),
+				Select:                 aws.String(dynamodb.SelectCount),
+			},
+			Count:        3,
+			ScannedCount: 3,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newOrdersClient(t)
+			putOrders(t, svc,
+				order("c1", "10", "CLOSED"),
+				order("c1", "2", "OPEN"),
+				order("c1", "11", "OPEN"),
+				order("c2", "1", "OPEN"),
+			)
+
+			values := map[string]*dynamodb.AttributeValue{
+				":c":    {S: aws.String("c1")},
+				":lo":   {N: aws.String("3")},
+				":hi":   {N: aws.String("11")},
+				":open": {S: aws.String("OPEN")},
+			}
+			in := c.Input
+			in.TableName = aws.String("orders")
+			in.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{}
+			for k, v := range values {
+				if containsPlaceholder(k, in.KeyConditionExpression, in.FilterExpression) {
+					in.ExpressionAttributeValues[k] = v
+				}
+			}
+
+			out, err := svc.Query(&in)
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if c.Expect != nil {
+				if e, a := c.Expect, orderNumbers(out.Items); !reflect.DeepEqual(e, a) {
+					t.Errorf("expect %v, got %v", e, a)
+				}
+			} else if out.Items != nil {
+				t.Errorf("expect no items, got %v", out.Items)
+			}
+			if e, a := c.Count, aws.Int64Value(out.Count); e != a {
+				t.Errorf("expect %v count, got %v", e, a)
+			}
+			if e, a := c.ScannedCount, aws.Int64Value(out.ScannedCount); e != a {
+				t.Errorf("expect %v scanned count, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestDB_QueryInvalidKeyCondition(t *testing.T) {
+	cases := map[string]string{
+		"no partition key":        "#n = :n",
+		"partition key compared":  "Customer > :c",
+		"partition key or":        "Customer = :c OR Customer = :d",
+		"partition key not equal": "Customer <> :c",
+		"non key attribute":       "Customer = :c AND #s = :d",
+		"two sort key conditions": "Customer = :c AND #n > :n AND #n < :n",
+		"sort key function":       "Customer = :c AND size(#n) > :n",
+		"sort key not":            "Customer = :c AND NOT #n = :n",
+		"sort key contains":       "Customer = :c AND contains(#n, :n)",
+		"operand order":           ":c = Customer",
+		"name operand":            "Customer = #s",
+	}
+
+	for name, expr := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newOrdersClient(t)
+
+			in := &dynamodb.QueryInput{
+				TableName:              aws.String("orders"),
+				KeyConditionExpression: aws.String(expr),
+				ExpressionAttributeNames: map[string]*string{
+					"#n": aws.String("Number"),
+					"#s": aws.String("Status"),
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":c": {S: aws.String("c1")},
+					":d": {S: aws.String("c2")},
+					":n": {N: aws.String("1")},
+				},
+			}
+			for k := range in.ExpressionAttributeNames {
+				if !containsPlaceholder(k, in.KeyConditionExpression) {
+					delete(in.ExpressionAttributeNames, k)
+				}
+			}
+			for k := range in.ExpressionAttributeValues {
+				if !containsPlaceholder(k, in.KeyConditionExpression) {
+					delete(in.ExpressionAttributeValues, k)
+				}
+			}
+
+			_, err := svc.Query(in)
+			expectErrorCode(t, dynamodbtest.ErrCodeValidationException, err)
+		})
+	}
+}
+
+func containsPlaceholder(placeholder string, exprs ...*string) bool {
+	for _, expr := range exprs {
+		if strings.Contains(aws.StringValue(expr), placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDB_QueryPages(t *testing.T) {
+	svc := newOrdersClient(t)
+	putOrders(t, svc,
+		order("c1", "1", "OPEN"),
+		order("c1", "2", "OPEN"),
+		order("c1", "3", "CLOSED"),
+		order("c1", "4", "OPEN"),
+		order("c1", "5", "OPEN"),
+	)
+
+	var pages [][]string
+	err := svc.QueryPages(&dynamodb.QueryInput{
+		TableName:                 aws.String("orders"),
+		KeyConditionExpression:    aws.String("Customer = :c"),
+		FilterExpression:          aws.String("#s = :s"),
+		ExpressionAttributeNames:  map[string]*string{"#s": aws.String("Status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": {S: aws.String("c1")}, ":s": {S: aws.String("OPEN")}},
+		Limit:                     aws.Int64(2),
+	}, func(out *dynamodb.QueryOutput, last bool) bool {
+		pages = append(pages, orderNumbers(out.Items))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expect := [][]string{{"c1/1", "c1/2"}, {"c1/4"}, {"c1/5"}}
+	if e, a := expect, pages; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestDB_QueryIndex(t *testing.T) {
+	svc := newOrdersClient(t)
+	it := order("c2", "1", "OPEN")
+	it["Total"] = &dynamodb.AttributeValue{N: aws.String("10")}
+	putOrders(t, svc,
+		order("c1", "1", "OPEN"),
+		order("c1", "2", "CLOSED"),
+		order("c1", "3", ""),
+		it,
+	)
+
+	var items []map[string]*dynamodb.AttributeValue
+	err := svc.QueryPages(&dynamodb.QueryInput{
+		TableName:                 aws.String("orders"),
+		IndexName:                 aws.String("status"),
+		KeyConditionExpression:    aws.String("#s = :s"),
+		ExpressionAttributeNames:  map[string]*string{"#s": aws.String("Status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":s": {S: aws.String("OPEN")}},
+		Limit:                     aws.Int64(1),
+	}, func(out *dynamodb.QueryOutput, last bool) bool {
+		if !last {
+			key := out.LastEvaluatedKey
+			if e, a := 3, len(key); e != a {
+				t.Errorf("expect %v key attributes, got %v", e, a)
+			}
+		}
+		items = append(items, out.Items...)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expect := []map[string]*dynamodb.AttributeValue{
+		order("c1", "1", "OPEN"),
+		order("c2", "1", "OPEN"),
+	}
+	if e, a := expect, items; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	_, err = svc.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("orders"),
+		IndexName:                 aws.String("unknown"),
+		KeyConditionExpression:    aws.String("Customer = :c"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": {S: aws.String("c1")}},
+	})
+	expectErrorCode(t, dynamodbtest.ErrCodeValidationException, err)
+}
+
+func TestDB_ScanPages(t *testing.T) {
+	svc := newOrdersClient(t)
+	putOrders(t, svc,
+		order("c2", "1", "OPEN"),
+		order("c1", "2", "OPEN"),
+		order("c1", "1", "OPEN"),
+		order("c3", "1", ""),
+	)
+
+	var numbers []string
+	pages := 0
+	err := svc.ScanPages(&dynamodb.ScanInput{
+		TableName: aws.String("orders"),
+		Limit:     aws.Int64(3),
+	}, func(out *dynamodb.ScanOutput, last bool) bool {
+		pages++
+		numbers = append(numbers, orderNumbers(out.Items)...)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 2, pages; e != a {
+		t.Errorf("expect %v pages, got %v", e, a)
+	}
+	expect := []string{"c1/1", "c1/2", "c2/1", "c3/1"}
+	if e, a := expect, numbers; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	out, err := svc.Scan(&dynamodb.ScanInput{
+		TableName: aws.String("orders"),
+		IndexName: aws.String("status"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := int64(3), aws.Int64Value(out.Count); e != a {
+		t.Errorf("expect %v items, got %v", e, a)
+	}
+}
+
+func TestDB_ScanSegments(t *testing.T) {
+	svc := newOrdersClient(t)
+	for _, c := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		putOrders(t, svc, order(c, "1", ""), order(c, "2", ""))
+	}
+
+	seen := map[string]int{}
+	for segment := int64(0); segment < 3; segment++ {
+		out, err := svc.Scan(&dynamodb.ScanInput{
+			TableName:     aws.String("orders"),
+			Segment:       aws.Int64(segment),
+			TotalSegments: aws.Int64(3),
+		})
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		for _, number := range orderNumbers(out.Items) {
+			seen[number]++
+		}
+	}
+	if e, a := 16, len(seen); e != a {
+		t.Errorf("expect %v items, got %v", e, a)
+	}
+	for number, n := range seen {
+		if n != 1 {
+			t.Errorf("expect %v scanned once, got %v", number, n)
+		}
+	}
+
+	_, err := svc.Scan(&dynamodb.ScanInput{
+		TableName:     aws.String("orders"),
+		Segment:       aws.Int64(3),
+		TotalSegments: aws.Int64(3),
+	})
+	expectErrorCode(t, dynamodbtest.ErrCodeValidationException, err)
+}
@@ -0,0 +1,414 @@
+package dynamodbtest
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type item map[string]*dynamodb.AttributeValue
+
+// keySchema is the partition, and optional sort, key of a table or index.
+type keySchema struct {
+	hash  string
+	rng   string
+	names []string
+}
+
+func newKeySchema(elems []*dynamodb.KeySchemaElement) (keySchema, bool) {
+	var ks keySchema
+	for _, e := range elems {
+		switch aws.StringValue(e.KeyType) {
+		case dynamodb.KeyTypeHash:
+			if len(ks.hash) != 0 {
+				return keySchema{}, false
+			}
+			ks.hash = aws.StringValue(e.AttributeName)
+		case dynamodb.KeyTypeRange:
+			if len(ks.rng) != 0 {
+				return keySchema{}, false
+			}
+			ks.rng = aws.StringValue(e.AttributeName)
+		}
+	}
+	if len(ks.hash) == 0 {
+		return keySchema{}, false
+	}
+
+	ks.names = []string{ks.hash}
+	if len(ks.rng) != 0 {
+		ks.names = append(ks.names, ks.rng)
+	}
+	return ks, true
+}
+
+// index is a secondary index of a table.
+type index struct {
+	name       string
+	key        keySchema
+	projection *dynamodb.Projection
+}
+
+// table is a table of the DB.
+type table struct {
+	desc    *dynamodb.TableDescription
+	key     keySchema
+	attrs   map[string]string
+	indexes map[string]*index
+	items   map[string]item
+}
+
+func (db *DB) table(name *string) (*table, error) {
+	t, ok := db.tables[aws.StringValue(name)]
+	if !ok {
+		return nil, newResourceNotFoundError()
+	}
+	return t, nil
+}
+
+func (db *DB) createTable(region string, in *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	name := aws.StringValue(in.TableName)
+	if _, ok := db.tables[name]; ok {
+		return nil, &serviceError{
+			code:    dynamodb.ErrCodeResourceInUseException,
+			message: "Table already exists: " + name,
+		}
+	}
+
+	t := &table{
+		attrs:   map[string]string{},
+		indexes: map[string]*index{},
+		items:   map[string]item{},
+	}
+	for _, def := range in.AttributeDefinitions {
+		t.attrs[aws.StringValue(def.AttributeName)] = aws.StringValue(def.AttributeType)
+	}
+
+	var ok bool
+	if t.key, ok = newKeySchema(in.KeySchema); !ok {
+		return nil, newValidationError("Invalid KeySchema: the table must have one HASH key, and at most one RANGE key")
+	}
+	if err := t.validateKeySchema(t.key); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	arn := "arn:aws:dynamodb:" + region + ":123456789012:table/" + name
+	t.desc = &dynamodb.TableDescription{
+		TableName:            aws.String(name),
+		TableArn:             aws.String(arn),
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		CreationDateTime:     aws.Time(now),
+		KeySchema:            in.KeySchema,
+		AttributeDefinitions: in.AttributeDefinitions,
+		StreamSpecification:  in.StreamSpecification,
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+			NumberOfDecreasesToday: aws.Int64(0),
+			ReadCapacityUnits:      aws.Int64(0),
+			WriteCapacityUnits:     aws.Int64(0),
+		},
+	}
+	if in.ProvisionedThroughput != nil {
+		t.desc.ProvisionedThroughput.ReadCapacityUnits = in.ProvisionedThroughput.ReadCapacityUnits
+		t.desc.ProvisionedThroughput.WriteCapacityUnits = in.ProvisionedThroughput.WriteCapacityUnits
+	}
+	if in.BillingMode != nil {
+		t.desc.BillingModeSummary = &dynamodb.BillingModeSummary{
+			BillingMode: in.BillingMode,
+		}
+	}
+
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		idx, err := t.addIndex(gsi.IndexName, gsi.KeySchema, gsi.Projection)
+		if err != nil {
+			return nil, err
+		}
+		t.desc.GlobalSecondaryIndexes = append(t.desc.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:   gsi.IndexName,
+			IndexArn:    aws.String(arn + "/index/" + idx.name),
+			IndexStatus: aws.String(dynamodb.IndexStatusActive),
+			KeySchema:   gsi.KeySchema,
+			Projection:  gsi.Projection,
+		})
+	}
+	for _, lsi := range in.LocalSecondaryIndexes {
+		idx, err := t.addIndex(lsi.IndexName, lsi.KeySchema, lsi.Projection)
+		if err != nil {
+			return nil, err
+		}
+		if idx.key.hash != t.key.hash || len(idx.key.rng) == 0 {
+			return nil, newValidationError("Local secondary index %s must have the same hash key as the table, and a range key", idx.name)
+		}
+		t.desc.LocalSecondaryIndexes = append(t.desc.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndexDescription{
+			IndexName:  lsi.IndexName,
+			IndexArn:   aws.String(arn + "/index/" + idx.name),
+			KeySchema:  lsi.KeySchema,
+			Projection: lsi.Projection,
+		})
+	}
+
+	db.tables[name] = t
+	return &dynamodb.CreateTableOutput{TableDescription: t.describe()}, nil
+}
+
+func (t *table) validateKeySchema(ks keySchema) error {
+	for _, name := range ks.names {
+		switch t.attrs[name] {
+		case dynamodb.ScalarAttributeTypeS, dynamodb.ScalarAttributeTypeN, dynamodb.ScalarAttributeTypeB:
+		default:
+			return newValidationError("One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions. Keys: [%s]", name)
+		}
+	}
+	return nil
+}
+
+func (t *table) addIndex(name *string, elems []*dynamodb.KeySchemaElement, projection *dynamodb.Projection) (*index, error) {
+	idx := &index{
+		name:       aws.StringValue(name),
+		projection: projection,
+	}
+	if _, ok := t.indexes[idx.name]; ok {
+		return nil, newValidationError("Duplicate index name: %s", idx.name)
+	}
+
+	var ok bool
+	if idx.key, ok = newKeySchema(elems); !ok {
+		return nil, newValidationError("Invalid KeySchema for index %s", idx.name)
+	}
+	if err := t.validateKeySchema(idx.key); err != nil {
+		return nil, err
+	}
+
+	t.indexes[idx.name] = idx
+	return idx, nil
+}
+
+// describe returns the table's description, updated with the table's
+// current item count.
+func (t *table) describe() *dynamodb.TableDescription {
+	t.desc.ItemCount = aws.Int64(int64(len(t.items)))
+	for _, gsi := range t.desc.GlobalSecondaryIndexes {
+		gsi.ItemCount = aws.Int64(int64(len(t.indexItems(t.indexes[aws.StringValue(gsi.IndexName)]))))
+	}
+	for _, lsi := range t.desc.LocalSecondaryIndexes {
+		lsi.ItemCount = aws.Int64(int64(len(t.indexItems(t.indexes[aws.StringValue(lsi.IndexName)]))))
+	}
+	return t.desc
+}
+
+func (db *DB) describeTable(in *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.DescribeTableOutput{Table: t.describe()}, nil
+}
+
+func (db *DB) deleteTable(in *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	delete(db.tables, aws.StringValue(in.TableName))
+
+	desc := t.describe()
+	desc.TableStatus = aws.String(dynamodb.TableStatusDeleting)
+	return &dynamodb.DeleteTableOutput{TableDescription: desc}, nil
+}
+
+// validateKeyAttribute returns an error if the value is not of the type of
+// the key attribute.
+func (t *table) validateKeyAttribute(name string, av *dynamodb.AttributeValue) error {
+	var ok bool
+	switch t.attrs[name] {
+	case dynamodb.ScalarAttributeTypeS:
+		ok = av.S != nil && len(*av.S) != 0
+	case dynamodb.ScalarAttributeTypeN:
+		ok = av.N != nil
+	case dynamodb.ScalarAttributeTypeB:
+		ok = av.B != nil && len(av.B) != 0
+	}
+	if !ok {
+		return newValidationError("One or more parameter values were invalid: Type mismatch for key %s expected: %s", name, t.attrs[name])
+	}
+	return nil
+}
+
+// validateKey returns an error if the key does not match the table's key
+// schema.
+func (t *table) validateKey(key item) error {
+	if len(key) != len(t.key.names) {
+		return newValidationError("The provided key element does not match the schema")
+	}
+	for _, name := range t.key.names {
+		av, ok := key[name]
+		if !ok {
+			return newValidationError("The provided key element does not match the schema")
+		}
+		if err := t.validateKeyAttribute(name, av); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateItem returns an error if the item is missing the table's key
+// attributes, or has an index key attribute of the wrong type.
+func (t *table) validateItem(it item) error {
+	for _, name := range t.key.names {
+		av, ok := it[name]
+		if !ok {
+			return newValidationError("One or more parameter values were invalid: Missing the key %s in the item", name)
+		}
+		if err := t.validateKeyAttribute(name, av); err != nil {
+			return err
+		}
+	}
+	for _, idx := range t.indexes {
+		for _, name := range idx.key.names {
+			if av, ok := it[name]; ok {
+				if err := t.validateKeyAttribute(name, av); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// keyOf returns the table's key attributes of the item.
+func (t *table) keyOf(it item) item {
+	key := item{}
+	for _, name := range t.key.names {
+		key[name] = it[name]
+	}
+	return key
+}
+
+// itemKey returns the string identifying the item in the table.
+func (t *table) itemKey(it item) string {
+	parts := make([]string, 0, len(t.key.names))
+	for _, name := range t.key.names {
+		parts = append(parts, keyValueString(it[name]))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// keyValueString returns a string uniquely identifying the key value.
+// Numbers are normalized so equal numbers have the same string.
+func keyValueString(av *dynamodb.AttributeValue) string {
+	switch {
+	case av == nil:
+		return ""
+	case av.S != nil:
+		return "S" + *av.S
+	case av.N != nil:
+		if r, ok := new(big.Rat).SetString(*av.N); ok {
+			return "N" + r.RatString()
+		}
+		return "N" + *av.N
+	case av.B != nil:
+		return "B" + string(av.B)
+	}
+	return ""
+}
+
+// compareKeyValues compares the String, Number, or Binary values as DynamoDB
+// orders the sort key values of items.
+func compareKeyValues(a, b *dynamodb.AttributeValue) int {
+	switch {
+	case a == nil || b == nil:
+		if a == nil && b == nil {
+			return 0
+		} else if a == nil {
+			return -1
+		}
+		return 1
+	case a.S != nil && b.S != nil:
+		return strings.Compare(*a.S, *b.S)
+	case a.N != nil && b.N != nil:
+		ra, _ := new(big.Rat).SetString(*a.N)
+		rb, _ := new(big.Rat).SetString(*b.N)
+		if ra == nil || rb == nil {
+			return strings.Compare(*a.N, *b.N)
+		}
+		return ra.Cmp(rb)
+	case a.B != nil && b.B != nil:
+		return bytes.Compare(a.B, b.B)
+	}
+	return 0
+}
+
+// compareItems compares the items by the attributes in order.
+func compareItems(a, b item, attrs []string) int {
+	for _, name := range attrs {
+		if c := compareKeyValues(a[name], b[name]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// indexItems returns the items of the table in the index. Items without the
+// index's key attributes are not in the index.
+func (t *table) indexItems(idx *index) []item {
+	var items []item
+	for _, it := range t.items {
+		if idx != nil && !hasAttributes(it, idx.key.names) {
+			continue
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+func hasAttributes(it item, names []string) bool {
+	for _, name := range names {
+		if _, ok := it[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// sortItems sorts the items by the attributes.
+func sortItems(items []item, attrs []string) {
+	sort.Sort(itemSorter{items: items, attrs: attrs})
+}
+
+type itemSorter struct {
+	items []item
+	attrs []string
+}
+
+func (s itemSorter) Len() int      { return len(s.items) }
+func (s itemSorter) Swap(i, j int) { s.items[i], s.items[j] = s.items[j], s.items[i] }
+func (s itemSorter) Less(i, j int) bool {
+	return compareItems(s.items[i], s.items[j], s.attrs) < 0
+}
+
+// projectIndex returns the attributes of the item projected into the index.
+func (t *table) projectIndex(idx *index, it item) item {
+	if idx == nil || idx.projection == nil ||
+		aws.StringValue(idx.projection.ProjectionType) == dynamodb.ProjectionTypeAll {
+		return it
+	}
+
+	names := append(append([]string{}, t.key.names...), idx.key.names...)
+	if aws.StringValue(idx.projection.ProjectionType) == dynamodb.ProjectionTypeInclude {
+		names = append(names, aws.StringValueSlice(idx.projection.NonKeyAttributes)...)
+	}
+
+	projected := item{}
+	for _, name := range names {
+		if av, ok := it[name]; ok {
+			projected[name] = av
+		}
+	}
+	return projected
+}
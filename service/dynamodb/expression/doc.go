@@ -44,5 +44,36 @@ attribute names and values are aliased. That means that if the
 ExpressionAttributeNames and ExpressionAttributeValues member is not assigned
 with the corresponding Names() and Values() methods, the DynamoDB operation will
 run into a logic error.
+
+Parsing Expressions
+
+Existing Condition Expression and Update Expression strings, such as those
+stored in configuration files, can be parsed back into ConditionBuilders and
+UpdateBuilders with ParseCondition and ParseUpdate. The placeholders of the
+expression are resolved with the ExpressionAttributeNames and
+ExpressionAttributeValues maps the expression was written with.
+
+  cond, err := expression.ParseCondition("#s = :done", input.ExpressionAttributeNames,
+    input.ExpressionAttributeValues)
+  if err != nil {
+    fmt.Println(err)
+  }
+
+  cond = cond.And(expression.Name("Owner").Equal(expression.Value("me")))
+  expr, err := expression.NewBuilder().WithCondition(cond).Build()
+
+Evaluating Expressions
+
+ConditionBuilders can be evaluated against items locally with Evaluate, and
+UpdateBuilders applied to items with Apply, following the semantics of
+DynamoDB. The Expression struct's EvaluateCondition, EvaluateFilter, and
+ApplyUpdate methods do the same for built Expressions. This allows cached
+items to be filtered, and DynamoDB to be modeled in tests.
+
+  filt := expression.Name("Price").LessThan(expression.Value(10))
+  ok, err := filt.Evaluate(item)
+
+  update := expression.Add(expression.Name("Count"), expression.Value(1))
+  updated, err := update.Apply(item)
 */
 package expression
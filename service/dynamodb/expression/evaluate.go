@@ -0,0 +1,879 @@
+package expression
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Evaluate returns if the item satisfies the condition represented by the
+// ConditionBuilder, following the semantics of DynamoDB Condition Expressions.
+// Evaluate allows conditions and filters to be evaluated against items
+// locally, such as filtering cached items, or modeling DynamoDB in tests.
+//
+// Comparisons of operands of different types, or of attributes which do not
+// exist, are false, except for the not equal comparison which is true. The
+// ordering comparators and BETWEEN compare String, Number, and Binary
+// operands. The size function returns the length in bytes of String and
+// Binary attributes, and the number of elements of sets, lists, and maps.
+//
+// Example:
+//
+//     condition := expression.Name("Price").LessThan(expression.Value(10)).
+//         And(expression.Name("Tags").Contains("sale"))
+//
+//     ok, err := condition.Evaluate(item)
+func (cb ConditionBuilder) Evaluate(item map[string]*dynamodb.AttributeValue) (bool, error) {
+	return evaluateCondition(cb, item)
+}
+
+// Apply returns the item updated by the update operations represented by the
+// UpdateBuilder, following the semantics of DynamoDB Update Expressions. The
+// item passed in is not modified. The values of the operations are evaluated
+// against the item before it is updated.
+//
+// An error is returned if an operation would be rejected by DynamoDB, such as
+// referencing an attribute which does not exist, adding to an attribute which
+// is not a number or set, or setting a nested attribute whose parent does not
+// exist.
+//
+// Example:
+//
+//     update := expression.Set(expression.Name("Count"), expression.Name("Count").Plus(expression.Value(1))).
+//         Remove(expression.Name("Pending"))
+//
+//     updated, err := update.Apply(item)
+func (ub UpdateBuilder) Apply(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	if ub.operationList == nil {
+		return nil, newUnsetParameterError("Apply", "UpdateBuilder")
+	}
+	return applyUpdate(ub, item)
+}
+
+// EvaluateCondition returns if the item satisfies the Condition Expression of
+// the Expression. True is returned if the Expression has no Condition
+// Expression.
+//
+// Example:
+//
+//     expr, err := expression.NewBuilder().WithCondition(condition).Build()
+//     ok, err := expr.EvaluateCondition(item)
+func (e Expression) EvaluateCondition(item map[string]*dynamodb.AttributeValue) (bool, error) {
+	return e.evaluate(condition, item)
+}
+
+// EvaluateFilter returns if the item satisfies the Filter Expression of the
+// Expression. True is returned if the Expression has no Filter Expression.
+//
+// Example:
+//
+//     expr, err := expression.NewBuilder().WithFilter(filter).Build()
+//     ok, err := expr.EvaluateFilter(item)
+func (e Expression) EvaluateFilter(item map[string]*dynamodb.AttributeValue) (bool, error) {
+	return e.evaluate(filter, item)
+}
+
+// ApplyUpdate returns the item updated by the Update Expression of the
+// Expression. A copy of the item is returned if the Expression has no Update
+// Expression.
+//
+// Example:
+//
+//     expr, err := expression.NewBuilder().WithUpdate(update).Build()
+//     updated, err := expr.ApplyUpdate(item)
+func (e Expression) ApplyUpdate(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	s := e.returnExpression(update)
+	if s == nil {
+		return copyItem(item), nil
+	}
+
+	ub, err := ParseUpdate(*s, e.namesMap, e.valuesMap)
+	if err != nil {
+		return nil, err
+	}
+	return applyUpdate(ub, item)
+}
+
+func (e Expression) evaluate(expressionType expressionType, item map[string]*dynamodb.AttributeValue) (bool, error) {
+	s := e.returnExpression(expressionType)
+	if s == nil {
+		return true, nil
+	}
+
+	cb, err := ParseCondition(*s, e.namesMap, e.valuesMap)
+	if err != nil {
+		return false, err
+	}
+	return evaluateCondition(cb, item)
+}
+
+// evaluateCondition evaluates the ConditionBuilder against the item.
+func evaluateCondition(cb ConditionBuilder, item map[string]*dynamodb.AttributeValue) (bool, error) {
+	switch cb.mode {
+	case unsetCond:
+		return false, newUnsetParameterError("Evaluate", "ConditionBuilder")
+	case andCond, orCond:
+		for _, c := range cb.conditionList {
+			ok, err := evaluateCondition(c, item)
+			if err != nil {
+				return false, err
+			}
+			if cb.mode == andCond && !ok {
+				return false, nil
+			}
+			if cb.mode == orCond && ok {
+				return true, nil
+			}
+		}
+		return cb.mode == andCond, nil
+	case notCond:
+		ok, err := evaluateCondition(cb.conditionList[0], item)
+		return !ok, err
+	}
+
+	operands := make([]*dynamodb.AttributeValue, 0, len(cb.operandList))
+	for _, op := range cb.operandList {
+		av, err := evaluateConditionOperand(op, item)
+		if err != nil {
+			return false, err
+		}
+		operands = append(operands, av)
+	}
+
+	switch cb.mode {
+	case equalCond:
+		return attributeValuesEqual(operands[0], operands[1]), nil
+	case notEqualCond:
+		return !attributeValuesEqual(operands[0], operands[1]), nil
+	case lessThanCond, lessThanEqualCond, greaterThanCond, greaterThanEqualCond:
+		c, ok := compareAttributeValues(operands[0], operands[1])
+		if !ok {
+			return false, nil
+		}
+		switch cb.mode {
+		case lessThanCond:
+			return c < 0, nil
+		case lessThanEqualCond:
+			return c <= 0, nil
+		case greaterThanCond:
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case betweenCond:
+		lower, ok := compareAttributeValues(operands[0], operands[1])
+		if !ok {
+			return false, nil
+		}
+		upper, ok := compareAttributeValues(operands[0], operands[2])
+		return ok && lower >= 0 && upper <= 0, nil
+	case inCond:
+		for _, av := range operands[1:] {
+			if attributeValuesEqual(operands[0], av) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case attrExistsCond:
+		return operands[0] != nil, nil
+	case attrNotExistsCond:
+		return operands[0] == nil, nil
+	case attrTypeCond:
+		return operands[0] != nil && operands[1].S != nil &&
+			string(attributeValueType(operands[0])) == *operands[1].S, nil
+	case beginsWithCond:
+		left, right := operands[0], operands[1]
+		switch {
+		case left == nil || right == nil:
+			return false, nil
+		case left.S != nil && right.S != nil:
+			return strings.HasPrefix(*left.S, *right.S), nil
+		case left.B != nil && right.B != nil:
+			return bytes.HasPrefix(left.B, right.B), nil
+		}
+		return false, nil
+	case containsCond:
+		return attributeValueContains(operands[0], operands[1]), nil
+	}
+
+	return false, fmt.Errorf("evaluate condition error: unsupported mode: %v", cb.mode)
+}
+
+// evaluateConditionOperand returns the value of the operand of a condition,
+// or nil if the operand refers to an attribute which does not exist.
+func evaluateConditionOperand(op OperandBuilder, item map[string]*dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	switch op := op.(type) {
+	case NameBuilder:
+		path, err := parseDocumentPath(op)
+		if err != nil {
+			return nil, err
+		}
+		return resolveDocumentPath(item, path), nil
+	case KeyBuilder:
+		return item[op.key], nil
+	case SizeBuilder:
+		path, err := parseDocumentPath(op.nameBuilder)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := attributeValueSize(resolveDocumentPath(item, path))
+		if !ok {
+			return nil, nil
+		}
+		return &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(n))}, nil
+	case ValueBuilder:
+		return evaluateValue(op)
+	}
+
+	return nil, fmt.Errorf("evaluate condition error: unsupported operand: %T", op)
+}
+
+func evaluateValue(vb ValueBuilder) (*dynamodb.AttributeValue, error) {
+	operand, err := vb.BuildOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &operand.exprNode.values[0], nil
+}
+
+// documentPathElem is an element of a document path, either the name of an
+// attribute of a map, or the index of an element of a list.
+type documentPathElem struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// parseDocumentPath splits the name of the NameBuilder into the elements of
+// its document path.
+func parseDocumentPath(nb NameBuilder) ([]documentPathElem, error) {
+	if nb.name == "" {
+		return nil, newUnsetParameterError("Evaluate", "NameBuilder")
+	}
+
+	var path []documentPathElem
+	for _, word := range strings.Split(nb.name, ".") {
+		var indexes string
+		if i := strings.IndexByte(word, '['); i >= 0 && word[len(word)-1] == ']' {
+			word, indexes = word[:i], word[i:]
+		}
+		if word == "" {
+			return nil, newInvalidParameterError("Evaluate", "NameBuilder")
+		}
+		path = append(path, documentPathElem{name: word})
+
+		for len(indexes) > 0 {
+			end := strings.IndexByte(indexes, ']')
+			index, err := strconv.Atoi(indexes[1:end])
+			if indexes[0] != '[' || err != nil || index < 0 {
+				return nil, newInvalidParameterError("Evaluate", "NameBuilder")
+			}
+			path = append(path, documentPathElem{index: index, isIndex: true})
+			indexes = indexes[end+1:]
+		}
+	}
+
+	return path, nil
+}
+
+// resolveDocumentPath returns the value at the path in the item, or nil if
+// the value does not exist.
+func resolveDocumentPath(item map[string]*dynamodb.AttributeValue, path []documentPathElem) *dynamodb.AttributeValue {
+	av := &dynamodb.AttributeValue{M: item}
+	for _, elem := range path {
+		if elem.isIndex {
+			if elem.index >= len(av.L) {
+				return nil
+			}
+			av = av.L[elem.index]
+		} else {
+			av = av.M[elem.name]
+		}
+		if av == nil {
+			return nil
+		}
+	}
+	return av
+}
+
+// attributeValueType returns the DynamoDB type of the value.
+func attributeValueType(av *dynamodb.AttributeValue) DynamoDBAttributeType {
+	switch {
+	case av.S != nil:
+		return String
+	case av.N != nil:
+		return Number
+	case av.B != nil:
+		return Binary
+	case av.BOOL != nil:
+		return Boolean
+	case av.NULL != nil:
+		return Null
+	case av.SS != nil:
+		return StringSet
+	case av.NS != nil:
+		return NumberSet
+	case av.BS != nil:
+		return BinarySet
+	case av.L != nil:
+		return List
+	case av.M != nil:
+		return Map
+	}
+	return ""
+}
+
+// attributeValueSize returns the result of the size function for the value,
+// and false if the size function does not apply to the value.
+func attributeValueSize(av *dynamodb.AttributeValue) (int, bool) {
+	if av == nil {
+		return 0, false
+	}
+
+	switch attributeValueType(av) {
+	case String:
+		return len(*av.S), true
+	case Binary:
+		return len(av.B), true
+	case StringSet:
+		return len(av.SS), true
+	case NumberSet:
+		return len(av.NS), true
+	case BinarySet:
+		return len(av.BS), true
+	case List:
+		return len(av.L), true
+	case Map:
+		return len(av.M), true
+	}
+	return 0, false
+}
+
+// compareAttributeValues compares the String, Number, or Binary values,
+// returning false if the values are not of the same comparable type.
+func compareAttributeValues(a, b *dynamodb.AttributeValue) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+
+	switch {
+	case a.S != nil && b.S != nil:
+		return strings.Compare(*a.S, *b.S), true
+	case a.B != nil && b.B != nil:
+		return bytes.Compare(a.B, b.B), true
+	case a.N != nil && b.N != nil:
+		return compareNumbers(*a.N, *b.N)
+	}
+	return 0, false
+}
+
+// attributeValuesEqual returns if the values are of the same type and equal.
+// Sets are equal if they contain the same elements in any order.
+func attributeValuesEqual(a, b *dynamodb.AttributeValue) bool {
+	if a == nil || b == nil {
+		return false
+	}
+
+	t := attributeValueType(a)
+	if t != attributeValueType(b) {
+		return false
+	}
+
+	switch t {
+	case String, Number, Binary:
+		c, ok := compareAttributeValues(a, b)
+		return ok && c == 0
+	case Boolean:
+		return *a.BOOL == *b.BOOL
+	case Null:
+		return true
+	case StringSet, NumberSet, BinarySet:
+		as, bs := setElements(a), setElements(b)
+		if len(as) != len(bs) {
+			return false
+		}
+		for _, e := range as {
+			if !setContains(bs, e) {
+				return false
+			}
+		}
+		return true
+	case List:
+		if len(a.L) != len(b.L) {
+			return false
+		}
+		for i := range a.L {
+			if !attributeValuesEqual(a.L[i], b.L[i]) {
+				return false
+			}
+		}
+		return true
+	case Map:
+		if len(a.M) != len(b.M) {
+			return false
+		}
+		for k, v := range a.M {
+			if !attributeValuesEqual(v, b.M[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// attributeValueContains returns the result of the contains function, if the
+// String contains the substring, the set contains the element, or the list
+// contains the value.
+func attributeValueContains(av, v *dynamodb.AttributeValue) bool {
+	if av == nil || v == nil {
+		return false
+	}
+
+	switch attributeValueType(av) {
+	case String:
+		return v.S != nil && strings.Contains(*av.S, *v.S)
+	case StringSet, NumberSet, BinarySet:
+		return setContains(setElements(av), v)
+	case List:
+		for _, e := range av.L {
+			if attributeValuesEqual(e, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setElements returns the elements of the set as scalar values.
+func setElements(av *dynamodb.AttributeValue) []*dynamodb.AttributeValue {
+	var elems []*dynamodb.AttributeValue
+	for _, s := range av.SS {
+		elems = append(elems, &dynamodb.AttributeValue{S: s})
+	}
+	for _, n := range av.NS {
+		elems = append(elems, &dynamodb.AttributeValue{N: n})
+	}
+	for _, b := range av.BS {
+		elems = append(elems, &dynamodb.AttributeValue{B: b})
+	}
+	return elems
+}
+
+func setContains(elems []*dynamodb.AttributeValue, v *dynamodb.AttributeValue) bool {
+	for _, e := range elems {
+		if attributeValuesEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// newSet returns a set of the type containing the scalar elements.
+func newSet(t DynamoDBAttributeType, elems []*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	av := &dynamodb.AttributeValue{}
+	for _, e := range elems {
+		switch t {
+		case StringSet:
+			av.SS = append(av.SS, e.S)
+		case NumberSet:
+			av.NS = append(av.NS, e.N)
+		case BinarySet:
+			av.BS = append(av.BS, e.B)
+		}
+	}
+	return av
+}
+
+func isSetType(t DynamoDBAttributeType) bool {
+	return t == StringSet || t == NumberSet || t == BinarySet
+}
+
+func compareNumbers(a, b string) (int, bool) {
+	ra, ok := new(big.Rat).SetString(a)
+	if !ok {
+		return 0, false
+	}
+	rb, ok := new(big.Rat).SetString(b)
+	if !ok {
+		return 0, false
+	}
+	return ra.Cmp(rb), true
+}
+
+// addNumbers returns the sum, or difference, of the numbers, formatted with
+// the precision of the numbers.
+func addNumbers(a, b string, subtract bool) (string, error) {
+	ra, ok := new(big.Rat).SetString(a)
+	if !ok {
+		return "", fmt.Errorf("apply update error: invalid number: %q", a)
+	}
+	rb, ok := new(big.Rat).SetString(b)
+	if !ok {
+		return "", fmt.Errorf("apply update error: invalid number: %q", b)
+	}
+
+	if subtract {
+		ra.Sub(ra, rb)
+	} else {
+		ra.Add(ra, rb)
+	}
+
+	scale := numberScale(a)
+	if s := numberScale(b); s > scale {
+		scale = s
+	}
+	s := ra.FloatString(scale)
+	if strings.IndexByte(s, '.') >= 0 {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s, nil
+}
+
+// numberScale returns the number of decimal places needed to represent the
+// number exactly.
+func numberScale(n string) int {
+	n = strings.ToLower(n)
+	var exp int
+	if i := strings.IndexByte(n, 'e'); i >= 0 {
+		exp, _ = strconv.Atoi(n[i+1:])
+		n = n[:i]
+	}
+
+	var scale int
+	if i := strings.IndexByte(n, '.'); i >= 0 {
+		scale = len(n) - i - 1
+	}
+	if scale -= exp; scale < 0 {
+		scale = 0
+	}
+	return scale
+}
+
+// copyAttributeValue returns a deep copy of the value.
+func copyAttributeValue(av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if av == nil {
+		return nil
+	}
+
+	c := *av
+	if av.B != nil {
+		c.B = append([]byte{}, av.B...)
+	}
+	if av.SS != nil {
+		c.SS = append([]*string{}, av.SS...)
+	}
+	if av.NS != nil {
+		c.NS = append([]*string{}, av.NS...)
+	}
+	if av.BS != nil {
+		c.BS = make([][]byte, 0, len(av.BS))
+		for _, b := range av.BS {
+			c.BS = append(c.BS, append([]byte{}, b...))
+		}
+	}
+	if av.L != nil {
+		c.L = make([]*dynamodb.AttributeValue, 0, len(av.L))
+		for _, v := range av.L {
+			c.L = append(c.L, copyAttributeValue(v))
+		}
+	}
+	if av.M != nil {
+		c.M = make(map[string]*dynamodb.AttributeValue, len(av.M))
+		for k, v := range av.M {
+			c.M[k] = copyAttributeValue(v)
+		}
+	}
+	return &c
+}
+
+// copyItem returns a deep copy of the item.
+func copyItem(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	c := make(map[string]*dynamodb.AttributeValue, len(item))
+	for k, v := range item {
+		c[k] = copyAttributeValue(v)
+	}
+	return c
+}
+
+// updateAction is an update operation whose value has been evaluated against
+// the item before the update.
+type updateAction struct {
+	path  []documentPathElem
+	value *dynamodb.AttributeValue
+	mode  operationMode
+}
+
+// applyUpdate applies the operations of the UpdateBuilder to a copy of the
+// item.
+func applyUpdate(ub UpdateBuilder, item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	modes := modeList{}
+	for mode := range ub.operationList {
+		modes = append(modes, mode)
+	}
+	sort.Sort(modes)
+
+	var actions []updateAction
+	for _, mode := range modes {
+		for _, ob := range ub.operationList[mode] {
+			action, err := evaluateUpdateOperation(ob, item)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, action)
+		}
+	}
+
+	updated := &dynamodb.AttributeValue{M: copyItem(item)}
+
+	// Values are set before attributes are removed, so the list indexes of
+	// removed elements refer to the list before the update.
+	for _, action := range actions {
+		if action.mode == removeOperation {
+			continue
+		}
+		if err := setDocumentPath(updated, action.path, copyAttributeValue(action.value)); err != nil {
+			return nil, err
+		}
+	}
+
+	var lists []*dynamodb.AttributeValue
+	for _, action := range actions {
+		if action.mode != removeOperation {
+			continue
+		}
+		if list := removeDocumentPath(updated, action.path); list != nil {
+			lists = append(lists, list)
+		}
+	}
+	for _, list := range lists {
+		elems := list.L[:0]
+		for _, e := range list.L {
+			if e != nil {
+				elems = append(elems, e)
+			}
+		}
+		list.L = elems
+	}
+
+	return updated.M, nil
+}
+
+// evaluateUpdateOperation evaluates the value of the update operation against
+// the item before the update. A nil value is returned for operations which
+// remove the attribute.
+func evaluateUpdateOperation(ob operationBuilder, item map[string]*dynamodb.AttributeValue) (updateAction, error) {
+	path, err := parseDocumentPath(ob.name)
+	if err != nil {
+		return updateAction{}, err
+	}
+	action := updateAction{path: path, mode: ob.mode}
+
+	switch ob.mode {
+	case removeOperation:
+		return action, nil
+	case setOperation:
+		action.value, err = evaluateSetOperand(ob.value, item)
+		return action, err
+	}
+
+	value, err := evaluateConditionOperand(ob.value, item)
+	if err != nil {
+		return updateAction{}, err
+	}
+	current := resolveDocumentPath(item, path)
+	valueType := attributeValueType(value)
+
+	if ob.mode == addOperation {
+		switch {
+		case valueType != Number && !isSetType(valueType):
+			return updateAction{}, fmt.Errorf("apply update error: ADD value of %q must be a number or set, got %s", ob.name.name, valueType)
+		case current == nil:
+			action.value = value
+		case valueType == Number && current.N != nil:
+			n, err := addNumbers(*current.N, *value.N, false)
+			if err != nil {
+				return updateAction{}, err
+			}
+			action.value = &dynamodb.AttributeValue{N: aws.String(n)}
+		case valueType == attributeValueType(current):
+			elems := setElements(current)
+			for _, e := range setElements(value) {
+				if !setContains(elems, e) {
+					elems = append(elems, e)
+				}
+			}
+			action.value = newSet(valueType, elems)
+		default:
+			return updateAction{}, fmt.Errorf("apply update error: cannot ADD %s to %q of type %s", valueType, ob.name.name, attributeValueType(current))
+		}
+		return action, nil
+	}
+
+	// deleteOperation
+	switch {
+	case !isSetType(valueType):
+		return updateAction{}, fmt.Errorf("apply update error: DELETE value of %q must be a set, got %s", ob.name.name, valueType)
+	case current == nil:
+		action.mode = removeOperation
+	case valueType == attributeValueType(current):
+		var elems []*dynamodb.AttributeValue
+		removed := setElements(value)
+		for _, e := range setElements(current) {
+			if !setContains(removed, e) {
+				elems = append(elems, e)
+			}
+		}
+		if len(elems) == 0 {
+			action.mode = removeOperation
+		} else {
+			action.value = newSet(valueType, elems)
+		}
+	default:
+		return updateAction{}, fmt.Errorf("apply update error: cannot DELETE %s from %q of type %s", valueType, ob.name.name, attributeValueType(current))
+	}
+	return action, nil
+}
+
+// evaluateSetOperand returns the value of the operand of a SET operation,
+// evaluated against the item before the update.
+func evaluateSetOperand(op OperandBuilder, item map[string]*dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	switch op := op.(type) {
+	case NameBuilder:
+		av, err := evaluateConditionOperand(op, item)
+		if err != nil {
+			return nil, err
+		}
+		if av == nil {
+			return nil, fmt.Errorf("apply update error: attribute %q does not exist", op.name)
+		}
+		return av, nil
+	case ValueBuilder:
+		return evaluateValue(op)
+	case SetValueBuilder:
+		return evaluateSetValue(op, item)
+	}
+
+	return nil, fmt.Errorf("apply update error: unsupported operand: %T", op)
+}
+
+func evaluateSetValue(svb SetValueBuilder, item map[string]*dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	if svb.mode == ifNotExistsValueMode {
+		if nb, ok := svb.leftOperand.(NameBuilder); ok {
+			av, err := evaluateConditionOperand(nb, item)
+			if err != nil {
+				return nil, err
+			}
+			if av != nil {
+				return av, nil
+			}
+		}
+		return evaluateSetOperand(svb.rightOperand, item)
+	}
+
+	if svb.mode == unsetValue {
+		return nil, newUnsetParameterError("Apply", "SetValueBuilder")
+	}
+
+	left, err := evaluateSetOperand(svb.leftOperand, item)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evaluateSetOperand(svb.rightOperand, item)
+	if err != nil {
+		return nil, err
+	}
+
+	switch svb.mode {
+	case plusValueMode, minusValueMode:
+		if left.N == nil || right.N == nil {
+			return nil, fmt.Errorf("apply update error: operands of + and - must be numbers")
+		}
+		n, err := addNumbers(*left.N, *right.N, svb.mode == minusValueMode)
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{N: aws.String(n)}, nil
+	case listAppendValueMode:
+		if attributeValueType(left) != List || attributeValueType(right) != List {
+			return nil, fmt.Errorf("apply update error: operands of list_append must be lists")
+		}
+		l := make([]*dynamodb.AttributeValue, 0, len(left.L)+len(right.L))
+		return &dynamodb.AttributeValue{L: append(append(l, left.L...), right.L...)}, nil
+	}
+
+	return nil, fmt.Errorf("apply update error: unsupported mode: %v", svb.mode)
+}
+
+// setDocumentPath sets the value at the path of the item. The parent of the
+// path must exist. Setting a list index past the end of the list appends the
+// value to the list.
+func setDocumentPath(item *dynamodb.AttributeValue, path []documentPathElem, value *dynamodb.AttributeValue) error {
+	parent := item
+	if len(path) > 1 {
+		parent = resolveDocumentPath(item.M, path[:len(path)-1])
+	}
+
+	last := path[len(path)-1]
+	switch {
+	case last.isIndex && parent != nil && attributeValueType(parent) == List:
+		if last.index < len(parent.L) {
+			parent.L[last.index] = value
+		} else {
+			parent.L = append(parent.L, value)
+		}
+	case !last.isIndex && parent != nil && parent.M != nil:
+		parent.M[last.name] = value
+	default:
+		return fmt.Errorf("apply update error: document path %q is invalid for the item", formatDocumentPath(path))
+	}
+	return nil
+}
+
+// removeDocumentPath removes the value at the path of the item. List elements
+// are set to nil, and the list is returned to be compacted once all elements
+// have been removed.
+func removeDocumentPath(item *dynamodb.AttributeValue, path []documentPathElem) *dynamodb.AttributeValue {
+	parent := item
+	if len(path) > 1 {
+		parent = resolveDocumentPath(item.M, path[:len(path)-1])
+	}
+	if parent == nil {
+		return nil
+	}
+
+	last := path[len(path)-1]
+	if !last.isIndex {
+		delete(parent.M, last.name)
+		return nil
+	}
+	if last.index < len(parent.L) {
+		parent.L[last.index] = nil
+		return parent
+	}
+	return nil
+}
+
+func formatDocumentPath(path []documentPathElem) string {
+	var buf bytes.Buffer
+	for i, elem := range path {
+		if elem.isIndex {
+			fmt.Fprintf(&buf, "[%d]", elem.index)
+			continue
+		}
+		if i > 0 {
+			buf.WriteByte('.')
+		}
+		buf.WriteString(elem.name)
+	}
+	return buf.String()
+}
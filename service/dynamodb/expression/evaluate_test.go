@@ -0,0 +1,367 @@
+// +build go1.7
+
+package expression
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func evaluateTestItem() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"ID":     {S: aws.String("item-1")},
+		"Price":  {N: aws.String("9.5")},
+		"Count":  {N: aws.String("3")},
+		"Data":   {B: []byte("binary")},
+		"Active": {BOOL: aws.Bool(true)},
+		"Empty":  {NULL: aws.Bool(true)},
+		"Tags":   {SS: []*string{aws.String("sale"), aws.String("new")}},
+		"Sizes":  {NS: []*string{aws.String("1"), aws.String("2.50")}},
+		"History": {L: []*dynamodb.AttributeValue{
+			{S: aws.String("created")},
+			{M: map[string]*dynamodb.AttributeValue{
+				"Event": {S: aws.String("updated")},
+			}},
+		}},
+		"Info": {M: map[string]*dynamodb.AttributeValue{
+			"Title": {S: aws.String("A Title")},
+			"Ratings": {L: []*dynamodb.AttributeValue{
+				{N: aws.String("4")},
+				{N: aws.String("5")},
+			}},
+		}},
+	}
+}
+
+func TestConditionBuilderEvaluate(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    ConditionBuilder
+		expected bool
+	}{
+		{name: "string equal", input: Name("ID").Equal(Value("item-1")), expected: true},
+		{name: "string not equal", input: Name("ID").Equal(Value("item-2"))},
+		{name: "number equal normalized", input: Name("Price").Equal(Value(9.50)), expected: true},
+		{name: "binary equal", input: Name("Data").Equal(Value([]byte("binary"))), expected: true},
+		{name: "bool equal", input: Name("Active").Equal(Value(true)), expected: true},
+		{name: "different types", input: Name("Count").Equal(Value("3"))},
+		{name: "set equal any order", input: Name("Tags").Equal(attributeValueBuilder(&dynamodb.AttributeValue{SS: []*string{aws.String("new"), aws.String("sale")}})), expected: true},
+		{name: "number set equal", input: Name("Sizes").Equal(attributeValueBuilder(&dynamodb.AttributeValue{NS: []*string{aws.String("2.5"), aws.String("1")}})), expected: true},
+		{name: "map equal", input: Name("History[1]").Equal(Value(map[string]string{"Event": "updated"})), expected: true},
+		{name: "missing equal", input: Name("Missing").Equal(Value(1))},
+		{name: "missing not equal", input: Name("Missing").NotEqual(Value(1)), expected: true},
+		{name: "not equal", input: Name("Count").NotEqual(Value(3))},
+		{name: "number less than", input: Name("Price").LessThan(Value(10)), expected: true},
+		{name: "number compared numerically", input: Name("Count").LessThan(Value(10)), expected: true},
+		{name: "string greater than", input: Name("ID").GreaterThan(Value("item-0")), expected: true},
+		{name: "string compared by bytes", input: Name("Info.Title").LessThan(Value("a")), expected: true},
+		{name: "binary less than equal", input: Name("Data").LessThanEqual(Value([]byte("binary"))), expected: true},
+		{name: "greater than equal", input: Name("Count").GreaterThanEqual(Value(4))},
+		{name: "mismatched types not ordered", input: Name("Count").LessThan(Value("9"))},
+		{name: "bool not ordered", input: Name("Active").GreaterThanEqual(Value(true))},
+		{name: "between", input: Name("Price").Between(Value(9), Value(10)), expected: true},
+		{name: "between inclusive", input: Name("Count").Between(Value(1), Value(3)), expected: true},
+		{name: "not between", input: Name("Count").Between(Value(4), Value(10))},
+		{name: "in", input: Name("Count").In(Value(1), Value(3)), expected: true},
+		{name: "not in", input: Name("Count").In(Value(1), Value(2))},
+		{name: "nested path", input: Name("Info.Ratings[1]").Equal(Value(5)), expected: true},
+		{name: "nested list map path", input: Name("History[1].Event").Equal(Value("updated")), expected: true},
+		{name: "index out of range", input: Name("Info.Ratings[5]").AttributeExists()},
+		{name: "attribute exists", input: Name("Info.Title").AttributeExists(), expected: true},
+		{name: "attribute not exists", input: Name("Info.Missing").AttributeNotExists(), expected: true},
+		{name: "attribute type", input: Name("Sizes").AttributeType(NumberSet), expected: true},
+		{name: "attribute type null", input: Name("Empty").AttributeType(Null), expected: true},
+		{name: "attribute type mismatch", input: Name("Count").AttributeType(String)},
+		{name: "begins with", input: Name("ID").BeginsWith("item-"), expected: true},
+		{name: "begins with mismatch", input: Name("ID").BeginsWith("other")},
+		{name: "begins with number", input: Name("Count").BeginsWith("3")},
+		{name: "contains substring", input: Name("Info.Title").Contains("Tit"), expected: true},
+		{name: "contains set element", input: Name("Tags").Contains("sale"), expected: true},
+		{name: "contains missing set element", input: Name("Tags").Contains("old")},
+		{name: "contains number set element", input: ConditionBuilder{operandList: []OperandBuilder{Name("Sizes"), Value(2.5)}, mode: containsCond}, expected: true},
+		{name: "contains list element", input: Name("History").Contains("created"), expected: true},
+		{name: "size of string", input: Name("ID").Size().Equal(Value(6)), expected: true},
+		{name: "size of set", input: Name("Tags").Size().Equal(Value(2)), expected: true},
+		{name: "size of map", input: Name("Info").Size().GreaterThan(Value(1)), expected: true},
+		{name: "size of number", input: Name("Count").Size().GreaterThanEqual(Value(0))},
+		{name: "and", input: Name("Count").Equal(Value(3)).And(Name("Active").Equal(Value(true))), expected: true},
+		{name: "and false", input: Name("Count").Equal(Value(3)).And(Name("Active").Equal(Value(false)))},
+		{name: "or", input: Name("Count").Equal(Value(1)).Or(Name("Count").Equal(Value(3))), expected: true},
+		{name: "not", input: Name("Missing").AttributeExists().Not(), expected: true},
+		{name: "compare attributes", input: Name("Count").LessThan(Name("Price")), expected: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := c.input.Evaluate(evaluateTestItem())
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.expected, actual; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestConditionBuilderEvaluate_Errors(t *testing.T) {
+	cases := []struct {
+		name  string
+		input ConditionBuilder
+	}{
+		{name: "unset condition", input: ConditionBuilder{}},
+		{name: "unset nested condition", input: Name("ID").AttributeExists().And(ConditionBuilder{})},
+		{name: "invalid name", input: Name("a..b").AttributeExists()},
+		{name: "invalid list index", input: Name("a[x]").AttributeExists()},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := c.input.Evaluate(evaluateTestItem()); err == nil {
+				t.Errorf("expect error, got none")
+			}
+		})
+	}
+}
+
+func TestUpdateBuilderApply(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    UpdateBuilder
+		expected map[string]*dynamodb.AttributeValue
+	}{
+		{
+			name:  "set values",
+			input: Set(Name("New"), Value("value")).Set(Name("Info.Title"), Value("New Title")),
+			expected: map[string]*dynamodb.AttributeValue{
+				"New":        {S: aws.String("value")},
+				"Info.Title": {S: aws.String("New Title")},
+			},
+		},
+		{
+			name:  "set arithmetic",
+			input: Set(Name("Price"), Name("Price").Plus(Value(0.25))).Set(Name("Count"), Name("Count").Minus(Value(5))),
+			expected: map[string]*dynamodb.AttributeValue{
+				"Price": {N: aws.String("9.75")},
+				"Count": {N: aws.String("-2")},
+			},
+		},
+		{
+			name:  "set from original item",
+			input: Set(Name("Count"), Name("Price")).Set(Name("Price"), Name("Count")),
+			expected: map[string]*dynamodb.AttributeValue{
+				"Count": {N: aws.String("9.5")},
+				"Price": {N: aws.String("3")},
+			},
+		},
+		{
+			name:  "if not exists",
+			input: Set(Name("Count"), Name("Count").IfNotExists(Value(0))).Set(Name("Views"), Plus(Name("Views").IfNotExists(Value(0)), Value(1))),
+			expected: map[string]*dynamodb.AttributeValue{
+				"Count": {N: aws.String("3")},
+				"Views": {N: aws.String("1")},
+			},
+		},
+		{
+			name:  "list append",
+			input: Set(Name("History"), Name("History").ListAppend(Value([]string{"deleted"}))),
+			expected: map[string]*dynamodb.AttributeValue{
+				"History[2]": {S: aws.String("deleted")},
+			},
+		},
+		{
+			name:  "set list index past end appends",
+			input: Set(Name("Info.Ratings[10]"), Value(3)),
+			expected: map[string]*dynamodb.AttributeValue{
+				"Info.Ratings[2]": {N: aws.String("3")},
+			},
+		},
+		{
+			name:  "remove",
+			input: Remove(Name("Price")).Remove(Name("Info.Ratings[0]")).Remove(Name("History[0]")).Remove(Name("History[1]")),
+			expected: map[string]*dynamodb.AttributeValue{
+				"Price":           nil,
+				"Info.Ratings[0]": {N: aws.String("5")},
+				"History[0]":      nil,
+			},
+		},
+		{
+			name:  "add number",
+			input: Add(Name("Count"), Value(2)).Add(Name("Total"), Value(5)),
+			expected: map[string]*dynamodb.AttributeValue{
+				"Count": {N: aws.String("5")},
+				"Total": {N: aws.String("5")},
+			},
+		},
+		{
+			name:  "add to set",
+			input: Add(Name("Tags"), attributeValueBuilder(&dynamodb.AttributeValue{SS: []*string{aws.String("new"), aws.String("hot")}})),
+			expected: map[string]*dynamodb.AttributeValue{
+				"Tags": {SS: []*string{aws.String("sale"), aws.String("new"), aws.String("hot")}},
+			},
+		},
+		{
+			name:  "delete from set",
+			input: Delete(Name("Sizes"), attributeValueBuilder(&dynamodb.AttributeValue{NS: []*string{aws.String("2.5")}})),
+			expected: map[string]*dynamodb.AttributeValue{
+				"Sizes": {NS: []*string{aws.String("1")}},
+			},
+		},
+		{
+			name:  "delete all of set",
+			input: Delete(Name("Tags"), attributeValueBuilder(&dynamodb.AttributeValue{SS: []*string{aws.String("new"), aws.String("sale")}})).Delete(Name("Missing"), attributeValueBuilder(&dynamodb.AttributeValue{SS: []*string{aws.String("x")}})),
+			expected: map[string]*dynamodb.AttributeValue{
+				"Tags":    nil,
+				"Missing": nil,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			item := evaluateTestItem()
+			actual, err := c.input.Apply(item)
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			if e, a := evaluateTestItem(), item; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect item to not be modified, got %v", a)
+			}
+
+			for path, e := range c.expected {
+				p, err := parseDocumentPath(Name(path))
+				if err != nil {
+					t.Fatalf("expect no error, got %v", err)
+				}
+				if a := resolveDocumentPath(actual, p); !reflect.DeepEqual(e, a) {
+					t.Errorf("%s, expect %v, got %v", path, e, a)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateBuilderApply_Errors(t *testing.T) {
+	cases := []struct {
+		name  string
+		input UpdateBuilder
+		msg   string
+	}{
+		{name: "unset update", input: UpdateBuilder{}, msg: "unset parameter"},
+		{name: "missing attribute", input: Set(Name("Count"), Name("Missing").Plus(Value(1))), msg: `attribute "Missing" does not exist`},
+		{name: "plus string", input: Set(Name("Count"), Name("ID").Plus(Value(1))), msg: "must be numbers"},
+		{name: "list append not list", input: Set(Name("Count"), Name("Count").ListAppend(Value([]int{1}))), msg: "must be lists"},
+		{name: "missing parent", input: Set(Name("Missing.Child"), Value(1)), msg: `document path "Missing.Child" is invalid`},
+		{name: "index of map", input: Set(Name("Info[0]"), Value(1)), msg: `document path "Info[0]" is invalid`},
+		{name: "add string", input: Add(Name("Count"), Value("1")), msg: "must be a number or set"},
+		{name: "add number to set", input: Add(Name("Tags"), Value(1)), msg: "cannot ADD N"},
+		{name: "delete number", input: Delete(Name("Count"), Value(1)), msg: "must be a set"},
+		{name: "delete mismatched set", input: Delete(Name("Tags"), attributeValueBuilder(&dynamodb.AttributeValue{NS: []*string{aws.String("1")}})), msg: "cannot DELETE NS"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := c.input.Apply(evaluateTestItem())
+			if err == nil {
+				t.Fatalf("expect error, got none")
+			}
+			if e, a := c.msg, err.Error(); !strings.Contains(a, e) {
+				t.Errorf("expect %q in error, got %q", e, a)
+			}
+		})
+	}
+}
+
+func TestExpressionEvaluate(t *testing.T) {
+	expr, err := NewBuilder().
+		WithCondition(Name("Count").Equal(Value(3))).
+		WithFilter(Name("Tags").Contains("old")).
+		WithUpdate(Set(Name("Count"), Name("Count").Plus(Value(1))).Remove(Name("Tags"))).
+		Build()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	item := evaluateTestItem()
+
+	ok, err := expr.EvaluateCondition(item)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if !ok {
+		t.Errorf("expect condition to be true")
+	}
+
+	ok, err = expr.EvaluateFilter(item)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if ok {
+		t.Errorf("expect filter to be false")
+	}
+
+	updated, err := expr.ApplyUpdate(item)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "4", aws.StringValue(updated["Count"].N); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if _, ok := updated["Tags"]; ok {
+		t.Errorf("expect Tags to be removed")
+	}
+}
+
+func TestExpressionEvaluate_Unset(t *testing.T) {
+	expr, err := NewBuilder().WithProjection(NamesList(Name("ID"))).Build()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	item := evaluateTestItem()
+	if ok, err := expr.EvaluateCondition(item); err != nil || !ok {
+		t.Errorf("expect true without error, got %v, %v", ok, err)
+	}
+	if ok, err := expr.EvaluateFilter(item); err != nil || !ok {
+		t.Errorf("expect true without error, got %v, %v", ok, err)
+	}
+
+	updated, err := expr.ApplyUpdate(item)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := item, updated; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestAddNumbers(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		subtract bool
+		expected string
+	}{
+		{a: "1", b: "2", expected: "3"},
+		{a: "0.1", b: "0.2", expected: "0.3"},
+		{a: "1.50", b: "1.5", subtract: true, expected: "0"},
+		{a: "1e2", b: "1", expected: "101"},
+		{a: "1.5e-2", b: "1", expected: "1.015"},
+		{a: "12345678901234567890123456789012345678", b: "1", expected: "12345678901234567890123456789012345679"},
+		{a: "-1.25", b: "1", subtract: true, expected: "-2.25"},
+	}
+
+	for _, c := range cases {
+		actual, err := addNumbers(c.a, c.b, c.subtract)
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		if e, a := c.expected, actual; e != a {
+			t.Errorf("%s, %s, expect %v, got %v", c.a, c.b, e, a)
+		}
+	}
+}
@@ -0,0 +1,749 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ParseError is returned if an expression string passed to ParseCondition or
+// ParseUpdate is not a valid DynamoDB Expression. The error message includes
+// the offset in the expression string the error was found at.
+//
+// Example:
+//
+//     // err is of type ParseError
+//     _, err := expression.ParseCondition("foo = ", nil, nil)
+type ParseError struct {
+	expression string
+	offset     int
+	message    string
+}
+
+func (pe ParseError) Error() string {
+	return fmt.Sprintf("parse error: %s at offset %d in %q", pe.message, pe.offset, pe.expression)
+}
+
+// Offset returns the offset in the expression string the error was found at.
+func (pe ParseError) Offset() int {
+	return pe.offset
+}
+
+// ParseCondition parses the DynamoDB Condition Expression, or Filter
+// Expression, string into a ConditionBuilder. The ExpressionAttributeNames and
+// ExpressionAttributeValues placeholders in the expression are resolved with
+// the names and values maps, such as the ExpressionAttributeNames and
+// ExpressionAttributeValues members of the input struct the expression was
+// written for. Entries of the maps not used by the expression are ignored.
+//
+// The resulting ConditionBuilder can be validated, rewritten, or composed with
+// other ConditionBuilders, and is built with new placeholders. Attribute names
+// containing "." or "[" cannot be represented by a NameBuilder, and an error
+// is returned if a placeholder resolves to one.
+//
+// Example:
+//
+//     condition, err := expression.ParseCondition(
+//         "attribute_not_exists (#id) OR #count < :max",
+//         map[string]*string{
+//             "#id":    aws.String("ID"),
+//             "#count": aws.String("Count"),
+//         },
+//         map[string]*dynamodb.AttributeValue{
+//             ":max": {N: aws.String("10")},
+//         })
+//
+//     // Composed with another ConditionBuilder
+//     anotherCondition := condition.And(expression.Name("Enabled").Equal(expression.Value(true)))
+//
+// Expression Equivalent:
+//
+//     expression.Name("ID").AttributeNotExists().Or(expression.Name("Count").LessThan(expression.Value(10)))
+func ParseCondition(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (ConditionBuilder, error) {
+	p, err := newExprParser(expr, names, values)
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+
+	cond, err := p.parseOr()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+	if err := p.expectEnd(); err != nil {
+		return ConditionBuilder{}, err
+	}
+
+	return cond, nil
+}
+
+// ParseUpdate parses the DynamoDB Update Expression string into an
+// UpdateBuilder. The ExpressionAttributeNames and ExpressionAttributeValues
+// placeholders in the expression are resolved with the names and values maps,
+// as with ParseCondition.
+//
+// Example:
+//
+//     update, err := expression.ParseUpdate(
+//         "SET #count = #count + :one REMOVE Pending",
+//         map[string]*string{
+//             "#count": aws.String("Count"),
+//         },
+//         map[string]*dynamodb.AttributeValue{
+//             ":one": {N: aws.String("1")},
+//         })
+//
+// Expression Equivalent:
+//
+//     expression.Set(expression.Name("Count"), expression.Name("Count").Plus(expression.Value(1))).
+//         Remove(expression.Name("Pending"))
+func ParseUpdate(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (UpdateBuilder, error) {
+	p, err := newExprParser(expr, names, values)
+	if err != nil {
+		return UpdateBuilder{}, err
+	}
+
+	update, err := p.parseUpdate()
+	if err != nil {
+		return UpdateBuilder{}, err
+	}
+	if err := p.expectEnd(); err != nil {
+		return UpdateBuilder{}, err
+	}
+
+	return update, nil
+}
+
+// tokenKind specifies the kind of a token of an expression string.
+type tokenKind int
+
+const (
+	endToken tokenKind = iota
+	identToken
+	nameToken
+	valueToken
+	numberToken
+	punctToken
+)
+
+// exprToken is a token of an expression string.
+type exprToken struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// isKeyword returns if the token is the case insensitive keyword.
+func (t exprToken) isKeyword(keyword string) bool {
+	return t.kind == identToken && strings.EqualFold(t.text, keyword)
+}
+
+func (t exprToken) isPunct(punct string) bool {
+	return t.kind == punctToken && t.text == punct
+}
+
+func (t exprToken) String() string {
+	if t.kind == endToken {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// exprParser is a recursive descent parser of DynamoDB Expression strings.
+type exprParser struct {
+	expr   string
+	tokens []exprToken
+	pos    int
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+}
+
+func newExprParser(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*exprParser, error) {
+	p := &exprParser{
+		expr:   expr,
+		names:  names,
+		values: values,
+	}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *exprParser) errorf(offset int, format string, args ...interface{}) ParseError {
+	return ParseError{
+		expression: p.expr,
+		offset:     offset,
+		message:    fmt.Sprintf(format, args...),
+	}
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// tokenize splits the expression string into tokens.
+func (p *exprParser) tokenize() error {
+	s := p.expr
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '#' || c == ':':
+			j := i + 1
+			for j < len(s) && isIdentChar(s[j]) {
+				j++
+			}
+			if j == i+1 {
+				return p.errorf(i, "empty placeholder")
+			}
+			kind := nameToken
+			if c == ':' {
+				kind = valueToken
+			}
+			p.tokens = append(p.tokens, exprToken{kind: kind, text: s[i:j], offset: i})
+			i = j
+		case isDigit(c):
+			j := i
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			p.tokens = append(p.tokens, exprToken{kind: numberToken, text: s[i:j], offset: i})
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(s) && isIdentChar(s[j]) {
+				j++
+			}
+			p.tokens = append(p.tokens, exprToken{kind: identToken, text: s[i:j], offset: i})
+			i = j
+		case c == '<' || c == '>':
+			j := i + 1
+			if j < len(s) && (s[j] == '=' || (c == '<' && s[j] == '>')) {
+				j++
+			}
+			p.tokens = append(p.tokens, exprToken{kind: punctToken, text: s[i:j], offset: i})
+			i = j
+		case strings.IndexByte("()[],.=+-", c) >= 0:
+			p.tokens = append(p.tokens, exprToken{kind: punctToken, text: s[i : i+1], offset: i})
+			i++
+		default:
+			return p.errorf(i, "unexpected character %q", c)
+		}
+	}
+	p.tokens = append(p.tokens, exprToken{kind: endToken, offset: len(s)})
+
+	return nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+// peekN returns the token n tokens after the current token.
+func (p *exprParser) peekN(n int) exprToken {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != endToken {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) expectPunct(punct string) error {
+	if t := p.next(); !t.isPunct(punct) {
+		return p.errorf(t.offset, "expected %q, got %s", punct, t)
+	}
+	return nil
+}
+
+func (p *exprParser) expectEnd() error {
+	if t := p.peek(); t.kind != endToken {
+		return p.errorf(t.offset, "unexpected %s", t)
+	}
+	return nil
+}
+
+// parseOr parses conditions joined by OR, the lowest precedence operator.
+func (p *exprParser) parseOr() (ConditionBuilder, error) {
+	return p.parseCompound("OR", orCond, p.parseAnd)
+}
+
+// parseAnd parses conditions joined by AND.
+func (p *exprParser) parseAnd() (ConditionBuilder, error) {
+	return p.parseCompound("AND", andCond, p.parseNot)
+}
+
+func (p *exprParser) parseCompound(keyword string, mode conditionMode, parse func() (ConditionBuilder, error)) (ConditionBuilder, error) {
+	cond, err := parse()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+
+	conds := []ConditionBuilder{cond}
+	for p.peek().isKeyword(keyword) {
+		p.next()
+		cond, err := parse()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		conds = append(conds, cond)
+	}
+
+	if len(conds) == 1 {
+		return conds[0], nil
+	}
+	return ConditionBuilder{
+		conditionList: conds,
+		mode:          mode,
+	}, nil
+}
+
+// parseNot parses a condition optionally negated by NOT.
+func (p *exprParser) parseNot() (ConditionBuilder, error) {
+	if !p.peek().isKeyword("NOT") {
+		return p.parsePrimaryCondition()
+	}
+	p.next()
+
+	cond, err := p.parseNot()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+	return Not(cond), nil
+}
+
+// parsePrimaryCondition parses a parenthesized condition, a function
+// condition, or a comparison of operands.
+func (p *exprParser) parsePrimaryCondition() (ConditionBuilder, error) {
+	t := p.peek()
+	if t.isPunct("(") {
+		p.next()
+		cond, err := p.parseOr()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		if err := p.expectPunct(")"); err != nil {
+			return ConditionBuilder{}, err
+		}
+		return cond, nil
+	}
+
+	if t.kind == identToken && p.peekN(1).isPunct("(") {
+		switch t.text {
+		case "attribute_exists", "attribute_not_exists", "attribute_type", "begins_with", "contains":
+			return p.parseFunctionCondition()
+		}
+	}
+
+	return p.parseComparison()
+}
+
+// parseFunctionCondition parses the functions evaluating to a condition.
+func (p *exprParser) parseFunctionCondition() (ConditionBuilder, error) {
+	fn := p.next()
+	p.next()
+
+	name, err := p.parsePath()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+
+	var cond ConditionBuilder
+	switch fn.text {
+	case "attribute_exists":
+		cond = AttributeExists(name)
+	case "attribute_not_exists":
+		cond = AttributeNotExists(name)
+	case "attribute_type":
+		if err := p.expectPunct(","); err != nil {
+			return ConditionBuilder{}, err
+		}
+		t := p.peek()
+		value, err := p.parseValue()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		if value.S == nil {
+			return ConditionBuilder{}, p.errorf(t.offset, "attribute type %s must be a string", t)
+		}
+		cond = AttributeType(name, DynamoDBAttributeType(*value.S))
+	case "begins_with":
+		if err := p.expectPunct(","); err != nil {
+			return ConditionBuilder{}, err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		cond = ConditionBuilder{
+			operandList: []OperandBuilder{name, attributeValueBuilder(value)},
+			mode:        beginsWithCond,
+		}
+	case "contains":
+		if err := p.expectPunct(","); err != nil {
+			return ConditionBuilder{}, err
+		}
+		operand, err := p.parseConditionOperand()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		cond = ConditionBuilder{
+			operandList: []OperandBuilder{name, operand},
+			mode:        containsCond,
+		}
+	}
+
+	if err := p.expectPunct(")"); err != nil {
+		return ConditionBuilder{}, err
+	}
+	return cond, nil
+}
+
+// comparators maps the comparator tokens to their condition modes.
+var comparators = map[string]conditionMode{
+	"=":  equalCond,
+	"<>": notEqualCond,
+	"<":  lessThanCond,
+	"<=": lessThanEqualCond,
+	">":  greaterThanCond,
+	">=": greaterThanEqualCond,
+}
+
+// parseComparison parses the comparison of an operand with a comparator,
+// BETWEEN, or IN.
+func (p *exprParser) parseComparison() (ConditionBuilder, error) {
+	left, err := p.parseConditionOperand()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+
+	t := p.next()
+	switch {
+	case t.kind == punctToken && comparators[t.text] != unsetCond:
+		right, err := p.parseConditionOperand()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		return ConditionBuilder{
+			operandList: []OperandBuilder{left, right},
+			mode:        comparators[t.text],
+		}, nil
+
+	case t.isKeyword("BETWEEN"):
+		lower, err := p.parseConditionOperand()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		if t := p.next(); !t.isKeyword("AND") {
+			return ConditionBuilder{}, p.errorf(t.offset, "expected AND, got %s", t)
+		}
+		upper, err := p.parseConditionOperand()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		return Between(left, lower, upper), nil
+
+	case t.isKeyword("IN"):
+		if err := p.expectPunct("("); err != nil {
+			return ConditionBuilder{}, err
+		}
+		operands := []OperandBuilder{left}
+		for {
+			operand, err := p.parseConditionOperand()
+			if err != nil {
+				return ConditionBuilder{}, err
+			}
+			operands = append(operands, operand)
+			if !p.peek().isPunct(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expectPunct(")"); err != nil {
+			return ConditionBuilder{}, err
+		}
+		return ConditionBuilder{
+			operandList: operands,
+			mode:        inCond,
+		}, nil
+	}
+
+	return ConditionBuilder{}, p.errorf(t.offset, "expected comparator, BETWEEN, or IN, got %s", t)
+}
+
+// parseConditionOperand parses an operand of a condition, a path, a value, or
+// the size function.
+func (p *exprParser) parseConditionOperand() (OperandBuilder, error) {
+	t := p.peek()
+	switch {
+	case t.kind == valueToken:
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return attributeValueBuilder(value), nil
+	case t.kind == identToken && t.text == "size" && p.peekN(1).isPunct("("):
+		p.next()
+		p.next()
+		name, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		return name.Size(), nil
+	}
+
+	return p.parsePath()
+}
+
+// parseValue parses an ExpressionAttributeValues placeholder, resolving it to
+// its value.
+func (p *exprParser) parseValue() (*dynamodb.AttributeValue, error) {
+	t := p.next()
+	if t.kind != valueToken {
+		return nil, p.errorf(t.offset, "expected value placeholder, got %s", t)
+	}
+
+	value, ok := p.values[t.text]
+	if !ok || value == nil {
+		return nil, p.errorf(t.offset, "value placeholder %s is not defined", t)
+	}
+	return value, nil
+}
+
+// parsePath parses a document path of attribute names, or
+// ExpressionAttributeNames placeholders, list indexes, and map dereferences.
+func (p *exprParser) parsePath() (NameBuilder, error) {
+	var path []string
+	for {
+		t := p.next()
+		var name string
+		switch t.kind {
+		case identToken:
+			name = t.text
+		case nameToken:
+			v, ok := p.names[t.text]
+			if !ok || v == nil {
+				return NameBuilder{}, p.errorf(t.offset, "name placeholder %s is not defined", t)
+			}
+			name = aws.StringValue(v)
+			if len(name) == 0 || strings.ContainsAny(name, ".[]") {
+				return NameBuilder{}, p.errorf(t.offset, "name placeholder %s resolves to unsupported attribute name %q", t, name)
+			}
+		default:
+			return NameBuilder{}, p.errorf(t.offset, "expected attribute name, got %s", t)
+		}
+
+		for p.peek().isPunct("[") {
+			p.next()
+			index := p.next()
+			if index.kind != numberToken {
+				return NameBuilder{}, p.errorf(index.offset, "expected list index, got %s", index)
+			}
+			if err := p.expectPunct("]"); err != nil {
+				return NameBuilder{}, err
+			}
+			name += "[" + index.text + "]"
+		}
+		path = append(path, name)
+
+		if !p.peek().isPunct(".") {
+			break
+		}
+		p.next()
+	}
+
+	return Name(strings.Join(path, ".")), nil
+}
+
+// parseUpdate parses the SET, REMOVE, ADD, and DELETE clauses of an update
+// expression. Each clause may appear once, in any order.
+func (p *exprParser) parseUpdate() (UpdateBuilder, error) {
+	update := UpdateBuilder{}
+	seen := map[operationMode]bool{}
+
+	for p.peek().kind != endToken {
+		t := p.next()
+		mode := operationMode(strings.ToUpper(t.text))
+		if t.kind != identToken {
+			return UpdateBuilder{}, p.errorf(t.offset, "expected SET, REMOVE, ADD, or DELETE, got %s", t)
+		}
+		switch mode {
+		case setOperation, removeOperation, addOperation, deleteOperation:
+		default:
+			return UpdateBuilder{}, p.errorf(t.offset, "expected SET, REMOVE, ADD, or DELETE, got %s", t)
+		}
+		if seen[mode] {
+			return UpdateBuilder{}, p.errorf(t.offset, "duplicate %s clause", mode)
+		}
+		seen[mode] = true
+
+		for {
+			if err := p.parseUpdateAction(&update, mode); err != nil {
+				return UpdateBuilder{}, err
+			}
+			if !p.peek().isPunct(",") {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if len(seen) == 0 {
+		return UpdateBuilder{}, p.errorf(0, "empty update expression")
+	}
+	return update, nil
+}
+
+// parseUpdateAction parses an action of the update clause, adding it to the
+// UpdateBuilder.
+func (p *exprParser) parseUpdateAction(update *UpdateBuilder, mode operationMode) error {
+	name, err := p.parsePath()
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case setOperation:
+		if err := p.expectPunct("="); err != nil {
+			return err
+		}
+		value, err := p.parseSetValue()
+		if err != nil {
+			return err
+		}
+		*update = update.Set(name, value)
+	case removeOperation:
+		*update = update.Remove(name)
+	case addOperation, deleteOperation:
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		if mode == addOperation {
+			*update = update.Add(name, attributeValueBuilder(value))
+		} else {
+			*update = update.Delete(name, attributeValueBuilder(value))
+		}
+	}
+
+	return nil
+}
+
+// parseSetValue parses the value of a SET action, an operand optionally added
+// to, or subtracted from, another operand.
+func (p *exprParser) parseSetValue() (OperandBuilder, error) {
+	left, err := p.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if !t.isPunct("+") && !t.isPunct("-") {
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
+	if t.text == "+" {
+		return Plus(left, right), nil
+	}
+	return Minus(left, right), nil
+}
+
+// parseSetOperand parses an operand of a SET action, a path, a value, or the
+// if_not_exists and list_append functions.
+func (p *exprParser) parseSetOperand() (OperandBuilder, error) {
+	t := p.peek()
+	if t.kind == valueToken {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return attributeValueBuilder(value), nil
+	}
+
+	if t.kind != identToken || !p.peekN(1).isPunct("(") {
+		return p.parsePath()
+	}
+
+	switch t.text {
+	case "if_not_exists", "list_append":
+	default:
+		return nil, p.errorf(t.offset, "unsupported function %s", t)
+	}
+	p.next()
+	p.next()
+
+	var operand OperandBuilder
+	if t.text == "if_not_exists" {
+		name, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(","); err != nil {
+			return nil, err
+		}
+		value, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		operand = IfNotExists(name, value)
+	} else {
+		left, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(","); err != nil {
+			return nil, err
+		}
+		right, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		operand = ListAppend(left, right)
+	}
+
+	if err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return operand, nil
+}
+
+// attributeValue is a parsed ExpressionAttributeValues value. attributeValue
+// implements the dynamodbattribute.Marshaler interface so a ValueBuilder of
+// the value is marshaled to the value unchanged.
+type attributeValue struct {
+	value *dynamodb.AttributeValue
+}
+
+// MarshalDynamoDBAttributeValue sets av to the parsed value.
+func (v attributeValue) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	*av = *v.value
+	return nil
+}
+
+func attributeValueBuilder(value *dynamodb.AttributeValue) ValueBuilder {
+	return ValueBuilder{
+		value: attributeValue{value: value},
+	}
+}
@@ -0,0 +1,277 @@
+// +build go1.7
+
+package expression
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var parseNames = map[string]*string{
+	"#a":     aws.String("a"),
+	"#count": aws.String("Count"),
+	"#year":  aws.String("year"),
+	"#dot":   aws.String("has.dot"),
+}
+
+var parseValues = map[string]*dynamodb.AttributeValue{
+	":one":  {N: aws.String("1")},
+	":five": {N: aws.String("5")},
+	":str":  {S: aws.String("abc")},
+	":S":    {S: aws.String("S")},
+	":list": {L: []*dynamodb.AttributeValue{{S: aws.String("x")}}},
+	":set":  {SS: []*string{aws.String("x"), aws.String("y")}},
+	":num":  {NS: []*string{aws.String("2")}},
+	":b":    {B: []byte("prefix")},
+}
+
+func TestParseCondition(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected ConditionBuilder
+	}{
+		{
+			name:     "comparators",
+			input:    "#a = :one AND b <> :str AND c < :five AND c <= :five AND d > :one AND d >= :one",
+			expected: And(Name("a").Equal(Value(1)), Name("b").NotEqual(Value("abc")), Name("c").LessThan(Value(5)), Name("c").LessThanEqual(Value(5)), Name("d").GreaterThan(Value(1)), Name("d").GreaterThanEqual(Value(1))),
+		},
+		{
+			name:     "precedence",
+			input:    "a = :one OR b = :one AND NOT c = :one",
+			expected: Or(Name("a").Equal(Value(1)), And(Name("b").Equal(Value(1)), Not(Name("c").Equal(Value(1))))),
+		},
+		{
+			name:     "parentheses",
+			input:    "(a = :one OR b = :one) AND c = :one",
+			expected: And(Or(Name("a").Equal(Value(1)), Name("b").Equal(Value(1))), Name("c").Equal(Value(1))),
+		},
+		{
+			name:     "case insensitive keywords",
+			input:    "a between :one and :five or not b in (:one, :five)",
+			expected: Or(Name("a").Between(Value(1), Value(5)), Not(Name("b").In(Value(1), Value(5)))),
+		},
+		{
+			name:     "between",
+			input:    "size(a) BETWEEN :one AND :five",
+			expected: Name("a").Size().Between(Value(1), Value(5)),
+		},
+		{
+			name:     "in",
+			input:    ":one IN (a, b[0], :five)",
+			expected: Value(1).In(Name("a"), Name("b[0]"), Value(5)),
+		},
+		{
+			name:     "functions",
+			input:    "attribute_exists(a) AND attribute_not_exists (#a) AND attribute_type(b, :S) AND begins_with(c, :str) AND contains(d, :str) AND size(e) > :one",
+			expected: And(Name("a").AttributeExists(), Name("a").AttributeNotExists(), Name("b").AttributeType(String), Name("c").BeginsWith("abc"), Name("d").Contains("abc"), Name("e").Size().GreaterThan(Value(1))),
+		},
+		{
+			name:     "contains path",
+			input:    "contains(a, b)",
+			expected: ConditionBuilder{operandList: []OperandBuilder{Name("a"), Name("b")}, mode: containsCond},
+		},
+		{
+			name:     "begins_with binary",
+			input:    "begins_with(a, :b)",
+			expected: ConditionBuilder{operandList: []OperandBuilder{Name("a"), Value([]byte("prefix"))}, mode: beginsWithCond},
+		},
+		{
+			name:     "nested document path",
+			input:    "a.#year[1][2].c = :one",
+			expected: Name("a.year[1][2].c").Equal(Value(1)),
+		},
+		{
+			name:     "function names as attributes",
+			input:    "size = :one AND contains = :one",
+			expected: And(Name("size").Equal(Value(1)), Name("contains").Equal(Value(1))),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := ParseCondition(c.input, parseNames, parseValues)
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			e, err := NewBuilder().WithCondition(c.expected).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			a, err := NewBuilder().WithCondition(actual).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestParseUpdate(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected UpdateBuilder
+	}{
+		{
+			name:     "set",
+			input:    "SET a = :one, #count = #count + :one, c = c - :five, d.e[1] = f",
+			expected: Set(Name("a"), Value(1)).Set(Name("Count"), Name("Count").Plus(Value(1))).Set(Name("c"), Name("c").Minus(Value(5))).Set(Name("d.e[1]"), Name("f")),
+		},
+		{
+			name:     "set functions",
+			input:    "SET a = list_append(a, :list), b = if_not_exists(b, :one), c = if_not_exists(c, :five) + :one",
+			expected: Set(Name("a"), Name("a").ListAppend(Value([]string{"x"}))).Set(Name("b"), Name("b").IfNotExists(Value(1))).Set(Name("c"), Plus(Name("c").IfNotExists(Value(5)), Value(1))),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := ParseUpdate(c.input, parseNames, parseValues)
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			a, err := NewBuilder().WithUpdate(actual).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			e, err := NewBuilder().WithUpdate(c.expected).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestParseUpdate_AllClauses(t *testing.T) {
+	update, err := ParseUpdate("delete s :set add n :num remove a, b[0] set c = :str", parseNames, parseValues)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expr, err := NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "ADD #0 :0\nDELETE #1 :1\nREMOVE #2, #3[0]\nSET #4 = :2\n", aws.StringValue(expr.Update()); e != a {
+		t.Errorf("expect %q, got %q", e, a)
+	}
+
+	// Parsed values are used unchanged, including sets which Value cannot
+	// marshal from Go slices.
+	expected := map[string]*dynamodb.AttributeValue{
+		":0": parseValues[":num"],
+		":1": parseValues[":set"],
+		":2": parseValues[":str"],
+	}
+	if e, a := expected, expr.Values(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	cond := Or(
+		Name("a.b[1]").AttributeNotExists(),
+		And(Name("Count").Size().LessThan(Value(10)), Not(Name("Status").In(Value("done"), Value("failed")))),
+	)
+	update := Set(Name("Count"), Name("Count").Plus(Value(1))).
+		Set(Name("History"), ListAppend(Name("History").IfNotExists(Value([]string{})), Value([]string{"x"}))).
+		Remove(Name("Pending")).
+		Add(Name("Total"), Value(5))
+
+	expr, err := NewBuilder().WithCondition(cond).WithUpdate(update).Build()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	parsedCond, err := ParseCondition(aws.StringValue(expr.Condition()), expr.Names(), expr.Values())
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	parsedUpdate, err := ParseUpdate(aws.StringValue(expr.Update()), expr.Names(), expr.Values())
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	actual, err := NewBuilder().WithCondition(parsedCond).WithUpdate(parsedUpdate).Build()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := aws.StringValue(expr.Condition()), aws.StringValue(actual.Condition()); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := aws.StringValue(expr.Update()), aws.StringValue(actual.Update()); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := expr.Names(), actual.Names(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := expr.Values(), actual.Values(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  string
+		update bool
+		offset int
+		msg    string
+	}{
+		{name: "empty", input: "", offset: 0, msg: "expected attribute name"},
+		{name: "missing operand", input: "a = ", offset: 4, msg: "expected attribute name"},
+		{name: "missing comparator", input: "a :one", offset: 2, msg: "expected comparator"},
+		{name: "unclosed parenthesis", input: "(a = :one", offset: 9, msg: `expected ")"`},
+		{name: "trailing tokens", input: "a = :one b", offset: 9, msg: `unexpected "b"`},
+		{name: "between without and", input: "a BETWEEN :one OR :five", offset: 15, msg: "expected AND"},
+		{name: "undefined value", input: "a = :missing", offset: 4, msg: "value placeholder \":missing\" is not defined"},
+		{name: "undefined name", input: "#missing = :one", offset: 0, msg: "name placeholder \"#missing\" is not defined"},
+		{name: "unsupported name", input: "#dot = :one", offset: 0, msg: "unsupported attribute name"},
+		{name: "bad list index", input: "a[b] = :one", offset: 2, msg: "expected list index"},
+		{name: "attribute type not string", input: "attribute_type(a, :one)", offset: 18, msg: "must be a string"},
+		{name: "unexpected character", input: "a = :one & b", offset: 9, msg: "unexpected character"},
+		{name: "empty placeholder", input: "a = :", offset: 4, msg: "empty placeholder"},
+		{name: "empty update", input: "", update: true, offset: 0, msg: "empty update expression"},
+		{name: "unknown clause", input: "UPSERT a = :one", update: true, offset: 0, msg: "expected SET, REMOVE, ADD, or DELETE"},
+		{name: "duplicate clause", input: "SET a = :one SET b = :one", update: true, offset: 13, msg: "duplicate SET clause"},
+		{name: "add path", input: "ADD a b", update: true, offset: 6, msg: "expected value placeholder"},
+		{name: "unsupported function", input: "SET a = size(b)", update: true, offset: 8, msg: "unsupported function"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var err error
+			if c.update {
+				_, err = ParseUpdate(c.input, parseNames, parseValues)
+			} else {
+				_, err = ParseCondition(c.input, parseNames, parseValues)
+			}
+			if err == nil {
+				t.Fatalf("expect error, got none")
+			}
+
+			pe, ok := err.(ParseError)
+			if !ok {
+				t.Fatalf("expect ParseError, got %T, %v", err, err)
+			}
+			if e, a := c.offset, pe.Offset(); e != a {
+				t.Errorf("expect offset %v, got %v, %v", e, a, err)
+			}
+			if e, a := c.msg, err.Error(); !strings.Contains(a, e) {
+				t.Errorf("expect %q in error, got %q", e, a)
+			}
+		})
+	}
+}
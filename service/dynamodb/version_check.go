@@ -0,0 +1,287 @@
+package dynamodb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+)
+
+// Expression attribute placeholders used by WithVersionCheck. The names are
+// unlikely to collide with placeholders set by the request's input.
+const (
+	versionNamePlaceholder     = "#sdkVersion"
+	versionOldValuePlaceholder = ":sdkVersionOld"
+	versionNewValuePlaceholder = ":sdkVersionNew"
+)
+
+// ErrCodeVersionCheck is the error code returned by requests with
+// WithVersionCheck when the version check cannot be added to the request's
+// input.
+const ErrCodeVersionCheck = "VersionCheckError"
+
+// ErrVersionConflict is the error returned by PutItem and UpdateItem requests
+// with WithVersionCheck when the item's version attribute does not match the
+// expected version, because the item was written by another writer.
+//
+// The error wraps the ConditionalCheckFailedException returned by the
+// service, so existing checks of the error's code continue to match.
+type ErrVersionConflict struct {
+	awserr.RequestFailure
+
+	// The name of the version attribute.
+	AttributeName string
+
+	// The version the item was expected to have.
+	Version int64
+
+	// The current attributes of the item, if they were returned by the
+	// service with the ConditionalCheckFailedException. Nil otherwise.
+	Item map[string]*AttributeValue
+}
+
+// Error returns the string representation of the error.
+func (e *ErrVersionConflict) Error() string {
+	return fmt.Sprintf("version conflict, expected %s to be %d, %s",
+		e.AttributeName, e.Version, e.RequestFailure.Error())
+}
+
+// WithVersionCheck returns a request.Option which writes the PutItem or
+// UpdateItem request's item with optimistic locking, using the number
+// attribute named name as the item's version.
+//
+// The request's item is written only if the item does not exist, or its
+// version attribute does not exist or is equal to version. The version
+// attribute of the written item is incremented to version+1. Conditions in
+// the request's ConditionExpression must also be met for the item to be
+// written.
+//
+// For PutItem requests the version attribute of the input's Item is set to
+// version+1. For UpdateItem requests the attribute is set to version+1 by the
+// SET clause of the input's UpdateExpression. The changes are made to a copy
+// of the input, the caller's input is not modified and can be reused.
+//
+// If the version check fails the request's error will be an
+// *ErrVersionConflict. The option has no effect on other operations.
+//
+// Example:
+//     _, err := svc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+//         TableName:        aws.String("orders"),
+//         Key:              key,
+//         UpdateExpression: aws.String("SET #s = :s"),
+//         ExpressionAttributeNames: map[string]*string{
+//             "#s": aws.String("Status"),
+//         },
+//         ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+//             ":s": {S: aws.String("SHIPPED")},
+//         },
+//     }, dynamodb.WithVersionCheck("Version", 3))
+//     if conflict, ok := err.(*dynamodb.ErrVersionConflict); ok {
+//         // The order was modified since version 3 was read.
+//     }
+func WithVersionCheck(name string, version int64) request.Option {
+	return func(r *request.Request) {
+		v := &versionCheck{name: name, version: version}
+
+		r.Handlers.Validate.PushFrontNamed(request.NamedHandler{
+			Name: "dynamodb.VersionCheck", Fn: v.applyVersionCheck,
+		})
+		r.Handlers.UnmarshalError.PushFrontNamed(request.NamedHandler{
+			Name: "dynamodb.VersionCheckReadItem", Fn: v.readErrorItem,
+		})
+		r.Handlers.UnmarshalError.PushBackNamed(request.NamedHandler{
+			Name: "dynamodb.VersionConflict", Fn: v.translateError,
+		})
+	}
+}
+
+type versionCheck struct {
+	name    string
+	version int64
+	item    map[string]*AttributeValue
+}
+
+// applyVersionCheck adds the version condition, and the incremented version,
+// to a copy of the request's input. The caller's input, and its maps, are not
+// modified so the input can be reused.
+func (v *versionCheck) applyVersionCheck(r *request.Request) {
+	oldValue := &AttributeValue{N: aws.String(strconv.FormatInt(v.version, 10))}
+	newValue := &AttributeValue{N: aws.String(strconv.FormatInt(v.version+1, 10))}
+
+	var err error
+	switch p := r.Params.(type) {
+	case *PutItemInput:
+		in := *p
+		in.Item = copyAttributeValues(p.Item)
+		in.Item[v.name] = newValue
+
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues, err = v.addPlaceholders(
+			p.ExpressionAttributeNames, p.ExpressionAttributeValues, oldValue, nil)
+		in.ConditionExpression = versionCondition(p.ConditionExpression)
+		r.Params = &in
+
+	case *UpdateItemInput:
+		in := *p
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues, err = v.addPlaceholders(
+			p.ExpressionAttributeNames, p.ExpressionAttributeValues, oldValue, newValue)
+		in.ConditionExpression = versionCondition(p.ConditionExpression)
+		in.UpdateExpression = aws.String(addSetAction(aws.StringValue(p.UpdateExpression),
+			versionNamePlaceholder+" = "+versionNewValuePlaceholder))
+		r.Params = &in
+
+	default:
+		return
+	}
+
+	if err != nil {
+		r.Error = err
+	}
+}
+
+// addPlaceholders returns copies of the expression attribute names and values
+// with the version check's placeholders added, returning an error if the
+// placeholders are already set.
+func (v *versionCheck) addPlaceholders(
+	names map[string]*string, values map[string]*AttributeValue,
+	oldValue, newValue *AttributeValue,
+) (map[string]*string, map[string]*AttributeValue, error) {
+	names = copyAttributeNames(names)
+	values = copyAttributeValues(values)
+
+	for _, k := range []string{versionOldValuePlaceholder, versionNewValuePlaceholder} {
+		if _, ok := values[k]; ok {
+			return names, values, awserr.New(ErrCodeVersionCheck,
+				fmt.Sprintf("expression attribute value %s is reserved for the version check", k), nil)
+		}
+	}
+	if _, ok := names[versionNamePlaceholder]; ok {
+		return names, values, awserr.New(ErrCodeVersionCheck,
+			fmt.Sprintf("expression attribute name %s is reserved for the version check",
+				versionNamePlaceholder), nil)
+	}
+
+	names[versionNamePlaceholder] = aws.String(v.name)
+	values[versionOldValuePlaceholder] = oldValue
+	if newValue != nil {
+		values[versionNewValuePlaceholder] = newValue
+	}
+
+	return names, values, nil
+}
+
+func copyAttributeNames(m map[string]*string) map[string]*string {
+	out := make(map[string]*string, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func copyAttributeValues(m map[string]*AttributeValue) map[string]*AttributeValue {
+	out := make(map[string]*AttributeValue, len(m)+2)
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// versionCondition returns the condition expression with the version
+// condition added.
+func versionCondition(expr *string) *string {
+	cond := fmt.Sprintf("(attribute_not_exists(%s) OR %s = %s)",
+		versionNamePlaceholder, versionNamePlaceholder, versionOldValuePlaceholder)
+
+	if s := aws.StringValue(expr); len(strings.TrimSpace(s)) != 0 {
+		cond = "(" + s + ") AND " + cond
+	}
+
+	return aws.String(cond)
+}
+
+// addSetAction adds the action to the SET clause of the update expression,
+// adding a SET clause if the expression does not have one.
+func addSetAction(expr, action string) string {
+	if i := setClauseIndex(expr); i >= 0 {
+		return expr[:i] + " " + action + "," + expr[i:]
+	}
+
+	if len(strings.TrimSpace(expr)) == 0 {
+		return "SET " + action
+	}
+	return expr + " SET " + action
+}
+
+// setClauseIndex returns the index following the SET keyword of the update
+// expression, or -1 if the expression has no SET clause.
+func setClauseIndex(expr string) int {
+	isWordByte := func(c byte) bool {
+		r := rune(c)
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || c == '_' || c == '#' || c == ':' || c == '.'
+	}
+
+	for i := 0; i+3 <= len(expr); i++ {
+		if !strings.EqualFold(expr[i:i+3], "SET") {
+			continue
+		}
+		if i > 0 && isWordByte(expr[i-1]) {
+			continue
+		}
+		if i+3 < len(expr) && isWordByte(expr[i+3]) {
+			continue
+		}
+		return i + 3
+	}
+
+	return -1
+}
+
+// readErrorItem reads the item from the error response's body, if the item
+// was returned with the ConditionalCheckFailedException.
+func (v *versionCheck) readErrorItem(r *request.Request) {
+	if r.HTTPResponse == nil || r.HTTPResponse.Body == nil {
+		return
+	}
+
+	b, err := ioutil.ReadAll(r.HTTPResponse.Body)
+	r.HTTPResponse.Body.Close()
+	r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if err != nil || len(b) == 0 {
+		return
+	}
+
+	var resp struct {
+		Item map[string]*AttributeValue `type:"map"`
+	}
+	if err := jsonutil.UnmarshalJSON(&resp, bytes.NewReader(b)); err == nil {
+		v.item = resp.Item
+	}
+}
+
+// translateError translates the request's ConditionalCheckFailedException
+// error into an ErrVersionConflict.
+func (v *versionCheck) translateError(r *request.Request) {
+	reqErr, ok := r.Error.(awserr.RequestFailure)
+	if !ok || reqErr.Code() != ErrCodeConditionalCheckFailedException {
+		return
+	}
+
+	switch r.Params.(type) {
+	case *PutItemInput, *UpdateItemInput:
+	default:
+		return
+	}
+
+	r.Error = &ErrVersionConflict{
+		RequestFailure: reqErr,
+		AttributeName:  v.name,
+		Version:        v.version,
+		Item:           v.item,
+	}
+}
@@ -0,0 +1,274 @@
+package dynamodb_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func mockVersionCheckResponse(req *request.Request, status int, body string) {
+	req.Handlers.Build.RemoveByName("crr.endpointdiscovery")
+	req.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	})
+}
+
+func TestWithVersionCheck_PutItem(t *testing.T) {
+	cases := map[string]struct {
+		Input           *dynamodb.PutItemInput
+		ExpectCondition string
+	}{
+		"no condition": {
+			Input: &dynamodb.PutItemInput{
+				TableName: aws.String("orders"),
+				Item: map[string]*dynamodb.AttributeValue{
+					"ID":      {S: aws.String("1")},
+					"Version": {N: aws.String("3")},
+				},
+			},
+			ExpectCondition: "(attribute_not_exists(#sdkVersion) OR #sdkVersion = :sdkVersionOld)",
+		},
+		"with condition": {
+			Input: &dynamodb.PutItemInput{
+				TableName: aws.String("orders"),
+				Item: map[string]*dynamodb.AttributeValue{
+					"ID": {S: aws.String("1")},
+				},
+				ConditionExpression: aws.String("#s <> :s"),
+				ExpressionAttributeNames: map[string]*string{
+					"#s": aws.String("Status"),
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":s": {S: aws.String("CLOSED")},
+				},
+			},
+			ExpectCondition: "(#s <> :s) AND (attribute_not_exists(#sdkVersion) OR #sdkVersion = :sdkVersionOld)",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, _ := db.PutItemRequest(c.Input)
+			req.ApplyOptions(dynamodb.WithVersionCheck("Version", 3))
+			mockVersionCheckResponse(req, 200, "{}")
+			if err := req.Send(); err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			in := req.Params.(*dynamodb.PutItemInput)
+			if e, a := "4", aws.StringValue(in.Item["Version"].N); e != a {
+				t.Errorf("expect %v version, got %v", e, a)
+			}
+			if e, a := c.ExpectCondition, aws.StringValue(in.ConditionExpression); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := "Version", aws.StringValue(in.ExpressionAttributeNames["#sdkVersion"]); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := "3", aws.StringValue(in.ExpressionAttributeValues[":sdkVersionOld"].N); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestWithVersionCheck_UpdateItem(t *testing.T) {
+	cases := map[string]struct {
+		UpdateExpression string
+		Expect           string
+	}{
+		"no update expression": {
+			Expect: "SET #sdkVersion = :sdkVersionNew",
+		},
+		"set clause": {
+			UpdateExpression: "SET #s = :s REMOVE Pending",
+			Expect:           "SET #sdkVersion = :sdkVersionNew, #s = :s REMOVE Pending",
+		},
+		"lower case set clause": {
+			UpdateExpression: "remove Pending set #s = :s",
+			Expect:           "remove Pending set #sdkVersion = :sdkVersionNew, #s = :s",
+		},
+		"no set clause": {
+			UpdateExpression: "REMOVE Settings",
+			Expect:           "REMOVE Settings SET #sdkVersion = :sdkVersionNew",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			input := &dynamodb.UpdateItemInput{
+				TableName: aws.String("orders"),
+				Key: map[string]*dynamodb.AttributeValue{
+					"ID": {S: aws.String("1")},
+				},
+			}
+			if len(c.UpdateExpression) != 0 {
+				input.UpdateExpression = aws.String(c.UpdateExpression)
+			}
+
+			req, _ := db.UpdateItemRequest(input)
+			req.ApplyOptions(dynamodb.WithVersionCheck("Version", 7))
+			mockVersionCheckResponse(req, 200, "{}")
+			if err := req.Send(); err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			in := req.Params.(*dynamodb.UpdateItemInput)
+			if e, a := c.Expect, aws.StringValue(in.UpdateExpression); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := "8", aws.StringValue(in.ExpressionAttributeValues[":sdkVersionNew"].N); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := "7", aws.StringValue(in.ExpressionAttributeValues[":sdkVersionOld"].N); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestWithVersionCheck_ReuseInput(t *testing.T) {
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("orders"),
+		Item: map[string]*dynamodb.AttributeValue{
+			"ID":      {S: aws.String("1")},
+			"Version": {N: aws.String("3")},
+		},
+		ConditionExpression: aws.String("#s <> :s"),
+		ExpressionAttributeNames: map[string]*string{
+			"#s": aws.String("Status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":s": {S: aws.String("CLOSED")},
+		},
+	}
+	orig := awsutil.CopyOf(input).(*dynamodb.PutItemInput)
+
+	for _, version := range []int64{3, 4} {
+		req, _ := db.PutItemRequest(input)
+		req.ApplyOptions(dynamodb.WithVersionCheck("Version", version))
+		mockVersionCheckResponse(req, 200, "{}")
+		if err := req.Send(); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+
+		in := req.Params.(*dynamodb.PutItemInput)
+		if e, a := "(#s <> :s) AND (attribute_not_exists(#sdkVersion) OR #sdkVersion = :sdkVersionOld)",
+			aws.StringValue(in.ConditionExpression); e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+		if e, a := strconv.FormatInt(version+1, 10), aws.StringValue(in.Item["Version"].N); e != a {
+			t.Errorf("expect %v version, got %v", e, a)
+		}
+
+		if e, a := orig, input; !reflect.DeepEqual(e, a) {
+			t.Errorf("expect input not to be modified, %v, got %v", e, a)
+		}
+	}
+}
+
+func TestWithVersionCheck_Conflict(t *testing.T) {
+	cases := map[string]struct {
+		Body       string
+		ExpectItem map[string]*dynamodb.AttributeValue
+	}{
+		"without item": {
+			Body: `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException",` +
+				`"message":"The conditional request failed"}`,
+		},
+		"with item": {
+			Body: `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException",` +
+				`"message":"The conditional request failed",` +
+				`"Item":{"ID":{"S":"1"},"Version":{"N":"5"}}}`,
+			ExpectItem: map[string]*dynamodb.AttributeValue{
+				"ID":      {S: aws.String("1")},
+				"Version": {N: aws.String("5")},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, _ := db.PutItemRequest(&dynamodb.PutItemInput{
+				TableName: aws.String("orders"),
+				Item: map[string]*dynamodb.AttributeValue{
+					"ID": {S: aws.String("1")},
+				},
+			})
+			req.ApplyOptions(dynamodb.WithVersionCheck("Version", 3))
+			mockVersionCheckResponse(req, 400, c.Body)
+
+			err := req.Send()
+			conflict, ok := err.(*dynamodb.ErrVersionConflict)
+			if !ok {
+				t.Fatalf("expect *ErrVersionConflict, got %T, %v", err, err)
+			}
+			if e, a := dynamodb.ErrCodeConditionalCheckFailedException, conflict.Code(); e != a {
+				t.Errorf("expect %v code, got %v", e, a)
+			}
+			if e, a := 400, conflict.StatusCode(); e != a {
+				t.Errorf("expect %v status, got %v", e, a)
+			}
+			if e, a := "Version", conflict.AttributeName; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := int64(3), conflict.Version; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+			if e, a := c.ExpectItem, conflict.Item; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestWithVersionCheck_OtherErrors(t *testing.T) {
+	req, _ := db.PutItemRequest(&dynamodb.PutItemInput{
+		TableName: aws.String("orders"),
+		Item:      map[string]*dynamodb.AttributeValue{},
+	})
+	req.ApplyOptions(dynamodb.WithVersionCheck("Version", 3))
+	mockVersionCheckResponse(req, 400,
+		`{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"not found"}`)
+
+	err := req.Send()
+	if _, ok := err.(*dynamodb.ErrVersionConflict); ok {
+		t.Fatalf("expect other error, got %v", err)
+	}
+	if e, a := dynamodb.ErrCodeResourceNotFoundException, err.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestWithVersionCheck_ReservedPlaceholder(t *testing.T) {
+	req, _ := db.PutItemRequest(&dynamodb.PutItemInput{
+		TableName: aws.String("orders"),
+		Item:      map[string]*dynamodb.AttributeValue{},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":sdkVersionOld": {N: aws.String("1")},
+		},
+	})
+	req.ApplyOptions(dynamodb.WithVersionCheck("Version", 3))
+	mockVersionCheckResponse(req, 200, "{}")
+
+	err := req.Send()
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := dynamodb.ErrCodeVersionCheck, err.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
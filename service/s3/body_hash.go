@@ -28,8 +28,6 @@ const (
 // contentMD5 computes and sets the HTTP Content-MD5 header for requests that
 // require it.
 func contentMD5(r *request.Request) {
-	h := md5.New()
-
 	if !aws.IsReaderSeekable(r.Body) {
 		if r.Config.Logger != nil {
 			r.Config.Logger.Log(fmt.Sprintf(
@@ -39,16 +37,28 @@ func contentMD5(r *request.Request) {
 		return
 	}
 
-	if _, err := copySeekableBody(h, r.Body); err != nil {
+	sum, err := BodyMD5(r.Body)
+	if err != nil {
 		r.Error = awserr.New("ContentMD5", "failed to compute body MD5", err)
 		return
 	}
 
 	// encode the md5 checksum in base64 and set the request header.
-	v := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	v := base64.StdEncoding.EncodeToString(sum)
 	r.HTTPRequest.Header.Set(contentMD5Header, v)
 }
 
+// BodyMD5 returns the MD5 checksum of the body read from its current
+// position. The body is seeked back to the position once read. This is the
+// checksum the Content-MD5 header of requests is computed from.
+func BodyMD5(body io.ReadSeeker) ([]byte, error) {
+	h := md5.New()
+	if _, err := copySeekableBody(h, body); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
 // computeBodyHashes will add Content MD5 and Content Sha256 hashes to the
 // request. If the body is not seekable or S3DisableContentMD5Validation set
 // this handler will be ignored.
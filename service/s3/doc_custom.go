@@ -84,6 +84,31 @@
 // See the s3manager package's GetBucketRegion function documentation for more information
 // https://docs.aws.amazon.com/sdk-for-go/api/service/s3/s3manager/#GetBucketRegion
 //
+// Presigned POST Uploads
+//
+// PresignPost creates a presigned POST policy which allows a browser to upload
+// an object to S3 with an HTML form, without the browser needing AWS
+// credentials. The policy's conditions restrict what the browser may upload.
+//
+//   svc := s3.New(session.Must(session.NewSession()))
+//
+//   out, err := svc.PresignPost(&s3.PresignPostInput{
+//       Bucket:    aws.String(myBucket),
+//       KeyPrefix: aws.String("uploads/"),
+//       Conditions: []s3.PostPolicyCondition{
+//           s3.PostPolicyContentLengthRange(1, 10*1024*1024),
+//       },
+//       Expires: 15 * time.Minute,
+//   })
+//   if err != nil {
+//       return fmt.Errorf("failed to presign POST, %v", err)
+//   }
+//
+//   // Use out.URL as the form's action, and include each of out.Fields as
+//   // a form field, before the file field.
+//
+// See the PresignPost method documentation for more information.
+//
 // S3 Crypto Client
 //
 // The s3crypto package provides the tools to upload and download encrypted
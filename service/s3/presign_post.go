@@ -0,0 +1,229 @@
+package s3
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const opPresignPost = "PresignPost"
+
+// presignPostFilenameVariable is the variable S3 will replace with the name
+// of the file uploaded by the browser.
+const presignPostFilenameVariable = "${filename}"
+
+// A PostPolicyCondition is a condition of a presigned POST policy document
+// which the fields of the POST upload's form must satisfy for the upload to
+// be accepted.
+type PostPolicyCondition struct {
+	value interface{}
+}
+
+// MarshalJSON marshals the condition into its JSON policy document form.
+func (c PostPolicyCondition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.value)
+}
+
+// PostPolicyEquals returns a condition requiring the form field's value to
+// exactly match the value provided. The field name is the form field's name,
+// such as "Content-Type".
+func PostPolicyEquals(field, value string) PostPolicyCondition {
+	return PostPolicyCondition{value: []interface{}{"eq", "$" + field, value}}
+}
+
+// PostPolicyStartsWith returns a condition requiring the form field's value
+// to start with the prefix provided. An empty prefix allows any value for
+// the field.
+func PostPolicyStartsWith(field, prefix string) PostPolicyCondition {
+	return PostPolicyCondition{value: []interface{}{"starts-with", "$" + field, prefix}}
+}
+
+// PostPolicyContentLengthRange returns a condition requiring the size of the
+// uploaded content, in bytes, to be within the range of min and max
+// inclusive.
+func PostPolicyContentLengthRange(min, max int64) PostPolicyCondition {
+	return PostPolicyCondition{value: []interface{}{"content-length-range", min, max}}
+}
+
+// PresignPostInput provides the parameters to create a presigned POST policy
+// with PresignPost.
+type PresignPostInput struct {
+	// The bucket the object will be uploaded to.
+	//
+	// Bucket is a required field
+	Bucket *string
+
+	// The key the object will be uploaded to. Either Key or KeyPrefix must
+	// be set. If both are set Key will be used.
+	Key *string
+
+	// The prefix the key of the uploaded object must start with. The form's
+	// key field will be set to the prefix followed by "${filename}", which S3
+	// replaces with the name of the file uploaded. The key field may be
+	// changed by the browser to any value starting with the prefix.
+	KeyPrefix *string
+
+	// Form fields which will be included in the upload with the values
+	// provided, such as "acl", "Content-Type", "success_action_status", or
+	// "x-amz-meta-*" fields. The policy requires each field to match exactly.
+	Fields map[string]string
+
+	// Additional conditions the upload's form fields must satisfy. Fields
+	// with conditions that are not in Fields must be added to the form by
+	// the browser.
+	Conditions []PostPolicyCondition
+
+	// The duration the presigned POST policy is valid for.
+	//
+	// Expires is a required field
+	Expires time.Duration
+}
+
+// Validate inspects the fields of the type to determine if they are valid.
+func (s *PresignPostInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "PresignPostInput"}
+	if s.Bucket == nil {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if s.Bucket != nil && len(*s.Bucket) < 1 {
+		invalidParams.Add(request.NewErrParamMinLen("Bucket", 1))
+	}
+	if s.Key == nil && s.KeyPrefix == nil {
+		invalidParams.Add(request.NewErrParamRequired("Key"))
+	}
+	if s.Key != nil && len(*s.Key) < 1 {
+		invalidParams.Add(request.NewErrParamMinLen("Key", 1))
+	}
+	if s.Expires <= 0 {
+		invalidParams.Add(request.NewErrParamMinValue("Expires", 1))
+	}
+
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// PresignPostOutput is the presigned POST policy returned by PresignPost.
+type PresignPostOutput struct {
+	// The URL the form must be POSTed to.
+	URL string
+
+	// The form fields which must be included in the POST upload's form. The
+	// file being uploaded must be the last field of the form.
+	Fields map[string]string
+}
+
+// presignPostBucket is the request parameters used to resolve the URL of the
+// bucket a presigned POST will be uploaded to.
+type presignPostBucket struct {
+	_ struct{} `type:"structure"`
+
+	Bucket *string `location:"uri" locationName:"Bucket" type:"string" required:"true"`
+}
+
+func (s *presignPostBucket) getBucket() (v string) {
+	if s.Bucket == nil {
+		return v
+	}
+	return *s.Bucket
+}
+
+// PresignPost creates a presigned POST policy which allows browsers to
+// upload objects to S3 with an HTML form, without the browser needing AWS
+// credentials. The policy is signed with Signature Version 4 using the
+// client's credentials and region.
+//
+// The returned URL and form fields should be used to build the HTML form.
+// The file being uploaded must be the last field in the form.
+//
+//    out, err := svc.PresignPost(&s3.PresignPostInput{
+//        Bucket:    aws.String("mybucket"),
+//        KeyPrefix: aws.String("uploads/"),
+//        Fields: map[string]string{
+//            "acl": "private",
+//        },
+//        Conditions: []s3.PostPolicyCondition{
+//            s3.PostPolicyStartsWith("Content-Type", "image/"),
+//            s3.PostPolicyContentLengthRange(1, 10*1024*1024),
+//        },
+//        Expires: 15 * time.Minute,
+//    })
+//
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+// for more information on POST policies.
+func (c *S3) PresignPost(input *PresignPostInput) (*PresignPostOutput, error) {
+	if input == nil {
+		input = &PresignPostInput{}
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	op := &request.Operation{
+		Name:       opPresignPost,
+		HTTPMethod: "POST",
+		HTTPPath:   "/{Bucket}",
+	}
+	req := c.newRequest(op, &presignPostBucket{Bucket: input.Bucket}, nil)
+	if err := req.Build(); err != nil {
+		return nil, err
+	}
+
+	bucket := aws.StringValue(input.Bucket)
+	key := aws.StringValue(input.Key)
+	keyCond := PostPolicyEquals("key", key)
+	if input.Key == nil {
+		prefix := aws.StringValue(input.KeyPrefix)
+		key = prefix + presignPostFilenameVariable
+		keyCond = PostPolicyStartsWith("key", prefix)
+	}
+
+	fields := map[string]string{}
+	conditions := []interface{}{
+		map[string]string{"bucket": bucket},
+		keyCond,
+	}
+	for k, v := range input.Fields {
+		fields[k] = v
+		conditions = append(conditions, map[string]string{k: v})
+	}
+	for _, cond := range input.Conditions {
+		conditions = append(conditions, cond)
+	}
+
+	// The signing name and region are not set for custom endpoints, and
+	// fall back to the service's name and the config's region as they do
+	// when signing requests.
+	name := c.ClientInfo.SigningName
+	if name == "" {
+		name = c.ClientInfo.ServiceName
+	}
+	region := c.ClientInfo.SigningRegion
+	if region == "" {
+		region = aws.StringValue(c.Config.Region)
+	}
+
+	signer := v4.NewSigner(c.Config.Credentials, func(s *v4.Signer) {
+		s.Debug = c.Config.LogLevel.Value()
+		s.Logger = c.Config.Logger
+	})
+	signed, err := signer.PresignPOSTPolicy(conditions, name, region,
+		input.Expires, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range signed {
+		fields[k] = v
+	}
+	fields["key"] = key
+
+	return &PresignPostOutput{
+		URL:    req.HTTPRequest.URL.String(),
+		Fields: fields,
+	}, nil
+}
@@ -0,0 +1,181 @@
+package s3_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestPresignPost(t *testing.T) {
+	cases := map[string]struct {
+		Config       *aws.Config
+		Input        *s3.PresignPostInput
+		ExpectURL    string
+		ExpectKey    string
+		ExpectFields map[string]string
+		ExpectConds  []interface{}
+	}{
+		"key": {
+			Input: &s3.PresignPostInput{
+				Bucket:  aws.String("bucket"),
+				Key:     aws.String("my/key"),
+				Expires: time.Hour,
+			},
+			ExpectURL: "https://bucket.s3.mock-region.amazonaws.com/",
+			ExpectKey: "my/key",
+			ExpectConds: []interface{}{
+				map[string]interface{}{"bucket": "bucket"},
+				[]interface{}{"eq", "$key", "my/key"},
+			},
+		},
+		"key prefix": {
+			Input: &s3.PresignPostInput{
+				Bucket:    aws.String("bucket"),
+				KeyPrefix: aws.String("uploads/"),
+				Fields: map[string]string{
+					"acl": "private",
+				},
+				Conditions: []s3.PostPolicyCondition{
+					s3.PostPolicyStartsWith("Content-Type", "image/"),
+					s3.PostPolicyContentLengthRange(1, 1024),
+				},
+				Expires: time.Hour,
+			},
+			ExpectURL: "https://bucket.s3.mock-region.amazonaws.com/",
+			ExpectKey: "uploads/${filename}",
+			ExpectFields: map[string]string{
+				"acl": "private",
+			},
+			ExpectConds: []interface{}{
+				map[string]interface{}{"bucket": "bucket"},
+				[]interface{}{"starts-with", "$key", "uploads/"},
+				map[string]interface{}{"acl": "private"},
+				[]interface{}{"starts-with", "$Content-Type", "image/"},
+				[]interface{}{"content-length-range", float64(1), float64(1024)},
+			},
+		},
+		"path style": {
+			Config: &aws.Config{S3ForcePathStyle: aws.Bool(true)},
+			Input: &s3.PresignPostInput{
+				Bucket:  aws.String("bucket"),
+				Key:     aws.String("key"),
+				Expires: time.Hour,
+			},
+			ExpectURL: "https://s3.mock-region.amazonaws.com/bucket",
+			ExpectKey: "key",
+			ExpectConds: []interface{}{
+				map[string]interface{}{"bucket": "bucket"},
+				[]interface{}{"eq", "$key", "key"},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := s3.New(unit.Session, c.Config)
+
+			out, err := svc.PresignPost(c.Input)
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			if e, a := c.ExpectURL, out.URL; e != a {
+				t.Errorf("expect %v URL, got %v", e, a)
+			}
+			if e, a := c.ExpectKey, out.Fields["key"]; e != a {
+				t.Errorf("expect %v key, got %v", e, a)
+			}
+			for k, v := range c.ExpectFields {
+				if e, a := v, out.Fields[k]; e != a {
+					t.Errorf("expect %v %s field, got %v", e, k, a)
+				}
+			}
+			for _, k := range []string{"policy", "x-amz-algorithm", "x-amz-credential", "x-amz-date", "x-amz-security-token", "x-amz-signature"} {
+				if len(out.Fields[k]) == 0 {
+					t.Errorf("expect %s field to be set", k)
+				}
+			}
+
+			b, err := base64.StdEncoding.DecodeString(out.Fields["policy"])
+			if err != nil {
+				t.Fatalf("expect no error decoding policy, got %v", err)
+			}
+			var policy struct {
+				Conditions []interface{}
+			}
+			if err := json.Unmarshal(b, &policy); err != nil {
+				t.Fatalf("expect no error unmarshaling policy, got %v", err)
+			}
+
+			// The signing conditions are added after the input's conditions.
+			if e, a := len(c.ExpectConds)+4, len(policy.Conditions); e != a {
+				t.Fatalf("expect %v conditions, got %v", e, a)
+			}
+			if e, a := c.ExpectConds, policy.Conditions[:len(c.ExpectConds)]; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v conditions, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestPresignPost_CustomEndpoint(t *testing.T) {
+	svc := s3.New(unit.Session, &aws.Config{
+		Endpoint:         aws.String("https://storage.example.com"),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+
+	out, err := svc.PresignPost(&s3.PresignPostInput{
+		Bucket:  aws.String("bucket"),
+		Key:     aws.String("key"),
+		Expires: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := "https://storage.example.com/bucket", out.URL; e != a {
+		t.Errorf("expect %v URL, got %v", e, a)
+	}
+	if e, a := "/mock-region/s3/aws4_request", out.Fields["x-amz-credential"]; !strings.HasSuffix(a, e) {
+		t.Errorf("expect credential to end with %v, got %v", e, a)
+	}
+}
+
+func TestPresignPost_InvalidParams(t *testing.T) {
+	svc := s3.New(unit.Session)
+
+	_, err := svc.PresignPost(&s3.PresignPostInput{})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	invalidParams, ok := err.(request.ErrInvalidParams)
+	if !ok {
+		t.Fatalf("expect %T error, got %T", request.ErrInvalidParams{}, err)
+	}
+	if e, a := 3, invalidParams.Len(); e != a {
+		t.Errorf("expect %v invalid params, got %v", e, a)
+	}
+}
+
+func TestPresignPost_AnonymousCredentials(t *testing.T) {
+	svc := s3.New(unit.Session, &aws.Config{Credentials: credentials.AnonymousCredentials})
+
+	_, err := svc.PresignPost(&s3.PresignPostInput{
+		Bucket:  aws.String("bucket"),
+		Key:     aws.String("key"),
+		Expires: time.Hour,
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+}
@@ -0,0 +1,133 @@
+package s3crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"crypto/subtle"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// AESGCMCommitKey is the CEK algorithm of content encrypted with AES GCM
+// with a key committed to by the envelope. The content is encrypted with a
+// key derived from the data key and IV with HKDF-SHA512, and the envelope
+// stores a commitment derived the same way, so the data key can't be
+// replaced by one decrypting the content to a different plaintext.
+const AESGCMCommitKey = "AES/GCM/HkdfSha512CommitKey"
+
+const gcmCommitmentSize = 32
+
+var (
+	gcmCommitDeriveKeyInfo = []byte(AESGCMCommitKey + "DERIVEKEY")
+	gcmCommitCommitKeyInfo = []byte(AESGCMCommitKey + "COMMITKEY")
+)
+
+type gcmCommitContentCipherBuilder struct {
+	generator CipherDataGenerator
+}
+
+// AESGCMCommitContentCipherBuilder returns a new encryption only mode
+// structure encrypting content with AES GCM, and committing to the key in
+// the envelope. Objects encrypted with it can be decrypted by the
+// DecryptionClient in each DecryptionMode.
+//
+// The content encryption algorithm is authenticated with the key if the
+// generator supports it, as the KMS context, symmetric, and RSA key
+// generators do.
+//
+// Example:
+//	handler := s3crypto.NewKMSContextKeyGenerator(kms.New(sess), cmkID, s3crypto.MaterialDescription{})
+//	svc := s3crypto.NewEncryptionClient(sess, s3crypto.AESGCMCommitContentCipherBuilder(handler))
+func AESGCMCommitContentCipherBuilder(generator CipherDataGenerator) ContentCipherBuilder {
+	return gcmCommitContentCipherBuilder{generator}
+}
+
+func (builder gcmCommitContentCipherBuilder) ContentCipher() (ContentCipher, error) {
+	return builder.objectContentCipher("", "")
+}
+
+// objectContentCipher returns a content cipher whose key is bound to the
+// object's bucket and key if the generator supports it.
+func (builder gcmCommitContentCipherBuilder) objectContentCipher(bucket, key string) (ContentCipher, error) {
+	cd := CipherData{
+		Key:          generateBytes(gcmKeySize),
+		IV:           generateBytes(gcmNonceSize),
+		CEKAlgorithm: AESGCMCommitKey,
+	}
+
+	var err error
+	switch g := builder.generator.(type) {
+	case objectCipherDataEncrypter:
+		cd, err = g.encryptObjectCipherData(cd, bucket, key)
+	case CipherDataEncrypter:
+		cd, err = g.EncryptCipherData(cd)
+	default:
+		cd, err = g.GenerateCipherData(gcmKeySize, gcmNonceSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newAESGCMCommitContentCipher(cd)
+}
+
+// newAESGCMCommitContentCipher returns the AES GCM content cipher of the key
+// derived from the cipher data. The cipher data's key commitment is verified
+// if it has one, and set otherwise.
+func newAESGCMCommitContentCipher(cd CipherData) (ContentCipher, error) {
+	if len(cd.IV) != gcmNonceSize {
+		return nil, awserr.New("InvalidNonceError", AESGCMCommitKey+" requires a 12 byte nonce", nil)
+	}
+
+	derivedKey := hkdfSHA512(cd.Key, cd.IV, gcmCommitDeriveKeyInfo, gcmKeySize)
+	commitment := hkdfSHA512(cd.Key, cd.IV, gcmCommitCommitKeyInfo, gcmCommitmentSize)
+	if len(cd.KeyCommitment) > 0 && subtle.ConstantTimeCompare(cd.KeyCommitment, commitment) != 1 {
+		return nil, errKeyCommitmentMismatch
+	}
+
+	cd.Key = derivedKey
+	cd.KeyCommitment = commitment
+	cd.CEKAlgorithm = AESGCMCommitKey
+	cd.TagLength = "128"
+
+	cipher, err := newAESGCM(cd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCMContentCipher{
+		CipherData: cd,
+		Cipher:     cipher,
+	}, nil
+}
+
+// newAESGCMCommitDecryptContentCipher is the CEK entry of AESGCMCommitKey,
+// which requires the envelope to have a key commitment.
+func newAESGCMCommitDecryptContentCipher(cd CipherData) (ContentCipher, error) {
+	if len(cd.KeyCommitment) == 0 {
+		return nil, errKeyCommitmentMismatch
+	}
+	return newAESGCMCommitContentCipher(cd)
+}
+
+var errKeyCommitmentMismatch = awserr.New("KeyCommitmentMismatchError",
+	"the envelope's key commitment does not match its key", nil)
+
+// hkdfSHA512 derives n bytes from the secret with HKDF-SHA512, as defined by
+// RFC 5869.
+func hkdfSHA512(secret, salt, info []byte, n int) []byte {
+	extract := hmac.New(sha512.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	var out, t []byte
+	for i := byte(1); len(out) < n; i++ {
+		expand := hmac.New(sha512.New, prk)
+		expand.Write(t)
+		expand.Write(info)
+		expand.Write([]byte{i})
+		t = expand.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:n]
+}
@@ -0,0 +1,108 @@
+package s3crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// encryptCommitted encrypts the plaintext with the AESGCMCommitKey content
+// cipher of a symmetric master key, and returns the ciphertext and envelope.
+func encryptCommitted(t *testing.T, masterKey SymmetricMasterKey, plaintext []byte) ([]byte, Envelope) {
+	generator := NewSymmetricKeyGenerator(AESGCMWrap, masterKey.Key, masterKey.MaterialDescription)
+	cc, err := AESGCMCommitContentCipherBuilder(generator).ContentCipher()
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	reader, err := cc.EncryptContents(bytes.NewReader(plaintext))
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	ciphertext, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	env, err := encodeCipherData(cc.GetCipherData())
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	return ciphertext, env
+}
+
+func newCommitDecryptionClient(masterKey SymmetricMasterKey) *DecryptionClient {
+	return &DecryptionClient{
+		WrapRegistry: map[string]WrapEntry{AESGCMWrap: NewSymmetricKeyWrapEntry(masterKey)},
+		CEKRegistry:  map[string]CEKEntry{AESGCMCommitKey: newAESGCMCommitDecryptContentCipher},
+	}
+}
+
+func TestAESGCMCommitContentCipher_RoundTrip(t *testing.T) {
+	masterKey := SymmetricMasterKey{
+		Key:                 bytes.Repeat([]byte{1}, 32),
+		MaterialDescription: MaterialDescription{"key": aws.String("1")},
+	}
+	plaintext := []byte("content with a committed key")
+	ciphertext, env := encryptCommitted(t, masterKey, plaintext)
+
+	if e, a := AESGCMCommitKey, env.CEKAlg; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	commitment, _ := base64.StdEncoding.DecodeString(env.KeyCommitment)
+	if e, a := gcmCommitmentSize, len(commitment); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+
+	cc, err := newCommitDecryptionClient(masterKey).contentCipherFromEnvelope(env)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	reader, err := cc.DecryptContents(ioutil.NopCloser(bytes.NewReader(ciphertext)))
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	b, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := plaintext, b; !bytes.Equal(e, a) {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+}
+
+func TestAESGCMCommitContentCipher_KeyCommitmentMismatch(t *testing.T) {
+	masterKey := SymmetricMasterKey{
+		Key:                 bytes.Repeat([]byte{1}, 32),
+		MaterialDescription: MaterialDescription{"key": aws.String("1")},
+	}
+	_, env := encryptCommitted(t, masterKey, []byte("content"))
+	_, other := encryptCommitted(t, masterKey, []byte("content"))
+
+	cases := map[string]func(*Envelope){
+		"missing commitment": func(env *Envelope) {
+			env.KeyCommitment = ""
+		},
+		"other commitment": func(env *Envelope) {
+			env.KeyCommitment = other.KeyCommitment
+		},
+		"other key": func(env *Envelope) {
+			env.CipherKey = other.CipherKey
+		},
+	}
+
+	for name, tamper := range cases {
+		tampered := env
+		tamper(&tampered)
+
+		_, err := newCommitDecryptionClient(masterKey).contentCipherFromEnvelope(tampered)
+		if err == nil {
+			t.Fatalf("%s, expected error, but received none", name)
+		}
+		if e, a := "KeyCommitmentMismatchError", err.(awserr.Error).Code(); e != a {
+			t.Errorf("%s, expected %v, but received %v", name, e, a)
+		}
+	}
+}
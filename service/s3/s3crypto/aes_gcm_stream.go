@@ -0,0 +1,237 @@
+package s3crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+const (
+	gcmBlockSize = 16
+	gcmTagSize   = 16
+
+	// gcmMaxContentLength is the maximum length of content encrypted with
+	// a single key and nonce, 2^39-256 bits.
+	gcmMaxContentLength = (1<<39 - 256) / 8
+)
+
+// gcmStream encrypts content with AES GCM in sequential chunks, producing
+// the same ciphertext and tag as sealing all of the content at once with
+// the AEAD of crypto/cipher. Go's AEAD interface requires all of the content
+// to be in memory, so the GCM counter mode encryption and GHASH are
+// implemented here.
+//
+// The state of the stream is a value which can be saved, and restored to
+// encrypt content again from the same position.
+type gcmStream struct {
+	block   cipher.Block
+	nonce   []byte
+	tagMask [gcmTagSize]byte
+
+	// productTable contains the first sixteen powers of the hash key.
+	productTable [16]gcmFieldElement
+
+	state gcmStreamState
+}
+
+// gcmStreamState is the position of a gcmStream.
+type gcmStreamState struct {
+	// The GHASH of the ciphertext's complete blocks.
+	y gcmFieldElement
+
+	// The ciphertext of the incomplete block not yet hashed.
+	pending [gcmBlockSize]byte
+
+	// The number of bytes encrypted.
+	n int64
+}
+
+// gcmFieldElement is a value in GF(2¹²⁸), with the coefficient of x⁰ in the
+// most significant bit of low.
+type gcmFieldElement struct {
+	low, high uint64
+}
+
+func newGCMStream(key, nonce []byte) (*gcmStream, error) {
+	if len(nonce) != gcmNonceSize {
+		return nil, awserr.New("InvalidNonceError", "AES GCM streaming requires a 12 byte nonce", nil)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &gcmStream{
+		block: block,
+		nonce: append([]byte{}, nonce...),
+	}
+
+	var h [gcmBlockSize]byte
+	block.Encrypt(h[:], h[:])
+	x := gcmFieldElement{
+		binary.BigEndian.Uint64(h[:8]),
+		binary.BigEndian.Uint64(h[8:]),
+	}
+	s.productTable[reverseBits(1)] = x
+	for i := 2; i < 16; i += 2 {
+		s.productTable[reverseBits(i)] = gcmDouble(&s.productTable[reverseBits(i/2)])
+		s.productTable[reverseBits(i+1)] = gcmAdd(&s.productTable[reverseBits(i)], &x)
+	}
+
+	counter := s.counter(1)
+	block.Encrypt(s.tagMask[:], counter[:])
+
+	return s, nil
+}
+
+// counter returns the counter block with the counter value.
+func (s *gcmStream) counter(v uint32) [gcmBlockSize]byte {
+	var b [gcmBlockSize]byte
+	copy(b[:], s.nonce)
+	binary.BigEndian.PutUint32(b[gcmNonceSize:], v)
+	return b
+}
+
+// encrypt encrypts src into dst, which must be at least as long as src,
+// continuing from the position of the stream.
+func (s *gcmStream) encrypt(dst, src []byte) error {
+	if s.state.n+int64(len(src)) > gcmMaxContentLength {
+		return awserr.New("ContentLengthError", "content is too long to encrypt with AES GCM", nil)
+	}
+
+	var keystream [gcmBlockSize]byte
+	for len(src) > 0 {
+		off := int(s.state.n % gcmBlockSize)
+
+		// The first counter value is used for the tag, and content starts
+		// with the second.
+		counter := s.counter(uint32(s.state.n/gcmBlockSize) + 2)
+		s.block.Encrypt(keystream[:], counter[:])
+
+		n := gcmBlockSize - off
+		if n > len(src) {
+			n = len(src)
+		}
+		for i := 0; i < n; i++ {
+			dst[i] = src[i] ^ keystream[off+i]
+		}
+		copy(s.state.pending[off:], dst[:n])
+		if off+n == gcmBlockSize {
+			s.updateBlock(&s.state.y, s.state.pending[:])
+		}
+
+		s.state.n += int64(n)
+		dst, src = dst[n:], src[n:]
+	}
+
+	return nil
+}
+
+// sum returns the authentication tag of the content encrypted so far.
+func (s *gcmStream) sum() []byte {
+	y := s.state.y
+	if off := int(s.state.n % gcmBlockSize); off != 0 {
+		var block [gcmBlockSize]byte
+		copy(block[:], s.state.pending[:off])
+		s.updateBlock(&y, block[:])
+	}
+
+	// The length block, of the additional data, which is empty, and the
+	// ciphertext in bits.
+	y.high ^= uint64(s.state.n) * 8
+	s.mul(&y)
+
+	tag := make([]byte, gcmTagSize)
+	binary.BigEndian.PutUint64(tag, y.low)
+	binary.BigEndian.PutUint64(tag[8:], y.high)
+	for i := range tag {
+		tag[i] ^= s.tagMask[i]
+	}
+	return tag
+}
+
+func (s *gcmStream) updateBlock(y *gcmFieldElement, block []byte) {
+	y.low ^= binary.BigEndian.Uint64(block)
+	y.high ^= binary.BigEndian.Uint64(block[8:])
+	s.mul(y)
+}
+
+// gcmReductionTable is stored irreducible polynomial's double & add precomputed results.
+// 0000, 1c20, 3840, 2460, 7080, 6ca0, 48c0, 54e0, e100, fd20, d940, c560, 9180, 8da0, a9c0, b5e0
+var gcmReductionTable = []uint16{
+	0x0000, 0x1c20, 0x3840, 0x2460, 0x7080, 0x6ca0, 0x48c0, 0x54e0,
+	0xe100, 0xfd20, 0xd940, 0xc560, 0x9180, 0x8da0, 0xa9c0, 0xb5e0,
+}
+
+// mul sets y to y*H, where H is the hash key.
+//
+// Copied from Go stdlib crypto/cipher
+func (s *gcmStream) mul(y *gcmFieldElement) {
+	var z gcmFieldElement
+
+	for i := 0; i < 2; i++ {
+		word := y.high
+		if i == 1 {
+			word = y.low
+		}
+
+		// Multiplication works by multiplying z by 16 and adding in
+		// one of the precomputed multiples of H.
+		for j := 0; j < 64; j += 4 {
+			msw := z.high & 0xf
+			z.high >>= 4
+			z.high |= z.low << 60
+			z.low >>= 4
+			z.low ^= uint64(gcmReductionTable[msw]) << 48
+
+			// the values in |table| are ordered for
+			// little-endian bit positions. See the comment
+			// in newGCMStream.
+			t := &s.productTable[word&0xf]
+
+			z.low ^= t.low
+			z.high ^= t.high
+			word >>= 4
+		}
+	}
+
+	*y = z
+}
+
+// reverseBits reverses the order of the bits of 4-bit number in i.
+func reverseBits(i int) int {
+	i = ((i << 2) & 0xc) | ((i >> 2) & 0x3)
+	i = ((i << 1) & 0xa) | ((i >> 1) & 0x5)
+	return i
+}
+
+// gcmAdd adds two elements of GF(2¹²⁸) and returns the sum.
+func gcmAdd(x, y *gcmFieldElement) gcmFieldElement {
+	// Addition in a characteristic 2 field is just XOR.
+	return gcmFieldElement{x.low ^ y.low, x.high ^ y.high}
+}
+
+// gcmDouble returns the result of doubling an element of GF(2¹²⁸).
+func gcmDouble(x *gcmFieldElement) (double gcmFieldElement) {
+	msbSet := x.high&1 == 1
+
+	// Because of the bit-ordering, doubling is actually a right shift.
+	double.high = x.high >> 1
+	double.high |= x.low << 63
+	double.low = x.low >> 1
+
+	// If the most-significant bit was set before shifting then it,
+	// conceptually, becomes a term of x^128. This is greater than the
+	// irreducible polynomial so the result has to be reduced. The
+	// irreducible polynomial is 1+x+x^2+x^7+x^128. We can subtract that to
+	// eliminate the term at x^128 which also means subtracting the other
+	// four terms. In characteristic 2 fields, subtraction == addition ==
+	// XOR.
+	if msbSet {
+		double.low ^= 0xe100000000000000
+	}
+
+	return
+}
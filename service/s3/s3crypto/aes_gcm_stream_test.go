@@ -0,0 +1,112 @@
+package s3crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+)
+
+func TestGCMStream_NIST(t *testing.T) {
+	iv, _ := hex.DecodeString("0d18e06c7c725ac9e362e1ce")
+	key, _ := hex.DecodeString("31bdadd96698c204aa9ce1448ea94ae1fb4a9a0b3c9d773b51bb1822666b8f22")
+	plaintext, _ := hex.DecodeString("2db5168e932556f8089a0622981d017d")
+	expected, _ := hex.DecodeString("fa4362189661d163fcd6a56d8bf0405a")
+	tag, _ := hex.DecodeString("d636ac1bbedd5cc3ee727dc2ab4a9489")
+
+	s, err := newGCMStream(key, iv)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	ciphertext := make([]byte, len(plaintext))
+	if err := s.encrypt(ciphertext, plaintext); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if !bytes.Equal(expected, ciphertext) {
+		t.Errorf("expected %x, but received %x", expected, ciphertext)
+	}
+	if e, a := tag, s.sum(); !bytes.Equal(e, a) {
+		t.Errorf("expected %x, but received %x", e, a)
+	}
+}
+
+func TestGCMStream_Chunks(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, gcmKeySize)
+	nonce := bytes.Repeat([]byte{3}, gcmNonceSize)
+	plaintext := make([]byte, 1000)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 31)
+	}
+
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+
+	cases := []struct {
+		length int
+		chunks []int
+	}{
+		{0, nil},
+		{5, []int{5}},
+		{16, []int{16}},
+		{100, []int{1, 15, 16, 17, 51}},
+		{1000, []int{333, 333, 334}},
+		{1000, []int{16, 0, 984}},
+	}
+
+	for i, c := range cases {
+		expected := aead.Seal(nil, nonce, plaintext[:c.length], nil)
+
+		s, err := newGCMStream(key, nonce)
+		if err != nil {
+			t.Fatalf("%d, expected no error, but received %v", i, err)
+		}
+		var ciphertext []byte
+		src := plaintext[:c.length]
+		for _, n := range c.chunks {
+			dst := make([]byte, n)
+			if err := s.encrypt(dst, src[:n]); err != nil {
+				t.Fatalf("%d, expected no error, but received %v", i, err)
+			}
+			ciphertext = append(ciphertext, dst...)
+			src = src[n:]
+		}
+		ciphertext = append(ciphertext, s.sum()...)
+
+		if !bytes.Equal(expected, ciphertext) {
+			t.Errorf("%d, expected %x, but received %x", i, expected, ciphertext)
+		}
+	}
+}
+
+func TestGCMStream_RestoreState(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, gcmKeySize)
+	nonce := bytes.Repeat([]byte{3}, gcmNonceSize)
+	plaintext := bytes.Repeat([]byte("abcdefg"), 10)
+
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	expected := aead.Seal(nil, nonce, plaintext, nil)
+
+	s, _ := newGCMStream(key, nonce)
+	ciphertext := make([]byte, len(plaintext))
+	s.encrypt(ciphertext[:23], plaintext[:23])
+
+	state := s.state
+	s.encrypt(ciphertext[23:], bytes.Repeat([]byte{0}, len(plaintext)-23))
+	s.state = state
+	s.encrypt(ciphertext[23:], plaintext[23:])
+
+	if e, a := expected, append(ciphertext, s.sum()...); !bytes.Equal(e, a) {
+		t.Errorf("expected %x, but received %x", e, a)
+	}
+}
+
+func TestGCMStream_ContentTooLong(t *testing.T) {
+	s, _ := newGCMStream(make([]byte, gcmKeySize), make([]byte, gcmNonceSize))
+	s.state.n = gcmMaxContentLength - 1
+
+	if err := s.encrypt(make([]byte, 2), make([]byte, 2)); err == nil {
+		t.Errorf("expected error, but received none")
+	}
+}
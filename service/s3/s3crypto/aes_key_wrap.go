@@ -0,0 +1,166 @@
+package s3crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+const keyWrapBlockSize = 8
+
+var (
+	// keyWrapIV is the default initial value of RFC 3394.
+	keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+	// keyWrapPadIV is the constant half of the alternative initial value of
+	// RFC 5649, followed by the length of the key.
+	keyWrapPadIV = []byte{0xa6, 0x59, 0x59, 0xa6}
+)
+
+// aesKeyWrap wraps the key with the key encryption key, as defined by RFC
+// 3394. The key must be a multiple of 8 bytes, and at least 16 bytes long.
+func aesKeyWrap(kek, key []byte) ([]byte, error) {
+	if len(key) < 2*keyWrapBlockSize || len(key)%keyWrapBlockSize != 0 {
+		return nil, awserr.New("InvalidKeyError", "key to wrap must be a multiple of 8 bytes, and at least 16 bytes", nil)
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyWrap(block, keyWrapIV, key), nil
+}
+
+// aesKeyUnwrap unwraps the key wrapped with the key encryption key, as
+// defined by RFC 3394.
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 3*keyWrapBlockSize || len(wrapped)%keyWrapBlockSize != 0 {
+		return nil, errInvalidWrappedKey
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, key := keyUnwrap(block, wrapped)
+	if subtle.ConstantTimeCompare(iv, keyWrapIV) != 1 {
+		return nil, errInvalidWrappedKey
+	}
+	return key, nil
+}
+
+// aesKeyWrapPad wraps the key with the key encryption key, as defined by RFC
+// 5649. The key can be of any length.
+func aesKeyWrapPad(kek, key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, awserr.New("InvalidKeyError", "key to wrap must not be empty", nil)
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, keyWrapBlockSize)
+	copy(iv, keyWrapPadIV)
+	binary.BigEndian.PutUint32(iv[4:], uint32(len(key)))
+
+	padded := make([]byte, (len(key)+keyWrapBlockSize-1)/keyWrapBlockSize*keyWrapBlockSize)
+	copy(padded, key)
+
+	if len(padded) == keyWrapBlockSize {
+		// A single block is encrypted with its initial value in one
+		// operation.
+		wrapped := append(iv, padded...)
+		block.Encrypt(wrapped, wrapped)
+		return wrapped, nil
+	}
+	return keyWrap(block, iv, padded), nil
+}
+
+// aesKeyUnwrapPad unwraps the key wrapped with the key encryption key, as
+// defined by RFC 5649.
+func aesKeyUnwrapPad(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 2*keyWrapBlockSize || len(wrapped)%keyWrapBlockSize != 0 {
+		return nil, errInvalidWrappedKey
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	var iv, padded []byte
+	if len(wrapped) == 2*keyWrapBlockSize {
+		b := make([]byte, len(wrapped))
+		block.Decrypt(b, wrapped)
+		iv, padded = b[:keyWrapBlockSize], b[keyWrapBlockSize:]
+	} else {
+		iv, padded = keyUnwrap(block, wrapped)
+	}
+
+	n := int64(binary.BigEndian.Uint32(iv[4:]))
+	if subtle.ConstantTimeCompare(iv[:4], keyWrapPadIV) != 1 ||
+		n <= int64(len(padded)-keyWrapBlockSize) || n > int64(len(padded)) {
+		return nil, errInvalidWrappedKey
+	}
+	valid := 1
+	for _, b := range padded[n:] {
+		valid &= subtle.ConstantTimeByteEq(b, 0)
+	}
+	if valid != 1 {
+		return nil, errInvalidWrappedKey
+	}
+	return padded[:n], nil
+}
+
+var errInvalidWrappedKey = awserr.New("InvalidWrappedKeyError", "unable to unwrap the key", nil)
+
+// keyWrap wraps the key, a multiple of 8 bytes, with the block cipher and
+// initial value.
+func keyWrap(block cipher.Block, iv, key []byte) []byte {
+	n := len(key) / keyWrapBlockSize
+	wrapped := make([]byte, len(key)+keyWrapBlockSize)
+	copy(wrapped[keyWrapBlockSize:], key)
+
+	var b [aes.BlockSize]byte
+	copy(b[:keyWrapBlockSize], iv)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			r := wrapped[i*keyWrapBlockSize : (i+1)*keyWrapBlockSize]
+			copy(b[keyWrapBlockSize:], r)
+			block.Encrypt(b[:], b[:])
+
+			t := binary.BigEndian.Uint64(b[:keyWrapBlockSize]) ^ uint64(n*j+i)
+			binary.BigEndian.PutUint64(b[:keyWrapBlockSize], t)
+			copy(r, b[keyWrapBlockSize:])
+		}
+	}
+	copy(wrapped, b[:keyWrapBlockSize])
+
+	return wrapped
+}
+
+// keyUnwrap unwraps the key with the block cipher, returning the initial
+// value and the key.
+func keyUnwrap(block cipher.Block, wrapped []byte) (iv, key []byte) {
+	n := len(wrapped)/keyWrapBlockSize - 1
+	key = make([]byte, len(wrapped)-keyWrapBlockSize)
+	copy(key, wrapped[keyWrapBlockSize:])
+
+	var b [aes.BlockSize]byte
+	copy(b[:keyWrapBlockSize], wrapped)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			r := key[(i-1)*keyWrapBlockSize : i*keyWrapBlockSize]
+			t := binary.BigEndian.Uint64(b[:keyWrapBlockSize]) ^ uint64(n*j+i)
+			binary.BigEndian.PutUint64(b[:keyWrapBlockSize], t)
+			copy(b[keyWrapBlockSize:], r)
+			block.Decrypt(b[:], b[:])
+			copy(r, b[keyWrapBlockSize:])
+		}
+	}
+
+	return b[:keyWrapBlockSize], key
+}
@@ -0,0 +1,134 @@
+package s3crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestAESKeyWrap(t *testing.T) {
+	// Test vectors from RFC 3394
+	cases := []struct {
+		kek, key, wrapped string
+	}{
+		{
+			"000102030405060708090A0B0C0D0E0F",
+			"00112233445566778899AABBCCDDEEFF",
+			"1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5",
+		},
+		{
+			"000102030405060708090A0B0C0D0E0F1011121314151617",
+			"00112233445566778899AABBCCDDEEFF0001020304050607",
+			"031D33264E15D33268F24EC260743EDCE1C6C7DDEE725A936BA814915C6762D2",
+		},
+		{
+			"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			"00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+			"28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21",
+		},
+	}
+
+	for i, c := range cases {
+		kek, _ := hex.DecodeString(c.kek)
+		key, _ := hex.DecodeString(c.key)
+		expected, _ := hex.DecodeString(c.wrapped)
+
+		wrapped, err := aesKeyWrap(kek, key)
+		if err != nil {
+			t.Fatalf("%d, expected no error, but received %v", i, err)
+		}
+		if !bytes.Equal(expected, wrapped) {
+			t.Errorf("%d, expected %x, but received %x", i, expected, wrapped)
+		}
+
+		unwrapped, err := aesKeyUnwrap(kek, wrapped)
+		if err != nil {
+			t.Fatalf("%d, expected no error, but received %v", i, err)
+		}
+		if !bytes.Equal(key, unwrapped) {
+			t.Errorf("%d, expected %x, but received %x", i, key, unwrapped)
+		}
+
+		wrapped[len(wrapped)-1] ^= 1
+		if _, err := aesKeyUnwrap(kek, wrapped); err == nil {
+			t.Errorf("%d, expected error, but received none", i)
+		}
+	}
+}
+
+func TestAESKeyWrap_InvalidLength(t *testing.T) {
+	kek := make([]byte, 16)
+	for _, n := range []int{0, 8, 17} {
+		if _, err := aesKeyWrap(kek, make([]byte, n)); err == nil {
+			t.Errorf("%d, expected error, but received none", n)
+		}
+	}
+	for _, n := range []int{0, 16, 25} {
+		if _, err := aesKeyUnwrap(kek, make([]byte, n)); err == nil {
+			t.Errorf("%d, expected error, but received none", n)
+		}
+	}
+}
+
+func TestAESKeyWrapPad(t *testing.T) {
+	// Test vectors from RFC 5649
+	cases := []struct {
+		kek, key, wrapped string
+	}{
+		{
+			"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+			"c37b7e6492584340bed12207808941155068f738",
+			"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+		},
+		{
+			"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+			"466f7250617369",
+			"afbeb0f07dfbf5419200f2ccb50bb24f",
+		},
+	}
+
+	for i, c := range cases {
+		kek, _ := hex.DecodeString(c.kek)
+		key, _ := hex.DecodeString(c.key)
+		expected, _ := hex.DecodeString(c.wrapped)
+
+		wrapped, err := aesKeyWrapPad(kek, key)
+		if err != nil {
+			t.Fatalf("%d, expected no error, but received %v", i, err)
+		}
+		if !bytes.Equal(expected, wrapped) {
+			t.Errorf("%d, expected %x, but received %x", i, expected, wrapped)
+		}
+
+		unwrapped, err := aesKeyUnwrapPad(kek, wrapped)
+		if err != nil {
+			t.Fatalf("%d, expected no error, but received %v", i, err)
+		}
+		if !bytes.Equal(key, unwrapped) {
+			t.Errorf("%d, expected %x, but received %x", i, key, unwrapped)
+		}
+
+		wrapped[0] ^= 1
+		if _, err := aesKeyUnwrapPad(kek, wrapped); err == nil {
+			t.Errorf("%d, expected error, but received none", i)
+		}
+	}
+}
+
+func TestAESKeyWrapPad_RoundTrip(t *testing.T) {
+	kek := bytes.Repeat([]byte{1}, 32)
+	for n := 1; n <= 40; n++ {
+		key := bytes.Repeat([]byte{byte(n)}, n)
+		wrapped, err := aesKeyWrapPad(kek, key)
+		if err != nil {
+			t.Fatalf("%d, expected no error, but received %v", n, err)
+		}
+		unwrapped, err := aesKeyUnwrapPad(kek, wrapped)
+		if err != nil {
+			t.Fatalf("%d, expected no error, but received %v", n, err)
+		}
+		if !bytes.Equal(key, unwrapped) {
+			t.Errorf("%d, expected %x, but received %x", n, key, unwrapped)
+		}
+	}
+}
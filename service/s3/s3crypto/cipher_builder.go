@@ -8,6 +8,12 @@ type ContentCipherBuilder interface {
 	ContentCipher() (ContentCipher, error)
 }
 
+// objectContentCipherBuilder is a ContentCipherBuilder which binds the
+// content cipher's key to the bucket and key of the object it encrypts.
+type objectContentCipherBuilder interface {
+	objectContentCipher(bucket, key string) (ContentCipher, error)
+}
+
 // ContentCipher deals with encrypting and decrypting content
 type ContentCipher interface {
 	EncryptContents(io.Reader) (io.Reader, error)
@@ -26,6 +32,9 @@ type CipherData struct {
 	MaterialDescription MaterialDescription
 	// EncryptedKey should be populated when calling GenerateCipherData
 	EncryptedKey []byte
+	// KeyCommitment is the commitment to the content encryption key of
+	// content ciphers with key commitment.
+	KeyCommitment []byte
 
 	Padder Padder
 }
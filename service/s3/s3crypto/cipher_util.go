@@ -18,7 +18,13 @@ func (client *DecryptionClient) contentCipherFromEnvelope(env Envelope) (Content
 }
 
 func (client *DecryptionClient) wrapFromEnvelope(env Envelope) (CipherDataDecrypter, error) {
-	f, ok := client.WrapRegistry[env.WrapAlg]
+	return wrapFromRegistry(client.WrapRegistry, env)
+}
+
+// wrapFromRegistry returns the key decrypter of the registry's wrap entry for
+// the envelope's wrap algorithm.
+func wrapFromRegistry(registry map[string]WrapEntry, env Envelope) (CipherDataDecrypter, error) {
+	f, ok := registry[env.WrapAlg]
 	if !ok || f == nil {
 		return nil, awserr.New(
 			"InvalidWrapAlgorithmError",
@@ -60,11 +66,17 @@ func (client *DecryptionClient) cekFromEnvelope(env Envelope, decrypter CipherDa
 		return nil, err
 	}
 
+	commitment, err := base64.StdEncoding.DecodeString(env.KeyCommitment)
+	if err != nil {
+		return nil, err
+	}
+
 	cd := CipherData{
-		Key:          key,
-		IV:           iv,
-		CEKAlgorithm: env.CEKAlg,
-		Padder:       client.getPadder(env.CEKAlg),
+		Key:           key,
+		IV:            iv,
+		CEKAlgorithm:  env.CEKAlg,
+		KeyCommitment: commitment,
+		Padder:        client.getPadder(env.CEKAlg),
 	}
 	return f(cd)
 }
@@ -86,26 +98,40 @@ func (client *DecryptionClient) getPadder(cekAlg string) Padder {
 }
 
 func encodeMeta(reader hashReader, cd CipherData) (Envelope, error) {
-	iv := base64.StdEncoding.EncodeToString(cd.IV)
-	key := base64.StdEncoding.EncodeToString(cd.EncryptedKey)
+	env, err := encodeCipherData(cd)
+	if err != nil {
+		return Envelope{}, err
+	}
 
 	md5 := reader.GetValue()
 	contentLength := reader.GetContentLength()
 
-	md5Str := base64.StdEncoding.EncodeToString(md5)
+	env.UnencryptedMD5 = base64.StdEncoding.EncodeToString(md5)
+	env.UnencryptedContentLen = strconv.FormatInt(contentLength, 10)
+	return env, nil
+}
+
+// encodeCipherData returns the envelope of the cipher data, without the
+// unencrypted content's MD5 and length.
+func encodeCipherData(cd CipherData) (Envelope, error) {
+	iv := base64.StdEncoding.EncodeToString(cd.IV)
+	key := base64.StdEncoding.EncodeToString(cd.EncryptedKey)
+
 	matdesc, err := cd.MaterialDescription.encodeDescription()
 	if err != nil {
 		return Envelope{}, err
 	}
 
-	return Envelope{
-		CipherKey:             key,
-		IV:                    iv,
-		MatDesc:               string(matdesc),
-		WrapAlg:               cd.WrapAlgorithm,
-		CEKAlg:                cd.CEKAlgorithm,
-		TagLen:                cd.TagLength,
-		UnencryptedMD5:        md5Str,
-		UnencryptedContentLen: strconv.FormatInt(contentLength, 10),
-	}, nil
+	env := Envelope{
+		CipherKey: key,
+		IV:        iv,
+		MatDesc:   string(matdesc),
+		WrapAlg:   cd.WrapAlgorithm,
+		CEKAlg:    cd.CEKAlgorithm,
+		TagLen:    cd.TagLength,
+	}
+	if len(cd.KeyCommitment) > 0 {
+		env.KeyCommitment = base64.StdEncoding.EncodeToString(cd.KeyCommitment)
+	}
+	return env, nil
 }
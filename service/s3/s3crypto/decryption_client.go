@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/kms"
@@ -17,14 +18,39 @@ type WrapEntry func(Envelope) (CipherDataDecrypter, error)
 // CEKEntry is a builder thatn returns a proper content decrypter and error
 type CEKEntry func(CipherData) (ContentCipher, error)
 
+// DecryptionMode is the set of envelope formats a DecryptionClient decrypts.
+type DecryptionMode int
+
+const (
+	// MigrationDecryptionMode decrypts objects encrypted with AES GCM, with
+	// and without key commitment, and rejects objects encrypted with AES
+	// CBC. It is the default mode, which reads objects written before and
+	// after moving to the AESGCMCommitContentCipherBuilder.
+	MigrationDecryptionMode DecryptionMode = iota
+
+	// StrictDecryptionMode only decrypts objects encrypted with
+	// AESGCMCommitKey. Keys wrapped by KMS must be wrapped with
+	// KMSContextWrap and bound to the object's bucket and key.
+	StrictDecryptionMode
+
+	// LegacyDecryptionMode decrypts objects of each envelope format,
+	// including objects encrypted with AES CBC, whose content is not
+	// authenticated.
+	LegacyDecryptionMode
+)
+
 // DecryptionClient is an S3 crypto client. The decryption client
 // will handle all get object requests from Amazon S3.
 // Supported key wrapping algorithms:
 //	*AWS KMS
+//	* AES Key Wrap, with and without padding
+//	* AES/GCM
+//	* RSA-OAEP-SHA1
 //
 // Supported content ciphers:
 //	* AES/GCM
-//	* AES/CBC
+//	* AES/GCM with key commitment
+//	* AES/CBC, in LegacyDecryptionMode
 type DecryptionClient struct {
 	S3Client s3iface.S3API
 	// LoadStrategy is used to load the metadata either from the metadata of the object
@@ -36,6 +62,20 @@ type DecryptionClient struct {
 	WrapRegistry   map[string]WrapEntry
 	CEKRegistry    map[string]CEKEntry
 	PadderRegistry map[string]Padder
+
+	// AllowUnauthenticatedRangeGets allows GetObject requests with a Range
+	// of objects encrypted with AES GCM. The range is decrypted without
+	// authenticating the content, since the tag can only be verified with
+	// all of it, so the decrypted content may have been tampered with.
+	//
+	// Ranged gets return an error if this is not set.
+	AllowUnauthenticatedRangeGets bool
+
+	// Mode is the set of envelope formats decrypted by the client. Objects
+	// whose envelope isn't allowed by the mode return a LegacyEnvelopeError.
+	//
+	// Defaults to MigrationDecryptionMode.
+	Mode DecryptionMode
 }
 
 // NewDecryptionClient instantiates a new S3 crypto client
@@ -56,9 +96,13 @@ func NewDecryptionClient(prov client.ConfigProvider, options ...func(*Decryption
 			KMSWrap: (kmsKeyHandler{
 				kms: kms.New(prov),
 			}).decryptHandler,
+			KMSContextWrap: (kmsContextKeyHandler{
+				kms: kms.New(prov),
+			}).decryptHandler,
 		},
 		CEKRegistry: map[string]CEKEntry{
 			AESGCMNoPadding: newAESGCMContentCipher,
+			AESGCMCommitKey: newAESGCMCommitDecryptContentCipher,
 			strings.Join([]string{AESCBC, AESCBCPadder.Name()}, "/"): newAESCBCContentCipher,
 		},
 		PadderRegistry: map[string]Padder{
@@ -76,6 +120,14 @@ func NewDecryptionClient(prov client.ConfigProvider, options ...func(*Decryption
 // GetObjectRequest will make a request to s3 and retrieve the object. In this process
 // decryption will be done. The SDK only supports V2 reads of KMS and GCM.
 //
+// A Range of an object encrypted with AES GCM can be retrieved if the client's
+// AllowUnauthenticatedRangeGets is set. The range is decrypted without being
+// authenticated, and the output's ContentRange and ContentLength are those of
+// the decrypted range.
+//
+// Objects whose envelope isn't allowed by the client's Mode, or whose key is
+// bound by KMSContextWrap to a different bucket or key, return an error.
+//
 // Example:
 //	sess := session.New()
 //	svc := s3crypto.NewDecryptionClient(sess)
@@ -86,14 +138,34 @@ func NewDecryptionClient(prov client.ConfigProvider, options ...func(*Decryption
 //	err := req.Send()
 func (c *DecryptionClient) GetObjectRequest(input *s3.GetObjectInput) (*request.Request, *s3.GetObjectOutput) {
 	req, out := c.S3Client.GetObjectRequest(input)
+
+	var rng *byteRange
+	if len(aws.StringValue(input.Range)) > 0 {
+		rng = &byteRange{}
+		req.Handlers.Build.PushBack(func(r *request.Request) {
+			c.buildRangeGet(r, rng)
+		})
+	}
+
 	req.Handlers.Unmarshal.PushBack(func(r *request.Request) {
 		env, err := c.LoadStrategy.Load(r)
+		if err == nil {
+			err = c.checkEnvelope(env, input)
+		}
 		if err != nil {
 			r.Error = err
 			out.Body.Close()
 			return
 		}
 
+		if rng != nil {
+			if err := c.decryptRange(r, out, env, *rng); err != nil {
+				r.Error = err
+				out.Body.Close()
+			}
+			return
+		}
+
 		// If KMS should return the correct CEK algorithm with the proper
 		// KMS key provider
 		cipher, err := c.contentCipherFromEnvelope(env)
@@ -114,6 +186,44 @@ func (c *DecryptionClient) GetObjectRequest(input *s3.GetObjectInput) (*request.
 	return req, out
 }
 
+// checkEnvelope returns an error if the envelope isn't allowed by the client's
+// mode, or its key is bound to an object other than the input's.
+func (c *DecryptionClient) checkEnvelope(env Envelope, input *s3.GetObjectInput) error {
+	switch {
+	case strings.HasPrefix(env.CEKAlg, AESCBC) && c.Mode != LegacyDecryptionMode:
+		return awserr.New("LegacyEnvelopeError",
+			"objects encrypted with "+AESCBC+" are only decrypted in LegacyDecryptionMode", nil)
+	case c.Mode == StrictDecryptionMode && env.CEKAlg != AESGCMCommitKey:
+		return awserr.New("LegacyEnvelopeError",
+			"only objects encrypted with "+AESGCMCommitKey+" are decrypted in StrictDecryptionMode, "+env.CEKAlg, nil)
+	case c.Mode == StrictDecryptionMode && env.WrapAlg == KMSWrap:
+		return awserr.New("LegacyEnvelopeError",
+			"keys wrapped with "+KMSWrap+" are not decrypted in StrictDecryptionMode", nil)
+	}
+	if env.WrapAlg != KMSContextWrap {
+		return nil
+	}
+
+	m := MaterialDescription{}
+	if err := m.decodeDescription([]byte(env.MatDesc)); err != nil {
+		return err
+	}
+	bucket, ok := m[kmsContextBucketKey]
+	if !ok {
+		if c.Mode == StrictDecryptionMode {
+			return awserr.New("LegacyEnvelopeError",
+				"keys not bound to their object are not decrypted in StrictDecryptionMode", nil)
+		}
+		return nil
+	}
+	if aws.StringValue(bucket) != aws.StringValue(input.Bucket) ||
+		aws.StringValue(m[kmsContextObjectKeyKey]) != aws.StringValue(input.Key) {
+		return awserr.New("EnvelopeMismatchError",
+			"the object's key is bound to a different bucket or key", nil)
+	}
+	return nil
+}
+
 // GetObject is a wrapper for GetObjectRequest
 func (c *DecryptionClient) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
 	req, out := c.GetObjectRequest(input)
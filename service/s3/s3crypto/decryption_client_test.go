@@ -108,7 +108,9 @@ func TestGetObjectCBC(t *testing.T) {
 		Region:           aws.String("us-west-2"),
 	})
 
-	c := s3crypto.NewDecryptionClient(sess)
+	c := s3crypto.NewDecryptionClient(sess, func(c *s3crypto.DecryptionClient) {
+		c.Mode = s3crypto.LegacyDecryptionMode
+	})
 	if c == nil {
 		t.Error("expected non-nil value")
 	}
@@ -177,7 +179,9 @@ func TestGetObjectCBC2(t *testing.T) {
 		Region:           aws.String("us-west-2"),
 	})
 
-	c := s3crypto.NewDecryptionClient(sess)
+	c := s3crypto.NewDecryptionClient(sess, func(c *s3crypto.DecryptionClient) {
+		c.Mode = s3crypto.LegacyDecryptionMode
+	})
 	if c == nil {
 		t.Error("expected non-nil value")
 	}
@@ -248,3 +252,120 @@ func TestGetObjectWithContext(t *testing.T) {
 		t.Errorf("expected error message to contain %q, but did not %q", e, a)
 	}
 }
+
+func TestDecryptionClient_Modes(t *testing.T) {
+	_, svc := newRewrapS3()
+	m := newMockKMS()
+	symmetric := s3crypto.NewSymmetricKeyGenerator(s3crypto.AESWrap, oldMasterKey.Key, oldMasterKey.MaterialDescription)
+	kmsContext := s3crypto.NewKMSContextKeyGenerator(m, "cmk", nil)
+	builders := map[string]s3crypto.ContentCipherBuilder{
+		"gcm":         s3crypto.AESGCMContentCipherBuilder(symmetric),
+		"cbc":         s3crypto.AESCBCContentCipherBuilder(symmetric, s3crypto.AESCBCPadder),
+		"commit":      s3crypto.AESGCMCommitContentCipherBuilder(symmetric),
+		"kms commit":  s3crypto.AESGCMCommitContentCipherBuilder(s3crypto.NewKMSKeyGenerator(m, "cmk")),
+		"kms context": s3crypto.AESGCMCommitContentCipherBuilder(kmsContext),
+		"kms unbound": s3crypto.AESGCMContentCipherBuilder(kmsContext),
+	}
+	body := []byte("content to decrypt")
+	for key, builder := range builders {
+		c := s3crypto.NewEncryptionClient(unit.Session, builder, func(c *s3crypto.EncryptionClient) {
+			c.S3Client = svc
+		})
+		if _, err := c.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(body),
+		}); err != nil {
+			t.Fatalf("%s, expected no error, but received %v", key, err)
+		}
+	}
+
+	cases := map[s3crypto.DecryptionMode]map[string]string{
+		s3crypto.MigrationDecryptionMode: {
+			"gcm": "", "cbc": "LegacyEnvelopeError", "commit": "",
+			"kms commit": "", "kms context": "", "kms unbound": "",
+		},
+		s3crypto.StrictDecryptionMode: {
+			"gcm": "LegacyEnvelopeError", "cbc": "LegacyEnvelopeError", "commit": "",
+			"kms commit": "LegacyEnvelopeError", "kms context": "", "kms unbound": "LegacyEnvelopeError",
+		},
+		s3crypto.LegacyDecryptionMode: {
+			"gcm": "", "cbc": "", "commit": "",
+			"kms commit": "", "kms context": "", "kms unbound": "",
+		},
+	}
+
+	for mode, codes := range cases {
+		c := s3crypto.NewDecryptionClient(unit.Session, func(c *s3crypto.DecryptionClient) {
+			c.S3Client = svc
+			c.LoadStrategy = s3crypto.HeaderV2LoadStrategy{}
+			c.WrapRegistry[s3crypto.AESWrap] = s3crypto.NewSymmetricKeyWrapEntry(oldMasterKey)
+			c.WrapRegistry[s3crypto.KMSWrap] = s3crypto.NewKMSWrapEntry(m)
+			c.WrapRegistry[s3crypto.KMSContextWrap] = s3crypto.NewKMSContextWrapEntry(m)
+			c.Mode = mode
+		})
+		for key, code := range codes {
+			out, err := c.GetObject(&s3.GetObjectInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String(key),
+			})
+			if len(code) != 0 {
+				if err == nil {
+					t.Fatalf("%d %s, expected error, but received none", mode, key)
+				}
+				if e, a := code, err.(awserr.Error).Code(); e != a {
+					t.Errorf("%d %s, expected %v, but received %v", mode, key, e, a)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%d %s, expected no error, but received %v", mode, key, err)
+			}
+			b, err := ioutil.ReadAll(out.Body)
+			if err != nil {
+				t.Fatalf("%d %s, expected no error, but received %v", mode, key, err)
+			}
+			if e, a := body, b; !bytes.Equal(e, a) {
+				t.Errorf("%d %s, expected %v, but received %v", mode, key, e, a)
+			}
+		}
+	}
+}
+
+func TestDecryptionClient_CommitRangeGet(t *testing.T) {
+	_, svc := newRewrapS3()
+	generator := s3crypto.NewSymmetricKeyGenerator(s3crypto.AESGCMWrap, oldMasterKey.Key, oldMasterKey.MaterialDescription)
+	encClient := s3crypto.NewEncryptionClient(unit.Session, s3crypto.AESGCMCommitContentCipherBuilder(generator), func(c *s3crypto.EncryptionClient) {
+		c.S3Client = svc
+	})
+	if _, err := encClient.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   strings.NewReader("0123456789abcdefghijklmnopqrstuvwxyz"),
+	}); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	c := s3crypto.NewDecryptionClient(unit.Session, func(c *s3crypto.DecryptionClient) {
+		c.S3Client = svc
+		c.LoadStrategy = s3crypto.HeaderV2LoadStrategy{}
+		c.WrapRegistry[s3crypto.AESGCMWrap] = s3crypto.NewSymmetricKeyWrapEntry(oldMasterKey)
+		c.Mode = s3crypto.StrictDecryptionMode
+		c.AllowUnauthenticatedRangeGets = true
+	})
+	out, err := c.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Range:  aws.String("bytes=20-25"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	b, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := "klmnop", string(b); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+}
@@ -2,8 +2,8 @@
 Package s3crypto provides encryption to S3 using KMS and AES GCM.
 
 Keyproviders are interfaces that handle masterkeys. Masterkeys are used to encrypt and decrypt the randomly
-generated cipher keys. The SDK currently uses KMS to do this. A user does not need to provide a master key
-since all that information is hidden in KMS.
+generated cipher keys. The SDK uses KMS to do this by default, in which case a user does not need to provide
+a master key since all that information is hidden in KMS. Local AES and RSA master keys are also supported.
 
 Modes are interfaces that handle content encryption and decryption. It is an abstraction layer that instantiates
 the ciphers. If content is being encrypted we generate the key and iv of the cipher. For decryption, we use the
@@ -41,6 +41,98 @@ Configuration of the S3 cryptography client
 
 The default SaveStrategy is to the object's header.
 
+Uploading large objects
+
+Objects can be uploaded in parts with an encrypted multipart upload, using the
+EncryptionClient's CreateMultipartUpload, UploadPart, and CompleteMultipartUpload.
+Multipart uploads require the AESGCMContentCipherBuilder. A single content encryption
+key is used for all of the upload's parts, which must be uploaded in order. The last
+part must be uploaded with UploadFinalPart, which appends the GCM authentication tag
+to the part.
+
+An s3manager.Uploader which encrypts the objects it uploads can be created with NewUploader.
+
+	uploader := s3crypto.NewUploader(svc)
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   f,
+	})
+
+Local master keys
+
+Keys can be wrapped with an AES master key with NewSymmetricKeyGenerator, using AES Key Wrap (AESWrap), AES Key
+Wrap with Padding (AESWrapPad), or AES GCM (AESGCMWrap), or encrypted with an RSA public key with
+NewRSAKeyGenerator (RSAOAEPSHA1Wrap). The material description is stored with the object, and identifies
+which master key to decrypt it with. These use the same envelope format as the other AWS encryption clients.
+
+	matdesc := s3crypto.MaterialDescription{"key": aws.String("2019-10")}
+	handler := s3crypto.NewSymmetricKeyGenerator(s3crypto.AESGCMWrap, masterKey, matdesc)
+	svc := s3crypto.NewEncryptionClient(sess, s3crypto.AESGCMContentCipherBuilder(handler))
+
+The DecryptionClient must be given the master keys to decrypt with, and picks the key whose material description
+is equal to the object's.
+
+	svc := s3crypto.NewDecryptionClient(sess, func(c *s3crypto.DecryptionClient) {
+		c.WrapRegistry[s3crypto.AESGCMWrap] = s3crypto.NewSymmetricKeyWrapEntry(s3crypto.SymmetricMasterKey{
+			Key:                 masterKey,
+			MaterialDescription: matdesc,
+		})
+	})
+
+Rotating master keys
+
+The keys of existing objects can be rewrapped with a new master key with a RewrapClient, without downloading and
+uploading their content. The key is decrypted with the WrapRegistry, and encrypted with the new master key's
+generator. Envelopes saved in an object's metadata are saved by copying the object in place.
+
+	handler := s3crypto.NewKMSKeyGenerator(kms.New(sess), newCMKID)
+	svc := s3crypto.NewRewrapClient(sess, handler)
+	report, err := svc.RewrapObjects(&s3crypto.RewrapObjectsInput{
+		Bucket: aws.String("bucket"),
+		Prefix: aws.String("prefix/"),
+		DryRun: true,
+	})
+
+Ranged gets
+
+Ranges of objects encrypted with AES GCM can be retrieved with the DecryptionClient's GetObject
+when AllowUnauthenticatedRangeGets is set. The range is decrypted with the AES CTR keystream
+GCM encrypts with, without verifying the authentication tag, so the content returned is not
+authenticated. Only set this if unauthenticated content is acceptable.
+
+An s3manager.Downloader which downloads and decrypts objects in parallel parts can be created
+with NewDownloader. It requires ranged gets to be allowed.
+
+	svc := s3crypto.NewDecryptionClient(sess, func(c *s3crypto.DecryptionClient) {
+		c.AllowUnauthenticatedRangeGets = true
+	})
+	downloader := s3crypto.NewDownloader(svc)
+	_, err := downloader.Download(f, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+
+Key commitment and decryption modes
+
+The AESGCMCommitContentCipherBuilder encrypts content with a key derived from the data key, and stores a
+commitment to the data key in the envelope, so an envelope can't be changed to decrypt the content with
+another key. The kms+context wrap algorithm of NewKMSContextKeyGenerator binds keys to the content encryption
+algorithm, and the bucket and key of the object, with the KMS encryption context.
+
+	handler := s3crypto.NewKMSContextKeyGenerator(kms.New(sess), cmkID, s3crypto.MaterialDescription{})
+	svc := s3crypto.NewEncryptionClient(sess, s3crypto.AESGCMCommitContentCipherBuilder(handler))
+
+The DecryptionClient's Mode selects the envelope formats it decrypts. MigrationDecryptionMode, the default,
+decrypts objects encrypted with AES GCM with and without key commitment, so objects can be read while moving
+to the AESGCMCommitContentCipherBuilder. StrictDecryptionMode only decrypts objects with key commitment, and
+requires keys wrapped by KMS to be bound to their object. Objects encrypted with AES CBC are only decrypted
+in LegacyDecryptionMode.
+
+	svc := s3crypto.NewDecryptionClient(sess, func(c *s3crypto.DecryptionClient) {
+		c.Mode = s3crypto.StrictDecryptionMode
+	})
+
 The InstructionFileSuffix defaults to .instruction. Careful here though, if you do this, be sure you know
 what that suffix is in grabbing data.  All requests will look for fooKey.example instead of fooKey.instruction.
 This suffix only affects gets and not puts. Put uses the keyprovider's suffix.
@@ -0,0 +1,50 @@
+package s3crypto
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// NewDownloader returns an s3manager.Downloader which decrypts the objects it
+// downloads with the DecryptionClient. Pass in additional functional options
+// to customize the downloader's behavior.
+//
+// The Downloader downloads objects in parts with ranged gets, so the
+// DecryptionClient's AllowUnauthenticatedRangeGets must be set, and objects
+// must be encrypted with AES GCM. The downloaded content is not
+// authenticated. The downloader's checksum validation is disabled as the
+// checksums are computed from the encrypted object. This is set after the
+// options are applied.
+//
+// Example:
+//	svc := s3crypto.NewDecryptionClient(sess, func(c *s3crypto.DecryptionClient) {
+//		c.AllowUnauthenticatedRangeGets = true
+//	})
+//	downloader := s3crypto.NewDownloader(svc, func(d *s3manager.Downloader) {
+//		d.PartSize = 64 * 1024 * 1024 // 64MB per part
+//	})
+//	_, err := downloader.Download(f, &s3.GetObjectInput{
+//		Bucket: aws.String("testBucket"),
+//		Key:    aws.String("testKey"),
+//	})
+func NewDownloader(c *DecryptionClient, options ...func(*s3manager.Downloader)) *s3manager.Downloader {
+	d := s3manager.NewDownloaderWithClient(&downloaderClient{S3API: c.S3Client, c: c}, options...)
+
+	d.ValidateChecksums = false
+
+	return d
+}
+
+// downloaderClient is the S3 client of a Downloader created by NewDownloader,
+// decrypting the objects the Downloader downloads with the DecryptionClient.
+type downloaderClient struct {
+	s3iface.S3API
+	c *DecryptionClient
+}
+
+func (d *downloaderClient) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	return d.c.GetObjectWithContext(ctx, input, opts...)
+}
@@ -3,6 +3,7 @@ package s3crypto
 import (
 	"encoding/hex"
 	"io"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
@@ -33,6 +34,11 @@ type EncryptionClient struct {
 	// MinFileSize is the minimum size for the content to write to a
 	// temporary file instead of using memory.
 	MinFileSize int64
+
+	// The encryption state of the multipart uploads created by the client,
+	// by upload ID.
+	uploadsMu sync.Mutex
+	uploads   map[string]*multipartUpload
 }
 
 // NewEncryptionClient instantiates a new S3 crypto client
@@ -84,7 +90,7 @@ func (c *EncryptionClient) PutObjectRequest(input *s3.PutObjectInput) (*request.
 		return req, out
 	}
 
-	encryptor, err := c.ContentCipherBuilder.ContentCipher()
+	encryptor, err := c.contentCipher(input.Bucket, input.Key)
 	req.Handlers.Build.PushFront(func(r *request.Request) {
 		if err != nil {
 			r.Error = err
@@ -125,6 +131,15 @@ func (c *EncryptionClient) PutObjectRequest(input *s3.PutObjectInput) (*request.
 	return req, out
 }
 
+// contentCipher returns a content cipher of the ContentCipherBuilder, bound to
+// the object if the builder supports it.
+func (c *EncryptionClient) contentCipher(bucket, key *string) (ContentCipher, error) {
+	if builder, ok := c.ContentCipherBuilder.(objectContentCipherBuilder); ok {
+		return builder.objectContentCipher(aws.StringValue(bucket), aws.StringValue(key))
+	}
+	return c.ContentCipherBuilder.ContentCipher()
+}
+
 // PutObject is a wrapper for PutObjectRequest
 func (c *EncryptionClient) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
 	req, out := c.PutObjectRequest(input)
@@ -15,6 +15,7 @@ const (
 	tagLengthHeader                = "x-amz-tag-len"
 	unencryptedMD5Header           = "x-amz-unencrypted-content-md5"
 	unencryptedContentLengthHeader = "x-amz-unencrypted-content-length"
+	keyCommitmentHeader            = "x-amz-key-commitment"
 )
 
 // Envelope encryption starts off by generating a random symmetric key using
@@ -34,4 +35,7 @@ type Envelope struct {
 	TagLen                string `json:"x-amz-tag-len"`
 	UnencryptedMD5        string `json:"x-amz-unencrypted-content-md5"`
 	UnencryptedContentLen string `json:"x-amz-unencrypted-content-length"`
+	// KeyCommitment is the base64 encoded commitment to the content
+	// encryption key of the AESGCMCommitKey content cipher.
+	KeyCommitment string `json:"x-amz-key-commitment,omitempty"`
 }
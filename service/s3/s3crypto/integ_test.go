@@ -84,7 +84,9 @@ func TestInteg_DecryptFixtures(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.CEKAlg+"-"+c.Lang, func(t *testing.T) {
-			decClient := s3crypto.NewDecryptionClient(sess)
+			decClient := s3crypto.NewDecryptionClient(sess, func(c *s3crypto.DecryptionClient) {
+				c.Mode = s3crypto.LegacyDecryptionMode
+			})
 			s3Client := s3.New(sess)
 
 			fixtures := getFixtures(t, s3Client, c.CEKAlg, bucket)
@@ -9,6 +9,19 @@ type CipherDataGenerator interface {
 	GenerateCipherData(int, int) (CipherData, error)
 }
 
+// CipherDataEncrypter is a handler to encrypt the key of existing cipher
+// data, setting the encrypted key, wrap algorithm, and material description.
+// The CipherDataGenerators of the SDK implement CipherDataEncrypter.
+type CipherDataEncrypter interface {
+	EncryptCipherData(CipherData) (CipherData, error)
+}
+
+// objectCipherDataEncrypter is a CipherDataEncrypter which binds the
+// encrypted key to the bucket and key of the object it encrypts.
+type objectCipherDataEncrypter interface {
+	encryptObjectCipherData(cd CipherData, bucket, key string) (CipherData, error)
+}
+
 // CipherDataDecrypter is a handler to decrypt keys from the envelope.
 type CipherDataDecrypter interface {
 	DecryptKey([]byte) ([]byte, error)
@@ -0,0 +1,175 @@
+package s3crypto
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+const (
+	// KMSContextWrap is the wrap algorithm of keys encrypted by KMS with an
+	// encryption context binding them to the content encryption algorithm,
+	// and the bucket and key of the object they encrypt.
+	KMSContextWrap = "kms+context"
+
+	kmsContextCEKAlgKey    = "aws:x-amz-cek-alg"
+	kmsContextBucketKey    = "aws:x-amz-bucket"
+	kmsContextObjectKeyKey = "aws:x-amz-object-key"
+)
+
+// kmsContextKeyHandler encrypts keys with KMS, binding them to the objects
+// they encrypt with the encryption context.
+type kmsContextKeyHandler struct {
+	kms   kmsiface.KMSAPI
+	cmkID *string
+
+	CipherData
+}
+
+// NewKMSContextKeyGenerator builds a new KMS key provider using the customer
+// key ID, which encrypts keys with an encryption context of the material
+// description and the content encryption algorithm. Keys of objects
+// encrypted by the EncryptionClient are also bound to the object's bucket
+// and key. The encryption context is stored with the object as its material
+// description, and its keys must not start with "aws:".
+//
+// Example:
+//	handler := s3crypto.NewKMSContextKeyGenerator(kms.New(sess), cmkID, s3crypto.MaterialDescription{})
+//	svc := s3crypto.NewEncryptionClient(sess, s3crypto.AESGCMCommitContentCipherBuilder(handler))
+func NewKMSContextKeyGenerator(kmsClient kmsiface.KMSAPI, cmkID string, matdesc MaterialDescription) CipherDataGenerator {
+	if matdesc == nil {
+		matdesc = MaterialDescription{}
+	}
+
+	// These values are read only making them thread safe
+	kp := &kmsContextKeyHandler{
+		kms:   kmsClient,
+		cmkID: &cmkID,
+	}
+	kp.CipherData.WrapAlgorithm = KMSContextWrap
+	kp.CipherData.MaterialDescription = matdesc
+	return kp
+}
+
+// NewKMSContextWrapEntry builds returns a new KMS context key provider and
+// its decrypt handler. The decrypt handler verifies the content encryption
+// algorithm of the encryption context is the envelope's.
+//
+// Example:
+//	svc := s3crypto.NewDecryptionClient(sess, func(svc *s3crypto.DecryptionClient) {
+//		svc.WrapRegistry[s3crypto.KMSContextWrap] = s3crypto.NewKMSContextWrapEntry(customKMSClient)
+//	})
+func NewKMSContextWrapEntry(kmsClient kmsiface.KMSAPI) WrapEntry {
+	// These values are read only making them thread safe
+	kp := &kmsContextKeyHandler{
+		kms: kmsClient,
+	}
+
+	return kp.decryptHandler
+}
+
+// decryptHandler initializes a KMS context key provider with the encryption
+// context of the envelope's material description.
+func (kp kmsContextKeyHandler) decryptHandler(env Envelope) (CipherDataDecrypter, error) {
+	m := MaterialDescription{}
+	if err := m.decodeDescription([]byte(env.MatDesc)); err != nil {
+		return nil, err
+	}
+
+	if cekAlg, ok := m[kmsContextCEKAlgKey]; !ok || aws.StringValue(cekAlg) != env.CEKAlg {
+		return nil, awserr.New("EnvelopeMismatchError",
+			"the encryption context's content encryption algorithm does not match the envelope's, "+env.CEKAlg, nil)
+	}
+
+	kp.CipherData.MaterialDescription = m
+	kp.WrapAlgorithm = KMSContextWrap
+	return &kp, nil
+}
+
+// DecryptKey makes a call to KMS to decrypt the key with the encryption
+// context.
+func (kp *kmsContextKeyHandler) DecryptKey(key []byte) ([]byte, error) {
+	out, err := kp.kms.Decrypt(&kms.DecryptInput{
+		EncryptionContext: map[string]*string(kp.CipherData.MaterialDescription),
+		CiphertextBlob:    key,
+		GrantTokens:       []*string{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+// GenerateCipherData makes a call to KMS to generate a data key bound to
+// the AESGCMNoPadding content encryption algorithm. It can only be used with
+// the AESGCMContentCipherBuilder.
+func (kp *kmsContextKeyHandler) GenerateCipherData(keySize, ivSize int) (CipherData, error) {
+	if ivSize != gcmNonceSize {
+		return CipherData{}, awserr.New("UnsupportedContentCipherError",
+			KMSContextWrap+" requires the AESGCMContentCipherBuilder", nil)
+	}
+
+	matdesc := kp.encryptionContext(AESGCMNoPadding, "", "")
+	out, err := kp.kms.GenerateDataKey(&kms.GenerateDataKeyInput{
+		EncryptionContext: matdesc,
+		KeyId:             kp.cmkID,
+		KeySpec:           aws.String("AES_256"),
+	})
+	if err != nil {
+		return CipherData{}, err
+	}
+
+	cd := CipherData{
+		Key:                 out.Plaintext,
+		IV:                  generateBytes(ivSize),
+		CEKAlgorithm:        AESGCMNoPadding,
+		WrapAlgorithm:       KMSContextWrap,
+		MaterialDescription: matdesc,
+		EncryptedKey:        out.CiphertextBlob,
+	}
+	return cd, nil
+}
+
+// EncryptCipherData makes a call to KMS to encrypt the cipher data's key
+// bound to its content encryption algorithm.
+func (kp *kmsContextKeyHandler) EncryptCipherData(cd CipherData) (CipherData, error) {
+	return kp.encryptObjectCipherData(cd, "", "")
+}
+
+// encryptObjectCipherData makes a call to KMS to encrypt the cipher data's
+// key bound to its content encryption algorithm, and the object's bucket and
+// key if they are known.
+func (kp *kmsContextKeyHandler) encryptObjectCipherData(cd CipherData, bucket, key string) (CipherData, error) {
+	matdesc := kp.encryptionContext(cd.CEKAlgorithm, bucket, key)
+	out, err := kp.kms.Encrypt(&kms.EncryptInput{
+		EncryptionContext: matdesc,
+		KeyId:             kp.cmkID,
+		Plaintext:         cd.Key,
+	})
+	if err != nil {
+		return CipherData{}, err
+	}
+
+	cd.WrapAlgorithm = KMSContextWrap
+	cd.MaterialDescription = matdesc
+	cd.EncryptedKey = out.CiphertextBlob
+	return cd, nil
+}
+
+// encryptionContext returns the handler's material description with the
+// content encryption algorithm, and the object's bucket and key if they are
+// known.
+func (kp *kmsContextKeyHandler) encryptionContext(cekAlg, bucket, key string) MaterialDescription {
+	matdesc := MaterialDescription{}
+	for k, v := range kp.CipherData.MaterialDescription {
+		matdesc[k] = v
+	}
+
+	matdesc[kmsContextCEKAlgKey] = aws.String(cekAlg)
+	if len(bucket) > 0 || len(key) > 0 {
+		matdesc[kmsContextBucketKey] = aws.String(bucket)
+		matdesc[kmsContextObjectKeyKey] = aws.String(key)
+	}
+	return matdesc
+}
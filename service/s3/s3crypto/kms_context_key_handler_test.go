@@ -0,0 +1,120 @@
+package s3crypto_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3crypto"
+)
+
+func newKMSContextClients(svc *s3.S3, m *mockKMS, builder func(s3crypto.CipherDataGenerator) s3crypto.ContentCipherBuilder) (*s3crypto.EncryptionClient, *s3crypto.DecryptionClient) {
+	generator := s3crypto.NewKMSContextKeyGenerator(m, "cmk", s3crypto.MaterialDescription{"team": aws.String("a")})
+	encClient := s3crypto.NewEncryptionClient(unit.Session, builder(generator), func(c *s3crypto.EncryptionClient) {
+		c.S3Client = svc
+	})
+	decClient := s3crypto.NewDecryptionClient(unit.Session, func(c *s3crypto.DecryptionClient) {
+		c.S3Client = svc
+		c.LoadStrategy = s3crypto.HeaderV2LoadStrategy{}
+		c.WrapRegistry[s3crypto.KMSContextWrap] = s3crypto.NewKMSContextWrapEntry(m)
+		c.Mode = s3crypto.StrictDecryptionMode
+	})
+	return encClient, decClient
+}
+
+func TestKMSContextKeyGenerator_BindsObject(t *testing.T) {
+	f, svc := newRewrapS3()
+	m := newMockKMS()
+	encClient, decClient := newKMSContextClients(svc, m, s3crypto.AESGCMCommitContentCipherBuilder)
+
+	body := []byte("content bound to its object")
+	if _, err := encClient.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader(body),
+	}); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	expected := map[string]string{
+		"team":                 "a",
+		"aws:x-amz-cek-alg":    s3crypto.AESGCMCommitKey,
+		"aws:x-amz-bucket":     "bucket",
+		"aws:x-amz-object-key": "key",
+	}
+	if e, a := expected, m.contexts["blob-0"]; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := s3crypto.KMSContextWrap, aws.StringValue(f.objects["key"].metadata["X-Amz-Wrap-Alg"]); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+
+	out, err := decClient.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	b, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := body, b; !bytes.Equal(e, a) {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+
+	// An object copied with its envelope to another key isn't decrypted.
+	f.objects["moved"] = f.objects["key"]
+	_, err = decClient.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("moved"),
+	})
+	if err == nil {
+		t.Fatalf("expected error, but received none")
+	}
+	if e, a := "EnvelopeMismatchError", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+}
+
+func TestKMSContextKeyGenerator_CEKAlgorithmMismatch(t *testing.T) {
+	f, svc := newRewrapS3()
+	m := newMockKMS()
+	encClient, decClient := newKMSContextClients(svc, m, s3crypto.AESGCMContentCipherBuilder)
+	decClient.Mode = s3crypto.MigrationDecryptionMode
+
+	if _, err := encClient.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader([]byte("content")),
+	}); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := s3crypto.AESGCMNoPadding, m.contexts["blob-0"]["aws:x-amz-cek-alg"]; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if _, err := decClient.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	}); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	f.objects["key"].metadata["X-Amz-Cek-Alg"] = aws.String(s3crypto.AESGCMCommitKey)
+	_, err := decClient.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err == nil {
+		t.Fatalf("expected error, but received none")
+	}
+	if e, a := "EnvelopeMismatchError", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+}
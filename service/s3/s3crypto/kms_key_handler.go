@@ -131,3 +131,20 @@ func (kp *kmsKeyHandler) GenerateCipherData(keySize, ivSize int) (CipherData, er
 	}
 	return cd, nil
 }
+
+// EncryptCipherData makes a call to KMS to encrypt the cipher data's key.
+func (kp *kmsKeyHandler) EncryptCipherData(cd CipherData) (CipherData, error) {
+	out, err := kp.kms.Encrypt(&kms.EncryptInput{
+		EncryptionContext: kp.CipherData.MaterialDescription,
+		KeyId:             kp.cmkID,
+		Plaintext:         cd.Key,
+	})
+	if err != nil {
+		return CipherData{}, err
+	}
+
+	cd.WrapAlgorithm = KMSWrap
+	cd.MaterialDescription = kp.CipherData.MaterialDescription
+	cd.EncryptedKey = out.CiphertextBlob
+	return cd, nil
+}
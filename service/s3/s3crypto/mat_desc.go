@@ -16,3 +16,17 @@ func (md *MaterialDescription) encodeDescription() ([]byte, error) {
 func (md *MaterialDescription) decodeDescription(b []byte) error {
 	return json.Unmarshal(b, &md)
 }
+
+// equal returns whether the material descriptions have the same values.
+func (md MaterialDescription) equal(other MaterialDescription) bool {
+	if len(md) != len(other) {
+		return false
+	}
+	for k, v := range md {
+		o, ok := other[k]
+		if !ok || (v == nil) != (o == nil) || (v != nil && *v != *o) {
+			return false
+		}
+	}
+	return true
+}
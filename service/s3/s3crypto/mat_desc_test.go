@@ -33,3 +33,26 @@ func TestDecodeMaterialDescription(t *testing.T) {
 		t.Error("expected material description to be equivalent, but received otherwise")
 	}
 }
+
+func TestMaterialDescriptionEqual(t *testing.T) {
+	cases := []struct {
+		a, b     MaterialDescription
+		expected bool
+	}{
+		{nil, MaterialDescription{}, true},
+		{MaterialDescription{"foo": aws.String("bar")}, MaterialDescription{"foo": aws.String("bar")}, true},
+		{MaterialDescription{"foo": aws.String("bar")}, MaterialDescription{"foo": aws.String("baz")}, false},
+		{MaterialDescription{"foo": aws.String("bar")}, MaterialDescription{"bar": aws.String("bar")}, false},
+		{MaterialDescription{"foo": aws.String("bar")}, MaterialDescription{"foo": nil}, false},
+		{MaterialDescription{"foo": aws.String("bar")}, MaterialDescription{}, false},
+	}
+
+	for i, c := range cases {
+		if e, a := c.expected, c.a.equal(c.b); e != a {
+			t.Errorf("%d, expected %v, but received %v", i, e, a)
+		}
+		if e, a := c.expected, c.b.equal(c.a); e != a {
+			t.Errorf("%d, expected %v, but received %v", i, e, a)
+		}
+	}
+}
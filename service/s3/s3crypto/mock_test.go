@@ -2,9 +2,15 @@ package s3crypto_test
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"reflect"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 	"github.com/aws/aws-sdk-go/service/s3/s3crypto"
 )
 
@@ -68,3 +74,44 @@ func (cipher *mockContentCipher) DecryptContents(src io.ReadCloser) (io.ReadClos
 	size := len(b)
 	return ioutil.NopCloser(bytes.NewReader(make([]byte, size))), nil
 }
+
+// mockKMS is a fake KMS which only decrypts keys with the encryption context
+// they were encrypted with.
+type mockKMS struct {
+	kmsiface.KMSAPI
+
+	keys     map[string][]byte
+	contexts map[string]map[string]string
+}
+
+func newMockKMS() *mockKMS {
+	return &mockKMS{
+		keys:     map[string][]byte{},
+		contexts: map[string]map[string]string{},
+	}
+}
+
+func (m *mockKMS) Encrypt(input *kms.EncryptInput) (*kms.EncryptOutput, error) {
+	blob := fmt.Sprintf("blob-%d", len(m.keys))
+	m.keys[blob] = input.Plaintext
+	m.contexts[blob] = aws.StringValueMap(input.EncryptionContext)
+	return &kms.EncryptOutput{CiphertextBlob: []byte(blob)}, nil
+}
+
+func (m *mockKMS) GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	key := bytes.Repeat([]byte{byte(len(m.keys))}, 32)
+	out, err := m.Encrypt(&kms.EncryptInput{Plaintext: key, EncryptionContext: input.EncryptionContext})
+	if err != nil {
+		return nil, err
+	}
+	return &kms.GenerateDataKeyOutput{Plaintext: key, CiphertextBlob: out.CiphertextBlob}, nil
+}
+
+func (m *mockKMS) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	blob := string(input.CiphertextBlob)
+	key, ok := m.keys[blob]
+	if !ok || !reflect.DeepEqual(m.contexts[blob], aws.StringValueMap(input.EncryptionContext)) {
+		return nil, awserr.New(kms.ErrCodeInvalidCiphertextException, "invalid ciphertext", nil)
+	}
+	return &kms.DecryptOutput{Plaintext: key}, nil
+}
@@ -0,0 +1,525 @@
+package s3crypto
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/internal/sdkio"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// maxUploadParts is the maximum number of parts of a multipart upload.
+const maxUploadParts = 10000
+
+// multipartUpload is the encryption state of a multipart upload created by an
+// EncryptionClient.
+type multipartUpload struct {
+	m sync.Mutex
+
+	cd     CipherData
+	stream *gcmStream
+
+	// The SSE-C and request payer parameters of the upload, which the part
+	// containing the authentication tag of an upload without parts is
+	// uploaded with.
+	sseCustomerAlgorithm *string
+	sseCustomerKey       *string
+	sseCustomerKeyMD5    *string
+	requestPayer         *string
+
+	// The part numbers of the encrypted parts, and the state of the stream
+	// before the last part was encrypted so it can be uploaded again.
+	parts []int64
+	prev  gcmStreamState
+
+	// If the last part encrypted is the final part, with the authentication
+	// tag appended to its content.
+	final bool
+
+	// The part containing only the authentication tag, uploaded when an
+	// upload without parts is completed.
+	tagPart *s3.CompletedPart
+}
+
+func newMultipartUpload(cc ContentCipher, input *s3.CreateMultipartUploadInput) (*multipartUpload, error) {
+	gcm, ok := cc.(*aesGCMContentCipher)
+	if !ok {
+		return nil, awserr.New("UnsupportedContentCipherError",
+			"encrypted multipart uploads require the AESGCMContentCipherBuilder or AESGCMCommitContentCipherBuilder", nil)
+	}
+
+	stream, err := newGCMStream(gcm.CipherData.Key, gcm.CipherData.IV)
+	if err != nil {
+		return nil, err
+	}
+
+	return &multipartUpload{
+		cd:                   gcm.CipherData,
+		stream:               stream,
+		sseCustomerAlgorithm: input.SSECustomerAlgorithm,
+		sseCustomerKey:       input.SSECustomerKey,
+		sseCustomerKeyMD5:    input.SSECustomerKeyMD5,
+		requestPayer:         input.RequestPayer,
+	}, nil
+}
+
+// encryptPart encrypts the part's content from src to dst, appending the
+// authentication tag if it is the final part. Parts must be encrypted in the
+// order of their part numbers, but the last part encrypted may be encrypted
+// again.
+func (u *multipartUpload) encryptPart(num int64, src io.Reader, dst io.Writer, final bool) error {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	if n := len(u.parts); n != 0 {
+		switch last := u.parts[n-1]; {
+		case num == last:
+			u.stream.state = u.prev
+			u.parts = u.parts[:n-1]
+		case u.final:
+			return awserr.New("InvalidPartOrderError",
+				fmt.Sprintf("part %d uploaded after the final part %d", num, last), nil)
+		case num < last:
+			return awserr.New("InvalidPartOrderError",
+				fmt.Sprintf("part %d uploaded after part %d, parts of an encrypted multipart upload must be uploaded in order",
+					num, last), nil)
+		}
+	}
+	if num < 1 || num > maxUploadParts {
+		return awserr.New("InvalidPartNumberError",
+			fmt.Sprintf("part number %d must be between 1 and %d", num, maxUploadParts), nil)
+	}
+
+	prev := u.stream.state
+	err := u.encrypt(src, dst)
+	if err == nil && final {
+		_, err = dst.Write(u.stream.sum())
+	}
+	if err != nil {
+		u.stream.state = prev
+		u.final = false
+		return err
+	}
+
+	u.prev = prev
+	u.parts = append(u.parts, num)
+	u.final = final
+	return nil
+}
+
+func (u *multipartUpload) encrypt(src io.Reader, dst io.Writer) error {
+	buf := make([]byte, 32*1024)
+	out := make([]byte, len(buf))
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if err := u.stream.encrypt(out, buf[:n]); err != nil {
+				return err
+			}
+			if _, err := dst.Write(out[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// complete validates the parts of the CompleteMultipartUpload input, which
+// must be the parts encrypted by the upload, ending with the final part. An
+// upload without parts is completed with a single part containing only the
+// authentication tag, which is added to the input's parts.
+func (u *multipartUpload) complete(ctx aws.Context, client s3iface.S3API, input *s3.CompleteMultipartUploadInput) error {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	if input.MultipartUpload == nil {
+		input.MultipartUpload = &s3.CompletedMultipartUpload{}
+	}
+	parts := input.MultipartUpload.Parts
+
+	if len(u.parts) == 0 && len(parts) == 0 {
+		out, err := client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+			Bucket:               input.Bucket,
+			Key:                  input.Key,
+			UploadId:             input.UploadId,
+			PartNumber:           aws.Int64(1),
+			Body:                 bytes.NewReader(u.stream.sum()),
+			SSECustomerAlgorithm: u.sseCustomerAlgorithm,
+			SSECustomerKey:       u.sseCustomerKey,
+			SSECustomerKeyMD5:    u.sseCustomerKeyMD5,
+			RequestPayer:         u.requestPayer,
+		})
+		if err != nil {
+			return err
+		}
+		u.tagPart = &s3.CompletedPart{ETag: out.ETag, PartNumber: aws.Int64(1)}
+		u.parts = []int64{1}
+		u.final = true
+	}
+	// The tag part may not be in the parts if completing the upload is
+	// being retried with a new input.
+	if u.tagPart != nil && len(parts) == 0 {
+		parts = []*s3.CompletedPart{u.tagPart}
+	}
+
+	match := len(parts) == len(u.parts)
+	for i := 0; match && i < len(parts); i++ {
+		match = aws.Int64Value(parts[i].PartNumber) == u.parts[i]
+	}
+	if !match {
+		return awserr.New("InvalidPartOrderError",
+			"the completed parts must be the parts uploaded by the encryption client, in order", nil)
+	}
+	if !u.final {
+		return awserr.New("MissingFinalPartError",
+			"the last part of an encrypted multipart upload must be uploaded with UploadFinalPart", nil)
+	}
+
+	input.MultipartUpload.Parts = parts
+	return nil
+}
+
+// envelope returns the envelope of the upload. The unencrypted content
+// length is included once the final part has been encrypted.
+func (u *multipartUpload) envelope() (Envelope, error) {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	env, err := encodeCipherData(u.cd)
+	if err != nil {
+		return Envelope{}, err
+	}
+	if u.final {
+		env.UnencryptedContentLen = strconv.FormatInt(u.stream.state.n, 10)
+	}
+	return env, nil
+}
+
+// savesAtCreate returns if the SaveStrategy saves the envelope of multipart
+// uploads when they are created, instead of when they are completed. The
+// metadata of an object uploaded in parts can only be set when the upload is
+// created.
+func (c *EncryptionClient) savesAtCreate() bool {
+	switch c.SaveStrategy.(type) {
+	case HeaderV2SaveStrategy, *HeaderV2SaveStrategy:
+		return true
+	}
+	return false
+}
+
+func (c *EncryptionClient) multipartUpload(uploadID *string) (*multipartUpload, error) {
+	c.uploadsMu.Lock()
+	defer c.uploadsMu.Unlock()
+
+	u, ok := c.uploads[aws.StringValue(uploadID)]
+	if !ok {
+		return nil, awserr.New("UnknownUploadError",
+			"multipart upload was not created by the encryption client, "+aws.StringValue(uploadID), nil)
+	}
+	return u, nil
+}
+
+func (c *EncryptionClient) setMultipartUpload(uploadID *string, u *multipartUpload) {
+	c.uploadsMu.Lock()
+	defer c.uploadsMu.Unlock()
+
+	if u == nil {
+		delete(c.uploads, aws.StringValue(uploadID))
+		return
+	}
+	if c.uploads == nil {
+		c.uploads = map[string]*multipartUpload{}
+	}
+	c.uploads[aws.StringValue(uploadID)] = u
+}
+
+// CreateMultipartUploadRequest generates a content encryption key for a
+// multipart upload whose parts will be encrypted with AES GCM. The upload's
+// parts must be uploaded with the EncryptionClient's UploadPart, in the order
+// of their part numbers, the last part with its UploadFinalPart, and the
+// upload completed with its CompleteMultipartUpload. A single content encryption key and GCM stream is
+// used across all of the upload's parts, producing an object which can be
+// decrypted by the DecryptionClient as if it had been uploaded with
+// PutObject.
+//
+// The ContentCipherBuilder must be the AESGCMContentCipherBuilder. The
+// envelope is saved to the object's metadata when the upload is created if the
+// SaveStrategy is the HeaderV2SaveStrategy, otherwise the SaveStrategy saves it
+// when the upload is completed.
+//
+// Example:
+//	svc := s3crypto.NewEncryptionClient(session.New(), s3crypto.AESGCMContentCipherBuilder(handler))
+//	req, out := svc.CreateMultipartUploadRequest(&s3.CreateMultipartUploadInput{
+//	  Key: aws.String("testKey"),
+//	  Bucket: aws.String("testBucket"),
+//	})
+//	err := req.Send()
+func (c *EncryptionClient) CreateMultipartUploadRequest(input *s3.CreateMultipartUploadInput) (*request.Request, *s3.CreateMultipartUploadOutput) {
+	req, out := c.S3Client.CreateMultipartUploadRequest(input)
+
+	encryptor, err := c.contentCipher(input.Bucket, input.Key)
+	var upload *multipartUpload
+	if err == nil {
+		upload, err = newMultipartUpload(encryptor, input)
+	}
+	req.Handlers.Build.PushFront(func(r *request.Request) {
+		if err != nil {
+			r.Error = err
+			return
+		}
+		if !c.savesAtCreate() {
+			return
+		}
+
+		env, err := upload.envelope()
+		if err != nil {
+			r.Error = err
+			return
+		}
+		r.Error = c.SaveStrategy.Save(env, r)
+	})
+	req.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error == nil {
+			c.setMultipartUpload(out.UploadId, upload)
+		}
+	})
+
+	return req, out
+}
+
+// CreateMultipartUpload is a wrapper for CreateMultipartUploadRequest
+func (c *EncryptionClient) CreateMultipartUpload(input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	req, out := c.CreateMultipartUploadRequest(input)
+	return out, req.Send()
+}
+
+// CreateMultipartUploadWithContext is a wrapper for
+// CreateMultipartUploadRequest with the additional context, and request
+// options support.
+func (c *EncryptionClient) CreateMultipartUploadWithContext(ctx aws.Context, input *s3.CreateMultipartUploadInput, opts ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	req, out := c.CreateMultipartUploadRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+// UploadPartRequest encrypts the part's contents into a temp file, continuing
+// the upload's GCM stream from the previous part. It then streams that data
+// to S3.
+//
+// Parts must be uploaded in the order of their part numbers, and only the
+// most recently uploaded part can be uploaded again, such as after it failed.
+// The upload's last part must be uploaded with UploadFinalPartRequest.
+//
+// Example:
+//	req, out := svc.UploadPartRequest(&s3.UploadPartInput{
+//	  Key: aws.String("testKey"),
+//	  Bucket: aws.String("testBucket"),
+//	  UploadId: createOut.UploadId,
+//	  PartNumber: aws.Int64(1),
+//	  Body: bytes.NewReader(part),
+//	})
+//	err := req.Send()
+func (c *EncryptionClient) UploadPartRequest(input *s3.UploadPartInput) (*request.Request, *s3.UploadPartOutput) {
+	return c.uploadPartRequest(input, false)
+}
+
+// UploadPart is a wrapper for UploadPartRequest
+func (c *EncryptionClient) UploadPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	req, out := c.UploadPartRequest(input)
+	return out, req.Send()
+}
+
+// UploadPartWithContext is a wrapper for UploadPartRequest with the additional
+// context, and request options support.
+func (c *EncryptionClient) UploadPartWithContext(ctx aws.Context, input *s3.UploadPartInput, opts ...request.Option) (*s3.UploadPartOutput, error) {
+	req, out := c.UploadPartRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+// UploadFinalPartRequest encrypts the upload's last part as UploadPartRequest
+// does, appending the authentication tag of the upload's GCM stream to the
+// part's encrypted content. Every part before the final part must be at least
+// 5MB, as with any multipart upload.
+//
+// No parts can be uploaded after the final part, other than uploading the
+// final part again, such as after it failed.
+//
+// Example:
+//	req, out := svc.UploadFinalPartRequest(&s3.UploadPartInput{
+//	  Key: aws.String("testKey"),
+//	  Bucket: aws.String("testBucket"),
+//	  UploadId: createOut.UploadId,
+//	  PartNumber: aws.Int64(2),
+//	  Body: bytes.NewReader(part),
+//	})
+//	err := req.Send()
+func (c *EncryptionClient) UploadFinalPartRequest(input *s3.UploadPartInput) (*request.Request, *s3.UploadPartOutput) {
+	return c.uploadPartRequest(input, true)
+}
+
+// UploadFinalPart is a wrapper for UploadFinalPartRequest
+func (c *EncryptionClient) UploadFinalPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	req, out := c.UploadFinalPartRequest(input)
+	return out, req.Send()
+}
+
+// UploadFinalPartWithContext is a wrapper for UploadFinalPartRequest with the
+// additional context, and request options support.
+func (c *EncryptionClient) UploadFinalPartWithContext(ctx aws.Context, input *s3.UploadPartInput, opts ...request.Option) (*s3.UploadPartOutput, error) {
+	req, out := c.UploadFinalPartRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+func (c *EncryptionClient) uploadPartRequest(input *s3.UploadPartInput, final bool) (*request.Request, *s3.UploadPartOutput) {
+	req, out := c.S3Client.UploadPartRequest(input)
+
+	upload, err := c.multipartUpload(input.UploadId)
+	if err != nil {
+		req.Error = err
+		return req, out
+	}
+
+	n, err := aws.SeekerLen(input.Body)
+	if err != nil {
+		req.Error = err
+		return req, out
+	}
+
+	dst, err := getWriterStore(req, c.TempFolderPath, n >= c.MinFileSize)
+	if err != nil {
+		req.Error = err
+		return req, out
+	}
+
+	req.Handlers.Build.PushFront(func(r *request.Request) {
+		var md5Hash hash.Hash
+		w := io.Writer(dst)
+		if input.ContentMD5 != nil {
+			md5Hash = md5.New()
+			w = io.MultiWriter(w, md5Hash)
+		}
+		sha := newSHA256Writer(w)
+
+		if err := upload.encryptPart(aws.Int64Value(input.PartNumber), input.Body, sha, final); err != nil {
+			r.Error = err
+			return
+		}
+
+		// The Content-MD5 of the part is of its encrypted content.
+		if md5Hash != nil {
+			input.ContentMD5 = aws.String(base64.StdEncoding.EncodeToString(md5Hash.Sum(nil)))
+		}
+		shaHex := hex.EncodeToString(sha.GetValue())
+		req.HTTPRequest.Header.Set("X-Amz-Content-Sha256", shaHex)
+
+		dst.Seek(0, sdkio.SeekStart)
+		input.Body = dst
+	})
+
+	return req, out
+}
+
+// CompleteMultipartUploadRequest saves the envelope with the SaveStrategy if
+// it was not saved when the upload was created, and then completes the
+// upload.
+//
+// The input's parts must be all of the parts uploaded with UploadPart, in
+// order, followed by the part uploaded with UploadFinalPart. An upload
+// without parts is completed with a single part containing the
+// authentication tag.
+func (c *EncryptionClient) CompleteMultipartUploadRequest(input *s3.CompleteMultipartUploadInput) (*request.Request, *s3.CompleteMultipartUploadOutput) {
+	req, out := c.S3Client.CompleteMultipartUploadRequest(input)
+
+	upload, err := c.multipartUpload(input.UploadId)
+	if err != nil {
+		req.Error = err
+		return req, out
+	}
+
+	req.Handlers.Build.PushFront(func(r *request.Request) {
+		if err := upload.complete(r.Context(), c.S3Client, input); err != nil {
+			r.Error = err
+			return
+		}
+		if c.savesAtCreate() {
+			return
+		}
+
+		env, err := upload.envelope()
+		if err != nil {
+			r.Error = err
+			return
+		}
+		r.Error = c.SaveStrategy.Save(env, r)
+	})
+	req.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error == nil {
+			c.setMultipartUpload(input.UploadId, nil)
+		}
+	})
+
+	return req, out
+}
+
+// CompleteMultipartUpload is a wrapper for CompleteMultipartUploadRequest
+func (c *EncryptionClient) CompleteMultipartUpload(input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	req, out := c.CompleteMultipartUploadRequest(input)
+	return out, req.Send()
+}
+
+// CompleteMultipartUploadWithContext is a wrapper for
+// CompleteMultipartUploadRequest with the additional context, and request
+// options support.
+func (c *EncryptionClient) CompleteMultipartUploadWithContext(ctx aws.Context, input *s3.CompleteMultipartUploadInput, opts ...request.Option) (*s3.CompleteMultipartUploadOutput, error) {
+	req, out := c.CompleteMultipartUploadRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+// AbortMultipartUploadRequest aborts the multipart upload, discarding its
+// encryption state once the upload is aborted.
+func (c *EncryptionClient) AbortMultipartUploadRequest(input *s3.AbortMultipartUploadInput) (*request.Request, *s3.AbortMultipartUploadOutput) {
+	req, out := c.S3Client.AbortMultipartUploadRequest(input)
+	req.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error == nil {
+			c.setMultipartUpload(input.UploadId, nil)
+		}
+	})
+	return req, out
+}
+
+// AbortMultipartUpload is a wrapper for AbortMultipartUploadRequest
+func (c *EncryptionClient) AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	req, out := c.AbortMultipartUploadRequest(input)
+	return out, req.Send()
+}
+
+// AbortMultipartUploadWithContext is a wrapper for AbortMultipartUploadRequest
+// with the additional context, and request options support.
+func (c *EncryptionClient) AbortMultipartUploadWithContext(ctx aws.Context, input *s3.AbortMultipartUploadInput, opts ...request.Option) (*s3.AbortMultipartUploadOutput, error) {
+	req, out := c.AbortMultipartUploadRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
@@ -0,0 +1,492 @@
+package s3crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3crypto"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// multipartS3 is a fake S3 serving the requests of multipart uploads.
+type multipartS3 struct {
+	m        sync.Mutex
+	objects  map[string][]byte
+	metadata map[string]map[string]*string
+	uploads  map[string]map[int64][]byte
+	n        int
+
+	// The sizes of the parts objects were completed with.
+	partSizes map[string][]int
+}
+
+func newMultipartS3() (*multipartS3, *s3.S3) {
+	f := &multipartS3{
+		objects:  map[string][]byte{},
+		metadata: map[string]map[string]*string{},
+		uploads:  map[string]map[int64][]byte{},
+
+		partSizes: map[string][]int{},
+	}
+
+	svc := s3.New(unit.Session, &aws.Config{
+		MaxRetries:       aws.Int(0),
+		S3ForcePathStyle: aws.Bool(true),
+		Region:           aws.String("us-west-2"),
+	})
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Unmarshal.Clear()
+	svc.Handlers.UnmarshalMeta.Clear()
+	svc.Handlers.UnmarshalError.Clear()
+	svc.Handlers.ValidateResponse.Clear()
+	svc.Handlers.Send.PushBack(f.send)
+
+	return f, svc
+}
+
+func (f *multipartS3) send(r *request.Request) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	r.HTTPResponse = &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+	switch in := r.Params.(type) {
+	case *s3.PutObjectInput:
+		b, err := ioutil.ReadAll(r.HTTPRequest.Body)
+		if err != nil {
+			r.Error = err
+			return
+		}
+		f.objects[*in.Key] = b
+		f.metadata[*in.Key] = in.Metadata
+	case *s3.CreateMultipartUploadInput:
+		f.n++
+		id := strconv.Itoa(f.n)
+		f.uploads[id] = map[int64][]byte{}
+		f.metadata[*in.Key] = in.Metadata
+		r.Data.(*s3.CreateMultipartUploadOutput).UploadId = aws.String(id)
+	case *s3.UploadPartInput:
+		b, err := ioutil.ReadAll(r.HTTPRequest.Body)
+		if err != nil {
+			r.Error = err
+			return
+		}
+		f.uploads[*in.UploadId][*in.PartNumber] = b
+		r.Data.(*s3.UploadPartOutput).ETag = aws.String(fmt.Sprintf("etag-%d", *in.PartNumber))
+	case *s3.CompleteMultipartUploadInput:
+		var b []byte
+		var sizes []int
+		for _, p := range in.MultipartUpload.Parts {
+			part, ok := f.uploads[*in.UploadId][*p.PartNumber]
+			if !ok || aws.StringValue(p.ETag) != fmt.Sprintf("etag-%d", *p.PartNumber) {
+				r.Error = awserr.New("InvalidPart", "part not uploaded", nil)
+				return
+			}
+			b = append(b, part...)
+			sizes = append(sizes, len(part))
+		}
+		f.objects[*in.Key] = b
+		f.partSizes[*in.Key] = sizes
+		delete(f.uploads, *in.UploadId)
+	case *s3.AbortMultipartUploadInput:
+		delete(f.uploads, *in.UploadId)
+	default:
+		r.Error = awserr.New("UnsupportedOperation", r.Operation.Name, nil)
+	}
+}
+
+// decryptObject decrypts the object encrypted with the key and IV of the
+// mockGenerator.
+func decryptObject(t *testing.T, b []byte) []byte {
+	block, _ := aes.NewCipher(make([]byte, 32))
+	aead, _ := cipher.NewGCM(block)
+	plaintext, err := aead.Open(nil, make([]byte, 12), b, nil)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	return plaintext
+}
+
+func newMultipartClient(svc *s3.S3, options ...func(*s3crypto.EncryptionClient)) *s3crypto.EncryptionClient {
+	c := s3crypto.NewEncryptionClient(unit.Session, s3crypto.AESGCMContentCipherBuilder(mockGenerator{}), options...)
+	c.S3Client = svc
+	return c
+}
+
+func uploadParts(t *testing.T, c *s3crypto.EncryptionClient, uploadID *string, parts ...[]byte) []*s3.CompletedPart {
+	var completed []*s3.CompletedPart
+	for i, part := range parts {
+		upload := c.UploadPart
+		if i == len(parts)-1 {
+			upload = c.UploadFinalPart
+		}
+		out, err := upload(&s3.UploadPartInput{
+			Bucket:     aws.String("bucket"),
+			Key:        aws.String("key"),
+			UploadId:   uploadID,
+			PartNumber: aws.Int64(int64(i + 1)),
+			Body:       bytes.NewReader(part),
+		})
+		if err != nil {
+			t.Fatalf("expected no error, but received %v", err)
+		}
+		completed = append(completed, &s3.CompletedPart{ETag: out.ETag, PartNumber: aws.Int64(int64(i + 1))})
+	}
+	return completed
+}
+
+func TestEncryptionClient_MultipartUpload(t *testing.T) {
+	f, svc := newMultipartS3()
+	c := newMultipartClient(svc)
+
+	create, err := c.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	metadata := f.metadata["key"]
+	for _, k := range []string{"X-Amz-Key-V2", "X-Amz-Iv", "X-Amz-Cek-Alg", "X-Amz-Tag-Len"} {
+		if _, ok := metadata[k]; !ok {
+			t.Errorf("expected %s metadata, but received %v", k, metadata)
+		}
+	}
+	if e, a := s3crypto.AESGCMNoPadding, aws.StringValue(metadata["X-Amz-Cek-Alg"]); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if _, ok := metadata["X-Amz-Unencrypted-Content-Length"]; ok {
+		t.Errorf("expected no content length metadata")
+	}
+
+	parts := [][]byte{
+		bytes.Repeat([]byte("a"), 1000),
+		bytes.Repeat([]byte("b"), 333),
+		bytes.Repeat([]byte("c"), 17),
+	}
+	completed := uploadParts(t, c, create.UploadId, parts...)
+
+	// The final part can be uploaded again.
+	if _, err := c.UploadFinalPart(&s3.UploadPartInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("key"),
+		UploadId:   create.UploadId,
+		PartNumber: aws.Int64(3),
+		Body:       bytes.NewReader(parts[2]),
+	}); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	input := &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String("bucket"),
+		Key:             aws.String("key"),
+		UploadId:        create.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	}
+	if _, err := c.CompleteMultipartUpload(input); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := 3, len(input.MultipartUpload.Parts); e != a {
+		t.Errorf("expected %v parts, but received %v", e, a)
+	}
+
+	plaintext := decryptObject(t, f.objects["key"])
+	if e, a := bytes.Join(parts, nil), plaintext; !bytes.Equal(e, a) {
+		t.Errorf("expected %d bytes, but received %d", len(e), len(a))
+	}
+
+	// The upload's encryption state is discarded once completed.
+	_, err = c.UploadPart(&s3.UploadPartInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("key"),
+		UploadId:   create.UploadId,
+		PartNumber: aws.Int64(4),
+		Body:       bytes.NewReader(nil),
+	})
+	if err == nil {
+		t.Errorf("expected error, but received none")
+	}
+}
+
+func TestEncryptionClient_MultipartUploadInstructionFile(t *testing.T) {
+	f, svc := newMultipartS3()
+	c := newMultipartClient(svc, func(c *s3crypto.EncryptionClient) {
+		c.SaveStrategy = s3crypto.S3SaveStrategy{Client: svc}
+	})
+
+	create, err := c.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := 0, len(f.metadata["key"]); e != a {
+		t.Errorf("expected %v metadata, but received %v", e, a)
+	}
+
+	completed := uploadParts(t, c, create.UploadId, []byte("hello "), []byte("world"))
+	if _, ok := f.objects["key.instruction"]; ok {
+		t.Errorf("expected no instruction file before the upload is completed")
+	}
+
+	if _, err := c.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String("bucket"),
+		Key:             aws.String("key"),
+		UploadId:        create.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	}); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	var env s3crypto.Envelope
+	if err := json.Unmarshal(f.objects["key.instruction"], &env); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := "11", env.UnencryptedContentLen; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := s3crypto.AESGCMNoPadding, env.CEKAlg; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := "hello world", string(decryptObject(t, f.objects["key"])); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+}
+
+func TestEncryptionClient_MultipartUploadEmpty(t *testing.T) {
+	f, svc := newMultipartS3()
+	c := newMultipartClient(svc)
+
+	create, err := c.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if _, err := c.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("key"),
+		UploadId: create.UploadId,
+	}); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	if e, a := 0, len(decryptObject(t, f.objects["key"])); e != a {
+		t.Errorf("expected %v bytes, but received %v", e, a)
+	}
+}
+
+func TestEncryptionClient_MultipartUploadErrors(t *testing.T) {
+	cases := map[string]struct {
+		builder s3crypto.ContentCipherBuilder
+		run     func(*s3crypto.EncryptionClient, *string) error
+		code    string
+	}{
+		"unsupported content cipher": {
+			builder: mockCipherBuilder{mockGenerator{}},
+			code:    "UnsupportedContentCipherError",
+		},
+		"part out of order": {
+			run: func(c *s3crypto.EncryptionClient, id *string) error {
+				for _, num := range []int64{2, 1} {
+					if _, err := c.UploadPart(&s3.UploadPartInput{
+						Bucket:     aws.String("bucket"),
+						Key:        aws.String("key"),
+						UploadId:   id,
+						PartNumber: aws.Int64(num),
+						Body:       bytes.NewReader([]byte("data")),
+					}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			code: "InvalidPartOrderError",
+		},
+		"part after final part": {
+			run: func(c *s3crypto.EncryptionClient, id *string) error {
+				uploads := []func(*s3.UploadPartInput) (*s3.UploadPartOutput, error){c.UploadFinalPart, c.UploadPart}
+				for i, upload := range uploads {
+					if _, err := upload(&s3.UploadPartInput{
+						Bucket:     aws.String("bucket"),
+						Key:        aws.String("key"),
+						UploadId:   id,
+						PartNumber: aws.Int64(int64(i + 1)),
+						Body:       bytes.NewReader([]byte("data")),
+					}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			code: "InvalidPartOrderError",
+		},
+		"missing final part": {
+			run: func(c *s3crypto.EncryptionClient, id *string) error {
+				out, err := c.UploadPart(&s3.UploadPartInput{
+					Bucket:     aws.String("bucket"),
+					Key:        aws.String("key"),
+					UploadId:   id,
+					PartNumber: aws.Int64(1),
+					Body:       bytes.NewReader([]byte("data")),
+				})
+				if err != nil {
+					return err
+				}
+				_, err = c.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+					Bucket:   aws.String("bucket"),
+					Key:      aws.String("key"),
+					UploadId: id,
+					MultipartUpload: &s3.CompletedMultipartUpload{Parts: []*s3.CompletedPart{
+						{ETag: out.ETag, PartNumber: aws.Int64(1)},
+					}},
+				})
+				return err
+			},
+			code: "MissingFinalPartError",
+		},
+		"completed parts mismatch": {
+			run: func(c *s3crypto.EncryptionClient, id *string) error {
+				_, err := c.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+					Bucket:   aws.String("bucket"),
+					Key:      aws.String("key"),
+					UploadId: id,
+					MultipartUpload: &s3.CompletedMultipartUpload{Parts: []*s3.CompletedPart{
+						{ETag: aws.String("etag-1"), PartNumber: aws.Int64(1)},
+					}},
+				})
+				return err
+			},
+			code: "InvalidPartOrderError",
+		},
+		"unknown upload": {
+			run: func(c *s3crypto.EncryptionClient, id *string) error {
+				_, err := c.UploadPart(&s3.UploadPartInput{
+					Bucket:     aws.String("bucket"),
+					Key:        aws.String("key"),
+					UploadId:   aws.String("unknown"),
+					PartNumber: aws.Int64(1),
+					Body:       bytes.NewReader([]byte("data")),
+				})
+				return err
+			},
+			code: "UnknownUploadError",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, svc := newMultipartS3()
+			client := newMultipartClient(svc)
+			if c.builder != nil {
+				client.ContentCipherBuilder = c.builder
+			}
+
+			create, err := client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String("key"),
+			})
+			if c.run != nil {
+				if err != nil {
+					t.Fatalf("expected no error, but received %v", err)
+				}
+				err = c.run(client, create.UploadId)
+			}
+
+			aerr, ok := err.(awserr.Error)
+			if !ok {
+				t.Fatalf("expected %s error, but received %v", c.code, err)
+			}
+			if e, a := c.code, aerr.Code(); e != a {
+				t.Errorf("expected %v, but received %v", e, a)
+			}
+		})
+	}
+}
+
+func TestNewUploader(t *testing.T) {
+	f, svc := newMultipartS3()
+	c := newMultipartClient(svc)
+
+	uploader := s3crypto.NewUploader(c, func(u *s3manager.Uploader) {
+		u.Concurrency = 5
+		u.PartSize = s3manager.MinUploadPartSize
+	})
+	if e, a := 1, uploader.Concurrency; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := s3manager.MaxUploadParts, uploader.MaxUploadParts; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+
+	cases := map[string]struct {
+		size       int
+		unseekable bool
+		parts      int
+	}{
+		"single part": {size: 1024},
+		"multipart":   {size: 2*int(s3manager.MinUploadPartSize) + 1024, parts: 3},
+		"exact parts": {size: 2 * int(s3manager.MinUploadPartSize), parts: 2},
+		"unseekable exact parts": {
+			size: 2 * int(s3manager.MinUploadPartSize), unseekable: true, parts: 2,
+		},
+	}
+	for key, c := range cases {
+		data := make([]byte, c.size)
+		for i := range data {
+			data[i] = byte(i)
+		}
+
+		body := io.Reader(bytes.NewReader(data))
+		if c.unseekable {
+			body = ioutil.NopCloser(body)
+		}
+		if _, err := uploader.Upload(&s3manager.UploadInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String(key),
+			Body:   body,
+		}); err != nil {
+			t.Fatalf("%s, expected no error, but received %v", key, err)
+		}
+
+		if e, a := data, decryptObject(t, f.objects[key]); !bytes.Equal(e, a) {
+			t.Errorf("%s, expected %d bytes, but received %d", key, len(e), len(a))
+		}
+
+		// Every part but the final part must be at least the minimum part
+		// size, so the authentication tag is not uploaded as its own part.
+		sizes := f.partSizes[key]
+		if e, a := c.parts, len(sizes); e != a {
+			t.Errorf("%s, expected %v parts, but received %v", key, e, a)
+		}
+		for i := 0; i < len(sizes)-1; i++ {
+			if sizes[i] < int(s3manager.MinUploadPartSize) {
+				t.Errorf("%s, expected part %d to be at least %d bytes, but received %d",
+					key, i+1, s3manager.MinUploadPartSize, sizes[i])
+			}
+		}
+	}
+
+	if e, a := 0, len(f.uploads); e != a {
+		t.Errorf("expected %v incomplete uploads, but received %v", e, a)
+	}
+}
@@ -0,0 +1,220 @@
+package s3crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// byteRange is a single range of a Range header. A suffix range of the last
+// n bytes has a start of -1 and an end of n, and a range without an end an
+// end of -1.
+type byteRange struct {
+	start, end int64
+}
+
+// parseByteRange parses a Range header value of a single range of bytes.
+func parseByteRange(v string) (byteRange, error) {
+	invalid := awserr.New("InvalidRangeError",
+		"range must be a single range of bytes, "+v, nil)
+
+	if !strings.HasPrefix(v, "bytes=") {
+		return byteRange{}, invalid
+	}
+	parts := strings.Split(strings.TrimPrefix(v, "bytes="), "-")
+	if len(parts) != 2 {
+		return byteRange{}, invalid
+	}
+
+	if len(parts[0]) == 0 {
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || n <= 0 {
+			return byteRange{}, invalid
+		}
+		return byteRange{start: -1, end: n}, nil
+	}
+
+	rng := byteRange{end: -1}
+	var err error
+	if rng.start, err = strconv.ParseInt(parts[0], 10, 64); err != nil || rng.start < 0 {
+		return byteRange{}, invalid
+	}
+	if len(parts[1]) != 0 {
+		if rng.end, err = strconv.ParseInt(parts[1], 10, 64); err != nil || rng.end < rng.start {
+			return byteRange{}, invalid
+		}
+	}
+	return rng, nil
+}
+
+// cipherRange returns the Range header value of the ciphertext needed to
+// decrypt the range. Explicit ranges are aligned to the start of the AES
+// block they start in, and suffix ranges extended by the authentication tag
+// stored at the end of the object.
+func (rng byteRange) cipherRange() string {
+	switch {
+	case rng.start < 0:
+		return fmt.Sprintf("bytes=-%d", rng.end+gcmTagSize)
+	case rng.end < 0:
+		return fmt.Sprintf("bytes=%d-", rng.start-rng.start%gcmBlockSize)
+	default:
+		return fmt.Sprintf("bytes=%d-%d", rng.start-rng.start%gcmBlockSize, rng.end)
+	}
+}
+
+// parseContentRange parses the Content-Range header value of a response,
+// returning the first and last byte of the range, and the length of the
+// object.
+func parseContentRange(v string) (start, end, total int64, err error) {
+	invalid := awserr.New("InvalidContentRangeError",
+		"unable to parse the response content range, "+v, nil)
+
+	if !strings.HasPrefix(v, "bytes ") {
+		return 0, 0, 0, invalid
+	}
+	parts := strings.Split(strings.TrimPrefix(v, "bytes "), "/")
+	if len(parts) != 2 {
+		return 0, 0, 0, invalid
+	}
+	bounds := strings.Split(parts[0], "-")
+	if len(bounds) != 2 {
+		return 0, 0, 0, invalid
+	}
+
+	if start, err = strconv.ParseInt(bounds[0], 10, 64); err != nil {
+		return 0, 0, 0, invalid
+	}
+	if end, err = strconv.ParseInt(bounds[1], 10, 64); err != nil {
+		return 0, 0, 0, invalid
+	}
+	if total, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return 0, 0, 0, invalid
+	}
+	return start, end, total, nil
+}
+
+// buildRangeGet replaces the Range header of a ranged get with the range of
+// the ciphertext needed to decrypt it.
+func (c *DecryptionClient) buildRangeGet(r *request.Request, rng *byteRange) {
+	input := r.Params.(*s3.GetObjectInput)
+	if !c.AllowUnauthenticatedRangeGets {
+		r.Error = awserr.New("RangeGetNotAllowedError",
+			"ranged gets are not authenticated, and must be allowed with AllowUnauthenticatedRangeGets", nil)
+		return
+	}
+
+	var err error
+	if *rng, err = parseByteRange(aws.StringValue(input.Range)); err != nil {
+		r.Error = err
+		return
+	}
+	r.HTTPRequest.Header.Set("Range", rng.cipherRange())
+}
+
+// decryptRange decrypts the range of an object encrypted with AES GCM
+// without authenticating it. The range is decrypted with AES CTR, which GCM
+// encrypts content with, starting from the counter of the first block of the
+// range received. The response's content range and length are set to those
+// of the decrypted content.
+func (c *DecryptionClient) decryptRange(r *request.Request, out *s3.GetObjectOutput, env Envelope, rng byteRange) error {
+	if (env.CEKAlg != AESGCMNoPadding && env.CEKAlg != AESGCMCommitKey) || env.TagLen != "128" {
+		return awserr.New("RangeGetNotSupportedError",
+			"ranged gets are only supported for objects encrypted with "+AESGCMNoPadding+" or "+AESGCMCommitKey, nil)
+	}
+
+	cc, err := c.contentCipherFromEnvelope(env)
+	if err != nil {
+		return err
+	}
+	cd := cc.GetCipherData()
+	if len(cd.IV) != gcmNonceSize {
+		return awserr.New("InvalidNonceError", "ranged gets require a 12 byte nonce", nil)
+	}
+	block, err := aes.NewCipher(cd.Key)
+	if err != nil {
+		return err
+	}
+
+	// The whole object is returned if the range was ignored.
+	start, end, total := int64(0), aws.Int64Value(out.ContentLength)-1, aws.Int64Value(out.ContentLength)
+	if out.ContentRange != nil {
+		if start, end, total, err = parseContentRange(*out.ContentRange); err != nil {
+			return err
+		}
+	}
+
+	contentLen := total - gcmTagSize
+	plainStart, plainEnd := rng.start, contentLen
+	switch {
+	case rng.start < 0:
+		plainStart = contentLen - rng.end
+		if plainStart < 0 {
+			plainStart = 0
+		}
+	case rng.end >= 0 && rng.end < contentLen:
+		plainEnd = rng.end + 1
+	}
+	if plainEnd > end+1 {
+		plainEnd = end + 1
+	}
+	if plainStart < start || plainStart >= plainEnd {
+		return awserr.NewRequestFailure(
+			awserr.New("InvalidRange", "the requested range is not satisfiable", nil),
+			http.StatusRequestedRangeNotSatisfiable, r.RequestID)
+	}
+
+	var counter [gcmBlockSize]byte
+	copy(counter[:], cd.IV)
+	// The first counter value is used for the tag, and content starts with
+	// the second.
+	binary.BigEndian.PutUint32(counter[gcmNonceSize:], uint32(start/gcmBlockSize)+2)
+	stream := cipher.NewCTR(block, counter[:])
+	if off := start % gcmBlockSize; off != 0 {
+		discard := make([]byte, off)
+		stream.XORKeyStream(discard, discard)
+	}
+
+	out.Body = &CryptoReadCloser{
+		Body: out.Body,
+		Decrypter: &rangeReader{
+			r:    io.LimitReader(cipher.StreamReader{S: stream, R: out.Body}, plainEnd-start),
+			skip: plainStart - start,
+		},
+	}
+	out.ContentLength = aws.Int64(plainEnd - plainStart)
+	out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", plainStart, plainEnd-1, contentLen))
+
+	return nil
+}
+
+// rangeReader discards the bytes before the start of a range when it is
+// first read from.
+type rangeReader struct {
+	r    io.Reader
+	skip int64
+}
+
+func (rr *rangeReader) Read(b []byte) (int, error) {
+	if rr.skip > 0 {
+		n, err := io.CopyN(ioutil.Discard, rr.r, rr.skip)
+		rr.skip -= n
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+	}
+	return rr.r.Read(b)
+}
@@ -0,0 +1,275 @@
+package s3crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3crypto"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+var (
+	rangeKey = bytes.Repeat([]byte{5}, 32)
+	rangeIV  = bytes.Repeat([]byte{9}, 12)
+)
+
+type rangeKeyDecrypter struct{}
+
+func (rangeKeyDecrypter) DecryptKey(key []byte) ([]byte, error) {
+	return rangeKey, nil
+}
+
+// rangeS3 is a fake S3 serving ranges of an object encrypted with AES GCM.
+type rangeS3 struct {
+	m      sync.Mutex
+	object []byte
+	header http.Header
+	ranges []string
+}
+
+func newRangeClient(plaintext []byte, options ...func(*s3crypto.DecryptionClient)) (*rangeS3, *s3crypto.DecryptionClient) {
+	block, _ := aes.NewCipher(rangeKey)
+	aead, _ := cipher.NewGCM(block)
+
+	f := &rangeS3{
+		object: aead.Seal(nil, rangeIV, plaintext, nil),
+		header: http.Header{
+			http.CanonicalHeaderKey("x-amz-meta-x-amz-key-v2"):   []string{base64.StdEncoding.EncodeToString([]byte("key"))},
+			http.CanonicalHeaderKey("x-amz-meta-x-amz-iv"):       []string{base64.StdEncoding.EncodeToString(rangeIV)},
+			http.CanonicalHeaderKey("x-amz-meta-x-amz-matdesc"):  []string{`{}`},
+			http.CanonicalHeaderKey("x-amz-meta-x-amz-wrap-alg"): []string{"test"},
+			http.CanonicalHeaderKey("x-amz-meta-x-amz-cek-alg"):  []string{s3crypto.AESGCMNoPadding},
+			http.CanonicalHeaderKey("x-amz-meta-x-amz-tag-len"):  []string{"128"},
+		},
+	}
+
+	svc := s3.New(unit.Session, &aws.Config{
+		MaxRetries:       aws.Int(0),
+		S3ForcePathStyle: aws.Bool(true),
+		Region:           aws.String("us-west-2"),
+	})
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(f.send)
+
+	c := s3crypto.NewDecryptionClient(unit.Session, options...)
+	c.S3Client = svc
+	c.WrapRegistry["test"] = func(s3crypto.Envelope) (s3crypto.CipherDataDecrypter, error) {
+		return rangeKeyDecrypter{}, nil
+	}
+	return f, c
+}
+
+func (f *rangeS3) send(r *request.Request) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	header := http.Header{}
+	for k, v := range f.header {
+		header[k] = v
+	}
+	r.HTTPResponse = &http.Response{StatusCode: 200, Header: header}
+
+	start, end := int64(0), int64(len(f.object)-1)
+	if rng := r.HTTPRequest.Header.Get("Range"); len(rng) != 0 {
+		f.ranges = append(f.ranges, rng)
+		bounds := strings.Split(strings.TrimPrefix(rng, "bytes="), "-")
+		if len(bounds[0]) == 0 {
+			n, _ := strconv.ParseInt(bounds[1], 10, 64)
+			if start = int64(len(f.object)) - n; start < 0 {
+				start = 0
+			}
+		} else {
+			start, _ = strconv.ParseInt(bounds[0], 10, 64)
+			if len(bounds[1]) != 0 {
+				if e, _ := strconv.ParseInt(bounds[1], 10, 64); e < end {
+					end = e
+				}
+			}
+		}
+		r.HTTPResponse.StatusCode = 206
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(f.object)))
+	}
+	header.Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(f.object[start : end+1]))
+}
+
+func allowRangeGets(c *s3crypto.DecryptionClient) {
+	c.AllowUnauthenticatedRangeGets = true
+}
+
+func legacyMode(c *s3crypto.DecryptionClient) {
+	c.Mode = s3crypto.LegacyDecryptionMode
+}
+
+func TestDecryptionClient_RangeGet(t *testing.T) {
+	plaintext := make([]byte, 1000)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 7)
+	}
+
+	cases := []struct {
+		rng         string
+		cipherRange string
+		start, end  int
+	}{
+		{"bytes=0-99", "bytes=0-99", 0, 100},
+		{"bytes=37-99", "bytes=32-99", 37, 100},
+		{"bytes=995-1010", "bytes=992-1010", 995, 1000},
+		{"bytes=990-", "bytes=976-", 990, 1000},
+		{"bytes=0-", "bytes=0-", 0, 1000},
+		{"bytes=-5", "bytes=-21", 995, 1000},
+		{"bytes=-5000", "bytes=-5016", 0, 1000},
+	}
+
+	for _, c := range cases {
+		f, client := newRangeClient(plaintext, allowRangeGets)
+		out, err := client.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("key"),
+			Range:  aws.String(c.rng),
+		})
+		if err != nil {
+			t.Fatalf("%s, expected no error, but received %v", c.rng, err)
+		}
+		b, err := ioutil.ReadAll(out.Body)
+		if err != nil {
+			t.Fatalf("%s, expected no error, but received %v", c.rng, err)
+		}
+
+		if e, a := c.cipherRange, f.ranges[0]; e != a {
+			t.Errorf("%s, expected %v, but received %v", c.rng, e, a)
+		}
+		if e, a := plaintext[c.start:c.end], b; !bytes.Equal(e, a) {
+			t.Errorf("%s, expected %x, but received %x", c.rng, e, a)
+		}
+		if e, a := int64(c.end-c.start), aws.Int64Value(out.ContentLength); e != a {
+			t.Errorf("%s, expected %v, but received %v", c.rng, e, a)
+		}
+		if e, a := fmt.Sprintf("bytes %d-%d/1000", c.start, c.end-1), aws.StringValue(out.ContentRange); e != a {
+			t.Errorf("%s, expected %v, but received %v", c.rng, e, a)
+		}
+	}
+}
+
+func TestDecryptionClient_RangeGetErrors(t *testing.T) {
+	cases := map[string]struct {
+		rng      string
+		options  []func(*s3crypto.DecryptionClient)
+		cekAlg   string
+		code     string
+		status   int
+		notFetch bool
+	}{
+		"not allowed": {
+			rng: "bytes=0-9", code: "RangeGetNotAllowedError", notFetch: true,
+		},
+		"multiple ranges": {
+			rng: "bytes=0-9,20-29", options: []func(*s3crypto.DecryptionClient){allowRangeGets},
+			code: "InvalidRangeError", notFetch: true,
+		},
+		"tag range": {
+			rng: "bytes=100-109", options: []func(*s3crypto.DecryptionClient){allowRangeGets},
+			code: "InvalidRange", status: http.StatusRequestedRangeNotSatisfiable,
+		},
+		"cbc": {
+			rng: "bytes=0-9", options: []func(*s3crypto.DecryptionClient){allowRangeGets, legacyMode},
+			cekAlg: s3crypto.AESCBC + "/" + s3crypto.AESCBCPadder.Name(),
+			code:   "RangeGetNotSupportedError",
+		},
+		"cbc not allowed": {
+			rng: "bytes=0-9", options: []func(*s3crypto.DecryptionClient){allowRangeGets},
+			cekAlg: s3crypto.AESCBC + "/" + s3crypto.AESCBCPadder.Name(),
+			code:   "LegacyEnvelopeError",
+		},
+	}
+
+	for name, c := range cases {
+		f, client := newRangeClient(make([]byte, 100), c.options...)
+		if len(c.cekAlg) != 0 {
+			f.header.Set("x-amz-meta-x-amz-cek-alg", c.cekAlg)
+		}
+		_, err := client.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("key"),
+			Range:  aws.String(c.rng),
+		})
+		if err == nil {
+			t.Fatalf("%s, expected error, but received none", name)
+		}
+		if e, a := c.code, err.(awserr.Error).Code(); e != a {
+			t.Errorf("%s, expected %v, but received %v", name, e, a)
+		}
+		if c.status != 0 {
+			if e, a := c.status, err.(awserr.RequestFailure).StatusCode(); e != a {
+				t.Errorf("%s, expected %v, but received %v", name, e, a)
+			}
+		}
+		if e, a := c.notFetch, len(f.ranges) == 0; e != a {
+			t.Errorf("%s, expected %v, but received %v", name, e, a)
+		}
+	}
+}
+
+func TestNewDownloader(t *testing.T) {
+	plaintext := make([]byte, 1000)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 13)
+	}
+	f, client := newRangeClient(plaintext, allowRangeGets)
+
+	d := s3crypto.NewDownloader(client, func(d *s3manager.Downloader) {
+		d.PartSize = 100
+		d.Concurrency = 3
+		d.ValidateChecksums = true
+	})
+	if d.ValidateChecksums {
+		t.Errorf("expected checksum validation to be disabled")
+	}
+
+	w := &aws.WriteAtBuffer{}
+	n, err := d.Download(w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := int64(len(plaintext)), n; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := plaintext, w.Bytes(); !bytes.Equal(e, a) {
+		t.Errorf("expected %x, but received %x", e, a)
+	}
+	if e, a := 10, len(f.ranges); e != a {
+		t.Errorf("expected %v parts, but received %v", e, a)
+	}
+}
+
+func TestNewDownloader_NotAllowed(t *testing.T) {
+	_, client := newRangeClient(make([]byte, 10))
+
+	_, err := s3crypto.NewDownloader(client).Download(&aws.WriteAtBuffer{}, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err == nil {
+		t.Fatalf("expected error, but received none")
+	}
+	if e, a := "RangeGetNotAllowedError", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+}
@@ -0,0 +1,413 @@
+package s3crypto
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// RewrapClient rewraps the keys of encrypted objects with a new master key,
+// without downloading and uploading their content. It is used to rotate the
+// master key of existing objects.
+//
+// The object's envelope is loaded with the LoadStrategy, and its key
+// decrypted with the wrap entry of the WrapRegistry for its wrap algorithm.
+// The key is encrypted with the Generator, and the new envelope saved with the
+// SaveStrategy. Envelopes saved in an object's metadata are saved by copying
+// the object in place.
+type RewrapClient struct {
+	S3Client s3iface.S3API
+
+	// LoadStrategy is used to load the metadata either from the metadata of the object
+	// or from a separate file in s3.
+	//
+	// Defaults to our default load strategy.
+	LoadStrategy LoadStrategy
+
+	// SaveStrategy will dictate where the new envelope is saved.
+	//
+	// Defaults to the object's metadata
+	SaveStrategy SaveStrategy
+
+	// WrapRegistry is used to decrypt the objects' keys with the master key
+	// they were wrapped with.
+	WrapRegistry map[string]WrapEntry
+
+	// Generator encrypts the objects' keys with the new master key. It must
+	// implement CipherDataEncrypter, as the CipherDataGenerators of the SDK
+	// do.
+	Generator CipherDataGenerator
+
+	// Copier is used to copy objects in place to save their new envelope in
+	// their metadata. Objects larger than 5GB are copied with a multipart
+	// upload.
+	Copier *s3manager.Copier
+}
+
+// NewRewrapClient instantiates a new client rewrapping keys with the
+// generator's master key.
+//
+// Example:
+//	sess := session.New()
+//	handler := s3crypto.NewKMSKeyGenerator(kms.New(sess), newCMKID)
+//	svc := s3crypto.NewRewrapClient(sess, handler)
+func NewRewrapClient(prov client.ConfigProvider, generator CipherDataGenerator, options ...func(*RewrapClient)) *RewrapClient {
+	s3client := s3.New(prov)
+	client := &RewrapClient{
+		S3Client: s3client,
+		LoadStrategy: defaultV2LoadStrategy{
+			client: s3client,
+		},
+		SaveStrategy: HeaderV2SaveStrategy{},
+		WrapRegistry: map[string]WrapEntry{
+			KMSWrap: (kmsKeyHandler{
+				kms: kms.New(prov),
+			}).decryptHandler,
+			KMSContextWrap: (kmsContextKeyHandler{
+				kms: kms.New(prov),
+			}).decryptHandler,
+		},
+		Generator: generator,
+		Copier:    s3manager.NewCopierWithClient(s3client),
+	}
+	for _, option := range options {
+		option(client)
+	}
+
+	return client
+}
+
+// RewrapObjectInput is the input of RewrapObject.
+type RewrapObjectInput struct {
+	// The bucket of the object.
+	Bucket *string
+
+	// The key of the object.
+	Key *string
+
+	// DryRun rewraps the object's key without saving its new envelope,
+	// verifying the key can be decrypted and encrypted with the master keys.
+	DryRun bool
+}
+
+// RewrapObjectOutput is the output of RewrapObject.
+type RewrapObjectOutput struct {
+	// The wrap algorithm and material description the object's key was
+	// wrapped with.
+	PreviousWrapAlgorithm       string
+	PreviousMaterialDescription string
+
+	// The wrap algorithm and material description the object's key is
+	// wrapped with.
+	WrapAlgorithm       string
+	MaterialDescription string
+
+	// Copied is true if the object was copied in place to save its envelope
+	// in its metadata, or to remove its previous envelope from it.
+	Copied bool
+
+	// The version of the object created by the copy. Will only be populated
+	// if the bucket is versioned.
+	VersionID *string
+}
+
+// RewrapObject rewraps the key of an object with the client's master key.
+//
+// Objects whose envelope is saved in their metadata are copied in place,
+// keeping their metadata, tags, and server side encryption, but not their
+// ACL. In a versioned bucket the copy is a new version of the object, and
+// previous versions keep the key wrapped with the previous master key. An
+// instruction file is not removed when the envelope is saved in the object's
+// metadata instead.
+//
+// Example:
+//	out, err := svc.RewrapObject(&s3crypto.RewrapObjectInput{
+//		Bucket: aws.String("testBucket"),
+//		Key:    aws.String("testKey"),
+//	})
+func (c *RewrapClient) RewrapObject(input *RewrapObjectInput) (*RewrapObjectOutput, error) {
+	return c.RewrapObjectWithContext(aws.BackgroundContext(), input)
+}
+
+// RewrapObjectWithContext is the same as RewrapObject with the additional
+// support for Context input parameters, and request options. The Context
+// must not be nil. A nil Context will cause a panic.
+func (c *RewrapClient) RewrapObjectWithContext(ctx aws.Context, input *RewrapObjectInput, opts ...request.Option) (*RewrapObjectOutput, error) {
+	encrypter, ok := c.Generator.(CipherDataEncrypter)
+	if !ok {
+		return nil, awserr.New("UnsupportedGeneratorError",
+			"the generator must implement CipherDataEncrypter to rewrap keys", nil)
+	}
+
+	headReq, head := c.S3Client.HeadObjectRequest(&s3.HeadObjectInput{
+		Bucket: input.Bucket,
+		Key:    input.Key,
+	})
+	headReq.SetContext(ctx)
+	headReq.ApplyOptions(opts...)
+	if err := headReq.Send(); err != nil {
+		return nil, err
+	}
+
+	env, err := c.LoadStrategy.Load(headReq)
+	if err != nil {
+		return nil, err
+	}
+
+	newEnv, err := c.rewrapEnvelope(env, encrypter, input.Bucket, input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &RewrapObjectOutput{
+		PreviousWrapAlgorithm:       env.WrapAlg,
+		PreviousMaterialDescription: env.MatDesc,
+		WrapAlgorithm:               newEnv.WrapAlg,
+		MaterialDescription:         newEnv.MatDesc,
+	}
+	if input.DryRun {
+		return out, nil
+	}
+
+	copyInput := inPlaceCopyInput(input, head)
+	copyReq, _ := c.S3Client.CopyObjectRequest(copyInput)
+	if err := c.SaveStrategy.Save(newEnv, copyReq); err != nil {
+		return nil, err
+	}
+
+	// The object is only copied if its envelope is saved in its metadata,
+	// or was before and must be removed.
+	if !hasEnvelopeMetadata(copyInput.Metadata) && !hasEnvelopeMetadata(head.Metadata) {
+		return out, nil
+	}
+
+	copied, err := c.Copier.CopyWithContext(ctx, copyInput, s3manager.WithCopierRequestOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+	out.Copied = true
+	out.VersionID = copied.VersionID
+
+	return out, nil
+}
+
+// rewrapEnvelope decrypts the envelope's key with the wrap entry of its wrap
+// algorithm, and returns the envelope with the key encrypted by the
+// encrypter, bound to the object if the encrypter supports it.
+func (c *RewrapClient) rewrapEnvelope(env Envelope, encrypter CipherDataEncrypter, bucket, objectKey *string) (Envelope, error) {
+	decrypter, err := wrapFromRegistry(c.WrapRegistry, env)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	encryptedKey, err := base64.StdEncoding.DecodeString(env.CipherKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+	key, err := decrypter.DecryptKey(encryptedKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	cd := CipherData{
+		Key:          key,
+		CEKAlgorithm: env.CEKAlg,
+		TagLength:    env.TagLen,
+	}
+	if e, ok := encrypter.(objectCipherDataEncrypter); ok {
+		cd, err = e.encryptObjectCipherData(cd, aws.StringValue(bucket), aws.StringValue(objectKey))
+	} else {
+		cd, err = encrypter.EncryptCipherData(cd)
+	}
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	matdesc, err := cd.MaterialDescription.encodeDescription()
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	env.CipherKey = base64.StdEncoding.EncodeToString(cd.EncryptedKey)
+	env.WrapAlg = cd.WrapAlgorithm
+	env.MatDesc = string(matdesc)
+	return env, nil
+}
+
+// envelopeHeaders are the metadata keys of an envelope saved in an object's
+// metadata.
+var envelopeHeaders = []string{
+	keyV2Header,
+	ivHeader,
+	matDescHeader,
+	cekAlgorithmHeader,
+	wrapAlgorithmHeader,
+	tagLengthHeader,
+	unencryptedMD5Header,
+	unencryptedContentLengthHeader,
+	keyCommitmentHeader,
+}
+
+func isEnvelopeHeader(k string) bool {
+	for _, h := range envelopeHeaders {
+		if http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(h) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasEnvelopeMetadata(metadata map[string]*string) bool {
+	for k := range metadata {
+		if http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(keyV2Header) {
+			return true
+		}
+	}
+	return false
+}
+
+// inPlaceCopyInput returns the input of a copy of the object to itself,
+// replacing its metadata with its current metadata without the envelope.
+// The copy is conditional on the object not having been modified.
+func inPlaceCopyInput(input *RewrapObjectInput, head *s3.HeadObjectOutput) *s3.CopyObjectInput {
+	metadata := map[string]*string{}
+	for k, v := range head.Metadata {
+		if !isEnvelopeHeader(k) {
+			metadata[k] = v
+		}
+	}
+
+	source := url.URL{Path: aws.StringValue(input.Bucket) + "/" + aws.StringValue(input.Key)}
+	in := &s3.CopyObjectInput{
+		Bucket:                  input.Bucket,
+		Key:                     input.Key,
+		CopySource:              aws.String(source.EscapedPath()),
+		CopySourceIfMatch:       head.ETag,
+		MetadataDirective:       aws.String(s3.MetadataDirectiveReplace),
+		Metadata:                metadata,
+		CacheControl:            head.CacheControl,
+		ContentDisposition:      head.ContentDisposition,
+		ContentEncoding:         head.ContentEncoding,
+		ContentLanguage:         head.ContentLanguage,
+		ContentType:             head.ContentType,
+		ServerSideEncryption:    head.ServerSideEncryption,
+		SSEKMSKeyId:             head.SSEKMSKeyId,
+		StorageClass:            head.StorageClass,
+		WebsiteRedirectLocation: head.WebsiteRedirectLocation,
+	}
+	if t, err := http.ParseTime(aws.StringValue(head.Expires)); err == nil {
+		in.Expires = aws.Time(t)
+	}
+
+	return in
+}
+
+// RewrapObjectsInput is the input of RewrapObjects.
+type RewrapObjectsInput struct {
+	// The bucket of the objects.
+	Bucket *string
+
+	// The prefix of the keys of the objects to rewrap.
+	Prefix *string
+
+	// Objects whose key ends with the suffix are instruction files, and are
+	// not rewrapped.
+	//
+	// Defaults to DefaultInstructionKeySuffix.
+	InstructionFileSuffix string
+
+	// DryRun rewraps the objects' keys without saving their new envelopes.
+	DryRun bool
+}
+
+// RewrapReport is the report of the objects rewrapped by RewrapObjects.
+type RewrapReport struct {
+	// The objects whose keys were rewrapped, or would have been in a dry run.
+	Rewrapped []RewrapResult
+
+	// The objects whose keys could not be rewrapped.
+	Failed []RewrapResult
+}
+
+// RewrapResult is the result of rewrapping the key of an object.
+type RewrapResult struct {
+	// The key of the object.
+	Key string
+
+	// The output of RewrapObject if the key was rewrapped.
+	Output *RewrapObjectOutput
+
+	// The error rewrapping the key, if it failed.
+	Err error
+}
+
+// RewrapObjects rewraps the keys of the objects with keys beginning with the
+// prefix, reporting the objects rewrapped, and the errors of those which
+// could not be. An error is only returned if the objects cannot be listed, in
+// which case the report of the objects rewrapped until then is returned.
+//
+// See RewrapObject for how each object is rewrapped.
+//
+// Example:
+//	report, err := svc.RewrapObjects(&s3crypto.RewrapObjectsInput{
+//		Bucket: aws.String("testBucket"),
+//		Prefix: aws.String("reports/"),
+//		DryRun: true,
+//	})
+func (c *RewrapClient) RewrapObjects(input *RewrapObjectsInput) (*RewrapReport, error) {
+	return c.RewrapObjectsWithContext(aws.BackgroundContext(), input)
+}
+
+// RewrapObjectsWithContext is the same as RewrapObjects with the additional
+// support for Context input parameters, and request options. The Context
+// must not be nil. A nil Context will cause a panic.
+func (c *RewrapClient) RewrapObjectsWithContext(ctx aws.Context, input *RewrapObjectsInput, opts ...request.Option) (*RewrapReport, error) {
+	suffix := input.InstructionFileSuffix
+	if len(suffix) == 0 {
+		suffix = DefaultInstructionKeySuffix
+	}
+
+	report := &RewrapReport{}
+	err := c.S3Client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: input.Bucket,
+		Prefix: input.Prefix,
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			if strings.HasSuffix(key, suffix) {
+				continue
+			}
+
+			out, err := c.RewrapObjectWithContext(ctx, &RewrapObjectInput{
+				Bucket: input.Bucket,
+				Key:    obj.Key,
+				DryRun: input.DryRun,
+			}, opts...)
+			if err != nil {
+				report.Failed = append(report.Failed, RewrapResult{Key: key, Err: err})
+			} else {
+				report.Rewrapped = append(report.Rewrapped, RewrapResult{Key: key, Output: out})
+			}
+
+			if ctx.Err() != nil {
+				return false
+			}
+		}
+		return true
+	}, opts...)
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	return report, err
+}
@@ -0,0 +1,423 @@
+package s3crypto_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3crypto"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+type rewrapObject struct {
+	body     []byte
+	metadata map[string]*string
+	etag     string
+}
+
+// rewrapS3 is a fake S3 serving the requests of rewrapping objects.
+type rewrapS3 struct {
+	objects map[string]*rewrapObject
+	copies  int
+	n       int
+}
+
+func newRewrapS3() (*rewrapS3, *s3.S3) {
+	f := &rewrapS3{objects: map[string]*rewrapObject{}}
+
+	svc := s3.New(unit.Session, &aws.Config{
+		MaxRetries:       aws.Int(0),
+		S3ForcePathStyle: aws.Bool(true),
+		Region:           aws.String("us-west-2"),
+	})
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Unmarshal.Clear()
+	svc.Handlers.UnmarshalMeta.Clear()
+	svc.Handlers.UnmarshalError.Clear()
+	svc.Handlers.ValidateResponse.Clear()
+	svc.Handlers.Send.PushBack(f.send)
+
+	return f, svc
+}
+
+func (f *rewrapS3) put(key string, body []byte, metadata map[string]*string) {
+	f.n++
+	f.objects[key] = &rewrapObject{body: body, metadata: metadata, etag: fmt.Sprintf("etag-%d", f.n)}
+}
+
+func (f *rewrapS3) object(r *request.Request, key string) *rewrapObject {
+	obj, ok := f.objects[key]
+	if !ok {
+		r.Error = awserr.NewRequestFailure(awserr.New("NoSuchKey", "key not found, "+key, nil), 404, "")
+		return nil
+	}
+
+	// The metadata headers are read by the header load strategy.
+	for k, v := range obj.metadata {
+		r.HTTPResponse.Header.Set("X-Amz-Meta-"+k, *v)
+	}
+	return obj
+}
+
+func (f *rewrapS3) send(r *request.Request) {
+	r.HTTPResponse = &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+	switch in := r.Params.(type) {
+	case *s3.HeadObjectInput:
+		if obj := f.object(r, *in.Key); obj != nil {
+			out := r.Data.(*s3.HeadObjectOutput)
+			out.Metadata = obj.metadata
+			out.ETag = aws.String(obj.etag)
+			out.ContentLength = aws.Int64(int64(len(obj.body)))
+		}
+	case *s3.GetObjectInput:
+		if obj := f.object(r, *in.Key); obj != nil {
+			out := r.Data.(*s3.GetObjectOutput)
+			out.Metadata = obj.metadata
+			out.Body = ioutil.NopCloser(bytes.NewReader(obj.body))
+			out.ContentLength = aws.Int64(int64(len(obj.body)))
+		}
+	case *s3.PutObjectInput:
+		b, err := ioutil.ReadAll(in.Body)
+		if err != nil {
+			r.Error = err
+			return
+		}
+		f.put(*in.Key, b, in.Metadata)
+	case *s3.CopyObjectInput:
+		source, _ := url.QueryUnescape(*in.CopySource)
+		obj, ok := f.objects[strings.TrimPrefix(source, *in.Bucket+"/")]
+		if !ok || obj.etag != aws.StringValue(in.CopySourceIfMatch) {
+			r.Error = awserr.NewRequestFailure(awserr.New("PreconditionFailed", "copy source modified", nil), 412, "")
+			return
+		}
+		metadata := obj.metadata
+		if aws.StringValue(in.MetadataDirective) == s3.MetadataDirectiveReplace {
+			metadata = in.Metadata
+		}
+		f.copies++
+		f.put(*in.Key, obj.body, metadata)
+		r.Data.(*s3.CopyObjectOutput).CopyObjectResult = &s3.CopyObjectResult{ETag: aws.String(f.objects[*in.Key].etag)}
+	case *s3.ListObjectsV2Input:
+		var keys []string
+		for k := range f.objects {
+			if strings.HasPrefix(k, aws.StringValue(in.Prefix)) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		out := r.Data.(*s3.ListObjectsV2Output)
+		for _, k := range keys {
+			out.Contents = append(out.Contents, &s3.Object{Key: aws.String(k)})
+		}
+	default:
+		r.Error = awserr.New("UnsupportedOperation", r.Operation.Name, nil)
+	}
+}
+
+var (
+	oldMasterKey = s3crypto.SymmetricMasterKey{
+		Key:                 bytes.Repeat([]byte{1}, 32),
+		MaterialDescription: s3crypto.MaterialDescription{"key": aws.String("old")},
+	}
+	newMasterKey = s3crypto.SymmetricMasterKey{
+		Key:                 bytes.Repeat([]byte{2}, 32),
+		MaterialDescription: s3crypto.MaterialDescription{"key": aws.String("new")},
+	}
+)
+
+func putEncrypted(t *testing.T, svc *s3.S3, save s3crypto.SaveStrategy, key string, body []byte) {
+	generator := s3crypto.NewSymmetricKeyGenerator(s3crypto.AESGCMWrap, oldMasterKey.Key, oldMasterKey.MaterialDescription)
+	c := s3crypto.NewEncryptionClient(unit.Session, s3crypto.AESGCMContentCipherBuilder(generator), func(c *s3crypto.EncryptionClient) {
+		c.S3Client = svc
+		c.SaveStrategy = save
+	})
+	if _, err := c.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	}); err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+}
+
+func getDecrypted(svc *s3.S3, load s3crypto.LoadStrategy, key string, masterKey s3crypto.SymmetricMasterKey) ([]byte, error) {
+	c := s3crypto.NewDecryptionClient(unit.Session, func(c *s3crypto.DecryptionClient) {
+		c.S3Client = svc
+		c.LoadStrategy = load
+		c.WrapRegistry[s3crypto.AESGCMWrap] = s3crypto.NewSymmetricKeyWrapEntry(masterKey)
+		c.WrapRegistry[s3crypto.AESWrap] = s3crypto.NewSymmetricKeyWrapEntry(masterKey)
+	})
+	out, err := c.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(out.Body)
+}
+
+func newRewrapClient(svc *s3.S3, options ...func(*s3crypto.RewrapClient)) *s3crypto.RewrapClient {
+	generator := s3crypto.NewSymmetricKeyGenerator(s3crypto.AESWrap, newMasterKey.Key, newMasterKey.MaterialDescription)
+	c := s3crypto.NewRewrapClient(unit.Session, generator, func(c *s3crypto.RewrapClient) {
+		c.S3Client = svc
+		c.LoadStrategy = s3crypto.HeaderV2LoadStrategy{}
+		c.WrapRegistry[s3crypto.AESGCMWrap] = s3crypto.NewSymmetricKeyWrapEntry(oldMasterKey)
+		c.Copier = s3manager.NewCopierWithClient(svc)
+	})
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+func TestRewrapClient_RewrapObject(t *testing.T) {
+	f, svc := newRewrapS3()
+	body := []byte("content to rewrap")
+	putEncrypted(t, svc, s3crypto.HeaderV2SaveStrategy{}, "key", body)
+	f.objects["key"].metadata["Owner"] = aws.String("team")
+
+	c := newRewrapClient(svc)
+	out, err := c.RewrapObject(&s3crypto.RewrapObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	if e, a := s3crypto.AESGCMWrap, out.PreviousWrapAlgorithm; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := `{"key":"old"}`, out.PreviousMaterialDescription; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := s3crypto.AESWrap, out.WrapAlgorithm; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := `{"key":"new"}`, out.MaterialDescription; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if !out.Copied {
+		t.Errorf("expected object to be copied")
+	}
+	if e, a := "team", aws.StringValue(f.objects["key"].metadata["Owner"]); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+
+	if _, err := getDecrypted(svc, s3crypto.HeaderV2LoadStrategy{}, "key", oldMasterKey); err == nil {
+		t.Errorf("expected error, but received none")
+	}
+	b, err := getDecrypted(svc, s3crypto.HeaderV2LoadStrategy{}, "key", newMasterKey)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := body, b; !bytes.Equal(e, a) {
+		t.Errorf("expected %s, but received %s", e, a)
+	}
+}
+
+func TestRewrapClient_InstructionFile(t *testing.T) {
+	f, svc := newRewrapS3()
+	body := []byte("content to rewrap")
+	putEncrypted(t, svc, s3crypto.S3SaveStrategy{Client: svc}, "key", body)
+	etag := f.objects["key"].etag
+
+	c := newRewrapClient(svc, func(c *s3crypto.RewrapClient) {
+		c.LoadStrategy = s3crypto.S3LoadStrategy{Client: svc}
+		c.SaveStrategy = s3crypto.S3SaveStrategy{Client: svc}
+	})
+	out, err := c.RewrapObject(&s3crypto.RewrapObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	if out.Copied {
+		t.Errorf("expected object not to be copied")
+	}
+	if e, a := etag, f.objects["key"].etag; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+
+	b, err := getDecrypted(svc, s3crypto.S3LoadStrategy{Client: svc}, "key", newMasterKey)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := body, b; !bytes.Equal(e, a) {
+		t.Errorf("expected %s, but received %s", e, a)
+	}
+}
+
+func TestRewrapClient_MoveToInstructionFile(t *testing.T) {
+	f, svc := newRewrapS3()
+	body := []byte("content to rewrap")
+	putEncrypted(t, svc, s3crypto.HeaderV2SaveStrategy{}, "key", body)
+
+	c := newRewrapClient(svc, func(c *s3crypto.RewrapClient) {
+		c.SaveStrategy = s3crypto.S3SaveStrategy{Client: svc}
+	})
+	out, err := c.RewrapObject(&s3crypto.RewrapObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	// The envelope is removed from the object's metadata.
+	if !out.Copied {
+		t.Errorf("expected object to be copied")
+	}
+	if e, a := 0, len(f.objects["key"].metadata); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+
+	b, err := getDecrypted(svc, s3crypto.S3LoadStrategy{Client: svc}, "key", newMasterKey)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := body, b; !bytes.Equal(e, a) {
+		t.Errorf("expected %s, but received %s", e, a)
+	}
+}
+
+func TestRewrapClient_DryRun(t *testing.T) {
+	f, svc := newRewrapS3()
+	putEncrypted(t, svc, s3crypto.HeaderV2SaveStrategy{}, "key", []byte("content"))
+	etag := f.objects["key"].etag
+
+	out, err := newRewrapClient(svc).RewrapObject(&s3crypto.RewrapObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if e, a := s3crypto.AESWrap, out.WrapAlgorithm; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := etag, f.objects["key"].etag; e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+	if e, a := 0, f.copies; e != a {
+		t.Errorf("expected %v copies, but received %v", e, a)
+	}
+}
+
+func TestRewrapClient_Errors(t *testing.T) {
+	_, svc := newRewrapS3()
+	putEncrypted(t, svc, s3crypto.HeaderV2SaveStrategy{}, "key", []byte("content"))
+
+	cases := map[string]struct {
+		options func(*s3crypto.RewrapClient)
+		code    string
+	}{
+		"generator": {
+			options: func(c *s3crypto.RewrapClient) { c.Generator = mockGenerator{} },
+			code:    "UnsupportedGeneratorError",
+		},
+		"wrong master key": {
+			options: func(c *s3crypto.RewrapClient) {
+				c.WrapRegistry[s3crypto.AESGCMWrap] = s3crypto.NewSymmetricKeyWrapEntry(newMasterKey)
+			},
+			code: "MasterKeyNotFoundError",
+		},
+		"unregistered wrap algorithm": {
+			options: func(c *s3crypto.RewrapClient) { delete(c.WrapRegistry, s3crypto.AESGCMWrap) },
+			code:    "InvalidWrapAlgorithmError",
+		},
+	}
+
+	for name, c := range cases {
+		_, err := newRewrapClient(svc, c.options).RewrapObject(&s3crypto.RewrapObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("key"),
+		})
+		if err == nil {
+			t.Fatalf("%s, expected error, but received none", name)
+		}
+		if e, a := c.code, err.(awserr.Error).Code(); e != a {
+			t.Errorf("%s, expected %v, but received %v", name, e, a)
+		}
+	}
+}
+
+func TestRewrapClient_RewrapObjects(t *testing.T) {
+	f, svc := newRewrapS3()
+	putEncrypted(t, svc, s3crypto.HeaderV2SaveStrategy{}, "logs/a", []byte("a"))
+	putEncrypted(t, svc, s3crypto.S3SaveStrategy{Client: svc}, "logs/b", []byte("b"))
+	f.put("logs/plain", []byte("not encrypted"), nil)
+	putEncrypted(t, svc, s3crypto.HeaderV2SaveStrategy{}, "other/c", []byte("c"))
+
+	c := newRewrapClient(svc, func(c *s3crypto.RewrapClient) {
+		c.LoadStrategy = headerOrInstructionFile{s3crypto.S3LoadStrategy{Client: svc}}
+	})
+
+	for _, dryRun := range []bool{true, false} {
+		report, err := c.RewrapObjects(&s3crypto.RewrapObjectsInput{
+			Bucket: aws.String("bucket"),
+			Prefix: aws.String("logs/"),
+			DryRun: dryRun,
+		})
+		if err != nil {
+			t.Fatalf("%v, expected no error, but received %v", dryRun, err)
+		}
+
+		var rewrapped []string
+		for _, r := range report.Rewrapped {
+			rewrapped = append(rewrapped, r.Key)
+		}
+		if e, a := "logs/a,logs/b", strings.Join(rewrapped, ","); e != a {
+			t.Errorf("%v, expected %v, but received %v", dryRun, e, a)
+		}
+		if e, a := 1, len(report.Failed); e != a {
+			t.Fatalf("%v, expected %v, but received %v", dryRun, e, a)
+		}
+		if e, a := "logs/plain", report.Failed[0].Key; e != a {
+			t.Errorf("%v, expected %v, but received %v", dryRun, e, a)
+		}
+		if report.Failed[0].Err == nil {
+			t.Errorf("%v, expected error, but received none", dryRun)
+		}
+	}
+
+	for _, key := range []string{"logs/a", "logs/b"} {
+		if _, err := getDecrypted(svc, headerOrInstructionFile{s3crypto.S3LoadStrategy{Client: svc}}, key, newMasterKey); err != nil {
+			t.Errorf("%s, expected no error, but received %v", key, err)
+		}
+	}
+	if _, err := getDecrypted(svc, s3crypto.HeaderV2LoadStrategy{}, "other/c", oldMasterKey); err != nil {
+		t.Errorf("expected no error, but received %v", err)
+	}
+}
+
+// headerOrInstructionFile loads the envelope from the object's metadata, or
+// its instruction file if it has no envelope metadata.
+type headerOrInstructionFile struct {
+	s3crypto.S3LoadStrategy
+}
+
+func (load headerOrInstructionFile) Load(r *request.Request) (s3crypto.Envelope, error) {
+	if len(r.HTTPResponse.Header.Get("X-Amz-Meta-X-Amz-Key-V2")) != 0 {
+		return s3crypto.HeaderV2LoadStrategy{}.Load(r)
+	}
+	return load.S3LoadStrategy.Load(r)
+}
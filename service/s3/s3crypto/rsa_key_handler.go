@@ -0,0 +1,148 @@
+package s3crypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+const (
+	// RSAOAEPSHA1Wrap is the wrap algorithm of keys encrypted with an RSA
+	// master key with RSA-OAEP, using SHA-1 for the hash and mask generation
+	// functions. The encrypted plaintext is the length of the key, the key,
+	// and the content encryption algorithm.
+	RSAOAEPSHA1Wrap = "RSA-OAEP-SHA1"
+)
+
+// RSAMasterKey is an RSA master key, and the material description
+// identifying it.
+type RSAMasterKey struct {
+	PrivateKey          *rsa.PrivateKey
+	MaterialDescription MaterialDescription
+}
+
+// rsaKeyHandler encrypts keys with an RSA master key
+type rsaKeyHandler struct {
+	publicKey  *rsa.PublicKey
+	privateKey *rsa.PrivateKey
+	cekAlg     string
+
+	CipherData
+}
+
+// NewRSAKeyGenerator builds a new key provider encrypting keys with the RSA
+// public key. The material description is stored with the object to identify
+// the master key used.
+//
+// The content encryption algorithm is authenticated with the key, so the
+// provider can only be used with the AESGCMContentCipherBuilder.
+//
+// Example:
+//	matdesc := s3crypto.MaterialDescription{"key": aws.String("2019-10")}
+//	handler := s3crypto.NewRSAKeyGenerator(&privateKey.PublicKey, matdesc)
+//	svc := s3crypto.NewEncryptionClient(sess, s3crypto.AESGCMContentCipherBuilder(handler))
+func NewRSAKeyGenerator(publicKey *rsa.PublicKey, matdesc MaterialDescription) CipherDataGenerator {
+	if matdesc == nil {
+		matdesc = MaterialDescription{}
+	}
+
+	// These values are read only making them thread safe
+	kp := &rsaKeyHandler{
+		publicKey: publicKey,
+	}
+	kp.CipherData.WrapAlgorithm = RSAOAEPSHA1Wrap
+	kp.CipherData.MaterialDescription = matdesc
+	return kp
+}
+
+// NewRSAKeyWrapEntry builds returns a new RSA key provider and its decrypt
+// handler. The master key used to decrypt an object is the key whose material
+// description is equal to the object's.
+//
+// Example:
+//	decryptHandler := s3crypto.NewRSAKeyWrapEntry(s3crypto.RSAMasterKey{
+//		PrivateKey:          privateKey,
+//		MaterialDescription: matdesc,
+//	})
+//
+//	svc := s3crypto.NewDecryptionClient(sess, func(svc *s3crypto.DecryptionClient) {
+//		svc.WrapRegistry[s3crypto.RSAOAEPSHA1Wrap] = decryptHandler
+//	})
+func NewRSAKeyWrapEntry(keys ...RSAMasterKey) WrapEntry {
+	return func(env Envelope) (CipherDataDecrypter, error) {
+		m := MaterialDescription{}
+		if err := m.decodeDescription([]byte(env.MatDesc)); err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if !m.equal(key.MaterialDescription) {
+				continue
+			}
+
+			kp := &rsaKeyHandler{
+				privateKey: key.PrivateKey,
+				cekAlg:     env.CEKAlg,
+			}
+			kp.CipherData.WrapAlgorithm = RSAOAEPSHA1Wrap
+			kp.CipherData.MaterialDescription = m
+			return kp, nil
+		}
+
+		return nil, awserr.New("MasterKeyNotFoundError",
+			"no master key matches the material description, "+env.MatDesc, nil)
+	}
+}
+
+// DecryptKey decrypts the key with the RSA private key.
+func (kp *rsaKeyHandler) DecryptKey(key []byte) ([]byte, error) {
+	b, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, kp.privateKey, key, nil)
+	if err != nil {
+		return nil, errInvalidWrappedKey
+	}
+
+	// The length of the key, followed by the key and the content encryption
+	// algorithm it must be used with.
+	if len(b) == 0 || int(b[0]) > len(b)-1 {
+		return nil, errInvalidWrappedKey
+	}
+	n := int(b[0])
+	if cekAlg := string(b[1+n:]); cekAlg != kp.cekAlg {
+		return nil, awserr.New("InvalidCEKAlgorithmError",
+			"cek algorithm doesn't match the encrypted key, "+kp.cekAlg, nil)
+	}
+	return b[1 : 1+n], nil
+}
+
+// GenerateCipherData generates a key and IV, and encrypts the key with the
+// RSA public key.
+func (kp *rsaKeyHandler) GenerateCipherData(keySize, ivSize int) (CipherData, error) {
+	if ivSize != gcmNonceSize {
+		return CipherData{}, awserr.New("UnsupportedContentCipherError",
+			RSAOAEPSHA1Wrap+" requires the AESGCMContentCipherBuilder", nil)
+	}
+
+	return kp.EncryptCipherData(CipherData{
+		Key:          generateBytes(keySize),
+		IV:           generateBytes(ivSize),
+		CEKAlgorithm: AESGCMNoPadding,
+	})
+}
+
+// EncryptCipherData encrypts the cipher data's key with the RSA public key.
+func (kp *rsaKeyHandler) EncryptCipherData(cd CipherData) (CipherData, error) {
+	b := append([]byte{byte(len(cd.Key))}, cd.Key...)
+	b = append(b, cd.CEKAlgorithm...)
+
+	encryptedKey, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, kp.publicKey, b, nil)
+	if err != nil {
+		return CipherData{}, err
+	}
+
+	cd.WrapAlgorithm = RSAOAEPSHA1Wrap
+	cd.MaterialDescription = kp.CipherData.MaterialDescription
+	cd.EncryptedKey = encryptedKey
+	return cd, nil
+}
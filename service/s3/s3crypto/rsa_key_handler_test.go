@@ -0,0 +1,90 @@
+package s3crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestRSAKeyHandler_RoundTrip(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	keys := []RSAMasterKey{
+		{PrivateKey: oldKey, MaterialDescription: MaterialDescription{"key": aws.String("old")}},
+		{PrivateKey: newKey, MaterialDescription: MaterialDescription{"key": aws.String("new")}},
+	}
+	entry := NewRSAKeyWrapEntry(keys...)
+
+	for _, key := range keys {
+		generator := NewRSAKeyGenerator(&key.PrivateKey.PublicKey, key.MaterialDescription)
+		cd, cc, err := roundTripEnvelope(t, generator, RSAOAEPSHA1Wrap, entry)
+		if err != nil {
+			t.Fatalf("expected no error, but received %v", err)
+		}
+
+		if e, a := RSAOAEPSHA1Wrap, cd.WrapAlgorithm; e != a {
+			t.Errorf("expected %v, but received %v", e, a)
+		}
+		if e, a := cd.Key, cc.GetCipherData().Key; !bytes.Equal(e, a) {
+			t.Errorf("expected %x, but received %x", e, a)
+		}
+	}
+
+	// The key encrypted for one master key can't be decrypted with another.
+	generator := NewRSAKeyGenerator(&oldKey.PublicKey, keys[1].MaterialDescription)
+	if _, _, err := roundTripEnvelope(t, generator, RSAOAEPSHA1Wrap, entry); err == nil {
+		t.Errorf("expected error, but received none")
+	}
+}
+
+func TestRSAKeyHandler_Errors(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	generator := NewRSAKeyGenerator(&key.PublicKey, nil)
+
+	if _, err := generator.GenerateCipherData(cbcKeySize, cbcNonceSize); err == nil {
+		t.Errorf("expected error, but received none")
+	}
+
+	cd, err := generator.GenerateCipherData(gcmKeySize, gcmNonceSize)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	entry := NewRSAKeyWrapEntry(RSAMasterKey{PrivateKey: key})
+	decrypter, err := entry(Envelope{
+		WrapAlg: RSAOAEPSHA1Wrap,
+		CEKAlg:  AESCBC + "/" + AESCBCPadder.Name(),
+		MatDesc: "{}",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	_, err = decrypter.DecryptKey(cd.EncryptedKey)
+	if err == nil {
+		t.Fatalf("expected error, but received none")
+	}
+	if e, a := "InvalidCEKAlgorithmError", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+
+	_, err = entry(Envelope{WrapAlg: RSAOAEPSHA1Wrap, MatDesc: `{"key":"other"}`})
+	if err == nil {
+		t.Fatalf("expected error, but received none")
+	}
+	if e, a := "MasterKeyNotFoundError", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+}
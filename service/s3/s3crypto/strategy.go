@@ -24,23 +24,36 @@ type S3SaveStrategy struct {
 	InstructionFileSuffix string
 }
 
-// Save will save the envelope contents to s3.
+// Save will save the envelope contents to s3. The request must be a
+// PutObject, CompleteMultipartUpload, or CopyObject request.
 func (strat S3SaveStrategy) Save(env Envelope, req *request.Request) error {
-	input := req.Params.(*s3.PutObjectInput)
+	var bucket, key *string
+	switch input := req.Params.(type) {
+	case *s3.PutObjectInput:
+		bucket, key = input.Bucket, input.Key
+	case *s3.CompleteMultipartUploadInput:
+		bucket, key = input.Bucket, input.Key
+	case *s3.CopyObjectInput:
+		bucket, key = input.Bucket, input.Key
+	default:
+		return awserr.New("InvalidSaveRequestError",
+			"instruction files can only be saved by PutObject, CompleteMultipartUpload, and CopyObject requests", nil)
+	}
+
 	b, err := json.Marshal(env)
 	if err != nil {
 		return err
 	}
 
 	instInput := s3.PutObjectInput{
-		Bucket: input.Bucket,
+		Bucket: bucket,
 		Body:   bytes.NewReader(b),
 	}
 
 	if strat.InstructionFileSuffix == "" {
-		instInput.Key = aws.String(*input.Key + DefaultInstructionKeySuffix)
+		instInput.Key = aws.String(*key + DefaultInstructionKeySuffix)
 	} else {
-		instInput.Key = aws.String(*input.Key + strat.InstructionFileSuffix)
+		instInput.Key = aws.String(*key + strat.InstructionFileSuffix)
 	}
 
 	_, err = strat.Client.PutObject(&instInput)
@@ -51,23 +64,52 @@ func (strat S3SaveStrategy) Save(env Envelope, req *request.Request) error {
 // the object.
 type HeaderV2SaveStrategy struct{}
 
-// Save will save the envelope to the request's header.
+// Save will save the envelope to the request's header. The request must be a
+// PutObject, CreateMultipartUpload, or CopyObject request. The metadata of a
+// CopyObject request is only used if its MetadataDirective is REPLACE.
+//
+// The unencrypted content's MD5 and length are not known when a multipart
+// upload is created, and are omitted from its header.
 func (strat HeaderV2SaveStrategy) Save(env Envelope, req *request.Request) error {
-	input := req.Params.(*s3.PutObjectInput)
-	if input.Metadata == nil {
-		input.Metadata = map[string]*string{}
+	var metadata map[string]*string
+	switch input := req.Params.(type) {
+	case *s3.PutObjectInput:
+		if input.Metadata == nil {
+			input.Metadata = map[string]*string{}
+		}
+		metadata = input.Metadata
+	case *s3.CreateMultipartUploadInput:
+		if input.Metadata == nil {
+			input.Metadata = map[string]*string{}
+		}
+		metadata = input.Metadata
+	case *s3.CopyObjectInput:
+		if input.Metadata == nil {
+			input.Metadata = map[string]*string{}
+		}
+		metadata = input.Metadata
+	default:
+		return awserr.New("InvalidSaveRequestError",
+			"envelope headers can only be saved by PutObject, CreateMultipartUpload, and CopyObject requests", nil)
 	}
 
-	input.Metadata[http.CanonicalHeaderKey(keyV2Header)] = &env.CipherKey
-	input.Metadata[http.CanonicalHeaderKey(ivHeader)] = &env.IV
-	input.Metadata[http.CanonicalHeaderKey(matDescHeader)] = &env.MatDesc
-	input.Metadata[http.CanonicalHeaderKey(wrapAlgorithmHeader)] = &env.WrapAlg
-	input.Metadata[http.CanonicalHeaderKey(cekAlgorithmHeader)] = &env.CEKAlg
-	input.Metadata[http.CanonicalHeaderKey(unencryptedMD5Header)] = &env.UnencryptedMD5
-	input.Metadata[http.CanonicalHeaderKey(unencryptedContentLengthHeader)] = &env.UnencryptedContentLen
+	metadata[http.CanonicalHeaderKey(keyV2Header)] = &env.CipherKey
+	metadata[http.CanonicalHeaderKey(ivHeader)] = &env.IV
+	metadata[http.CanonicalHeaderKey(matDescHeader)] = &env.MatDesc
+	metadata[http.CanonicalHeaderKey(wrapAlgorithmHeader)] = &env.WrapAlg
+	metadata[http.CanonicalHeaderKey(cekAlgorithmHeader)] = &env.CEKAlg
 
+	if len(env.UnencryptedMD5) > 0 {
+		metadata[http.CanonicalHeaderKey(unencryptedMD5Header)] = &env.UnencryptedMD5
+	}
+	if len(env.UnencryptedContentLen) > 0 {
+		metadata[http.CanonicalHeaderKey(unencryptedContentLengthHeader)] = &env.UnencryptedContentLen
+	}
 	if len(env.TagLen) > 0 {
-		input.Metadata[http.CanonicalHeaderKey(tagLengthHeader)] = &env.TagLen
+		metadata[http.CanonicalHeaderKey(tagLengthHeader)] = &env.TagLen
+	}
+	if len(env.KeyCommitment) > 0 {
+		metadata[http.CanonicalHeaderKey(keyCommitmentHeader)] = &env.KeyCommitment
 	}
 	return nil
 }
@@ -83,17 +125,28 @@ type S3LoadStrategy struct {
 	InstructionFileSuffix string
 }
 
-// Load from a given instruction file suffix
+// Load from a given instruction file suffix. The request must be a GetObject
+// or HeadObject request.
 func (load S3LoadStrategy) Load(req *request.Request) (Envelope, error) {
 	env := Envelope{}
 	if load.InstructionFileSuffix == "" {
 		load.InstructionFileSuffix = DefaultInstructionKeySuffix
 	}
 
-	input := req.Params.(*s3.GetObjectInput)
+	var bucket, key *string
+	switch input := req.Params.(type) {
+	case *s3.GetObjectInput:
+		bucket, key = input.Bucket, input.Key
+	case *s3.HeadObjectInput:
+		bucket, key = input.Bucket, input.Key
+	default:
+		return env, awserr.New("InvalidLoadRequestError",
+			"instruction files can only be loaded by GetObject and HeadObject requests", nil)
+	}
+
 	out, err := load.Client.GetObject(&s3.GetObjectInput{
-		Key:    aws.String(strings.Join([]string{*input.Key, load.InstructionFileSuffix}, "")),
-		Bucket: input.Bucket,
+		Key:    aws.String(strings.Join([]string{*key, load.InstructionFileSuffix}, "")),
+		Bucket: bucket,
 	})
 	if err != nil {
 		return env, err
@@ -121,6 +174,7 @@ func (load HeaderV2LoadStrategy) Load(req *request.Request) (Envelope, error) {
 	env.TagLen = req.HTTPResponse.Header.Get(strings.Join([]string{metaHeader, tagLengthHeader}, "-"))
 	env.UnencryptedMD5 = req.HTTPResponse.Header.Get(strings.Join([]string{metaHeader, unencryptedMD5Header}, "-"))
 	env.UnencryptedContentLen = req.HTTPResponse.Header.Get(strings.Join([]string{metaHeader, unencryptedContentLengthHeader}, "-"))
+	env.KeyCommitment = req.HTTPResponse.Header.Get(strings.Join([]string{metaHeader, keyCommitmentHeader}, "-"))
 	return env, nil
 }
 
@@ -75,3 +75,35 @@ func TestHeaderV2SaveStrategy(t *testing.T) {
 		}
 	}
 }
+
+func TestHeaderV2SaveStrategy_CreateMultipartUpload(t *testing.T) {
+	env := s3crypto.Envelope{
+		CipherKey: "Foo",
+		IV:        "Bar",
+		MatDesc:   "{}",
+		WrapAlg:   s3crypto.KMSWrap,
+		CEKAlg:    s3crypto.AESGCMNoPadding,
+		TagLen:    "128",
+	}
+	expected := map[string]*string{
+		"X-Amz-Key-V2":   aws.String("Foo"),
+		"X-Amz-Iv":       aws.String("Bar"),
+		"X-Amz-Matdesc":  aws.String("{}"),
+		"X-Amz-Wrap-Alg": aws.String(s3crypto.KMSWrap),
+		"X-Amz-Cek-Alg":  aws.String(s3crypto.AESGCMNoPadding),
+		"X-Amz-Tag-Len":  aws.String("128"),
+	}
+
+	params := &s3.CreateMultipartUploadInput{}
+	req := &request.Request{
+		Params: params,
+	}
+	strat := s3crypto.HeaderV2SaveStrategy{}
+	if err := strat.Save(env, req); err != nil {
+		t.Errorf("expected no error, but received %v", err)
+	}
+
+	if !reflect.DeepEqual(expected, params.Metadata) {
+		t.Errorf("expected %v, but received %v", expected, params.Metadata)
+	}
+}
@@ -0,0 +1,191 @@
+package s3crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+const (
+	// AESWrap is the wrap algorithm of keys wrapped with a symmetric master
+	// key with AES Key Wrap, as defined by RFC 3394.
+	AESWrap = "AESWrap"
+
+	// AESWrapPad is the wrap algorithm of keys wrapped with a symmetric
+	// master key with AES Key Wrap with Padding, as defined by RFC 5649.
+	AESWrapPad = "AESWrapPad"
+
+	// AESGCMWrap is the wrap algorithm of keys encrypted with a symmetric
+	// master key with AES GCM. The encrypted key is the nonce followed by the
+	// ciphertext and tag, authenticated with the content encryption
+	// algorithm as additional data.
+	AESGCMWrap = "AES/GCM"
+)
+
+// SymmetricMasterKey is an AES master key, and the material description
+// identifying it.
+type SymmetricMasterKey struct {
+	Key                 []byte
+	MaterialDescription MaterialDescription
+}
+
+// symmetricKeyHandler wraps keys with a symmetric master key
+type symmetricKeyHandler struct {
+	key    []byte
+	cekAlg string
+
+	CipherData
+}
+
+// NewSymmetricKeyGenerator builds a new key provider wrapping keys with the
+// AES master key and wrap algorithm, AESWrap, AESWrapPad, or AESGCMWrap. The
+// material description is stored with the object to identify the master key
+// used.
+//
+// AESGCMWrap authenticates the content encryption algorithm with the key, and
+// can only be used with the AESGCMContentCipherBuilder.
+//
+// Example:
+//	matdesc := s3crypto.MaterialDescription{"key": aws.String("2019-10")}
+//	handler := s3crypto.NewSymmetricKeyGenerator(s3crypto.AESGCMWrap, masterKey, matdesc)
+//	svc := s3crypto.NewEncryptionClient(sess, s3crypto.AESGCMContentCipherBuilder(handler))
+func NewSymmetricKeyGenerator(wrapAlg string, key []byte, matdesc MaterialDescription) CipherDataGenerator {
+	if matdesc == nil {
+		matdesc = MaterialDescription{}
+	}
+
+	// These values are read only making them thread safe
+	kp := &symmetricKeyHandler{
+		key: key,
+	}
+	kp.CipherData.WrapAlgorithm = wrapAlg
+	kp.CipherData.MaterialDescription = matdesc
+	return kp
+}
+
+// NewSymmetricKeyWrapEntry builds returns a new symmetric key provider and its
+// decrypt handler. The master key used to decrypt an object is the key whose
+// material description is equal to the object's.
+//
+// The entry unwraps keys of each symmetric wrap algorithm, and must be
+// registered for the algorithms used.
+//
+// Example:
+//	decryptHandler := s3crypto.NewSymmetricKeyWrapEntry(
+//		s3crypto.SymmetricMasterKey{Key: oldKey, MaterialDescription: oldMatDesc},
+//		s3crypto.SymmetricMasterKey{Key: newKey, MaterialDescription: newMatDesc},
+//	)
+//
+//	svc := s3crypto.NewDecryptionClient(sess, func(svc *s3crypto.DecryptionClient) {
+//		svc.WrapRegistry[s3crypto.AESWrap] = decryptHandler
+//		svc.WrapRegistry[s3crypto.AESGCMWrap] = decryptHandler
+//	})
+func NewSymmetricKeyWrapEntry(keys ...SymmetricMasterKey) WrapEntry {
+	return func(env Envelope) (CipherDataDecrypter, error) {
+		m := MaterialDescription{}
+		if err := m.decodeDescription([]byte(env.MatDesc)); err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if !m.equal(key.MaterialDescription) {
+				continue
+			}
+
+			kp := &symmetricKeyHandler{
+				key:    key.Key,
+				cekAlg: env.CEKAlg,
+			}
+			kp.CipherData.WrapAlgorithm = env.WrapAlg
+			kp.CipherData.MaterialDescription = m
+			return kp, nil
+		}
+
+		return nil, awserr.New("MasterKeyNotFoundError",
+			"no master key matches the material description, "+env.MatDesc, nil)
+	}
+}
+
+// DecryptKey unwraps the key with the master key.
+func (kp *symmetricKeyHandler) DecryptKey(key []byte) ([]byte, error) {
+	switch kp.WrapAlgorithm {
+	case AESWrap:
+		return aesKeyUnwrap(kp.key, key)
+	case AESWrapPad:
+		return aesKeyUnwrapPad(kp.key, key)
+	case AESGCMWrap:
+		aead, err := newKeyWrapGCM(kp.key)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) < gcmNonceSize {
+			return nil, errInvalidWrappedKey
+		}
+		plaintext, err := aead.Open(nil, key[:gcmNonceSize], key[gcmNonceSize:], []byte(kp.cekAlg))
+		if err != nil {
+			return nil, errInvalidWrappedKey
+		}
+		return plaintext, nil
+	default:
+		return nil, unsupportedWrapAlgorithm(kp.WrapAlgorithm)
+	}
+}
+
+// GenerateCipherData generates a key and IV, and wraps the key with the
+// master key.
+func (kp *symmetricKeyHandler) GenerateCipherData(keySize, ivSize int) (CipherData, error) {
+	cd := CipherData{
+		Key: generateBytes(keySize),
+		IV:  generateBytes(ivSize),
+	}
+	if kp.WrapAlgorithm == AESGCMWrap {
+		if ivSize != gcmNonceSize {
+			return CipherData{}, awserr.New("UnsupportedContentCipherError",
+				AESGCMWrap+" requires the AESGCMContentCipherBuilder", nil)
+		}
+		cd.CEKAlgorithm = AESGCMNoPadding
+	}
+
+	return kp.EncryptCipherData(cd)
+}
+
+// EncryptCipherData wraps the cipher data's key with the master key.
+func (kp *symmetricKeyHandler) EncryptCipherData(cd CipherData) (CipherData, error) {
+	var encryptedKey []byte
+	var err error
+	switch kp.WrapAlgorithm {
+	case AESWrap:
+		encryptedKey, err = aesKeyWrap(kp.key, cd.Key)
+	case AESWrapPad:
+		encryptedKey, err = aesKeyWrapPad(kp.key, cd.Key)
+	case AESGCMWrap:
+		var aead cipher.AEAD
+		if aead, err = newKeyWrapGCM(kp.key); err == nil {
+			nonce := generateBytes(gcmNonceSize)
+			encryptedKey = aead.Seal(nonce, nonce, cd.Key, []byte(cd.CEKAlgorithm))
+		}
+	default:
+		err = unsupportedWrapAlgorithm(kp.WrapAlgorithm)
+	}
+	if err != nil {
+		return CipherData{}, err
+	}
+
+	cd.WrapAlgorithm = kp.WrapAlgorithm
+	cd.MaterialDescription = kp.CipherData.MaterialDescription
+	cd.EncryptedKey = encryptedKey
+	return cd, nil
+}
+
+func newKeyWrapGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func unsupportedWrapAlgorithm(wrapAlg string) error {
+	return awserr.New("InvalidWrapAlgorithmError", "wrap algorithm isn't supported, "+wrapAlg, nil)
+}
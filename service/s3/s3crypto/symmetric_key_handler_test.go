@@ -0,0 +1,131 @@
+package s3crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// roundTripEnvelope generates cipher data for AES GCM content with the
+// generator, and decrypts the content cipher from its envelope with the
+// wrap entry.
+func roundTripEnvelope(t *testing.T, generator CipherDataGenerator, wrapAlg string, entry WrapEntry) (CipherData, ContentCipher, error) {
+	cc, err := AESGCMContentCipherBuilder(generator).ContentCipher()
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	cd := cc.GetCipherData()
+	env, err := encodeCipherData(cd)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	client := &DecryptionClient{
+		WrapRegistry: map[string]WrapEntry{wrapAlg: entry},
+		CEKRegistry:  map[string]CEKEntry{AESGCMNoPadding: newAESGCMContentCipher},
+	}
+	decrypted, err := client.contentCipherFromEnvelope(env)
+	return cd, decrypted, err
+}
+
+func TestSymmetricKeyHandler_RoundTrip(t *testing.T) {
+	oldKey := SymmetricMasterKey{
+		Key:                 bytes.Repeat([]byte{1}, 32),
+		MaterialDescription: MaterialDescription{"key": aws.String("old")},
+	}
+	newKey := SymmetricMasterKey{
+		Key:                 bytes.Repeat([]byte{2}, 16),
+		MaterialDescription: MaterialDescription{"key": aws.String("new")},
+	}
+	entry := NewSymmetricKeyWrapEntry(oldKey, newKey)
+
+	for _, wrapAlg := range []string{AESWrap, AESWrapPad, AESGCMWrap} {
+		for _, key := range []SymmetricMasterKey{oldKey, newKey} {
+			generator := NewSymmetricKeyGenerator(wrapAlg, key.Key, key.MaterialDescription)
+			cd, cc, err := roundTripEnvelope(t, generator, wrapAlg, entry)
+			if err != nil {
+				t.Fatalf("%s, expected no error, but received %v", wrapAlg, err)
+			}
+
+			if e, a := wrapAlg, cd.WrapAlgorithm; e != a {
+				t.Errorf("%s, expected %v, but received %v", wrapAlg, e, a)
+			}
+			if bytes.Equal(cd.Key, cd.EncryptedKey) {
+				t.Errorf("%s, expected key to be wrapped", wrapAlg)
+			}
+			if e, a := cd.Key, cc.GetCipherData().Key; !bytes.Equal(e, a) {
+				t.Errorf("%s, expected %x, but received %x", wrapAlg, e, a)
+			}
+		}
+	}
+}
+
+func TestSymmetricKeyHandler_MasterKeyNotFound(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	generator := NewSymmetricKeyGenerator(AESWrap, key, MaterialDescription{"key": aws.String("a")})
+	entry := NewSymmetricKeyWrapEntry(SymmetricMasterKey{
+		Key:                 key,
+		MaterialDescription: MaterialDescription{"key": aws.String("b")},
+	})
+
+	_, _, err := roundTripEnvelope(t, generator, AESWrap, entry)
+	if err == nil {
+		t.Fatalf("expected error, but received none")
+	}
+	if e, a := "MasterKeyNotFoundError", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+}
+
+func TestSymmetricKeyHandler_WrongKey(t *testing.T) {
+	for _, wrapAlg := range []string{AESWrap, AESWrapPad, AESGCMWrap} {
+		generator := NewSymmetricKeyGenerator(wrapAlg, bytes.Repeat([]byte{1}, 32), nil)
+		entry := NewSymmetricKeyWrapEntry(SymmetricMasterKey{Key: bytes.Repeat([]byte{2}, 32)})
+
+		_, _, err := roundTripEnvelope(t, generator, wrapAlg, entry)
+		if err == nil {
+			t.Fatalf("%s, expected error, but received none", wrapAlg)
+		}
+		if e, a := "InvalidWrappedKeyError", err.(awserr.Error).Code(); e != a {
+			t.Errorf("%s, expected %v, but received %v", wrapAlg, e, a)
+		}
+	}
+}
+
+func TestSymmetricKeyHandler_GCMWrapAuthenticatesCEKAlgorithm(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	generator := NewSymmetricKeyGenerator(AESGCMWrap, key, nil)
+	cd, err := generator.GenerateCipherData(gcmKeySize, gcmNonceSize)
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+
+	decrypter, err := NewSymmetricKeyWrapEntry(SymmetricMasterKey{Key: key})(Envelope{
+		WrapAlg: AESGCMWrap,
+		CEKAlg:  AESCBC + "/" + AESCBCPadder.Name(),
+		MatDesc: "{}",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but received %v", err)
+	}
+	if _, err := decrypter.DecryptKey(cd.EncryptedKey); err == nil {
+		t.Errorf("expected error, but received none")
+	}
+
+	if _, err := generator.GenerateCipherData(cbcKeySize, cbcNonceSize); err == nil {
+		t.Errorf("expected error, but received none")
+	}
+}
+
+func TestSymmetricKeyHandler_InvalidWrapAlgorithm(t *testing.T) {
+	generator := NewSymmetricKeyGenerator("AES/CTR", make([]byte, 32), nil)
+	_, err := generator.GenerateCipherData(gcmKeySize, gcmNonceSize)
+	if err == nil {
+		t.Fatalf("expected error, but received none")
+	}
+	if e, a := "InvalidWrapAlgorithmError", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expected %v, but received %v", e, a)
+	}
+}
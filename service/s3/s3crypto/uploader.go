@@ -0,0 +1,171 @@
+package s3crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// NewUploader returns an s3manager.Uploader which encrypts the objects it
+// uploads with the EncryptionClient. Objects uploaded in a single part are
+// encrypted with PutObject, and larger objects with an encrypted multipart
+// upload. Pass in additional functional options to customize the uploader's
+// behavior.
+//
+// The parts of an encrypted multipart upload must be encrypted in order, so
+// the uploader's Concurrency is set to 1. Each part is held back in memory
+// until the next part is read, so the last part can be uploaded with
+// UploadFinalPart. The uploader's checksum validation and checkpointing are
+// disabled as they are computed from the unencrypted parts. These are set
+// after the options are applied.
+//
+// Example:
+//	svc := s3crypto.NewEncryptionClient(sess, s3crypto.AESGCMContentCipherBuilder(handler))
+//	uploader := s3crypto.NewUploader(svc, func(u *s3manager.Uploader) {
+//		u.PartSize = 64 * 1024 * 1024 // 64MB per part
+//	})
+//	_, err := uploader.Upload(&s3manager.UploadInput{
+//		Bucket: aws.String("testBucket"),
+//		Key:    aws.String("testKey"),
+//		Body:   f,
+//	})
+func NewUploader(c *EncryptionClient, options ...func(*s3manager.Uploader)) *s3manager.Uploader {
+	u := s3manager.NewUploaderWithClient(&uploaderClient{S3API: c.S3Client, c: c}, options...)
+
+	u.Concurrency = 1
+	u.ValidateChecksums = false
+	u.CheckpointStore = nil
+
+	return u
+}
+
+// uploaderClient is the S3 client of an Uploader created by NewUploader,
+// encrypting the objects the Uploader uploads with the EncryptionClient.
+type uploaderClient struct {
+	s3iface.S3API
+	c *EncryptionClient
+
+	m       sync.Mutex
+	uploads map[string]*heldUpload
+}
+
+// heldUpload is the part of a multipart upload held back until it is known
+// if it is the upload's last part, and the ETags of the parts uploaded.
+type heldUpload struct {
+	ctx   aws.Context
+	part  *s3.UploadPartInput
+	body  []byte
+	opts  []request.Option
+	etags map[int64]*string
+}
+
+func (u *uploaderClient) PutObjectRequest(input *s3.PutObjectInput) (*request.Request, *s3.PutObjectOutput) {
+	return u.c.PutObjectRequest(input)
+}
+
+func (u *uploaderClient) CreateMultipartUploadWithContext(ctx aws.Context, input *s3.CreateMultipartUploadInput, opts ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	return u.c.CreateMultipartUploadWithContext(ctx, input, opts...)
+}
+
+// UploadPartWithContext uploads the part held back, which is not the last
+// part, and holds back a copy of the part. The part's ETag is added to the
+// completed parts when the upload is completed.
+func (u *uploaderClient) UploadPartWithContext(ctx aws.Context, input *s3.UploadPartInput, opts ...request.Option) (*s3.UploadPartOutput, error) {
+	held := u.heldUpload(input.UploadId, true)
+	if err := u.uploadHeld(held, false); err != nil {
+		return nil, err
+	}
+
+	// The part's body is only valid until the call returns.
+	b, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	held.ctx, held.part, held.body, held.opts = ctx, input, b, opts
+	return &s3.UploadPartOutput{}, nil
+}
+
+// CompleteMultipartUploadWithContext uploads the part held back as the
+// upload's final part, and completes the upload with the ETags of the parts
+// uploaded.
+func (u *uploaderClient) CompleteMultipartUploadWithContext(ctx aws.Context, input *s3.CompleteMultipartUploadInput, opts ...request.Option) (*s3.CompleteMultipartUploadOutput, error) {
+	if held := u.heldUpload(input.UploadId, false); held != nil {
+		if err := u.uploadHeld(held, true); err != nil {
+			return nil, err
+		}
+
+		in := *input
+		in.MultipartUpload = &s3.CompletedMultipartUpload{}
+		if input.MultipartUpload != nil {
+			for _, p := range input.MultipartUpload.Parts {
+				in.MultipartUpload.Parts = append(in.MultipartUpload.Parts, &s3.CompletedPart{
+					ETag:       held.etags[aws.Int64Value(p.PartNumber)],
+					PartNumber: p.PartNumber,
+				})
+			}
+		}
+		input = &in
+	}
+
+	out, err := u.c.CompleteMultipartUploadWithContext(ctx, input, opts...)
+	if err == nil {
+		u.deleteHeldUpload(input.UploadId)
+	}
+	return out, err
+}
+
+func (u *uploaderClient) AbortMultipartUploadWithContext(ctx aws.Context, input *s3.AbortMultipartUploadInput, opts ...request.Option) (*s3.AbortMultipartUploadOutput, error) {
+	u.deleteHeldUpload(input.UploadId)
+	return u.c.AbortMultipartUploadWithContext(ctx, input, opts...)
+}
+
+// uploadHeld uploads the part held back, if any, with UploadFinalPart if it
+// is the upload's last part. The part remains held back if it fails.
+func (u *uploaderClient) uploadHeld(held *heldUpload, final bool) error {
+	if held.part == nil {
+		return nil
+	}
+
+	upload := u.c.UploadPartWithContext
+	if final {
+		upload = u.c.UploadFinalPartWithContext
+	}
+	part := *held.part
+	part.Body = bytes.NewReader(held.body)
+	out, err := upload(held.ctx, &part, held.opts...)
+	if err != nil {
+		return err
+	}
+
+	held.etags[aws.Int64Value(part.PartNumber)] = out.ETag
+	held.ctx, held.part, held.body, held.opts = nil, nil, nil, nil
+	return nil
+}
+
+func (u *uploaderClient) heldUpload(uploadID *string, create bool) *heldUpload {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	held, ok := u.uploads[aws.StringValue(uploadID)]
+	if !ok && create {
+		held = &heldUpload{etags: map[int64]*string{}}
+		if u.uploads == nil {
+			u.uploads = map[string]*heldUpload{}
+		}
+		u.uploads[aws.StringValue(uploadID)] = held
+	}
+	return held
+}
+
+func (u *uploaderClient) deleteHeldUpload(uploadID *string) {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	delete(u.uploads, aws.StringValue(uploadID))
+}
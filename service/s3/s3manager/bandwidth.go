@@ -0,0 +1,120 @@
+package s3manager
+
+import (
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/internal/sdkratelimit"
+)
+
+// A BandwidthLimiter limits the rate, in bytes per second, data is
+// transferred by Uploaders and Downloaders. A BandwidthLimiter can be shared
+// by multiple Uploaders and Downloaders to limit their combined rate, and is
+// shared by all parts of a transfer.
+//
+// The limit can be adjusted while transfers are in progress with SetLimit.
+// A limit of zero or less disables the limit.
+//
+// It is safe to use a BandwidthLimiter concurrently across goroutines.
+//
+// Example:
+//     // Limit uploads and downloads to a combined 10MB/s.
+//     limiter := s3manager.NewBandwidthLimiter(10 * 1024 * 1024)
+//
+//     uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+//          u.BandwidthLimiter = limiter
+//     })
+//     downloader := s3manager.NewDownloader(sess, func(d *s3manager.Downloader) {
+//          d.BandwidthLimiter = limiter
+//     })
+type BandwidthLimiter struct {
+	bucket *sdkratelimit.TokenBucket
+}
+
+// NewBandwidthLimiter returns a BandwidthLimiter limiting transfers to the
+// number of bytes per second.
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	return &BandwidthLimiter{
+		bucket: sdkratelimit.NewTokenBucket(float64(bytesPerSecond)),
+	}
+}
+
+// Limit returns the limiter's current limit in bytes per second.
+func (l *BandwidthLimiter) Limit() int64 {
+	return int64(l.bucket.Rate())
+}
+
+// SetLimit sets the limiter's limit in bytes per second. The new limit
+// applies to transfers already in progress. A limit of zero or less disables
+// the limit.
+func (l *BandwidthLimiter) SetLimit(bytesPerSecond int64) {
+	l.bucket.SetRate(float64(bytesPerSecond))
+}
+
+// WaitN blocks until n bytes may be transferred, or the context is canceled.
+// Requests for more bytes than the limit are spread over multiple seconds.
+func (l *BandwidthLimiter) WaitN(ctx aws.Context, n int) error {
+	for n > 0 {
+		limit := l.Limit()
+		if limit <= 0 {
+			return nil
+		}
+
+		take := n
+		if int64(take) > limit {
+			take = int(limit)
+		}
+		if err := l.bucket.Wait(ctx, float64(take)); err != nil {
+			return err
+		}
+		n -= take
+	}
+
+	return nil
+}
+
+// limitedReadSeeker limits the rate the reader is read at once enabled. The
+// reader is enabled once the request is sent, so that reading the body to
+// compute its checksums is not limited.
+type limitedReadSeeker struct {
+	io.ReadSeeker
+	ctx     aws.Context
+	limiter *BandwidthLimiter
+
+	m       sync.Mutex
+	enabled bool
+}
+
+func newLimitedReadSeeker(ctx aws.Context, r io.ReadSeeker, l *BandwidthLimiter) *limitedReadSeeker {
+	return &limitedReadSeeker{ReadSeeker: r, ctx: ctx, limiter: l}
+}
+
+// requestOption returns the request option enabling the limit when the
+// request is sent.
+func (r *limitedReadSeeker) requestOption() request.Option {
+	return func(req *request.Request) {
+		req.Handlers.Send.PushFront(func(*request.Request) {
+			r.m.Lock()
+			r.enabled = true
+			r.m.Unlock()
+		})
+	}
+}
+
+// Read reads from the underlying reader, waiting for the bytes read to be
+// allowed by the limiter.
+func (r *limitedReadSeeker) Read(p []byte) (int, error) {
+	r.m.Lock()
+	enabled := r.enabled
+	r.m.Unlock()
+
+	n, err := r.ReadSeeker.Read(p)
+	if enabled && n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,76 @@
+package s3manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// stopBandwidthClock stops the limiter's clock, returning the delays the
+// limiter sleeps for. The timing of the limiter's token bucket is tested by
+// the sdkratelimit package.
+func stopBandwidthClock(l *BandwidthLimiter) *[]time.Duration {
+	var sleeps []time.Duration
+	now := time.Unix(0, 0)
+	l.bucket.Now = func() time.Time { return now }
+	l.bucket.Sleep = func(_ aws.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return &sleeps
+}
+
+func TestBandwidthLimiter_WaitN(t *testing.T) {
+	l := NewBandwidthLimiter(100)
+	sleeps := stopBandwidthClock(l)
+
+	// Bytes are taken from the bucket as tokens, at most the limit at a
+	// time.
+	if err := l.WaitN(aws.BackgroundContext(), 250); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expect := []time.Duration{time.Second, 2 * time.Second, 2500 * time.Millisecond}
+	if e, a := len(expect), len(*sleeps); e != a {
+		t.Fatalf("expect %v sleeps, got %v", e, a)
+	}
+	for i := range expect {
+		if e, a := expect[i], (*sleeps)[i]; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+}
+
+func TestBandwidthLimiter_SetLimit(t *testing.T) {
+	l := NewBandwidthLimiter(100)
+	sleeps := stopBandwidthClock(l)
+
+	l.SetLimit(200)
+	if e, a := int64(200), l.Limit(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if err := l.WaitN(aws.BackgroundContext(), 200); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 1, len(*sleeps); e != a {
+		t.Fatalf("expect %v sleeps, got %v", e, a)
+	}
+	if e, a := time.Second, (*sleeps)[0]; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestBandwidthLimiter_Unlimited(t *testing.T) {
+	for _, limit := range []int64{0, -1} {
+		l := NewBandwidthLimiter(limit)
+		sleeps := stopBandwidthClock(l)
+
+		if err := l.WaitN(aws.BackgroundContext(), 1<<20); err != nil {
+			t.Fatalf("%d, expect no error, got %v", limit, err)
+		}
+		if e, a := 0, len(*sleeps); e != a {
+			t.Errorf("%d, expect %v sleeps, got %v", limit, e, a)
+		}
+	}
+}
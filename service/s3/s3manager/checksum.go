@@ -0,0 +1,166 @@
+package s3manager
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// ChecksumMetadataKey is the object metadata key the Uploader stores the
+	// object's composite checksum with when ValidateChecksums is enabled.
+	//
+	// The composite checksum is the hex encoded MD5 of the concatenated MD5
+	// checksums of each part, followed by a dash and the number of parts,
+	// e.g. "9b2cf535f27731c974343645a3985328-2". This is the same format as
+	// the ETag S3 computes for multipart uploads.
+	ChecksumMetadataKey = "S3manager-Checksum"
+
+	// ChecksumPartSizeMetadataKey is the object metadata key the Uploader
+	// stores the size of the parts the composite checksum was computed with.
+	ChecksumPartSizeMetadataKey = "S3manager-Checksum-Part-Size"
+
+	// ErrCodeChecksumMismatch is the error code of the ChecksumMismatchError
+	// returned when the checksum of an object, or of a part, does not match
+	// the expected checksum.
+	ErrCodeChecksumMismatch = "ChecksumMismatch"
+)
+
+// ChecksumMismatchError is returned by the Uploader and Downloader when
+// ValidateChecksums is enabled and the checksum of the data transferred does
+// not match the expected checksum.
+//
+// Example:
+//     _, err := downloader.Download(f, input)
+//     if cerr, ok := err.(*s3manager.ChecksumMismatchError); ok {
+//         fmt.Println("corrupt download", cerr.Key, cerr.Expected, cerr.Actual)
+//     }
+type ChecksumMismatchError struct {
+	// The bucket and key of the object.
+	Bucket string
+	Key    string
+
+	// The number of the part which did not match, or zero if the checksum of
+	// the whole object did not match.
+	PartNumber int64
+
+	// The expected and actual checksums.
+	Expected string
+	Actual   string
+}
+
+// Code returns the error's code, ErrCodeChecksumMismatch.
+func (e *ChecksumMismatchError) Code() string {
+	return ErrCodeChecksumMismatch
+}
+
+// Message returns the error's message.
+func (e *ChecksumMismatchError) Message() string {
+	target := "object"
+	if e.PartNumber > 0 {
+		target = fmt.Sprintf("part %d", e.PartNumber)
+	}
+	return fmt.Sprintf("%s checksum mismatch for %s/%s, expected %s, got %s",
+		target, e.Bucket, e.Key, e.Expected, e.Actual)
+}
+
+// OrigErr returns nil, the error does not wrap another error.
+func (e *ChecksumMismatchError) OrigErr() error {
+	return nil
+}
+
+// Error returns the string representation of the error.
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code(), e.Message())
+}
+
+// partChecksums collects the MD5 checksums of an object's parts.
+type partChecksums struct {
+	m    sync.Mutex
+	sums map[int64][]byte
+}
+
+func newPartChecksums() *partChecksums {
+	return &partChecksums{sums: map[int64][]byte{}}
+}
+
+func (c *partChecksums) set(num int64, sum []byte) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.sums[num] = sum
+}
+
+func (c *partChecksums) get(num int64) []byte {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.sums[num]
+}
+
+// composite returns the composite checksum of the parts. An empty string is
+// returned if any part's checksum is missing.
+func (c *partChecksums) composite() string {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	h := md5.New()
+	for i := int64(1); i <= int64(len(c.sums)); i++ {
+		sum, ok := c.sums[i]
+		if !ok {
+			return ""
+		}
+		h.Write(sum)
+	}
+
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(h.Sum(nil)), len(c.sums))
+}
+
+func base64MD5(sum []byte) *string {
+	return aws.String(base64.StdEncoding.EncodeToString(sum))
+}
+
+func trimETag(etag *string) string {
+	return strings.Trim(aws.StringValue(etag), `"`)
+}
+
+// etagIsMD5 returns if the ETag of an object or part encrypted with the
+// encryption settings is its MD5 checksum. The ETag of objects encrypted with
+// SSE-C or SSE-KMS is not.
+func etagIsMD5(sseCustomerAlgorithm, serverSideEncryption *string) bool {
+	return sseCustomerAlgorithm == nil &&
+		aws.StringValue(serverSideEncryption) != s3.ServerSideEncryptionAwsKms
+}
+
+// checksumMetadata returns a copy of the metadata with the checksum and part
+// size metadata set. The checksum is omitted if empty.
+func checksumMetadata(md map[string]*string, checksum string, partSize int64) map[string]*string {
+	out := make(map[string]*string, len(md)+2)
+	for k, v := range md {
+		out[k] = v
+	}
+
+	out[ChecksumPartSizeMetadataKey] = aws.String(strconv.FormatInt(partSize, 10))
+	if len(checksum) > 0 {
+		out[ChecksumMetadataKey] = aws.String(checksum)
+	}
+	return out
+}
+
+// metadataValue returns the value of the metadata key, matching the key
+// case insensitively.
+func metadataValue(md map[string]*string, key string) string {
+	for k, v := range md {
+		if strings.EqualFold(k, key) {
+			return aws.StringValue(v)
+		}
+	}
+	return ""
+}
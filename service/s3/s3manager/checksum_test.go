@@ -0,0 +1,474 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+type checksumObject struct {
+	data     []byte
+	metadata map[string]*string
+	etag     string
+}
+
+// checksumStore is an in memory store of objects, serving uploads and
+// downloads with S3's ETag semantics.
+type checksumStore struct {
+	m       sync.Mutex
+	objects map[string]*checksumObject
+	parts   map[int64][]byte
+	meta    map[string]*string
+	names   []string
+	params  []interface{}
+
+	// corruptPart, if set, is the part whose returned ETag is invalid.
+	corruptPart int64
+
+	// corruptObject, if set, invalidates the ETag returned for objects.
+	corruptObject bool
+}
+
+func checksumSvc() (*s3.S3, *checksumStore) {
+	store := &checksumStore{objects: map[string]*checksumObject{}}
+
+	rerng := regexp.MustCompile(`bytes=(\d+)-(\d+)`)
+
+	svc := s3.New(unit.Session)
+	svc.Handlers.Unmarshal.Clear()
+	svc.Handlers.UnmarshalMeta.Clear()
+	svc.Handlers.UnmarshalError.Clear()
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		store.m.Lock()
+		defer store.m.Unlock()
+
+		store.names = append(store.names, r.Operation.Name)
+		store.params = append(store.params, r.Params)
+
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte{})),
+		}
+
+		switch p := r.Params.(type) {
+		case *s3.PutObjectInput:
+			b, _ := ioutil.ReadAll(p.Body)
+			sum := md5.Sum(b)
+			etag := hex.EncodeToString(sum[:])
+			store.objects[*p.Key] = &checksumObject{data: b, metadata: p.Metadata, etag: etag}
+			if store.corruptObject {
+				etag = "corrupt"
+			}
+			r.Data.(*s3.PutObjectOutput).ETag = aws.String(strconv.Quote(etag))
+		case *s3.CreateMultipartUploadInput:
+			store.parts = map[int64][]byte{}
+			store.meta = p.Metadata
+			r.Data.(*s3.CreateMultipartUploadOutput).UploadId = aws.String("UPLOAD-ID")
+		case *s3.UploadPartInput:
+			b, _ := ioutil.ReadAll(p.Body)
+			num := aws.Int64Value(p.PartNumber)
+			store.parts[num] = b
+			sum := md5.Sum(b)
+			etag := hex.EncodeToString(sum[:])
+			if num == store.corruptPart {
+				etag = "corrupt"
+			}
+			r.Data.(*s3.UploadPartOutput).ETag = aws.String(strconv.Quote(etag))
+		case *s3.CompleteMultipartUploadInput:
+			var data []byte
+			h := md5.New()
+			for i := int64(1); i <= int64(len(store.parts)); i++ {
+				data = append(data, store.parts[i]...)
+				sum := md5.Sum(store.parts[i])
+				h.Write(sum[:])
+			}
+			etag := fmt.Sprintf("%s-%d", hex.EncodeToString(h.Sum(nil)), len(store.parts))
+			store.objects[*p.Key] = &checksumObject{data: data, metadata: store.meta, etag: etag}
+			if store.corruptObject {
+				etag = "corrupt"
+			}
+			r.Data.(*s3.CompleteMultipartUploadOutput).ETag = aws.String(strconv.Quote(etag))
+		case *s3.DeleteObjectInput:
+			delete(store.objects, *p.Key)
+		case *s3.HeadObjectInput:
+			o := store.objects[*p.Key]
+			out := r.Data.(*s3.HeadObjectOutput)
+			out.ContentLength = aws.Int64(int64(len(o.data)))
+			out.ETag = aws.String(strconv.Quote(o.etag))
+			out.Metadata = o.metadata
+		case *s3.GetObjectInput:
+			o := store.objects[*p.Key]
+			rng := rerng.FindStringSubmatch(aws.StringValue(p.Range))
+			start, _ := strconv.ParseInt(rng[1], 10, 64)
+			fin, _ := strconv.ParseInt(rng[2], 10, 64)
+			if fin >= int64(len(o.data)) {
+				fin = int64(len(o.data)) - 1
+			}
+			out := r.Data.(*s3.GetObjectOutput)
+			out.Body = ioutil.NopCloser(bytes.NewReader(o.data[start : fin+1]))
+			out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, fin, len(o.data)))
+			out.ETag = aws.String(strconv.Quote(o.etag))
+		}
+	})
+
+	return svc, store
+}
+
+func (s *checksumStore) paramsOf(name string) []interface{} {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var ps []interface{}
+	for i, n := range s.names {
+		if n == name {
+			ps = append(ps, s.params[i])
+		}
+	}
+	return ps
+}
+
+// unseekableReader hides the io.Seeker and io.ReaderAt interfaces of the
+// reader.
+type unseekableReader struct {
+	r *bytes.Reader
+}
+
+func (r unseekableReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func compositeChecksum(b []byte, partSize int) string {
+	h := md5.New()
+	n := 0
+	for start := 0; start < len(b); start += partSize {
+		end := start + partSize
+		if end > len(b) {
+			end = len(b)
+		}
+		sum := md5.Sum(b[start:end])
+		h.Write(sum[:])
+		n++
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(h.Sum(nil)), n)
+}
+
+func TestUploadChecksums(t *testing.T) {
+	data := make([]byte, 1024*1024*12)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	cases := map[string]struct {
+		Body           io.Reader
+		ExpectChecksum string
+	}{
+		"seekable": {
+			Body:           bytes.NewReader(data),
+			ExpectChecksum: compositeChecksum(data, 1024*1024*5),
+		},
+		"unseekable": {
+			Body: unseekableReader{bytes.NewReader(data)},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc, store := checksumSvc()
+
+			u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+				u.ValidateChecksums = true
+			})
+			_, err := u.Upload(&s3manager.UploadInput{
+				Bucket:   aws.String("bucket"),
+				Key:      aws.String("key"),
+				Body:     c.Body,
+				Metadata: map[string]*string{"Foo": aws.String("bar")},
+			})
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			create := store.paramsOf("CreateMultipartUpload")[0].(*s3.CreateMultipartUploadInput)
+			expectMeta := map[string]*string{
+				"Foo":                                 aws.String("bar"),
+				s3manager.ChecksumPartSizeMetadataKey: aws.String("5242880"),
+			}
+			if len(c.ExpectChecksum) > 0 {
+				expectMeta[s3manager.ChecksumMetadataKey] = aws.String(c.ExpectChecksum)
+			}
+			if e, a := expectMeta, create.Metadata; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v metadata, got %v", awsStringMap(e), awsStringMap(a))
+			}
+
+			parts := store.paramsOf("UploadPart")
+			if e, a := 3, len(parts); e != a {
+				t.Fatalf("expect %v parts, got %v", e, a)
+			}
+			for _, p := range parts {
+				in := p.(*s3.UploadPartInput)
+				start := (aws.Int64Value(in.PartNumber) - 1) * 1024 * 1024 * 5
+				end := start + 1024*1024*5
+				if end > int64(len(data)) {
+					end = int64(len(data))
+				}
+				sum := md5.Sum(data[start:end])
+				if e, a := base64.StdEncoding.EncodeToString(sum[:]), aws.StringValue(in.ContentMD5); e != a {
+					t.Errorf("expect %v part %d Content-MD5, got %v", e, aws.Int64Value(in.PartNumber), a)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadChecksums_SinglePart(t *testing.T) {
+	svc, store := checksumSvc()
+
+	u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+		u.ValidateChecksums = true
+	})
+	_, err := u.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   unseekableReader{bytes.NewReader([]byte("hello"))},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	put := store.paramsOf("PutObject")[0].(*s3.PutObjectInput)
+	if e, a := "XUFAKrxLKna5cZ2REBfFkg==", aws.StringValue(put.ContentMD5); e != a {
+		t.Errorf("expect %v Content-MD5, got %v", e, a)
+	}
+	if e, a := compositeChecksum([]byte("hello"), 5), aws.StringValue(put.Metadata[s3manager.ChecksumMetadataKey]); e != a {
+		t.Errorf("expect %v checksum, got %v", e, a)
+	}
+}
+
+func TestUploadChecksums_ETagMismatch(t *testing.T) {
+	svc, store := checksumSvc()
+	store.corruptPart = 2
+
+	u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+		u.ValidateChecksums = true
+		u.Concurrency = 1
+	})
+	_, err := u.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	merr, ok := err.(s3manager.MultiUploadFailure)
+	if !ok {
+		t.Fatalf("expect MultiUploadFailure error, got %T", err)
+	}
+	cerr, ok := merr.OrigErr().(*s3manager.ChecksumMismatchError)
+	if !ok {
+		t.Fatalf("expect ChecksumMismatchError, got %T", merr.OrigErr())
+	}
+	if e, a := int64(2), cerr.PartNumber; e != a {
+		t.Errorf("expect %v part number, got %v", e, a)
+	}
+	if e, a := "corrupt", cerr.Actual; e != a {
+		t.Errorf("expect %v actual checksum, got %v", e, a)
+	}
+	if e, a := 1, len(store.paramsOf("AbortMultipartUpload")); e != a {
+		t.Errorf("expect %v abort calls, got %v", e, a)
+	}
+	if e, a := 0, len(store.paramsOf("CompleteMultipartUpload")); e != a {
+		t.Errorf("expect %v complete calls, got %v", e, a)
+	}
+}
+
+func TestUploadChecksums_ObjectETagMismatch(t *testing.T) {
+	cases := map[string]struct {
+		Body []byte
+	}{
+		"single part": {Body: []byte("hello")},
+		"multipart":   {Body: buf12MB},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc, store := checksumSvc()
+			store.corruptObject = true
+
+			u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+				u.ValidateChecksums = true
+			})
+			_, err := u.Upload(&s3manager.UploadInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String("key"),
+				Body:   bytes.NewReader(c.Body),
+			})
+			if err == nil {
+				t.Fatalf("expect error, got none")
+			}
+
+			cerr, ok := err.(*s3manager.ChecksumMismatchError)
+			if !ok {
+				t.Fatalf("expect ChecksumMismatchError, got %T, %v", err, err)
+			}
+			if e, a := "corrupt", cerr.Actual; e != a {
+				t.Errorf("expect %v actual checksum, got %v", e, a)
+			}
+
+			deletes := store.paramsOf("DeleteObject")
+			if e, a := 1, len(deletes); e != a {
+				t.Fatalf("expect %v delete calls, got %v", e, a)
+			}
+			if e, a := "key", aws.StringValue(deletes[0].(*s3.DeleteObjectInput).Key); e != a {
+				t.Errorf("expect %v key deleted, got %v", e, a)
+			}
+			if _, ok := store.objects["key"]; ok {
+				t.Errorf("expect mismatched object to be deleted")
+			}
+		})
+	}
+}
+
+func TestDownloadChecksums(t *testing.T) {
+	data := make([]byte, 1024*1024*12)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	cases := map[string]struct {
+		Body    io.Reader
+		Corrupt bool
+	}{
+		"seekable upload": {
+			Body: bytes.NewReader(data),
+		},
+		"unseekable upload": {
+			Body: unseekableReader{bytes.NewReader(data)},
+		},
+		"corrupt": {
+			Body:    bytes.NewReader(data),
+			Corrupt: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc, store := checksumSvc()
+
+			u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+				u.ValidateChecksums = true
+			})
+			_, err := u.Upload(&s3manager.UploadInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String("key"),
+				Body:   c.Body,
+			})
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			if c.Corrupt {
+				store.objects["key"].data[1024*1024*7] ^= 0xff
+			}
+
+			d := s3manager.NewDownloaderWithClient(svc, func(d *s3manager.Downloader) {
+				d.ValidateChecksums = true
+				d.PartSize = 1024 * 1024 * 8
+			})
+			w := &aws.WriteAtBuffer{}
+			_, err = d.Download(w, &s3.GetObjectInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String("key"),
+			})
+
+			if c.Corrupt {
+				cerr, ok := err.(*s3manager.ChecksumMismatchError)
+				if !ok {
+					t.Fatalf("expect ChecksumMismatchError, got %T, %v", err, err)
+				}
+				if e, a := s3manager.ErrCodeChecksumMismatch, cerr.Code(); e != a {
+					t.Errorf("expect %v error code, got %v", e, a)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if !bytes.Equal(data, w.Bytes()) {
+				t.Errorf("expect downloaded data to match")
+			}
+
+			// The object is downloaded in the parts it was uploaded in.
+			var ranges []string
+			for _, p := range store.paramsOf("GetObject") {
+				ranges = append(ranges, aws.StringValue(p.(*s3.GetObjectInput).Range))
+			}
+			sort.Strings(ranges)
+			expectRanges := []string{
+				"bytes=0-5242879",
+				"bytes=10485760-15728639",
+				"bytes=5242880-10485759",
+			}
+			if e, a := expectRanges, ranges; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v ranges, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestDownloadStreamChecksums(t *testing.T) {
+	svc, store := checksumSvc()
+
+	u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+		u.ValidateChecksums = true
+	})
+	_, err := u.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	store.objects["key"].data[0] ^= 0xff
+
+	d := s3manager.NewDownloaderWithClient(svc, func(d *s3manager.Downloader) {
+		d.ValidateChecksums = true
+	})
+	var w bytes.Buffer
+	_, err = d.DownloadStream(&w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if _, ok := err.(*s3manager.ChecksumMismatchError); !ok {
+		t.Fatalf("expect ChecksumMismatchError, got %T, %v", err, err)
+	}
+}
+
+func awsStringMap(m map[string]*string) map[string]string {
+	out := map[string]string{}
+	for k, v := range m {
+		out[k] = aws.StringValue(v)
+	}
+	return out
+}
@@ -0,0 +1,593 @@
+package s3manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// MaxCopyPartSize is the maximum size of a part copied with UploadPartCopy,
+// and of an object copied with CopyObject.
+const MaxCopyPartSize int64 = 1024 * 1024 * 1024 * 5
+
+// DefaultCopyPartSize is the default size of the ranges an object is split
+// into when copied with a multipart upload.
+const DefaultCopyPartSize int64 = 1024 * 1024 * 64
+
+// DefaultCopyConcurrency is the default number of goroutines to spin up when
+// using Copy().
+const DefaultCopyConcurrency = 10
+
+// ErrCodeInvalidCopySource is the error code returned when the CopySource of
+// the input cannot be parsed.
+const ErrCodeInvalidCopySource = "InvalidCopySource"
+
+// CopyOutput represents a response from the Copy() call.
+type CopyOutput struct {
+	// The entity tag of the copied object.
+	ETag *string
+
+	// The version of the copied object. Will only be populated if the
+	// destination bucket is versioned.
+	VersionID *string
+
+	// The ID of the multipart upload the object was copied with. Empty if
+	// the object was copied with a single CopyObject request. In the case of
+	// an error the error can be cast to the MultiUploadFailure interface to
+	// extract the upload ID.
+	UploadID string
+}
+
+// WithCopierRequestOptions appends to the Copier's API request options.
+func WithCopierRequestOptions(opts ...request.Option) func(*Copier) {
+	return func(c *Copier) {
+		c.RequestOptions = append(c.RequestOptions, opts...)
+	}
+}
+
+// The Copier structure that calls Copy(). It is safe to call Copy() on this
+// structure for multiple objects and across concurrent goroutines. Mutating
+// the Copier's properties is not safe to be done concurrently.
+type Copier struct {
+	// The size (in bytes) of the ranges an object is split into and copied
+	// with UploadPartCopy. Objects no larger than the part size are copied
+	// with a single CopyObject request. The part size is increased if the
+	// object would be copied in more than MaxUploadParts parts. The minimum
+	// allowed part size is 5MB, and if this value is set to zero, the
+	// DefaultCopyPartSize value will be used.
+	PartSize int64
+
+	// The number of goroutines to spin up in parallel per call to Copy when
+	// copying parts. If this is set to zero, the DefaultCopyConcurrency value
+	// will be used.
+	Concurrency int
+
+	// Setting this value to true will cause the SDK to avoid calling
+	// AbortMultipartUpload on a failure, leaving all successfully copied
+	// parts on S3 for manual recovery.
+	LeavePartsOnError bool
+
+	// MaxUploadParts is the max number of parts an object will be copied
+	// in. Defaults to package const's MaxUploadParts value.
+	MaxUploadParts int
+
+	// The client to use when copying to S3.
+	S3 s3iface.S3API
+
+	// The client used to read the metadata and tags of the source object.
+	// Must be a client for the source bucket's region.
+	//
+	// If nil, and the Copier was created with NewCopier, the source bucket's
+	// region is looked up with GetBucketRegion, and a client for that region
+	// is created. Otherwise the S3 client is used.
+	SourceS3 s3iface.S3API
+
+	// List of request options that will be passed down to individual API
+	// operation requests made by the copier.
+	RequestOptions []request.Option
+
+	sourceClients *copySourceClients
+}
+
+// NewCopier creates a new Copier instance to copy objects within S3. Pass In
+// additional functional options to customize the copier's behavior. Requires a
+// client.ConfigProvider in order to create S3 service clients. The
+// session.Session satisfies the client.ConfigProvider interface.
+//
+// Example:
+//     // The session the S3 Copier will use
+//     sess := session.Must(session.NewSession())
+//
+//     // Create a copier with the session and default options
+//     copier := s3manager.NewCopier(sess)
+//
+//     // Create a copier with the session and custom options
+//     copier := s3manager.NewCopier(sess, func(c *s3manager.Copier) {
+//          c.PartSize = 512 * 1024 * 1024 // 512MB per part
+//     })
+func NewCopier(c client.ConfigProvider, options ...func(*Copier)) *Copier {
+	cp := newCopier(s3.New(c), options...)
+	cp.sourceClients = &copySourceClients{
+		cfgProvider: c,
+		clients:     map[string]s3iface.S3API{},
+	}
+	return cp
+}
+
+// NewCopierWithClient creates a new Copier instance to copy objects within
+// S3. Pass in additional functional options to customize the copier's
+// behavior. Requires a S3 service client to make S3 API calls.
+//
+// Set the Copier's SourceS3 to copy objects from a bucket in a different
+// region than the client's region.
+//
+// Example:
+//     // S3 service client the Copier will use
+//     s3Svc := s3.New(sess)
+//
+//     // Create a copier with S3 client and default options
+//     copier := s3manager.NewCopierWithClient(s3Svc)
+func NewCopierWithClient(svc s3iface.S3API, options ...func(*Copier)) *Copier {
+	return newCopier(svc, options...)
+}
+
+func newCopier(svc s3iface.S3API, options ...func(*Copier)) *Copier {
+	c := &Copier{
+		S3:             svc,
+		PartSize:       DefaultCopyPartSize,
+		Concurrency:    DefaultCopyConcurrency,
+		MaxUploadParts: MaxUploadParts,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// Copy copies an object within S3.
+//
+// See CopyWithContext for more information.
+func (c Copier) Copy(input *s3.CopyObjectInput, options ...func(*Copier)) (*CopyOutput, error) {
+	return c.CopyWithContext(aws.BackgroundContext(), input, options...)
+}
+
+// CopyWithContext copies an object within S3, using a multipart upload to
+// copy ranges of objects larger than the Copier's PartSize concurrently.
+// Objects larger than 5GB, which cannot be copied with CopyObject, can be
+// copied with the Copier.
+//
+// The input's CopySource is the source bucket and key, separated by a slash,
+// and URL encoded. The version of the source object can be selected with the
+// "versionId" query parameter, as for CopyObject.
+//
+// The source object's metadata and tags are copied to the destination object
+// unless the input's MetadataDirective or TaggingDirective is REPLACE, in
+// which case the input's metadata or tags are used. Server side encryption,
+// storage class, ACL, and object lock settings of the destination object are
+// taken from the input. Reading the source object's tags requires the
+// s3:GetObjectTagging permission.
+//
+// Each part is copied with the source object's ETag as the CopySourceIfMatch
+// condition, so that the copy fails if the source object is modified during
+// the copy. If a part fails to copy the multipart upload is aborted, unless
+// the Copier's LeavePartsOnError is set.
+//
+// Additional functional options can be provided to configure the individual
+// copy. These options are copies of the Copier instance Copy is called from.
+// Modifying the options will not impact the original Copier instance.
+//
+// It is safe to call this method concurrently across goroutines.
+//
+// Example:
+//     out, err := copier.CopyWithContext(ctx, &s3.CopyObjectInput{
+//         Bucket:     aws.String("destination-bucket"),
+//         Key:        aws.String("backup/large-object"),
+//         CopySource: aws.String(url.PathEscape("source-bucket/large-object")),
+//     })
+func (c Copier) CopyWithContext(ctx aws.Context, input *s3.CopyObjectInput, options ...func(*Copier)) (*CopyOutput, error) {
+	impl := copier{ctx: ctx, in: input, cfg: c}
+
+	for _, option := range options {
+		option(&impl.cfg)
+	}
+	impl.cfg.RequestOptions = append(impl.cfg.RequestOptions, request.WithAppendUserAgent("S3Manager"))
+
+	return impl.copy()
+}
+
+// copySourceClients caches the clients created for the region of each source
+// bucket.
+type copySourceClients struct {
+	cfgProvider client.ConfigProvider
+
+	m       sync.Mutex
+	clients map[string]s3iface.S3API
+}
+
+// client returns a client for the bucket's region.
+func (c *copySourceClients) client(ctx aws.Context, bucket string, opts ...request.Option) (s3iface.S3API, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if svc, ok := c.clients[bucket]; ok {
+		return svc, nil
+	}
+
+	region, err := GetBucketRegion(ctx, c.cfgProvider, bucket, "", opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := s3.New(c.cfgProvider, &aws.Config{Region: aws.String(region)})
+	c.clients[bucket] = svc
+	return svc, nil
+}
+
+// copySource is the parsed CopySource of a copy.
+type copySource struct {
+	bucket    string
+	key       string
+	versionID *string
+}
+
+// parseCopySource parses the bucket, key and version ID from the URL encoded
+// copy source.
+func parseCopySource(v string) (copySource, error) {
+	var src copySource
+
+	v = strings.TrimPrefix(v, "/")
+	if i := strings.Index(v, "?"); i >= 0 {
+		query, err := url.ParseQuery(v[i+1:])
+		if err != nil {
+			return src, awserr.New(ErrCodeInvalidCopySource, "invalid copy source query", err)
+		}
+		if vid := query.Get("versionId"); len(vid) > 0 {
+			src.versionID = aws.String(vid)
+		}
+		v = v[:i]
+	}
+
+	parts := strings.SplitN(v, "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return src, awserr.New(ErrCodeInvalidCopySource,
+			"copy source must be the bucket and key separated by a slash", nil)
+	}
+
+	var err error
+	if src.bucket, err = url.PathUnescape(parts[0]); err != nil {
+		return src, awserr.New(ErrCodeInvalidCopySource, "invalid copy source bucket", err)
+	}
+	if src.key, err = url.PathUnescape(parts[1]); err != nil {
+		return src, awserr.New(ErrCodeInvalidCopySource, "invalid copy source key", err)
+	}
+
+	return src, nil
+}
+
+// copier is the internal implementation structure of a single Copy call.
+type copier struct {
+	ctx aws.Context
+	cfg Copier
+	in  *s3.CopyObjectInput
+
+	src     copySource
+	srcSvc  s3iface.S3API
+	head    *s3.HeadObjectOutput
+	size    int64
+	partLen int64
+
+	uploadID string
+	m        sync.Mutex
+	parts    []*s3.CompletedPart
+	err      error
+}
+
+// copy performs the copy, using a single CopyObject request for objects no
+// larger than the part size.
+func (c *copier) copy() (*CopyOutput, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+
+	if c.size <= c.partLen && c.size <= MaxCopyPartSize {
+		in := *c.in
+		if in.CopySourceIfMatch == nil {
+			in.CopySourceIfMatch = c.head.ETag
+		}
+		resp, err := c.cfg.S3.CopyObjectWithContext(c.ctx, &in, c.cfg.RequestOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		out := &CopyOutput{VersionID: resp.VersionId}
+		if resp.CopyObjectResult != nil {
+			out.ETag = resp.CopyObjectResult.ETag
+		}
+		return out, nil
+	}
+
+	return c.copyParts()
+}
+
+// init validates the copy's configuration, and reads the source object's
+// metadata.
+func (c *copier) init() error {
+	if c.cfg.Concurrency == 0 {
+		c.cfg.Concurrency = DefaultCopyConcurrency
+	}
+	if c.cfg.PartSize == 0 {
+		c.cfg.PartSize = DefaultCopyPartSize
+	}
+	if c.cfg.MaxUploadParts == 0 {
+		c.cfg.MaxUploadParts = MaxUploadParts
+	}
+	if c.cfg.PartSize < MinUploadPartSize {
+		msg := fmt.Sprintf("part size must be at least %d bytes", MinUploadPartSize)
+		return awserr.New("ConfigError", msg, nil)
+	}
+
+	var err error
+	if c.src, err = parseCopySource(aws.StringValue(c.in.CopySource)); err != nil {
+		return err
+	}
+
+	if c.srcSvc, err = c.sourceClient(); err != nil {
+		return err
+	}
+
+	c.head, err = c.srcSvc.HeadObjectWithContext(c.ctx, &s3.HeadObjectInput{
+		Bucket:               aws.String(c.src.bucket),
+		Key:                  aws.String(c.src.key),
+		VersionId:            c.src.versionID,
+		IfMatch:              c.in.CopySourceIfMatch,
+		IfNoneMatch:          c.in.CopySourceIfNoneMatch,
+		IfModifiedSince:      c.in.CopySourceIfModifiedSince,
+		IfUnmodifiedSince:    c.in.CopySourceIfUnmodifiedSince,
+		SSECustomerAlgorithm: c.in.CopySourceSSECustomerAlgorithm,
+		SSECustomerKey:       c.in.CopySourceSSECustomerKey,
+		SSECustomerKeyMD5:    c.in.CopySourceSSECustomerKeyMD5,
+		RequestPayer:         c.in.RequestPayer,
+	}, c.cfg.RequestOptions...)
+	if err != nil {
+		return err
+	}
+
+	c.size = aws.Int64Value(c.head.ContentLength)
+	c.partLen = c.cfg.PartSize
+	if c.size/c.partLen >= int64(c.cfg.MaxUploadParts) {
+		// Increase the part size so the object is copied in at most
+		// MaxUploadParts parts.
+		c.partLen = (c.size / int64(c.cfg.MaxUploadParts)) + 1
+	}
+	if c.partLen > MaxCopyPartSize {
+		msg := fmt.Sprintf("object of %d bytes cannot be copied in %d parts", c.size, c.cfg.MaxUploadParts)
+		return awserr.New("TotalPartsExceeded", msg, nil)
+	}
+
+	return nil
+}
+
+// sourceClient returns the client for the source bucket's region.
+func (c *copier) sourceClient() (s3iface.S3API, error) {
+	if c.cfg.SourceS3 != nil {
+		return c.cfg.SourceS3, nil
+	}
+	if c.cfg.sourceClients != nil {
+		return c.cfg.sourceClients.client(c.ctx, c.src.bucket, c.cfg.RequestOptions...)
+	}
+	return c.cfg.S3, nil
+}
+
+// copyParts copies the object with a multipart upload, copying ranges of
+// the object concurrently.
+func (c *copier) copyParts() (*CopyOutput, error) {
+	create, err := c.createInput()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.cfg.S3.CreateMultipartUploadWithContext(c.ctx, create, c.cfg.RequestOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c.uploadID = aws.StringValue(resp.UploadId)
+
+	ch := make(chan *s3.UploadPartCopyInput, c.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < c.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for in := range ch {
+				c.copyPart(in)
+			}
+		}()
+	}
+
+	var num int64
+	for start := int64(0); start < c.size && c.getErr() == nil; start += c.partLen {
+		num++
+		end := start + c.partLen - 1
+		if end >= c.size {
+			end = c.size - 1
+		}
+
+		ch <- &s3.UploadPartCopyInput{
+			Bucket:                         c.in.Bucket,
+			Key:                            c.in.Key,
+			UploadId:                       resp.UploadId,
+			PartNumber:                     aws.Int64(num),
+			CopySource:                     c.in.CopySource,
+			CopySourceRange:                aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+			CopySourceIfMatch:              c.head.ETag,
+			CopySourceSSECustomerAlgorithm: c.in.CopySourceSSECustomerAlgorithm,
+			CopySourceSSECustomerKey:       c.in.CopySourceSSECustomerKey,
+			CopySourceSSECustomerKeyMD5:    c.in.CopySourceSSECustomerKeyMD5,
+			SSECustomerAlgorithm:           c.in.SSECustomerAlgorithm,
+			SSECustomerKey:                 c.in.SSECustomerKey,
+			SSECustomerKeyMD5:              c.in.SSECustomerKeyMD5,
+			RequestPayer:                   c.in.RequestPayer,
+		}
+	}
+	close(ch)
+	wg.Wait()
+
+	if err := c.getErr(); err != nil {
+		return nil, c.fail(err)
+	}
+
+	sort.Sort(completedParts(c.parts))
+	complete, err := c.cfg.S3.CompleteMultipartUploadWithContext(c.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          c.in.Bucket,
+		Key:             c.in.Key,
+		UploadId:        resp.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: c.parts},
+		RequestPayer:    c.in.RequestPayer,
+	}, c.cfg.RequestOptions...)
+	if err != nil {
+		return nil, c.fail(err)
+	}
+
+	return &CopyOutput{
+		ETag:      complete.ETag,
+		VersionID: complete.VersionId,
+		UploadID:  c.uploadID,
+	}, nil
+}
+
+// createInput returns the input of the multipart upload, with the metadata
+// and tags of the source object unless they are replaced by the input's.
+func (c *copier) createInput() (*s3.CreateMultipartUploadInput, error) {
+	in := &s3.CreateMultipartUploadInput{
+		Bucket:                    c.in.Bucket,
+		Key:                       c.in.Key,
+		ACL:                       c.in.ACL,
+		GrantFullControl:          c.in.GrantFullControl,
+		GrantRead:                 c.in.GrantRead,
+		GrantReadACP:              c.in.GrantReadACP,
+		GrantWriteACP:             c.in.GrantWriteACP,
+		ObjectLockLegalHoldStatus: c.in.ObjectLockLegalHoldStatus,
+		ObjectLockMode:            c.in.ObjectLockMode,
+		ObjectLockRetainUntilDate: c.in.ObjectLockRetainUntilDate,
+		RequestPayer:              c.in.RequestPayer,
+		SSECustomerAlgorithm:      c.in.SSECustomerAlgorithm,
+		SSECustomerKey:            c.in.SSECustomerKey,
+		SSECustomerKeyMD5:         c.in.SSECustomerKeyMD5,
+		SSEKMSKeyId:               c.in.SSEKMSKeyId,
+		ServerSideEncryption:      c.in.ServerSideEncryption,
+		StorageClass:              c.in.StorageClass,
+		WebsiteRedirectLocation:   c.in.WebsiteRedirectLocation,
+	}
+
+	if aws.StringValue(c.in.MetadataDirective) == s3.MetadataDirectiveReplace {
+		in.CacheControl = c.in.CacheControl
+		in.ContentDisposition = c.in.ContentDisposition
+		in.ContentEncoding = c.in.ContentEncoding
+		in.ContentLanguage = c.in.ContentLanguage
+		in.ContentType = c.in.ContentType
+		in.Expires = c.in.Expires
+		in.Metadata = c.in.Metadata
+	} else {
+		in.CacheControl = c.head.CacheControl
+		in.ContentDisposition = c.head.ContentDisposition
+		in.ContentEncoding = c.head.ContentEncoding
+		in.ContentLanguage = c.head.ContentLanguage
+		in.ContentType = c.head.ContentType
+		in.Metadata = c.head.Metadata
+		if t, err := http.ParseTime(aws.StringValue(c.head.Expires)); err == nil {
+			in.Expires = aws.Time(t)
+		}
+	}
+
+	if aws.StringValue(c.in.TaggingDirective) == s3.TaggingDirectiveReplace {
+		in.Tagging = c.in.Tagging
+	} else {
+		resp, err := c.srcSvc.GetObjectTaggingWithContext(c.ctx, &s3.GetObjectTaggingInput{
+			Bucket:    aws.String(c.src.bucket),
+			Key:       aws.String(c.src.key),
+			VersionId: c.src.versionID,
+		}, c.cfg.RequestOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(resp.TagSet) > 0 {
+			tags := url.Values{}
+			for _, tag := range resp.TagSet {
+				tags.Add(aws.StringValue(tag.Key), aws.StringValue(tag.Value))
+			}
+			in.Tagging = aws.String(tags.Encode())
+		}
+	}
+
+	return in, nil
+}
+
+// copyPart copies an individual range of the object, unless a previous part
+// failed.
+func (c *copier) copyPart(in *s3.UploadPartCopyInput) {
+	if c.getErr() != nil {
+		return
+	}
+
+	resp, err := c.cfg.S3.UploadPartCopyWithContext(c.ctx, in, c.cfg.RequestOptions...)
+	if err != nil {
+		c.setErr(err)
+		return
+	}
+
+	var etag *string
+	if resp.CopyPartResult != nil {
+		etag = resp.CopyPartResult.ETag
+	}
+
+	c.m.Lock()
+	c.parts = append(c.parts, &s3.CompletedPart{ETag: etag, PartNumber: in.PartNumber})
+	c.m.Unlock()
+}
+
+// fail aborts the multipart upload, unless LeavePartsOnError is set, and
+// wraps the error with the upload ID.
+func (c *copier) fail(err error) error {
+	if !c.cfg.LeavePartsOnError {
+		c.cfg.S3.AbortMultipartUploadWithContext(c.ctx, &s3.AbortMultipartUploadInput{
+			Bucket:       c.in.Bucket,
+			Key:          c.in.Key,
+			UploadId:     aws.String(c.uploadID),
+			RequestPayer: c.in.RequestPayer,
+		}, c.cfg.RequestOptions...)
+	}
+
+	return multiUploadError{
+		awsError: awserr.New("MultipartCopy", "copy multipart failed", err),
+		uploadID: c.uploadID,
+	}
+}
+
+func (c *copier) getErr() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.err
+}
+
+func (c *copier) setErr(e error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.err == nil {
+		c.err = e
+	}
+}
@@ -0,0 +1,425 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// copySvc returns a client for a source object of the size, recording the
+// operation and parameters of each request made.
+func copySvc(size int64) (*s3.S3, *[]string, *[]interface{}) {
+	var m sync.Mutex
+	names := []string{}
+	params := []interface{}{}
+
+	svc := s3.New(unit.Session)
+	svc.Handlers.Unmarshal.Clear()
+	svc.Handlers.UnmarshalMeta.Clear()
+	svc.Handlers.UnmarshalError.Clear()
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		names = append(names, r.Operation.Name)
+		params = append(params, r.Params)
+
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte{})),
+		}
+
+		switch data := r.Data.(type) {
+		case *s3.HeadObjectOutput:
+			data.ContentLength = aws.Int64(size)
+			data.ETag = aws.String(`"SOURCE-ETAG"`)
+			data.ContentType = aws.String("text/plain")
+			data.Expires = aws.String("Thu, 01 Dec 1994 16:00:00 GMT")
+			data.Metadata = map[string]*string{"Source": aws.String("meta")}
+		case *s3.GetObjectTaggingOutput:
+			data.TagSet = []*s3.Tag{
+				{Key: aws.String("b"), Value: aws.String("2")},
+				{Key: aws.String("a"), Value: aws.String("1 2")},
+			}
+		case *s3.CopyObjectOutput:
+			data.CopyObjectResult = &s3.CopyObjectResult{ETag: aws.String("COPY-ETAG")}
+			data.VersionId = aws.String("VERSION-ID")
+		case *s3.CreateMultipartUploadOutput:
+			data.UploadId = aws.String("UPLOAD-ID")
+		case *s3.UploadPartCopyOutput:
+			num := aws.Int64Value(r.Params.(*s3.UploadPartCopyInput).PartNumber)
+			data.CopyPartResult = &s3.CopyPartResult{ETag: aws.String(fmt.Sprintf("ETAG%d", num))}
+		case *s3.CompleteMultipartUploadOutput:
+			data.ETag = aws.String("COMPLETE-ETAG")
+			data.VersionId = aws.String("VERSION-ID")
+		}
+	})
+
+	return svc, &names, &params
+}
+
+func copyParams(names []string, params []interface{}, name string) []interface{} {
+	var ps []interface{}
+	for i, n := range names {
+		if n == name {
+			ps = append(ps, params[i])
+		}
+	}
+	return ps
+}
+
+func TestCopy_SingleRequest(t *testing.T) {
+	svc, names, params := copySvc(1024)
+
+	copier := s3manager.NewCopierWithClient(svc)
+	out, err := copier.Copy(&s3.CopyObjectInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("key"),
+		CopySource: aws.String("source/source%20key?versionId=abc"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []string{"HeadObject", "CopyObject"}, *names; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v API calls, got %v", e, a)
+	}
+
+	head := (*params)[0].(*s3.HeadObjectInput)
+	if e, a := "source", aws.StringValue(head.Bucket); e != a {
+		t.Errorf("expect %v bucket, got %v", e, a)
+	}
+	if e, a := "source key", aws.StringValue(head.Key); e != a {
+		t.Errorf("expect %v key, got %v", e, a)
+	}
+	if e, a := "abc", aws.StringValue(head.VersionId); e != a {
+		t.Errorf("expect %v version ID, got %v", e, a)
+	}
+
+	cp := (*params)[1].(*s3.CopyObjectInput)
+	if e, a := `"SOURCE-ETAG"`, aws.StringValue(cp.CopySourceIfMatch); e != a {
+		t.Errorf("expect %v copy source if match, got %v", e, a)
+	}
+
+	if e, a := "COPY-ETAG", aws.StringValue(out.ETag); e != a {
+		t.Errorf("expect %v ETag, got %v", e, a)
+	}
+	if e, a := "", out.UploadID; e != a {
+		t.Errorf("expect no upload ID, got %v", a)
+	}
+}
+
+func TestCopy_Multipart(t *testing.T) {
+	svc, names, params := copySvc(12 * 1024 * 1024)
+
+	copier := s3manager.NewCopierWithClient(svc, func(c *s3manager.Copier) {
+		c.PartSize = s3manager.MinUploadPartSize
+		c.Concurrency = 2
+	})
+	out, err := copier.Copy(&s3.CopyObjectInput{
+		Bucket:               aws.String("bucket"),
+		Key:                  aws.String("key"),
+		CopySource:           aws.String("source/key"),
+		ServerSideEncryption: aws.String(s3.ServerSideEncryptionAwsKms),
+		SSEKMSKeyId:          aws.String("KMS-KEY"),
+		StorageClass:         aws.String(s3.StorageClassStandardIa),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	sorted := append([]string{}, *names...)
+	sort.Strings(sorted)
+	expectNames := []string{
+		"CompleteMultipartUpload",
+		"CreateMultipartUpload",
+		"GetObjectTagging",
+		"HeadObject",
+		"UploadPartCopy",
+		"UploadPartCopy",
+		"UploadPartCopy",
+	}
+	if e, a := expectNames, sorted; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v API calls, got %v", e, a)
+	}
+
+	create := copyParams(*names, *params, "CreateMultipartUpload")[0].(*s3.CreateMultipartUploadInput)
+	if e, a := "a=1+2&b=2", aws.StringValue(create.Tagging); e != a {
+		t.Errorf("expect %v tagging, got %v", e, a)
+	}
+	if e, a := "text/plain", aws.StringValue(create.ContentType); e != a {
+		t.Errorf("expect %v content type, got %v", e, a)
+	}
+	if e, a := "meta", aws.StringValue(create.Metadata["Source"]); e != a {
+		t.Errorf("expect %v metadata, got %v", e, a)
+	}
+	if create.Expires == nil {
+		t.Errorf("expect expires to be copied")
+	}
+	if e, a := "KMS-KEY", aws.StringValue(create.SSEKMSKeyId); e != a {
+		t.Errorf("expect %v KMS key, got %v", e, a)
+	}
+	if e, a := s3.StorageClassStandardIa, aws.StringValue(create.StorageClass); e != a {
+		t.Errorf("expect %v storage class, got %v", e, a)
+	}
+
+	ranges := map[int64]string{}
+	for _, p := range copyParams(*names, *params, "UploadPartCopy") {
+		in := p.(*s3.UploadPartCopyInput)
+		ranges[aws.Int64Value(in.PartNumber)] = aws.StringValue(in.CopySourceRange)
+		if e, a := `"SOURCE-ETAG"`, aws.StringValue(in.CopySourceIfMatch); e != a {
+			t.Errorf("expect %v copy source if match, got %v", e, a)
+		}
+		if e, a := "UPLOAD-ID", aws.StringValue(in.UploadId); e != a {
+			t.Errorf("expect %v upload ID, got %v", e, a)
+		}
+	}
+	expectRanges := map[int64]string{
+		1: "bytes=0-5242879",
+		2: "bytes=5242880-10485759",
+		3: "bytes=10485760-12582911",
+	}
+	if e, a := expectRanges, ranges; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v ranges, got %v", e, a)
+	}
+
+	complete := copyParams(*names, *params, "CompleteMultipartUpload")[0].(*s3.CompleteMultipartUploadInput)
+	for i, p := range complete.MultipartUpload.Parts {
+		if e, a := int64(i+1), aws.Int64Value(p.PartNumber); e != a {
+			t.Errorf("expect %v part number, got %v", e, a)
+		}
+		if e, a := fmt.Sprintf("ETAG%d", i+1), aws.StringValue(p.ETag); e != a {
+			t.Errorf("expect %v part ETag, got %v", e, a)
+		}
+	}
+
+	if e, a := "COMPLETE-ETAG", aws.StringValue(out.ETag); e != a {
+		t.Errorf("expect %v ETag, got %v", e, a)
+	}
+	if e, a := "UPLOAD-ID", out.UploadID; e != a {
+		t.Errorf("expect %v upload ID, got %v", e, a)
+	}
+}
+
+func TestCopy_MultipartReplaceDirectives(t *testing.T) {
+	svc, names, params := copySvc(12 * 1024 * 1024)
+
+	copier := s3manager.NewCopierWithClient(svc, func(c *s3manager.Copier) {
+		c.PartSize = s3manager.MinUploadPartSize
+	})
+	_, err := copier.Copy(&s3.CopyObjectInput{
+		Bucket:            aws.String("bucket"),
+		Key:               aws.String("key"),
+		CopySource:        aws.String("source/key"),
+		MetadataDirective: aws.String(s3.MetadataDirectiveReplace),
+		Metadata:          map[string]*string{"Replaced": aws.String("meta")},
+		ContentType:       aws.String("application/json"),
+		TaggingDirective:  aws.String(s3.TaggingDirectiveReplace),
+		Tagging:           aws.String("c=3"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if ps := copyParams(*names, *params, "GetObjectTagging"); len(ps) != 0 {
+		t.Errorf("expect no GetObjectTagging calls, got %v", len(ps))
+	}
+
+	create := copyParams(*names, *params, "CreateMultipartUpload")[0].(*s3.CreateMultipartUploadInput)
+	if e, a := "c=3", aws.StringValue(create.Tagging); e != a {
+		t.Errorf("expect %v tagging, got %v", e, a)
+	}
+	if e, a := "application/json", aws.StringValue(create.ContentType); e != a {
+		t.Errorf("expect %v content type, got %v", e, a)
+	}
+	expectMeta := map[string]*string{"Replaced": aws.String("meta")}
+	if e, a := expectMeta, create.Metadata; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v metadata, got %v", e, a)
+	}
+}
+
+func TestCopy_MaxUploadParts(t *testing.T) {
+	svc, names, params := copySvc(12 * 1024 * 1024)
+
+	copier := s3manager.NewCopierWithClient(svc, func(c *s3manager.Copier) {
+		c.PartSize = s3manager.MinUploadPartSize
+		c.MaxUploadParts = 2
+	})
+	_, err := copier.Copy(&s3.CopyObjectInput{
+		Bucket:           aws.String("bucket"),
+		Key:              aws.String("key"),
+		CopySource:       aws.String("source/key"),
+		TaggingDirective: aws.String(s3.TaggingDirectiveReplace),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := 2, len(copyParams(*names, *params, "UploadPartCopy")); e != a {
+		t.Errorf("expect %v parts, got %v", e, a)
+	}
+}
+
+func TestCopy_MultipartFailure(t *testing.T) {
+	svc, names, _ := copySvc(12 * 1024 * 1024)
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		if in, ok := r.Params.(*s3.UploadPartCopyInput); ok && aws.Int64Value(in.PartNumber) == 2 {
+			r.HTTPResponse.StatusCode = 400
+		}
+	})
+	svc.Handlers.UnmarshalError.PushBack(func(r *request.Request) {
+		r.Error = awserr.New("PreconditionFailed", "source modified", nil)
+	})
+
+	copier := s3manager.NewCopierWithClient(svc, func(c *s3manager.Copier) {
+		c.PartSize = s3manager.MinUploadPartSize
+		c.Concurrency = 1
+	})
+	_, err := copier.Copy(&s3.CopyObjectInput{
+		Bucket:           aws.String("bucket"),
+		Key:              aws.String("key"),
+		CopySource:       aws.String("source/key"),
+		TaggingDirective: aws.String(s3.TaggingDirectiveReplace),
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	aerr, ok := err.(s3manager.MultiUploadFailure)
+	if !ok {
+		t.Fatalf("expect MultiUploadFailure error, got %T", err)
+	}
+	if e, a := "UPLOAD-ID", aerr.UploadID(); e != a {
+		t.Errorf("expect %v upload ID, got %v", e, a)
+	}
+	if e, a := "PreconditionFailed", aerr.OrigErr().(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v error code, got %v", e, a)
+	}
+
+	expectNames := []string{
+		"HeadObject",
+		"CreateMultipartUpload",
+		"UploadPartCopy",
+		"UploadPartCopy",
+		"AbortMultipartUpload",
+	}
+	if e, a := expectNames, *names; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v API calls, got %v", e, a)
+	}
+}
+
+func TestCopy_SourceClient(t *testing.T) {
+	svc, names, _ := copySvc(1024)
+	srcSvc, srcNames, _ := copySvc(1024)
+
+	copier := s3manager.NewCopierWithClient(svc, func(c *s3manager.Copier) {
+		c.SourceS3 = srcSvc
+	})
+	_, err := copier.Copy(&s3.CopyObjectInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("key"),
+		CopySource: aws.String("source/key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []string{"HeadObject"}, *srcNames; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v source API calls, got %v", e, a)
+	}
+	if e, a := []string{"CopyObject"}, *names; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v API calls, got %v", e, a)
+	}
+}
+
+func TestCopy_InvalidCopySource(t *testing.T) {
+	cases := []string{"", "bucket", "bucket/", "/key", "bucket/%zz"}
+
+	for _, c := range cases {
+		t.Run(c, func(t *testing.T) {
+			svc, names, _ := copySvc(1024)
+
+			copier := s3manager.NewCopierWithClient(svc)
+			_, err := copier.Copy(&s3.CopyObjectInput{
+				Bucket:     aws.String("bucket"),
+				Key:        aws.String("key"),
+				CopySource: aws.String(c),
+			})
+			if err == nil {
+				t.Fatalf("expect error, got none")
+			}
+			if e, a := s3manager.ErrCodeInvalidCopySource, err.(awserr.Error).Code(); e != a {
+				t.Errorf("expect %v error code, got %v", e, a)
+			}
+			if e, a := 0, len(*names); e != a {
+				t.Errorf("expect %v API calls, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestCopy_SourceRegion(t *testing.T) {
+	var m sync.Mutex
+	regions := []string{}
+
+	sess := unit.Session.Copy()
+	sess.Handlers.Send.Clear()
+	sess.Handlers.Send.PushBack(func(r *request.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		regions = append(regions, r.Operation.Name+" "+aws.StringValue(r.Config.Region))
+
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte{})),
+		}
+		switch r.Operation.Name {
+		case "HeadBucket":
+			r.HTTPResponse.Header.Set("X-Amz-Bucket-Region", "eu-west-1")
+		case "HeadObject":
+			r.HTTPResponse.Header.Set("Content-Length", "1024")
+		case "CopyObject":
+			r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(
+				[]byte("<CopyObjectResult><ETag>COPY-ETAG</ETag></CopyObjectResult>")))
+		}
+	})
+
+	copier := s3manager.NewCopier(sess)
+	for i := 0; i < 2; i++ {
+		_, err := copier.Copy(&s3.CopyObjectInput{
+			Bucket:     aws.String("bucket"),
+			Key:        aws.String("key"),
+			CopySource: aws.String("source/key"),
+		})
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+	}
+
+	// The source bucket's region is only looked up once.
+	expectRegions := []string{
+		"HeadBucket mock-region",
+		"HeadObject eu-west-1",
+		"CopyObject mock-region",
+		"HeadObject eu-west-1",
+		"CopyObject mock-region",
+	}
+	if e, a := expectRegions, regions; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v requests, got %v", e, a)
+	}
+}
@@ -1,7 +1,10 @@
 package s3manager
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"strconv"
@@ -50,6 +53,31 @@ type Downloader struct {
 	// List of request options that will be passed down to individual API
 	// operation requests made by the downloader.
 	RequestOptions []request.Option
+
+	// ValidateChecksums enables checksum validation of downloaded objects
+	// uploaded by an Uploader with ValidateChecksums enabled. The object's
+	// metadata is read with HeadObject before the download starts, and the
+	// object is downloaded in parts of the size its checksum was computed
+	// with, overriding PartSize. The MD5 checksum of each part is computed
+	// as it is downloaded, and the object's composite checksum compared with
+	// the checksum stored in its metadata, or its ETag.
+	//
+	// A ChecksumMismatchError is returned if the checksums do not match.
+	// Since the mismatch is only known once all parts have been downloaded,
+	// the data written before the error is returned must be discarded.
+	//
+	// Objects without checksum metadata, and downloads of a Range, are
+	// downloaded without validation.
+	ValidateChecksums bool
+
+	// BandwidthLimiter, if set, limits the rate parts are downloaded at. The
+	// limiter is shared by all parts of a download, and can be shared with
+	// other Uploaders and Downloaders to limit their combined rate.
+	BandwidthLimiter *BandwidthLimiter
+
+	// ProgressListener, if set, is reported the progress of downloads. See
+	// ProgressEvent for the events reported.
+	ProgressListener ProgressListener
 }
 
 // WithDownloaderRequestOptions appends to the Downloader's API request options.
@@ -174,12 +202,21 @@ func (d Downloader) Download(w io.WriterAt, input *s3.GetObjectInput, options ..
 // to perform a single GetObjectInput request for that object's range. This will
 // caused the part size, and concurrency configurations to be ignored.
 func (d Downloader) DownloadWithContext(ctx aws.Context, w io.WriterAt, input *s3.GetObjectInput, options ...func(*Downloader)) (n int64, err error) {
-	impl := downloader{w: w, in: input, cfg: d, ctx: ctx}
+	impl := d.newDownloader(ctx, w, input, options...)
+
+	return impl.download()
+}
+
+// newDownloader returns the internal downloader for the input with the
+// Downloader's options applied.
+func (d Downloader) newDownloader(ctx aws.Context, w io.WriterAt, input *s3.GetObjectInput, options ...func(*Downloader)) *downloader {
+	impl := &downloader{w: w, in: input, cfg: d, ctx: ctx}
 
 	for _, option := range options {
 		option(&impl.cfg)
 	}
 	impl.cfg.RequestOptions = append(impl.cfg.RequestOptions, request.WithAppendUserAgent("S3Manager"))
+	impl.progress = newProgressReporter(impl.cfg.ProgressListener, input.Bucket, input.Key)
 
 	if s, ok := d.S3.(maxRetrier); ok {
 		impl.partBodyMaxRetries = s.MaxRetries()
@@ -194,7 +231,7 @@ func (d Downloader) DownloadWithContext(ctx aws.Context, w io.WriterAt, input *s
 		impl.cfg.PartSize = DefaultDownloadPartSize
 	}
 
-	return impl.download()
+	return impl
 }
 
 // DownloadWithIterator will download a batched amount of objects in S3 and writes them
@@ -234,7 +271,18 @@ func (d Downloader) DownloadWithContext(ctx aws.Context, w io.WriterAt, input *s
 //	if err := svc.DownloadWithIterator(aws.BackgroundContext(), iter); err != nil {
 //		return err
 //	}
-func (d Downloader) DownloadWithIterator(ctx aws.Context, iter BatchDownloadIterator, opts ...func(*Downloader)) error {
+func (d Downloader) DownloadWithIterator(ctx aws.Context, iter BatchDownloadIterator, opts ...func(*Downloader)) (err error) {
+	cfg := d
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if progress := newBatchProgress(cfg.ProgressListener); progress != nil {
+		opts = append(opts[:len(opts):len(opts)], func(d *Downloader) {
+			d.ProgressListener = progress
+		})
+		defer func() { progress.done(err) }()
+	}
+
 	var errs []Error
 	for iter.Next() {
 		object := iter.DownloadObject()
@@ -273,12 +321,28 @@ type downloader struct {
 	written    int64
 	err        error
 
+	// etag is the ETag of the object the first part was downloaded from,
+	// used to ensure all parts are downloaded from the same object if
+	// matchETag is set.
+	etag      string
+	matchETag bool
+
+	// checksums of the downloaded parts, and the object's expected checksum,
+	// if ValidateChecksums is enabled.
+	checksums      *partChecksums
+	expectChecksum string
+
+	progress *progressReporter
+
 	partBodyMaxRetries int
 }
 
 // download performs the implementation of the object download across ranged
 // GETs.
 func (d *downloader) download() (n int64, err error) {
+	d.progress.started()
+	defer func() { d.progress.done(err) }()
+
 	// If range is specified fall back to single download of that range
 	// this enables the functionality of ranged gets with the downloader but
 	// at the cost of no multipart downloads.
@@ -287,6 +351,10 @@ func (d *downloader) download() (n int64, err error) {
 		return d.written, d.err
 	}
 
+	if err := d.initChecksums(); err != nil {
+		return 0, err
+	}
+
 	// Spin off first worker to check additional header information
 	d.getChunk()
 
@@ -330,6 +398,10 @@ func (d *downloader) download() (n int64, err error) {
 		}
 	}
 
+	if d.err == nil && d.checksums != nil {
+		d.err = d.validateChecksum()
+	}
+
 	// Return error
 	return d.written, d.err
 }
@@ -400,22 +472,54 @@ func (d *downloader) downloadChunk(chunk dlchunk) error {
 	// Get the next byte range of data
 	in.Range = aws.String(chunk.ByteRange())
 
+	chunk.ctx = d.ctx
+	chunk.limiter = d.cfg.BandwidthLimiter
+	chunk.progress = d.progress
+
+	opts := d.cfg.RequestOptions
+	if d.progress != nil {
+		opts = append(opts[:len(opts):len(opts)], d.progress.retryOption(chunk.partNumber()))
+	}
+
+	// Ensure the object is not modified between the parts being downloaded.
+	if etag := d.getETag(); d.matchETag && len(etag) != 0 && in.IfMatch == nil && in.VersionId == nil {
+		in.IfMatch = aws.String(etag)
+	}
+
 	var n int64
 	var err error
+	var h hash.Hash
 	for retry := 0; retry <= d.partBodyMaxRetries; retry++ {
 		var resp *s3.GetObjectOutput
-		resp, err = d.cfg.S3.GetObjectWithContext(d.ctx, in, d.cfg.RequestOptions...)
+		if retry == 0 {
+			d.progress.report(PartStartedEvent, chunk.partNumber(), 0, nil)
+		}
+		resp, err = d.cfg.S3.GetObjectWithContext(d.ctx, in, opts...)
 		if err != nil {
 			return err
 		}
 		d.setTotalBytes(resp) // Set total if not yet set.
+		d.setETag(resp)       // Set ETag if not yet set.
+		if len(chunk.withRange) == 0 {
+			d.progress.setTotal(d.getTotalBytes())
+		} else if resp.ContentLength != nil {
+			// The size of a range is the size of the response.
+			d.progress.setTotal(*resp.ContentLength)
+		}
 
-		n, err = io.Copy(&chunk, resp.Body)
+		var dst io.Writer = &chunk
+		if d.checksums != nil && len(chunk.withRange) == 0 {
+			h = md5.New()
+			dst = io.MultiWriter(&chunk, h)
+		}
+
+		n, err = io.Copy(dst, resp.Body)
 		resp.Body.Close()
 		if err == nil {
 			break
 		}
 
+		d.progress.report(PartRetriedEvent, chunk.partNumber(), -chunk.cur, nil)
 		chunk.cur = 0
 		logMessage(d.cfg.S3, aws.LogDebugWithRequestRetries,
 			fmt.Sprintf("DEBUG: object part body download interrupted %s, err, %v, retrying attempt %d",
@@ -424,9 +528,83 @@ func (d *downloader) downloadChunk(chunk dlchunk) error {
 
 	d.incrWritten(n)
 
+	if err == nil && h != nil {
+		d.checksums.set(chunk.partNumber(), h.Sum(nil))
+	}
+	if err == nil {
+		d.progress.report(PartCompletedEvent, chunk.partNumber(), 0, nil)
+	}
+
 	return err
 }
 
+// initChecksums reads the object's checksum metadata if ValidateChecksums is
+// enabled, setting the part size to the size the checksum was computed with.
+func (d *downloader) initChecksums() error {
+	if !d.cfg.ValidateChecksums {
+		return nil
+	}
+
+	resp, err := d.cfg.S3.HeadObjectWithContext(d.ctx, &s3.HeadObjectInput{
+		Bucket:               d.in.Bucket,
+		Key:                  d.in.Key,
+		VersionId:            d.in.VersionId,
+		IfMatch:              d.in.IfMatch,
+		IfNoneMatch:          d.in.IfNoneMatch,
+		IfModifiedSince:      d.in.IfModifiedSince,
+		IfUnmodifiedSince:    d.in.IfUnmodifiedSince,
+		SSECustomerAlgorithm: d.in.SSECustomerAlgorithm,
+		SSECustomerKey:       d.in.SSECustomerKey,
+		SSECustomerKeyMD5:    d.in.SSECustomerKeyMD5,
+		RequestPayer:         d.in.RequestPayer,
+	}, d.cfg.RequestOptions...)
+	if err != nil {
+		return err
+	}
+
+	partSize, _ := strconv.ParseInt(metadataValue(resp.Metadata, ChecksumPartSizeMetadataKey), 10, 64)
+	checksum := metadataValue(resp.Metadata, ChecksumMetadataKey)
+	if len(checksum) == 0 && etagIsMD5(resp.SSECustomerAlgorithm, resp.ServerSideEncryption) {
+		checksum = trimETag(resp.ETag)
+	}
+	if partSize <= 0 || len(checksum) == 0 {
+		logMessage(d.cfg.S3, aws.LogDebug, fmt.Sprintf(
+			"DEBUG: object %s has no checksum metadata, checksum will not be validated",
+			aws.StringValue(d.in.Key)))
+		return nil
+	}
+
+	d.cfg.PartSize = partSize
+	d.expectChecksum = checksum
+	d.checksums = newPartChecksums()
+	// The parts must be downloaded from the object the checksum was read
+	// from.
+	d.etag = aws.StringValue(resp.ETag)
+	d.matchETag = true
+
+	return nil
+}
+
+// validateChecksum compares the composite checksum of the downloaded parts
+// with the object's checksum.
+func (d *downloader) validateChecksum() error {
+	actual := d.checksums.composite()
+	if !strings.Contains(d.expectChecksum, "-") {
+		// The checksum is the ETag of an object uploaded in a single part.
+		actual = hex.EncodeToString(d.checksums.get(1))
+	}
+
+	if actual != d.expectChecksum {
+		return &ChecksumMismatchError{
+			Bucket:   aws.StringValue(d.in.Bucket),
+			Key:      aws.StringValue(d.in.Key),
+			Expected: d.expectChecksum,
+			Actual:   actual,
+		}
+	}
+	return nil
+}
+
 func logMessage(svc s3iface.S3API, level aws.LogLevelType, msg string) {
 	s, ok := svc.(*s3.S3)
 	if !ok {
@@ -491,6 +669,27 @@ func (d *downloader) setTotalBytes(resp *s3.GetObjectOutput) {
 	}
 }
 
+// getETag is a thread-safe getter for the ETag of the object being
+// downloaded.
+func (d *downloader) getETag() string {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	return d.etag
+}
+
+// setETag is a thread-safe setter for the ETag of the object being
+// downloaded. Only the ETag of the first response is used.
+func (d *downloader) setETag(resp *s3.GetObjectOutput) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if len(d.etag) != 0 {
+		return
+	}
+	d.etag = aws.StringValue(resp.ETag)
+}
+
 func (d *downloader) incrWritten(n int64) {
 	d.m.Lock()
 	defer d.m.Unlock()
@@ -526,6 +725,13 @@ type dlchunk struct {
 
 	// specifies the byte range the chunk should be downloaded with.
 	withRange string
+
+	// limits the rate the chunk is written at, if set.
+	ctx     aws.Context
+	limiter *BandwidthLimiter
+
+	// reports the progress of the chunk's writes, if set.
+	progress *progressReporter
 }
 
 // Write wraps io.WriterAt for the dlchunk, writing from the dlchunk's start
@@ -538,12 +744,28 @@ func (c *dlchunk) Write(p []byte) (n int, err error) {
 		return 0, io.EOF
 	}
 
+	if c.limiter != nil {
+		if err := c.limiter.WaitN(c.ctx, len(p)); err != nil {
+			return 0, err
+		}
+	}
+
 	n, err = c.w.WriteAt(p, c.start+c.cur)
 	c.cur += int64(n)
+	c.progress.bytes(c.partNumber(), int64(n))
 
 	return
 }
 
+// partNumber returns the number of the chunk's part, starting at 1.
+func (c *dlchunk) partNumber() int64 {
+	if len(c.withRange) != 0 || c.size == 0 {
+		return 1
+	}
+
+	return c.start/c.size + 1
+}
+
 // ByteRange returns a HTTP Byte-Range header value that should be used by the
 // client to request the chunk's range.
 func (c *dlchunk) ByteRange() string {
@@ -0,0 +1,274 @@
+package s3manager
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// DownloadStream downloads an object in S3 and writes the payload, in order,
+// into w using concurrent GET requests.
+//
+// See DownloadStreamWithContext for more information.
+func (d Downloader) DownloadStream(w io.Writer, input *s3.GetObjectInput, options ...func(*Downloader)) (n int64, err error) {
+	return d.DownloadStreamWithContext(aws.BackgroundContext(), w, input, options...)
+}
+
+// DownloadStreamWithContext downloads an object in S3 and writes the payload,
+// in order, into w using concurrent GET requests. Unlike DownloadWithContext
+// w does not need to be an io.WriterAt, allowing the object to be streamed
+// into a writer such as a decompressor, or an io.Pipe.
+//
+// Parts of the object are downloaded concurrently and buffered in memory
+// until all parts before them have been written to w. The number of parts
+// buffered is limited by the Downloader's Concurrency, so the memory used is
+// roughly (Concurrency + 1) * PartSize bytes.
+//
+// The ETag of the object returned by the first part is used as the If-Match
+// condition of the remaining parts, unless the input's IfMatch or VersionId
+// are set, so that the parts are not downloaded from different versions of
+// an object modified during the download.
+//
+// Additional functional options can be provided to configure the individual
+// download. These options are copies of the Downloader instance the download
+// is called from. Modifying the options will not impact the original
+// Downloader instance.
+//
+// If the GetObjectInput's Range value is provided that will cause the
+// downloader to perform a single GetObjectInput request for that object's
+// range, which will be buffered in memory before being written to w.
+//
+// It is safe to call this method concurrently across goroutines.
+func (d Downloader) DownloadStreamWithContext(ctx aws.Context, w io.Writer, input *s3.GetObjectInput, options ...func(*Downloader)) (n int64, err error) {
+	impl := streamDownloader{
+		downloader: d.newDownloader(ctx, nil, input, options...),
+		w:          w,
+	}
+	impl.matchETag = true
+	impl.bufferPool = sync.Pool{
+		New: func() interface{} { return make([]byte, impl.cfg.PartSize) },
+	}
+
+	return impl.download()
+}
+
+// DownloadStreamReader returns an io.ReadCloser which reads the object's
+// payload downloaded with DownloadStreamWithContext. Any error downloading
+// the object will be returned by the reader's Read method.
+//
+// The reader must be closed once it is no longer needed. Closing the reader
+// before the object has been read will stop the download, though parts being
+// downloaded will be completed.
+//
+// Example:
+//     body := downloader.DownloadStreamReader(ctx, &s3.GetObjectInput{
+//         Bucket: aws.String(bucket),
+//         Key:    aws.String("archive.gz"),
+//     })
+//     defer body.Close()
+//
+//     gz, err := gzip.NewReader(body)
+//     if err != nil {
+//         return err
+//     }
+//     _, err = io.Copy(os.Stdout, gz)
+func (d Downloader) DownloadStreamReader(ctx aws.Context, input *s3.GetObjectInput, options ...func(*Downloader)) io.ReadCloser {
+	r, w := io.Pipe()
+
+	go func() {
+		_, err := d.DownloadStreamWithContext(ctx, w, input, options...)
+		w.CloseWithError(err)
+	}()
+
+	return r
+}
+
+// streamDownloader is the implementation structure used internally by
+// Downloader to download parts concurrently and write them in order.
+type streamDownloader struct {
+	*downloader
+	w io.Writer
+
+	// streamed is the number of bytes written to the stream.
+	streamed int64
+
+	// stopped is set once a chunk fails, or the stream cannot be written
+	// to. No further chunks are written once stopped.
+	stopped bool
+
+	bufferPool sync.Pool
+}
+
+// streamChunk is a part of the object buffered in memory until it can be
+// written to the stream.
+type streamChunk struct {
+	start int64
+	buf   []byte
+	n     int64
+	err   error
+
+	// done is closed once the chunk's part has been downloaded.
+	done chan struct{}
+}
+
+// WriteAt writes p into the chunk's buffer at the object offset off.
+func (c *streamChunk) WriteAt(p []byte, off int64) (n int, err error) {
+	off -= c.start
+	end := off + int64(len(p))
+	if end > int64(len(c.buf)) {
+		buf := make([]byte, end)
+		copy(buf, c.buf)
+		c.buf = buf
+	}
+
+	copy(c.buf[off:], p)
+	if end > c.n {
+		c.n = end
+	}
+
+	return len(p), nil
+}
+
+// download performs the implementation of the object download across ranged
+// GETs, writing the parts to the stream in order.
+func (d *streamDownloader) download() (n int64, err error) {
+	d.progress.started()
+	defer func() { d.progress.done(err) }()
+
+	if rng := aws.StringValue(d.in.Range); len(rng) > 0 {
+		c := d.newChunk(0)
+		if err := d.downloadChunk(dlchunk{w: c, withRange: rng}); err != nil {
+			return 0, err
+		}
+		d.writeChunk(c)
+		return d.streamed, d.getErr()
+	}
+
+	if err := d.initChecksums(); err != nil {
+		return 0, err
+	}
+
+	// Download the first part to determine the object's size.
+	d.getStreamChunk()
+
+	if total := d.getTotalBytes(); total >= 0 {
+		d.downloadParts(total)
+	} else {
+		// The object's size is not known, download parts sequentially
+		// until the range is not satisfiable.
+		for d.getErr() == nil {
+			d.getStreamChunk()
+		}
+
+		e, ok := d.getErr().(awserr.RequestFailure)
+		if ok && e.StatusCode() == http.StatusRequestedRangeNotSatisfiable {
+			d.setErr(nil)
+		}
+	}
+
+	if d.getErr() == nil && d.checksums != nil {
+		d.setErr(d.validateChecksum())
+	}
+
+	return d.streamed, d.getErr()
+}
+
+// downloadParts downloads the remaining parts of the object concurrently,
+// writing them to the stream in order.
+func (d *streamDownloader) downloadParts(total int64) {
+	// The order channel bounds the number of parts buffered waiting to be
+	// written to the stream.
+	order := make(chan *streamChunk, d.cfg.Concurrency)
+	ch := make(chan *streamChunk, d.cfg.Concurrency)
+
+	for i := 0; i < d.cfg.Concurrency; i++ {
+		d.wg.Add(1)
+		go d.downloadStreamPart(ch)
+	}
+
+	go func() {
+		defer close(ch)
+		defer close(order)
+
+		for pos := d.pos; pos < total && d.getErr() == nil; pos += d.cfg.PartSize {
+			c := d.newChunk(pos)
+			order <- c
+			ch <- c
+		}
+	}()
+
+	for c := range order {
+		<-c.done
+		d.writeChunk(c)
+	}
+
+	d.wg.Wait()
+}
+
+// downloadStreamPart is an individual goroutine worker reading from the ch
+// channel and downloading the chunk's part.
+func (d *streamDownloader) downloadStreamPart(ch chan *streamChunk) {
+	defer d.wg.Done()
+
+	for c := range ch {
+		if c.err = d.getErr(); c.err == nil {
+			chunk := dlchunk{w: c, start: c.start, size: d.cfg.PartSize}
+			if c.err = d.downloadChunk(chunk); c.err != nil {
+				d.setErr(c.err)
+			}
+		}
+		close(c.done)
+	}
+}
+
+// getStreamChunk downloads the next part of the object, and writes it to
+// the stream. Not thread safe.
+func (d *streamDownloader) getStreamChunk() {
+	if d.getErr() != nil {
+		return
+	}
+
+	c := d.newChunk(d.pos)
+	d.pos += d.cfg.PartSize
+
+	if c.err = d.downloadChunk(dlchunk{w: c, start: c.start, size: d.cfg.PartSize}); c.err != nil {
+		d.setErr(c.err)
+	}
+	d.writeChunk(c)
+}
+
+// newChunk returns a chunk buffering the part starting at the offset.
+func (d *streamDownloader) newChunk(start int64) *streamChunk {
+	return &streamChunk{
+		start: start,
+		buf:   d.bufferPool.Get().([]byte),
+		done:  make(chan struct{}),
+	}
+}
+
+// writeChunk writes the chunk's buffered part to the stream if the chunk and
+// all chunks before it were downloaded, and returns the chunk's buffer to the
+// pool. Parts downloaded before a failed part are still written so the stream
+// contains all of the object's contiguous payload that could be downloaded.
+func (d *streamDownloader) writeChunk(c *streamChunk) {
+	if c.err != nil {
+		d.stopped = true
+	}
+	if !d.stopped {
+		n, err := d.w.Write(c.buf[:c.n])
+		d.streamed += int64(n)
+		if err != nil {
+			d.stopped = true
+			d.setErr(err)
+		}
+	}
+
+	if int64(len(c.buf)) == d.cfg.PartSize {
+		d.bufferPool.Put(c.buf)
+	}
+	c.buf = nil
+}
@@ -0,0 +1,351 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// dlStreamSvc returns a client serving ranges of data. Earlier ranges are
+// delayed longer than later ranges so that parts complete out of order.
+func dlStreamSvc(data []byte, etag string) (*s3.S3, *[]string, *[]string) {
+	var m sync.Mutex
+	ranges := []string{}
+	ifMatches := []string{}
+
+	svc := s3.New(unit.Session)
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		rerng := regexp.MustCompile(`bytes=(\d+)-(\d+)`)
+		rng := rerng.FindStringSubmatch(r.HTTPRequest.Header.Get("Range"))
+		start, _ := strconv.ParseInt(rng[1], 10, 64)
+		fin, _ := strconv.ParseInt(rng[2], 10, 64)
+		fin++
+
+		if fin > int64(len(data)) {
+			fin = int64(len(data))
+		}
+
+		m.Lock()
+		ranges = append(ranges, rng[0])
+		ifMatches = append(ifMatches, r.HTTPRequest.Header.Get("If-Match"))
+		m.Unlock()
+
+		if start > 0 {
+			time.Sleep(time.Duration(int64(len(data))-start) * 50 * time.Microsecond)
+		}
+
+		bodyBytes := data[start:fin]
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader(bodyBytes)),
+			Header:     http.Header{},
+		}
+		r.HTTPResponse.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d",
+			start, fin-1, len(data)))
+		r.HTTPResponse.Header.Set("Content-Length", fmt.Sprintf("%d", len(bodyBytes)))
+		r.HTTPResponse.Header.Set("ETag", etag)
+	})
+
+	return svc, &ranges, &ifMatches
+}
+
+func streamTestData(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestDownloadStream(t *testing.T) {
+	data := streamTestData(100)
+	s, ranges, ifMatches := dlStreamSvc(data, `"etag"`)
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.Concurrency = 3
+		d.PartSize = 7
+	})
+
+	var w bytes.Buffer
+	n, err := d.DownloadStream(&w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := int64(len(data)), n; e != a {
+		t.Errorf("expect %d bytes written, got %d", e, a)
+	}
+	if e, a := data, w.Bytes(); !bytes.Equal(e, a) {
+		t.Errorf("expect %v bytes, got %v", e, a)
+	}
+	if e, a := 15, len(*ranges); e != a {
+		t.Errorf("expect %v ranges, got %v", e, a)
+	}
+
+	// The first part determines the ETag the remaining parts must match.
+	if e, a := "", (*ifMatches)[0]; e != a {
+		t.Errorf("expect no If-Match for first part, got %v", a)
+	}
+	for i, v := range (*ifMatches)[1:] {
+		if e, a := `"etag"`, v; e != a {
+			t.Errorf("%d, expect %v If-Match, got %v", i+1, e, a)
+		}
+	}
+}
+
+func TestDownloadStream_VersionIDNoIfMatch(t *testing.T) {
+	data := streamTestData(20)
+	s, _, ifMatches := dlStreamSvc(data, `"etag"`)
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.PartSize = 10
+	})
+
+	var w bytes.Buffer
+	_, err := d.DownloadStream(&w, &s3.GetObjectInput{
+		Bucket:    aws.String("bucket"),
+		Key:       aws.String("key"),
+		VersionId: aws.String("version"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []string{"", ""}, *ifMatches; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v If-Match, got %v", e, a)
+	}
+}
+
+func TestDownloadStream_WithRange(t *testing.T) {
+	data := streamTestData(100)
+	s, ranges, _ := dlStreamSvc(data, `"etag"`)
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.PartSize = 7
+	})
+
+	var w bytes.Buffer
+	n, err := d.DownloadStream(&w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Range:  aws.String("bytes=10-49"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := int64(40), n; e != a {
+		t.Errorf("expect %d bytes written, got %d", e, a)
+	}
+	if e, a := data[10:50], w.Bytes(); !bytes.Equal(e, a) {
+		t.Errorf("expect %v bytes, got %v", e, a)
+	}
+	if e, a := []string{"bytes=10-49"}, *ranges; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v ranges, got %v", e, a)
+	}
+}
+
+func TestDownloadStream_Error(t *testing.T) {
+	data := streamTestData(100)
+	s, _, _ := dlStreamSvc(data, `"etag"`)
+	s.Handlers.Send.PushBack(func(r *request.Request) {
+		if r.HTTPRequest.Header.Get("Range") == "bytes=40-49" {
+			r.HTTPResponse.StatusCode = http.StatusPreconditionFailed
+			r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader([]byte{}))
+		}
+	})
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.Concurrency = 2
+		d.PartSize = 10
+	})
+
+	var w bytes.Buffer
+	n, err := d.DownloadStream(&w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := "PreconditionFailed", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v error code, got %v", e, a)
+	}
+
+	// Only the parts before the failed part are written.
+	if e, a := int64(40), n; e != a {
+		t.Errorf("expect %d bytes written, got %d", e, a)
+	}
+	if e, a := data[:40], w.Bytes(); !bytes.Equal(e, a) {
+		t.Errorf("expect %v bytes, got %v", e, a)
+	}
+}
+
+func TestDownloadStream_UnknownLength(t *testing.T) {
+	s, names := dlLoggingSvcContentRangeTotalAny(buf2MB, []int{200, 416})
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.Concurrency = 1
+	})
+
+	var w bytes.Buffer
+	n, err := d.DownloadStream(&w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := int64(len(buf2MB)), n; e != a {
+		t.Errorf("expect %d bytes written, got %d", e, a)
+	}
+	if e, a := []string{"GetObject", "GetObject"}, *names; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v API calls, got %v", e, a)
+	}
+}
+
+func TestDownloadStreamReader(t *testing.T) {
+	data := streamTestData(100)
+	s, _, _ := dlStreamSvc(data, `"etag"`)
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.Concurrency = 3
+		d.PartSize = 7
+	})
+
+	r := d.DownloadStreamReader(aws.BackgroundContext(), &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := data, b; !bytes.Equal(e, a) {
+		t.Errorf("expect %v bytes, got %v", e, a)
+	}
+}
+
+func TestDownloadStreamReader_CloseEarly(t *testing.T) {
+	data := streamTestData(100)
+	s, ranges, _ := dlStreamSvc(data, `"etag"`)
+
+	var m sync.Mutex
+	done := make(chan struct{})
+	s.Handlers.Complete.PushBack(func(r *request.Request) {
+		m.Lock()
+		defer m.Unlock()
+		if r.HTTPRequest.Header.Get("Range") == "bytes=90-99" {
+			close(done)
+		}
+	})
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.Concurrency = 1
+		d.PartSize = 10
+	})
+
+	r := d.DownloadStreamReader(aws.BackgroundContext(), &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+
+	b := make([]byte, 5)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	r.Close()
+
+	// Give the download time to observe the closed reader.
+	select {
+	case <-done:
+		t.Errorf("expect download to stop before the last part")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if n := len(*ranges); n >= 10 {
+		t.Errorf("expect download to stop early, got %v ranges", n)
+	}
+}
+
+func TestDownload_NoIfMatch(t *testing.T) {
+	data := streamTestData(30)
+	s, _, ifMatches := dlStreamSvc(data, `"etag"`)
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.PartSize = 10
+	})
+
+	w := &aws.WriteAtBuffer{}
+	_, err := d.Download(w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	// Only DownloadStream requires the parts to match the first part's ETag.
+	if e, a := []string{"", "", ""}, *ifMatches; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v If-Match, got %v", e, a)
+	}
+}
+
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n += len(p); w.n > 20 {
+		return 0, fmt.Errorf("write failed")
+	}
+	return len(p), nil
+}
+
+func TestDownloadStream_WriterError(t *testing.T) {
+	data := streamTestData(100)
+	s, _, _ := dlStreamSvc(data, `"etag"`)
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.Concurrency = 1
+		d.PartSize = 10
+	})
+
+	n, err := d.DownloadStream(&failingWriter{}, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := "write failed", err.Error(); e != a {
+		t.Errorf("expect %v error, got %v", e, a)
+	}
+	if e, a := int64(20), n; e != a {
+		t.Errorf("expect %d bytes written, got %d", e, a)
+	}
+}
@@ -0,0 +1,390 @@
+package s3manager
+
+import (
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// ProgressEventType is the type of a ProgressEvent.
+type ProgressEventType int
+
+const (
+	// TransferStartedEvent is reported once when a transfer starts.
+	TransferStartedEvent ProgressEventType = iota
+
+	// TransferBytesEvent is reported as a part's bytes are transferred.
+	TransferBytesEvent
+
+	// PartStartedEvent is reported when the transfer of a part starts.
+	PartStartedEvent
+
+	// PartCompletedEvent is reported when a part has been transferred.
+	// Parts of a resumed upload which had already been uploaded are reported
+	// completed with their size as the event's Bytes.
+	PartCompletedEvent
+
+	// PartRetriedEvent is reported when the transfer of a part is retried.
+	// The bytes of the part already transferred are discarded, and reported
+	// as the event's negative Bytes.
+	PartRetriedEvent
+
+	// TransferCompletedEvent is reported once when a transfer completes.
+	TransferCompletedEvent
+
+	// TransferFailedEvent is reported once when a transfer fails. The
+	// event's Err is the error the transfer failed with.
+	TransferFailedEvent
+
+	// BatchCompletedEvent is reported once all objects of an
+	// UploadWithIterator or DownloadWithIterator batch have been
+	// transferred. The event's Err is the batch's error, if any object
+	// failed.
+	BatchCompletedEvent
+)
+
+// String returns the string representation of the event type.
+func (t ProgressEventType) String() string {
+	switch t {
+	case TransferStartedEvent:
+		return "TransferStarted"
+	case TransferBytesEvent:
+		return "TransferBytes"
+	case PartStartedEvent:
+		return "PartStarted"
+	case PartCompletedEvent:
+		return "PartCompleted"
+	case PartRetriedEvent:
+		return "PartRetried"
+	case TransferCompletedEvent:
+		return "TransferCompleted"
+	case TransferFailedEvent:
+		return "TransferFailed"
+	case BatchCompletedEvent:
+		return "BatchCompleted"
+	default:
+		return "Unknown"
+	}
+}
+
+// ProgressEvent is reported to a ProgressListener as an object is
+// transferred by an Uploader or Downloader.
+type ProgressEvent struct {
+	// The type of the event.
+	Type ProgressEventType
+
+	// The bucket and key of the object being transferred.
+	Bucket string
+	Key    string
+
+	// The number of the part the event is for, starting at 1. Zero for
+	// events of the whole transfer.
+	PartNumber int64
+
+	// The number of bytes the event transferred. Negative for
+	// PartRetriedEvents discarding the bytes already transferred of the
+	// part. The sum of the Bytes of a transfer's events is its
+	// TransferredBytes.
+	Bytes int64
+
+	// The number of bytes of the object transferred so far.
+	TransferredBytes int64
+
+	// The size of the object, or -1 if the size is not known yet. The size
+	// of an upload is only known if its Body is an io.Seeker, and the size
+	// of a download once its first part has been downloaded.
+	TotalBytes int64
+
+	// The error the transfer failed with, for TransferFailedEvents and
+	// BatchCompletedEvents.
+	Err error
+
+	// The progress of the UploadWithIterator or DownloadWithIterator batch
+	// the object is transferred in. Nil if the object is not transferred in
+	// a batch.
+	Batch *BatchProgress
+}
+
+// BatchProgress is the progress of an UploadWithIterator or
+// DownloadWithIterator batch.
+type BatchProgress struct {
+	// The number of objects which have completed, or failed, to transfer.
+	ObjectsCompleted int64
+	ObjectsFailed    int64
+
+	// The number of bytes transferred by the batch so far, including the
+	// bytes of the object being transferred.
+	TransferredBytes int64
+}
+
+// A ProgressListener is reported the progress of transfers by the Uploader
+// and Downloader.
+//
+// The events of a transfer are reported one at a time, in the order they
+// occurred, even though parts are transferred concurrently. A listener shared
+// by multiple Uploaders or Downloaders must be safe to call concurrently.
+// Events are queued while the listener is called, so parts do not wait for
+// the listener to return, but the listener is called by one of the
+// transfer's goroutines and should not block. All of a transfer's events are
+// reported before the transfer returns.
+type ProgressListener interface {
+	OnProgress(ProgressEvent)
+}
+
+// ProgressListenerFunc is a function which implements the ProgressListener
+// interface.
+//
+// Example:
+//     uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+//          u.ProgressListener = s3manager.ProgressListenerFunc(func(e s3manager.ProgressEvent) {
+//              if e.Type == s3manager.TransferBytesEvent {
+//                  fmt.Printf("\r%s %d/%d", e.Key, e.TransferredBytes, e.TotalBytes)
+//              }
+//          })
+//     })
+type ProgressListenerFunc func(ProgressEvent)
+
+// OnProgress calls the function with the event.
+func (fn ProgressListenerFunc) OnProgress(e ProgressEvent) {
+	fn(e)
+}
+
+// progressReporter reports the progress of a transfer to its listener. The
+// methods of a nil progressReporter do nothing, so transfers without a
+// listener do not need to check for one.
+//
+// Events are queued, and the listener is called without the lock held by
+// the goroutine which found the reporter idle. The goroutine reports queued
+// events until the queue is empty, so events are reported in order.
+type progressReporter struct {
+	m           sync.Mutex
+	listener    ProgressListener
+	bucket, key string
+	transferred int64
+	total       int64
+
+	queue     []ProgressEvent
+	reporting bool
+	idle      *sync.Cond
+}
+
+func newProgressReporter(l ProgressListener, bucket, key *string) *progressReporter {
+	if l == nil {
+		return nil
+	}
+
+	p := &progressReporter{
+		listener: l,
+		bucket:   aws.StringValue(bucket),
+		key:      aws.StringValue(key),
+		total:    -1,
+	}
+	p.idle = sync.NewCond(&p.m)
+	return p
+}
+
+// setTotal sets the size of the object, if it is not already known.
+func (p *progressReporter) setTotal(n int64) {
+	if p == nil {
+		return
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.total < 0 {
+		p.total = n
+	}
+}
+
+// report queues the event, and reports the queued events to the listener
+// unless another goroutine is already reporting them.
+func (p *progressReporter) report(t ProgressEventType, part, n int64, err error) {
+	if p == nil {
+		return
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.transferred += n
+	p.queue = append(p.queue, ProgressEvent{
+		Type:             t,
+		Bucket:           p.bucket,
+		Key:              p.key,
+		PartNumber:       part,
+		Bytes:            n,
+		TransferredBytes: p.transferred,
+		TotalBytes:       p.total,
+		Err:              err,
+	})
+	if p.reporting {
+		return
+	}
+
+	p.reporting = true
+	for len(p.queue) > 0 {
+		events := p.queue
+		p.queue = nil
+
+		p.m.Unlock()
+		for _, e := range events {
+			p.listener.OnProgress(e)
+		}
+		p.m.Lock()
+	}
+	p.reporting = false
+	p.idle.Broadcast()
+}
+
+// flush waits for the queued events to be reported.
+func (p *progressReporter) flush() {
+	if p == nil {
+		return
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	for p.reporting {
+		p.idle.Wait()
+	}
+}
+
+func (p *progressReporter) started() {
+	p.report(TransferStartedEvent, 0, 0, nil)
+}
+
+// done reports the transfer completed, or failed if err is not nil, and
+// waits for the transfer's events to be reported.
+func (p *progressReporter) done(err error) {
+	if err != nil {
+		p.report(TransferFailedEvent, 0, 0, err)
+	} else {
+		p.report(TransferCompletedEvent, 0, 0, nil)
+	}
+	p.flush()
+}
+
+func (p *progressReporter) bytes(part, n int64) {
+	if n == 0 {
+		return
+	}
+	p.report(TransferBytesEvent, part, n, nil)
+}
+
+// retryOption returns a request option reporting the part retried when the
+// request is retried.
+func (p *progressReporter) retryOption(part int64) request.Option {
+	return func(req *request.Request) {
+		req.Handlers.Send.PushFront(func(r *request.Request) {
+			if r.RetryCount > 0 {
+				p.report(PartRetriedEvent, part, 0, nil)
+			}
+		})
+	}
+}
+
+// progressReadSeeker reports the bytes read from the reader once enabled.
+// Like the limitedReadSeeker the reader is enabled once the request is sent,
+// so reading the body to compute its checksums is not reported.
+type progressReadSeeker struct {
+	io.ReadSeeker
+	progress *progressReporter
+	part     int64
+
+	m       sync.Mutex
+	enabled bool
+	read    int64
+}
+
+func newProgressReadSeeker(r io.ReadSeeker, p *progressReporter, part int64) *progressReadSeeker {
+	return &progressReadSeeker{ReadSeeker: r, progress: p, part: part}
+}
+
+// requestOption returns the request option enabling the reader when the
+// request is sent. When the request is retried the part is reported retried,
+// discarding the bytes read by the previous attempt.
+func (r *progressReadSeeker) requestOption() request.Option {
+	return func(req *request.Request) {
+		req.Handlers.Send.PushFront(func(req *request.Request) {
+			r.m.Lock()
+			defer r.m.Unlock()
+
+			if req.RetryCount > 0 {
+				r.progress.report(PartRetriedEvent, r.part, -r.read, nil)
+				r.read = 0
+			}
+			r.enabled = true
+		})
+	}
+}
+
+// Read reads from the underlying reader, reporting the bytes read.
+func (r *progressReadSeeker) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.enabled && n > 0 {
+		r.read += int64(n)
+		r.progress.bytes(r.part, int64(n))
+	}
+	return n, err
+}
+
+// batchProgress is the ProgressListener of the objects of a batch, adding
+// the batch's progress to the objects' events.
+type batchProgress struct {
+	listener ProgressListener
+	batch    BatchProgress
+
+	// bytes transferred by the batch's completed objects.
+	transferred int64
+}
+
+func newBatchProgress(l ProgressListener) *batchProgress {
+	if l == nil {
+		return nil
+	}
+	return &batchProgress{listener: l}
+}
+
+// OnProgress reports the object's event with the batch's progress. Objects
+// are transferred one at a time, so the events are not reported
+// concurrently.
+func (b *batchProgress) OnProgress(e ProgressEvent) {
+	b.batch.TransferredBytes = b.transferred + e.TransferredBytes
+
+	switch e.Type {
+	case TransferCompletedEvent:
+		b.batch.ObjectsCompleted++
+		b.transferred += e.TransferredBytes
+	case TransferFailedEvent:
+		b.batch.ObjectsFailed++
+		b.transferred += e.TransferredBytes
+	}
+
+	batch := b.batch
+	e.Batch = &batch
+	b.listener.OnProgress(e)
+}
+
+// done reports the batch completed with the batch's error, if any.
+func (b *batchProgress) done(err error) {
+	if b == nil {
+		return
+	}
+
+	batch := b.batch
+	b.listener.OnProgress(ProgressEvent{
+		Type:             BatchCompletedEvent,
+		TransferredBytes: b.transferred,
+		TotalBytes:       -1,
+		Err:              err,
+		Batch:            &batch,
+	})
+}
@@ -0,0 +1,153 @@
+package s3manager
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultProgressRateWindow is the default time window the ProgressRate
+// averages the transfer rate over.
+const DefaultProgressRateWindow = 5 * time.Second
+
+// ProgressRate is a ProgressListener computing the smoothed transfer rate of
+// the transfers it is reported, for displaying transfer rates and estimated
+// times remaining, or detecting stalled transfers.
+//
+// The rate is an exponentially weighted moving average of the rate bytes are
+// transferred at, weighting the rate of the most recent window the most. The
+// rate decays while no bytes are transferred, so a stalled transfer's rate
+// tends to zero.
+//
+// The progress of UploadWithIterator and DownloadWithIterator batches is the
+// progress of the whole batch. A ProgressRate should only be reported the
+// progress of one transfer, or batch, at a time.
+//
+// Example:
+//     rate := s3manager.NewProgressRate(0)
+//     uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+//          u.ProgressListener = s3manager.ProgressListenerFunc(func(e s3manager.ProgressEvent) {
+//              rate.OnProgress(e)
+//              if eta, ok := rate.ETA(e.TotalBytes - e.TransferredBytes); ok {
+//                  fmt.Printf("\r%.0f bytes/s, %v remaining", rate.Rate(), eta)
+//              }
+//          })
+//     })
+type ProgressRate struct {
+	window time.Duration
+
+	m           sync.Mutex
+	rate        float64
+	transferred int64
+	last        time.Time
+	lastBytes   time.Time
+
+	// now is replaced by tests.
+	now func() time.Time
+}
+
+// NewProgressRate returns a ProgressRate averaging the transfer rate over the
+// time window. If the window is zero or less DefaultProgressRateWindow is
+// used.
+func NewProgressRate(window time.Duration) *ProgressRate {
+	if window <= 0 {
+		window = DefaultProgressRateWindow
+	}
+
+	return &ProgressRate{window: window, now: time.Now}
+}
+
+// OnProgress updates the rate with the number of bytes transferred by the
+// event's transfer, or batch.
+func (r *ProgressRate) OnProgress(e ProgressEvent) {
+	transferred := e.TransferredBytes
+	if e.Batch != nil {
+		transferred = e.Batch.TransferredBytes
+	}
+
+	r.Update(transferred)
+}
+
+// Update updates the rate with the total number of bytes transferred so far.
+func (r *ProgressRate) Update(transferred int64) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	now := r.now()
+	if r.last.IsZero() {
+		r.transferred = transferred
+		r.last = now
+		r.lastBytes = now
+		return
+	}
+
+	elapsed := now.Sub(r.last)
+	if elapsed <= 0 {
+		// Bytes transferred within the same instant are averaged by the
+		// next update.
+		return
+	}
+
+	rate := float64(transferred-r.transferred) / elapsed.Seconds()
+	r.rate += r.weight(elapsed) * (rate - r.rate)
+	if transferred > r.transferred {
+		r.lastBytes = now
+	}
+	r.transferred = transferred
+	r.last = now
+}
+
+// weight returns the weight of a rate measured over the elapsed time.
+func (r *ProgressRate) weight(elapsed time.Duration) float64 {
+	return 1 - math.Exp(-float64(elapsed)/float64(r.window))
+}
+
+// Rate returns the smoothed transfer rate in bytes per second.
+func (r *ProgressRate) Rate() float64 {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.currentRate()
+}
+
+// currentRate returns the rate decayed by the time since the last update.
+// Must be called with the lock held.
+func (r *ProgressRate) currentRate() float64 {
+	if r.last.IsZero() {
+		return 0
+	}
+
+	rate := r.rate * (1 - r.weight(r.now().Sub(r.last)))
+	if rate < 0 {
+		return 0
+	}
+	return rate
+}
+
+// ETA returns the estimated time to transfer the remaining bytes at the
+// current rate. False is returned if the time cannot be estimated because
+// the rate is zero, or the number of remaining bytes is not known.
+func (r *ProgressRate) ETA(remaining int64) (time.Duration, bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	rate := r.currentRate()
+	if remaining < 0 || rate <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(float64(remaining) / rate * float64(time.Second)), true
+}
+
+// Idle returns the time since bytes were last transferred, which can be used
+// to detect stalled transfers. Zero is returned if no progress has been
+// reported.
+func (r *ProgressRate) Idle() time.Duration {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.lastBytes.IsZero() {
+		return 0
+	}
+	return r.now().Sub(r.lastBytes)
+}
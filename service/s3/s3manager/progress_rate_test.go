@@ -0,0 +1,67 @@
+package s3manager
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestProgressRate(t *testing.T) {
+	now := time.Unix(0, 0)
+	r := NewProgressRate(time.Second)
+	r.now = func() time.Time { return now }
+
+	if e, a := 0.0, r.Rate(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if _, ok := r.ETA(100); ok {
+		t.Errorf("expect no ETA without a rate")
+	}
+
+	// Transfer at a steady 100 bytes per second.
+	for i := int64(0); i <= 20; i++ {
+		r.OnProgress(ProgressEvent{TransferredBytes: i * 10})
+		now = now.Add(100 * time.Millisecond)
+	}
+	now = now.Add(-100 * time.Millisecond)
+
+	if e, a := 100.0, r.Rate(); math.Abs(e-a) > 15 {
+		t.Errorf("expect rate near %v, got %v", e, a)
+	}
+	eta, ok := r.ETA(1000)
+	if !ok {
+		t.Fatalf("expect ETA")
+	}
+	if e, a := 10*time.Second, eta; a < e-2*time.Second || a > e+2*time.Second {
+		t.Errorf("expect ETA near %v, got %v", e, a)
+	}
+	if _, ok := r.ETA(-1); ok {
+		t.Errorf("expect no ETA for unknown remaining bytes")
+	}
+
+	// Stall, the rate decays.
+	now = now.Add(5 * time.Second)
+	if a := r.Rate(); a > 1 {
+		t.Errorf("expect stalled rate to decay, got %v", a)
+	}
+	if e, a := 5*time.Second, r.Idle(); e != a {
+		t.Errorf("expect %v idle, got %v", e, a)
+	}
+}
+
+func TestProgressRate_Batch(t *testing.T) {
+	now := time.Unix(0, 0)
+	r := NewProgressRate(0)
+	r.now = func() time.Time { return now }
+
+	r.OnProgress(ProgressEvent{TransferredBytes: 50, Batch: &BatchProgress{TransferredBytes: 1000}})
+	now = now.Add(time.Second)
+	r.OnProgress(ProgressEvent{TransferredBytes: 10, Batch: &BatchProgress{TransferredBytes: 2000}})
+
+	if a := r.Rate(); a <= 0 {
+		t.Errorf("expect batch bytes to increase the rate, got %v", a)
+	}
+	if e, a := time.Duration(0), r.Idle(); e != a {
+		t.Errorf("expect %v idle, got %v", e, a)
+	}
+}
@@ -0,0 +1,326 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+type progressEvents []s3manager.ProgressEvent
+
+func (es *progressEvents) OnProgress(e s3manager.ProgressEvent) {
+	*es = append(*es, e)
+}
+
+func (es progressEvents) count(t s3manager.ProgressEventType) int {
+	var n int
+	for _, e := range es {
+		if e.Type == t {
+			n++
+		}
+	}
+	return n
+}
+
+func (es progressEvents) types() []s3manager.ProgressEventType {
+	var ts []s3manager.ProgressEventType
+	for _, e := range es {
+		if e.Type != s3manager.TransferBytesEvent {
+			ts = append(ts, e.Type)
+		}
+	}
+	return ts
+}
+
+// assertProgress asserts the events are of a complete transfer of total
+// bytes, whose events' bytes sum to the bytes transferred.
+func assertProgress(t *testing.T, es progressEvents, total int64, parts int) {
+	if len(es) < 2 {
+		t.Fatalf("expect transfer events, got %v", es)
+	}
+	if e, a := s3manager.TransferStartedEvent, es[0].Type; e != a {
+		t.Errorf("expect first event %v, got %v", e, a)
+	}
+	last := es[len(es)-1]
+	if e, a := s3manager.TransferCompletedEvent, last.Type; e != a {
+		t.Errorf("expect last event %v, got %v, %v", e, a, last.Err)
+	}
+	if e, a := total, last.TransferredBytes; e != a {
+		t.Errorf("expect %v bytes transferred, got %v", e, a)
+	}
+	if e, a := total, last.TotalBytes; e != a {
+		t.Errorf("expect %v total bytes, got %v", e, a)
+	}
+	if e, a := parts, es.count(s3manager.PartStartedEvent); e != a {
+		t.Errorf("expect %v parts started, got %v", e, a)
+	}
+	if e, a := parts, es.count(s3manager.PartCompletedEvent); e != a {
+		t.Errorf("expect %v parts completed, got %v", e, a)
+	}
+
+	var sum int64
+	for _, e := range es {
+		sum += e.Bytes
+		if sum != e.TransferredBytes {
+			t.Fatalf("expect event bytes to sum to %v, got %v", e.TransferredBytes, sum)
+		}
+	}
+}
+
+func TestUploadDownloadProgress(t *testing.T) {
+	cases := map[string]struct {
+		Body  io.Reader
+		Size  int64
+		Parts int
+	}{
+		"single part": {
+			Body:  bytes.NewReader(buf2MB),
+			Size:  int64(len(buf2MB)),
+			Parts: 1,
+		},
+		"multipart": {
+			Body:  bytes.NewReader(buf12MB),
+			Size:  int64(len(buf12MB)),
+			Parts: 3,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc, _ := checksumSvc()
+
+			var uploadEvents progressEvents
+			u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+				u.ProgressListener = &uploadEvents
+			})
+			_, err := u.Upload(&s3manager.UploadInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String("key"),
+				Body:   c.Body,
+			})
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			assertProgress(t, uploadEvents, c.Size, c.Parts)
+			if e, a := "key", uploadEvents[0].Key; e != a {
+				t.Errorf("expect %v key, got %v", e, a)
+			}
+			if e, a := c.Size, uploadEvents[0].TotalBytes; e != a {
+				t.Errorf("expect %v total bytes when started, got %v", e, a)
+			}
+
+			var downloadEvents progressEvents
+			d := s3manager.NewDownloaderWithClient(svc, func(d *s3manager.Downloader) {
+				d.ProgressListener = &downloadEvents
+			})
+			_, err = d.Download(&aws.WriteAtBuffer{}, &s3.GetObjectInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String("key"),
+			})
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			assertProgress(t, downloadEvents, c.Size, c.Parts)
+		})
+	}
+}
+
+func TestUploadProgress_ListenerDoesNotBlockParts(t *testing.T) {
+	svc, store := checksumSvc()
+
+	var events progressEvents
+	var blocked bool
+	listener := s3manager.ProgressListenerFunc(func(e s3manager.ProgressEvent) {
+		// Block the listener on the first part started until the other two
+		// parts are uploaded.
+		if e.Type == s3manager.PartStartedEvent && !blocked {
+			blocked = true
+			deadline := time.Now().Add(5 * time.Second)
+			for len(store.paramsOf("UploadPart")) < 2 {
+				if time.Now().After(deadline) {
+					t.Errorf("expect parts to be uploaded while the listener is called")
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		events.OnProgress(e)
+	})
+
+	u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+		u.ProgressListener = listener
+	})
+	_, err := u.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	assertProgress(t, events, int64(len(buf12MB)), 3)
+}
+
+func TestUploadProgress_PartRetried(t *testing.T) {
+	svc, _ := checksumSvc()
+
+	var failed bool
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		p, ok := r.Params.(*s3.UploadPartInput)
+		if ok && aws.Int64Value(p.PartNumber) == 2 && !failed {
+			// The part's body has been read, fail the attempt.
+			failed = true
+			r.HTTPResponse.StatusCode = 500
+		}
+	})
+
+	var events progressEvents
+	u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+		u.Concurrency = 1
+		u.ProgressListener = &events
+	})
+	_, err := u.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	assertProgress(t, events, int64(len(buf12MB)), 3)
+
+	var retried []s3manager.ProgressEvent
+	for _, e := range events {
+		if e.Type == s3manager.PartRetriedEvent {
+			retried = append(retried, e)
+		}
+	}
+	if e, a := 1, len(retried); e != a {
+		t.Fatalf("expect %v part retried, got %v", e, a)
+	}
+	if e, a := int64(2), retried[0].PartNumber; e != a {
+		t.Errorf("expect part %v retried, got %v", e, a)
+	}
+	if e, a := -s3manager.DefaultUploadPartSize, retried[0].Bytes; e != a {
+		t.Errorf("expect %v bytes discarded, got %v", e, a)
+	}
+}
+
+func TestDownloadProgress_PartBodyRetry(t *testing.T) {
+	s, _ := dlLoggingSvcWithErrReader([]testErrReader{
+		{Buf: []byte("ab"), Len: 3, Err: io.ErrUnexpectedEOF},
+		{Buf: []byte("123"), Len: 3, Err: io.EOF},
+	})
+
+	var events progressEvents
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.Concurrency = 1
+		d.ProgressListener = &events
+	})
+	_, err := d.Download(&aws.WriteAtBuffer{}, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	assertProgress(t, events, 3, 1)
+
+	expect := []s3manager.ProgressEventType{
+		s3manager.TransferStartedEvent,
+		s3manager.PartStartedEvent,
+		s3manager.PartRetriedEvent,
+		s3manager.PartCompletedEvent,
+		s3manager.TransferCompletedEvent,
+	}
+	if e, a := expect, events.types(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	for _, e := range events {
+		if e.Type == s3manager.PartRetriedEvent && e.Bytes != -2 {
+			t.Errorf("expect 2 bytes discarded, got %v", -e.Bytes)
+		}
+	}
+}
+
+func TestUploadWithIteratorProgress(t *testing.T) {
+	svc, _ := checksumSvc()
+
+	var events progressEvents
+	u := s3manager.NewUploaderWithClient(svc)
+	iter := &s3manager.UploadObjectsIterator{
+		Objects: []s3manager.BatchUploadObject{
+			{Object: &s3manager.UploadInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String("one"),
+				Body:   bytes.NewReader([]byte("hello")),
+			}},
+			{Object: &s3manager.UploadInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String("fail"),
+				Body:   &failreader{times: 1},
+			}},
+			{Object: &s3manager.UploadInput{
+				Bucket: aws.String("bucket"),
+				Key:    aws.String("two"),
+				Body:   bytes.NewReader([]byte("world!")),
+			}},
+		},
+	}
+
+	err := u.UploadWithIterator(aws.BackgroundContext(), iter, func(u *s3manager.Uploader) {
+		u.ProgressListener = &events
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	var keys []string
+	for _, e := range events {
+		if e.Type == s3manager.TransferStartedEvent {
+			keys = append(keys, e.Key)
+		}
+	}
+	if e, a := []string{"one", "fail", "two"}, keys; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := 1, events.count(s3manager.TransferFailedEvent); e != a {
+		t.Errorf("expect %v transfer failed, got %v", e, a)
+	}
+
+	last := events[len(events)-1]
+	if e, a := s3manager.BatchCompletedEvent, last.Type; e != a {
+		t.Fatalf("expect %v, got %v", e, a)
+	}
+	if last.Err == nil {
+		t.Errorf("expect batch error, got none")
+	}
+	expect := s3manager.BatchProgress{
+		ObjectsCompleted: 2,
+		ObjectsFailed:    1,
+		TransferredBytes: 11,
+	}
+	if e, a := expect, *last.Batch; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	for _, e := range events {
+		if e.Batch == nil {
+			t.Fatalf("expect batch progress, got none for %v", e.Type)
+		}
+		if e.Key == "two" && e.Type == s3manager.TransferCompletedEvent {
+			if e, a := int64(11), e.Batch.TransferredBytes; e != a {
+				t.Errorf("expect %v batch bytes, got %v", e, a)
+			}
+		}
+	}
+}
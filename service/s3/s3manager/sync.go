@@ -0,0 +1,776 @@
+package s3manager
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// DefaultSyncConcurrency is the default number of objects the Syncer will
+// transfer or delete concurrently.
+const DefaultSyncConcurrency = 5
+
+// ErrCodeSyncIncomplete is the error code of the BatchError returned when
+// one or more objects failed to be synced.
+const ErrCodeSyncIncomplete = "SyncIncomplete"
+
+// SyncDirection is the direction a directory and bucket prefix are synced in.
+type SyncDirection int
+
+const (
+	// SyncUpload syncs the local directory to the bucket prefix, uploading
+	// files which differ from the objects in the bucket.
+	SyncUpload SyncDirection = iota
+
+	// SyncDownload syncs the bucket prefix to the local directory,
+	// downloading objects which differ from the files in the directory.
+	SyncDownload
+)
+
+// SyncCompareMode is the method used to determine if a file and an object
+// differ.
+type SyncCompareMode int
+
+const (
+	// SyncCompareSizeAndModTime compares the size of the file and object, and
+	// the file's modification time with the object's last modified time.
+	// Files and objects differ if their sizes differ, or if the source was
+	// modified after the destination.
+	SyncCompareSizeAndModTime SyncCompareMode = iota
+
+	// SyncCompareETag compares the MD5 checksum of the file with the ETag of
+	// the object. Multipart objects are compared with the ETag the file would
+	// have if uploaded with the Syncer's Uploader PartSize.
+	//
+	// The ETag of objects encrypted with SSE-C or SSE-KMS is not the MD5
+	// checksum of the object, and will always be considered different.
+	SyncCompareETag
+)
+
+// SyncAction is the action taken to sync an individual object.
+type SyncAction string
+
+const (
+	// SyncActionUpload is the action of uploading a file to the bucket.
+	SyncActionUpload SyncAction = "upload"
+
+	// SyncActionDownload is the action of downloading an object to the local
+	// directory.
+	SyncActionDownload SyncAction = "download"
+
+	// SyncActionDelete is the action of deleting an object or file which does
+	// not exist in the source.
+	SyncActionDelete SyncAction = "delete"
+
+	// SyncActionSkip is the action of skipping an object which does not
+	// differ between the source and destination.
+	SyncActionSkip SyncAction = "skip"
+)
+
+// SyncInput provides the parameters of a directory sync.
+type SyncInput struct {
+	// The bucket to sync with.
+	//
+	// Bucket is a required field
+	Bucket *string
+
+	// The key prefix within the bucket to sync with. Object keys are the
+	// prefix followed by the file's path relative to LocalDir, using forward
+	// slashes. A trailing slash is added to a non-empty prefix if missing.
+	Prefix *string
+
+	// The local directory to sync with.
+	//
+	// LocalDir is a required field
+	LocalDir string
+
+	// The direction to sync in. Defaults to SyncUpload.
+	Direction SyncDirection
+
+	// The method used to compare files and objects. Defaults to
+	// SyncCompareSizeAndModTime.
+	Compare SyncCompareMode
+
+	// Delete objects or files in the destination which do not exist in the
+	// source.
+	Delete bool
+
+	// Glob patterns, as accepted by path.Match, of the files and objects to
+	// sync. If set, only files and objects matching at least one pattern are
+	// synced. Patterns are matched against both the path relative to
+	// LocalDir, with forward slashes, and the path's base name.
+	Include []string
+
+	// Glob patterns of the files and objects to exclude from the sync. Files
+	// and objects matching any pattern are neither transferred nor deleted.
+	// Exclude patterns take precedence over Include patterns.
+	Exclude []string
+
+	// Report the actions the sync would take without transferring or
+	// deleting any objects or files.
+	DryRun bool
+
+	// Results, if set, receives the result of each object synced, including
+	// skipped objects. Results must be read while the sync is in progress,
+	// and is closed by the Syncer once the sync completes.
+	Results chan<- SyncResult
+}
+
+// SyncResult is the result of syncing an individual object.
+type SyncResult struct {
+	// The action taken, or that would be taken for a dry run.
+	Action SyncAction
+
+	// The key of the object.
+	Key string
+
+	// The path of the local file.
+	Path string
+
+	// The size, in bytes, of the file or object transferred.
+	Size int64
+
+	// The error syncing the object, nil if the action succeeded.
+	Err error
+}
+
+// SyncOutput is the summary of a directory sync.
+type SyncOutput struct {
+	// The number of files uploaded.
+	Uploaded int
+
+	// The number of objects downloaded.
+	Downloaded int
+
+	// The number of objects or files deleted.
+	Deleted int
+
+	// The number of objects which did not differ.
+	Skipped int
+
+	// The number of objects which failed to sync.
+	Failed int
+}
+
+// The Syncer structure that calls Sync. It is safe to call Sync on this
+// structure for multiple objects and across concurrent goroutines. Mutating
+// the Syncer's properties is not safe to be done concurrently.
+type Syncer struct {
+	// The number of objects to transfer or delete concurrently. Each
+	// transfer may itself use multiple goroutines as configured by the
+	// Uploader and Downloader.
+	Concurrency int
+
+	// The client used to list and delete objects.
+	S3 s3iface.S3API
+
+	// The Uploader used to upload files.
+	Uploader *Uploader
+
+	// The Downloader used to download objects.
+	Downloader *Downloader
+
+	// List of request options that will be passed down to individual API
+	// operation requests made by the Syncer.
+	RequestOptions []request.Option
+}
+
+// NewSyncer creates a new Syncer instance to sync local directories with
+// bucket prefixes. Pass in additional functional options to customize the
+// syncer behavior.
+//
+// Example:
+//     // The session the S3 Syncer will use
+//     sess := session.Must(session.NewSession())
+//
+//     // Create a syncer with the session and default options
+//     syncer := s3manager.NewSyncer(sess)
+//
+//     // Create a syncer with the session and custom options
+//     syncer := s3manager.NewSyncer(sess, func(s *s3manager.Syncer) {
+//          s.Concurrency = 10
+//     })
+func NewSyncer(c client.ConfigProvider, options ...func(*Syncer)) *Syncer {
+	return NewSyncerWithClient(s3.New(c), options...)
+}
+
+// NewSyncerWithClient creates a new Syncer instance to sync local
+// directories with bucket prefixes using the S3 service client provided.
+// Pass in additional functional options to customize the syncer behavior.
+//
+// Example:
+//     // The session the S3 Syncer will use
+//     sess := session.Must(session.NewSession())
+//
+//     // S3 service client the Syncer will use
+//     s3Svc := s3.New(sess)
+//
+//     // Create a syncer with the s3 service client and default options
+//     syncer := s3manager.NewSyncerWithClient(s3Svc)
+func NewSyncerWithClient(svc s3iface.S3API, options ...func(*Syncer)) *Syncer {
+	s := &Syncer{
+		Concurrency: DefaultSyncConcurrency,
+		S3:          svc,
+		Uploader:    NewUploaderWithClient(svc),
+		Downloader:  NewDownloaderWithClient(svc),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// Sync syncs a local directory with a bucket prefix.
+//
+// See SyncWithContext for more information.
+func (s Syncer) Sync(input *SyncInput, options ...func(*Syncer)) (*SyncOutput, error) {
+	return s.SyncWithContext(aws.BackgroundContext(), input, options...)
+}
+
+// SyncWithContext syncs a local directory with a bucket prefix. The local
+// directory is walked and the bucket prefix listed, then files and objects
+// which differ are uploaded or downloaded, depending on the input's
+// Direction. Only the differences are transferred, with up to Concurrency
+// transfers in progress at a time.
+//
+// If one or more objects fail to sync the remaining objects will still be
+// synced, and a BatchError with the code ErrCodeSyncIncomplete is returned
+// containing the error of each object which failed.
+//
+// Downloaded files have their modification time set to the object's last
+// modified time, so that subsequent syncs comparing by size and modification
+// time skip the file.
+//
+// The context must be non-nil and will be used for request cancellation. If
+// the context is nil a panic will occur. In the future the SDK may create
+// sub-contexts for http.Requests. See https://golang.org/pkg/context/
+// for more information on using Contexts.
+//
+// Example:
+//     results := make(chan s3manager.SyncResult)
+//     go func() {
+//         for r := range results {
+//             fmt.Println(r.Action, r.Key, r.Err)
+//         }
+//     }()
+//
+//     out, err := syncer.SyncWithContext(ctx, &s3manager.SyncInput{
+//         Bucket:   aws.String("bucket"),
+//         Prefix:   aws.String("site/"),
+//         LocalDir: "public",
+//         Delete:   true,
+//         Exclude:  []string{"*.tmp"},
+//         Results:  results,
+//     })
+func (s Syncer) SyncWithContext(ctx aws.Context, input *SyncInput, options ...func(*Syncer)) (*SyncOutput, error) {
+	if input.Results != nil {
+		defer close(input.Results)
+	}
+
+	for _, option := range options {
+		option(&s)
+	}
+	if s.Concurrency == 0 {
+		s.Concurrency = DefaultSyncConcurrency
+	}
+
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	impl := syncer{ctx: ctx, cfg: s, in: input, prefix: syncPrefix(input.Prefix)}
+	return impl.sync()
+}
+
+func (in *SyncInput) validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "SyncInput"}
+	if in.Bucket == nil || len(*in.Bucket) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if len(in.LocalDir) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("LocalDir"))
+	}
+	for _, p := range append(append([]string{}, in.Include...), in.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			invalidParams.Add(request.NewErrParamFormat("Include/Exclude", err.Error(), p))
+		}
+	}
+
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func syncPrefix(prefix *string) string {
+	p := aws.StringValue(prefix)
+	if len(p) > 0 && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p
+}
+
+// syncFile is a file within the local directory.
+type syncFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// syncTask is an individual action to sync an object.
+type syncTask struct {
+	action SyncAction
+	key    string
+	file   *syncFile
+	object *s3.Object
+}
+
+// syncer is the implementation structure used internally by Syncer.
+type syncer struct {
+	ctx    aws.Context
+	cfg    Syncer
+	in     *SyncInput
+	prefix string
+
+	m    sync.Mutex
+	out  SyncOutput
+	errs []Error
+}
+
+// sync performs the implementation of the directory sync.
+func (s *syncer) sync() (*SyncOutput, error) {
+	files, err := s.localFiles()
+	if err != nil {
+		return nil, awserr.New("ReadLocalDir", "failed to read local directory", err)
+	}
+
+	objects, err := s.remoteObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.plan(files, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	var deletes []syncTask
+	ch := make(chan syncTask, s.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < s.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range ch {
+				s.run(t)
+			}
+		}()
+	}
+
+	for _, t := range tasks {
+		if t.action == SyncActionDelete && s.in.Direction == SyncUpload && !s.in.DryRun {
+			// Objects are deleted in batches once the transfers complete.
+			deletes = append(deletes, t)
+			continue
+		}
+		ch <- t
+	}
+	close(ch)
+	wg.Wait()
+
+	if len(deletes) > 0 {
+		s.deleteObjects(deletes)
+	}
+
+	out := s.out
+	if len(s.errs) > 0 {
+		return &out, NewBatchError(ErrCodeSyncIncomplete, "some objects have failed to sync.", s.errs)
+	}
+	return &out, nil
+}
+
+// localFiles walks the local directory returning the files to sync by their
+// object key.
+func (s *syncer) localFiles() (map[string]*syncFile, error) {
+	files := map[string]*syncFile{}
+
+	if _, err := os.Stat(s.in.LocalDir); os.IsNotExist(err) && s.in.Direction == SyncDownload {
+		return files, nil
+	}
+
+	err := filepath.Walk(s.in.LocalDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.in.LocalDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !s.match(rel) {
+			return nil
+		}
+
+		files[s.prefix+rel] = &syncFile{
+			path:    p,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// remoteObjects lists the bucket prefix returning the objects to sync by
+// their key.
+func (s *syncer) remoteObjects() (map[string]*s3.Object, error) {
+	objects := map[string]*s3.Object{}
+
+	input := &s3.ListObjectsV2Input{Bucket: s.in.Bucket}
+	if len(s.prefix) > 0 {
+		input.Prefix = aws.String(s.prefix)
+	}
+
+	err := s.cfg.S3.ListObjectsV2PagesWithContext(s.ctx, input,
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, o := range page.Contents {
+				key := aws.StringValue(o.Key)
+				rel := strings.TrimPrefix(key, s.prefix)
+				// Skip folder placeholder objects, and keys which cannot
+				// be written to a file within the local directory.
+				if len(rel) == 0 || strings.HasSuffix(rel, "/") || !validSyncPath(rel) {
+					continue
+				}
+				if !s.match(rel) {
+					continue
+				}
+				objects[key] = o
+			}
+			return true
+		}, s.cfg.RequestOptions...)
+
+	return objects, err
+}
+
+// validSyncPath returns if the relative path of an object is contained
+// within the local directory.
+func validSyncPath(rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns if the relative path should be synced based on the input's
+// include and exclude patterns.
+func (s *syncer) match(rel string) bool {
+	for _, p := range s.in.Exclude {
+		if matchSyncPattern(p, rel) {
+			return false
+		}
+	}
+
+	if len(s.in.Include) == 0 {
+		return true
+	}
+	for _, p := range s.in.Include {
+		if matchSyncPattern(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSyncPattern(pattern, rel string) bool {
+	if ok, _ := path.Match(pattern, rel); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, path.Base(rel))
+	return ok
+}
+
+// plan compares the local files and remote objects returning the tasks to
+// sync them, sorted by key.
+func (s *syncer) plan(files map[string]*syncFile, objects map[string]*s3.Object) ([]syncTask, error) {
+	keys := make([]string, 0, len(files)+len(objects))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	for k := range objects {
+		if _, ok := files[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	tasks := make([]syncTask, 0, len(keys))
+	for _, k := range keys {
+		t := syncTask{key: k, file: files[k], object: objects[k]}
+
+		switch {
+		case t.file != nil && t.object != nil:
+			differ, err := s.differ(t.file, t.object)
+			if err != nil {
+				return nil, awserr.New("ReadLocalFile", "failed to read local file", err)
+			}
+			switch {
+			case !differ:
+				t.action = SyncActionSkip
+			case s.in.Direction == SyncUpload:
+				t.action = SyncActionUpload
+			default:
+				t.action = SyncActionDownload
+			}
+		case t.file != nil:
+			if s.in.Direction == SyncUpload {
+				t.action = SyncActionUpload
+			} else if s.in.Delete {
+				t.action = SyncActionDelete
+			} else {
+				continue
+			}
+		default:
+			if s.in.Direction == SyncDownload {
+				t.action = SyncActionDownload
+			} else if s.in.Delete {
+				t.action = SyncActionDelete
+			} else {
+				continue
+			}
+		}
+
+		tasks = append(tasks, t)
+	}
+
+	return tasks, nil
+}
+
+// differ returns if the file and object differ based on the input's compare
+// mode.
+func (s *syncer) differ(f *syncFile, o *s3.Object) (bool, error) {
+	if f.size != aws.Int64Value(o.Size) {
+		return true, nil
+	}
+
+	if s.in.Compare == SyncCompareETag {
+		etag, err := fileETag(f.path, aws.StringValue(o.ETag), s.cfg.Uploader.PartSize)
+		if err != nil {
+			return false, err
+		}
+		return etag != strings.Trim(aws.StringValue(o.ETag), `"`), nil
+	}
+
+	modified := aws.TimeValue(o.LastModified)
+	if s.in.Direction == SyncUpload {
+		return f.modTime.After(modified), nil
+	}
+	return modified.After(f.modTime), nil
+}
+
+// fileETag returns the ETag S3 would compute for the file. If the object's
+// ETag is of a multipart upload the ETag is computed with the part size.
+func fileETag(p, objectETag string, partSize int64) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if !strings.Contains(objectETag, "-") {
+		h := md5.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	if partSize < MinUploadPartSize {
+		partSize = DefaultUploadPartSize
+	}
+
+	sums := md5.New()
+	var parts int
+	for {
+		h := md5.New()
+		n, err := io.CopyN(h, f, partSize)
+		if n > 0 {
+			sums.Write(h.Sum(nil))
+			parts++
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sums.Sum(nil)), parts), nil
+}
+
+// run performs the sync task, reporting its result.
+func (s *syncer) run(t syncTask) {
+	r := SyncResult{Action: t.action, Key: t.key}
+	if t.file != nil {
+		r.Path = t.file.path
+		r.Size = t.file.size
+	} else {
+		r.Path = s.localPath(t.key)
+	}
+	if t.action == SyncActionDownload {
+		r.Size = aws.Int64Value(t.object.Size)
+	}
+
+	if !s.in.DryRun && s.ctx.Err() == nil {
+		switch t.action {
+		case SyncActionUpload:
+			r.Err = s.upload(t)
+		case SyncActionDownload:
+			r.Err = s.download(t, r.Path)
+		case SyncActionDelete:
+			r.Err = os.Remove(r.Path)
+		}
+	} else if !s.in.DryRun {
+		r.Err = s.ctx.Err()
+	}
+
+	s.report(r)
+}
+
+func (s *syncer) localPath(key string) string {
+	return filepath.Join(s.in.LocalDir, filepath.FromSlash(strings.TrimPrefix(key, s.prefix)))
+}
+
+func (s *syncer) upload(t syncTask) error {
+	f, err := os.Open(t.file.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = s.cfg.Uploader.UploadWithContext(s.ctx, &UploadInput{
+		Bucket: s.in.Bucket,
+		Key:    aws.String(t.key),
+		Body:   f,
+	}, WithUploaderRequestOptions(s.cfg.RequestOptions...))
+	return err
+}
+
+// download downloads the object to a temporary file within the destination
+// directory, renaming it to the destination once complete so that a failed
+// download does not leave a partially written file.
+func (s *syncer) download(t syncTask, p string) error {
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, ".sync")
+	if err != nil {
+		return err
+	}
+
+	_, err = s.cfg.Downloader.DownloadWithContext(s.ctx, f, &s3.GetObjectInput{
+		Bucket:  s.in.Bucket,
+		Key:     aws.String(t.key),
+		IfMatch: t.object.ETag,
+	}, WithDownloaderRequestOptions(s.cfg.RequestOptions...))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	if t.object.LastModified != nil {
+		return os.Chtimes(p, *t.object.LastModified, *t.object.LastModified)
+	}
+	return nil
+}
+
+// deleteObjects deletes the objects of the tasks in batches, reporting the
+// result of each.
+func (s *syncer) deleteObjects(tasks []syncTask) {
+	objects := make([]BatchDeleteObject, 0, len(tasks))
+	for _, t := range tasks {
+		objects = append(objects, BatchDeleteObject{
+			Object: &s3.DeleteObjectInput{
+				Bucket: s.in.Bucket,
+				Key:    aws.String(t.key),
+			},
+		})
+	}
+
+	failed := map[string]error{}
+	batcher := BatchDelete{Client: s.cfg.S3, BatchSize: DefaultBatchSize}
+	err := batcher.Delete(s.ctx, &DeleteObjectsIterator{Objects: objects})
+	if batchErr, ok := err.(*BatchError); ok {
+		for _, e := range batchErr.Errors {
+			failed[aws.StringValue(e.Key)] = e.OrigErr
+		}
+	}
+
+	for _, t := range tasks {
+		s.report(SyncResult{
+			Action: t.action,
+			Key:    t.key,
+			Path:   s.localPath(t.key),
+			Size:   aws.Int64Value(t.object.Size),
+			Err:    failed[t.key],
+		})
+	}
+}
+
+// report records the result in the output, and sends it to the input's
+// result channel.
+func (s *syncer) report(r SyncResult) {
+	s.m.Lock()
+	if r.Err != nil {
+		s.out.Failed++
+		s.errs = append(s.errs, newError(r.Err, s.in.Bucket, aws.String(r.Key)))
+	} else {
+		switch r.Action {
+		case SyncActionUpload:
+			s.out.Uploaded++
+		case SyncActionDownload:
+			s.out.Downloaded++
+		case SyncActionDelete:
+			s.out.Deleted++
+		case SyncActionSkip:
+			s.out.Skipped++
+		}
+	}
+	s.m.Unlock()
+
+	if s.in.Results != nil {
+		s.in.Results <- r
+	}
+}
@@ -0,0 +1,385 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+type syncTestObject struct {
+	Body         string
+	ETag         string
+	LastModified time.Time
+}
+
+// syncSvc returns a client serving the objects, recording the operation and
+// key of each request made.
+func syncSvc(objects map[string]syncTestObject) (*s3.S3, *[]string) {
+	var m sync.Mutex
+	calls := []string{}
+
+	svc := s3.New(unit.Session)
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		var body string
+		switch p := r.Params.(type) {
+		case *s3.ListObjectsV2Input:
+			calls = append(calls, "ListObjectsV2 "+aws.StringValue(p.Prefix))
+			body = "<ListBucketResult>"
+			for k, o := range objects {
+				body += fmt.Sprintf("<Contents><Key>%s</Key><Size>%d</Size><ETag>%q</ETag><LastModified>%s</LastModified></Contents>",
+					k, len(o.Body), o.ETag, o.LastModified.UTC().Format(time.RFC3339))
+			}
+			body += "<IsTruncated>false</IsTruncated></ListBucketResult>"
+		case *s3.PutObjectInput:
+			calls = append(calls, "PutObject "+aws.StringValue(p.Key))
+		case *s3.DeleteObjectsInput:
+			keys := []string{}
+			for _, o := range p.Delete.Objects {
+				keys = append(keys, aws.StringValue(o.Key))
+			}
+			sort.Strings(keys)
+			calls = append(calls, "DeleteObjects "+strings.Join(keys, ","))
+			body = "<DeleteResult></DeleteResult>"
+		case *s3.GetObjectInput:
+			calls = append(calls, "GetObject "+aws.StringValue(p.Key))
+			o := objects[aws.StringValue(p.Key)]
+			r.HTTPResponse = &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(o.Body)),
+				Header:     http.Header{},
+			}
+			r.HTTPResponse.Header.Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d",
+				len(o.Body)-1, len(o.Body)))
+			r.HTTPResponse.Header.Set("Content-Length", fmt.Sprintf("%d", len(o.Body)))
+			return
+		}
+
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Header:     http.Header{},
+		}
+	})
+
+	return svc, &calls
+}
+
+func writeSyncFiles(t *testing.T, dir string, files map[string]string, modTime time.Time) {
+	for name, body := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		if err := ioutil.WriteFile(p, []byte(body), 0644); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+	}
+}
+
+func collectSyncResults(ch chan s3manager.SyncResult) func() map[string]s3manager.SyncAction {
+	results := map[string]s3manager.SyncAction{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range ch {
+			results[r.Key] = r.Action
+		}
+	}()
+
+	return func() map[string]s3manager.SyncAction {
+		<-done
+		return results
+	}
+}
+
+func TestSync_Upload(t *testing.T) {
+	cases := map[string]struct {
+		DryRun       bool
+		ExpectCalls  []string
+		ExpectOutput s3manager.SyncOutput
+	}{
+		"sync": {
+			ExpectCalls: []string{
+				"ListObjectsV2 site/",
+				"PutObject site/a.txt",
+				"DeleteObjects site/old.txt",
+			},
+			ExpectOutput: s3manager.SyncOutput{Uploaded: 1, Deleted: 1, Skipped: 1},
+		},
+		"dry run": {
+			DryRun: true,
+			ExpectCalls: []string{
+				"ListObjectsV2 site/",
+			},
+			ExpectOutput: s3manager.SyncOutput{Uploaded: 1, Deleted: 1, Skipped: 1},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "s3manager-sync")
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			now := time.Now()
+			writeSyncFiles(t, dir, map[string]string{
+				"a.txt":     "hello",
+				"dir/b.txt": "world",
+				"skip.tmp":  "temporary",
+			}, now.Add(-time.Hour))
+
+			svc, calls := syncSvc(map[string]syncTestObject{
+				"site/dir/b.txt":  {Body: "world", LastModified: now},
+				"site/old.txt":    {Body: "old", LastModified: now},
+				"site/other.tmp":  {Body: "excluded", LastModified: now},
+				"site/directory/": {LastModified: now},
+			})
+
+			ch := make(chan s3manager.SyncResult)
+			results := collectSyncResults(ch)
+
+			syncer := s3manager.NewSyncerWithClient(svc)
+			out, err := syncer.Sync(&s3manager.SyncInput{
+				Bucket:   aws.String("bucket"),
+				Prefix:   aws.String("site"),
+				LocalDir: dir,
+				Delete:   true,
+				Exclude:  []string{"*.tmp"},
+				DryRun:   c.DryRun,
+				Results:  ch,
+			})
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			if e, a := c.ExpectOutput, *out; e != a {
+				t.Errorf("expect %v output, got %v", e, a)
+			}
+			if e, a := c.ExpectCalls, *calls; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v calls, got %v", e, a)
+			}
+
+			expectResults := map[string]s3manager.SyncAction{
+				"site/a.txt":     s3manager.SyncActionUpload,
+				"site/dir/b.txt": s3manager.SyncActionSkip,
+				"site/old.txt":   s3manager.SyncActionDelete,
+			}
+			if e, a := expectResults, results(); !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v results, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestSync_Download(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3manager-sync")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now().Truncate(time.Second)
+	writeSyncFiles(t, dir, map[string]string{
+		"dir/b.txt": "stale",
+		"same.txt":  "same",
+		"gone.txt":  "gone",
+		"keep.log":  "not included",
+	}, now.Add(-time.Hour))
+
+	lastModified := now.Add(-time.Minute)
+	svc, calls := syncSvc(map[string]syncTestObject{
+		"a.txt":     {Body: "hello", LastModified: lastModified},
+		"dir/b.txt": {Body: "fresh", LastModified: lastModified},
+		"same.txt":  {Body: "same", LastModified: now.Add(-2 * time.Hour)},
+	})
+
+	syncer := s3manager.NewSyncerWithClient(svc, func(s *s3manager.Syncer) {
+		s.Concurrency = 1
+	})
+	out, err := syncer.Sync(&s3manager.SyncInput{
+		Bucket:    aws.String("bucket"),
+		LocalDir:  dir,
+		Direction: s3manager.SyncDownload,
+		Delete:    true,
+		Include:   []string{"*.txt"},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := (s3manager.SyncOutput{Downloaded: 2, Deleted: 1, Skipped: 1}), *out; e != a {
+		t.Errorf("expect %v output, got %v", e, a)
+	}
+	expectCalls := []string{"ListObjectsV2 ", "GetObject a.txt", "GetObject dir/b.txt"}
+	if e, a := expectCalls, *calls; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v calls, got %v", e, a)
+	}
+
+	expectFiles := map[string]string{
+		"a.txt":     "hello",
+		"dir/b.txt": "fresh",
+		"same.txt":  "same",
+		"keep.log":  "not included",
+	}
+	for name, body := range expectFiles {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Errorf("expect no error reading %s, got %v", name, err)
+			continue
+		}
+		if e, a := body, string(b); e != a {
+			t.Errorf("expect %v %s body, got %v", e, name, a)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gone.txt")); !os.IsNotExist(err) {
+		t.Errorf("expect gone.txt to be deleted, got %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := lastModified, info.ModTime(); !e.Equal(a) {
+		t.Errorf("expect %v mod time, got %v", e, a)
+	}
+}
+
+func TestSync_CompareETag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3manager-sync")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	writeSyncFiles(t, dir, map[string]string{
+		"same.txt":      "hello",
+		"changed.txt":   "jello",
+		"multipart.txt": "hello",
+	}, now)
+
+	svc, calls := syncSvc(map[string]syncTestObject{
+		// MD5 of "hello"
+		"same.txt":    {Body: "hello", ETag: "5d41402abc4b2a76b9719d911017c592", LastModified: now.Add(-time.Hour)},
+		"changed.txt": {Body: "hello", ETag: "5d41402abc4b2a76b9719d911017c592", LastModified: now.Add(-time.Hour)},
+		// MD5 of the MD5 of "hello", for a single part upload
+		"multipart.txt": {Body: "hello", ETag: "62109206880d38a4010a98e11243924a-1", LastModified: now.Add(-time.Hour)},
+	})
+
+	syncer := s3manager.NewSyncerWithClient(svc)
+	out, err := syncer.Sync(&s3manager.SyncInput{
+		Bucket:   aws.String("bucket"),
+		LocalDir: dir,
+		Compare:  s3manager.SyncCompareETag,
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := (s3manager.SyncOutput{Uploaded: 1, Skipped: 2}), *out; e != a {
+		t.Errorf("expect %v output, got %v", e, a)
+	}
+	if e, a := []string{"ListObjectsV2 ", "PutObject changed.txt"}, *calls; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v calls, got %v", e, a)
+	}
+}
+
+func TestSync_Error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3manager-sync")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeSyncFiles(t, dir, map[string]string{
+		"a.txt": "hello",
+		"b.txt": "world",
+	}, time.Now())
+
+	svc, _ := syncSvc(map[string]syncTestObject{})
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		if p, ok := r.Params.(*s3.PutObjectInput); ok && aws.StringValue(p.Key) == "b.txt" {
+			r.HTTPResponse.StatusCode = 400
+			r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader([]byte{}))
+		}
+	})
+
+	syncer := s3manager.NewSyncerWithClient(svc)
+	out, err := syncer.Sync(&s3manager.SyncInput{
+		Bucket:   aws.String("bucket"),
+		LocalDir: dir,
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	batchErr, ok := err.(*s3manager.BatchError)
+	if !ok {
+		t.Fatalf("expect %T error, got %T", batchErr, err)
+	}
+	if e, a := s3manager.ErrCodeSyncIncomplete, batchErr.Code(); e != a {
+		t.Errorf("expect %v error code, got %v", e, a)
+	}
+	if e, a := 1, len(batchErr.Errors); e != a {
+		t.Fatalf("expect %v errors, got %v", e, a)
+	}
+	if e, a := "b.txt", aws.StringValue(batchErr.Errors[0].Key); e != a {
+		t.Errorf("expect %v key, got %v", e, a)
+	}
+	if e, a := "BadRequest", batchErr.Errors[0].OrigErr.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v error code, got %v", e, a)
+	}
+
+	if e, a := (s3manager.SyncOutput{Uploaded: 1, Failed: 1}), *out; e != a {
+		t.Errorf("expect %v output, got %v", e, a)
+	}
+}
+
+func TestSync_InvalidParams(t *testing.T) {
+	svc, calls := syncSvc(map[string]syncTestObject{})
+
+	syncer := s3manager.NewSyncerWithClient(svc)
+	_, err := syncer.Sync(&s3manager.SyncInput{
+		Exclude: []string{"["},
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	invalidParams, ok := err.(request.ErrInvalidParams)
+	if !ok {
+		t.Fatalf("expect %T error, got %T", request.ErrInvalidParams{}, err)
+	}
+	if e, a := 3, invalidParams.Len(); e != a {
+		t.Errorf("expect %v invalid params, got %v", e, a)
+	}
+	if e, a := 0, len(*calls); e != a {
+		t.Errorf("expect %v calls, got %v", e, a)
+	}
+}
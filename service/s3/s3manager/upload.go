@@ -2,6 +2,7 @@ package s3manager
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"sort"
@@ -157,6 +158,50 @@ type Uploader struct {
 	// List of request options that will be passed down to individual API
 	// operation requests made by the uploader.
 	RequestOptions []request.Option
+
+	// CheckpointStore is used to save the upload ID of multipart uploads
+	// while they are in progress. If set, an upload to a bucket and key which
+	// has a checkpoint will resume the checkpointed upload instead of starting
+	// a new upload. The checkpoint is deleted once the upload completes, or
+	// is aborted.
+	//
+	// Set LeavePartsOnError to true to allow an upload which failed to be
+	// resumed, otherwise the upload will be aborted on failure. See
+	// ResumeUploadWithContext for the requirements of resuming an upload.
+	//
+	// Uploads encrypted with SSE-C or SSE-KMS are not resumed from their
+	// checkpoint, since the ETag of their parts is not the part's MD5, and a
+	// Body changed since the upload failed could not be detected. The
+	// checkpointed upload is aborted, and a new upload started instead.
+	CheckpointStore UploadCheckpointStore
+
+	// ValidateChecksums enables checksum validation of uploaded parts. The
+	// MD5 checksum of each part is sent as the part's Content-MD5, and
+	// compared with the ETag S3 returns for the part, unless the object is
+	// encrypted with SSE-C or SSE-KMS.
+	//
+	// The object's composite checksum, and the part size it was computed
+	// with, are stored in the object's metadata with the ChecksumMetadataKey
+	// and ChecksumPartSizeMetadataKey keys so the object can be validated
+	// when downloaded. The composite checksum can only be computed before
+	// the multipart upload is created if the Body is an io.ReaderAt and
+	// io.Seeker, such as an *os.File, which will be read twice. For other
+	// bodies only the part size is stored, and the object's ETag is used as
+	// its composite checksum.
+	//
+	// A ChecksumMismatchError is returned if a checksum does not match. The
+	// multipart upload is aborted if the checksum of a part does not match,
+	// and the uploaded object is deleted if the object's ETag does not match.
+	ValidateChecksums bool
+
+	// BandwidthLimiter, if set, limits the rate parts are uploaded at. The
+	// limiter is shared by all parts of an upload, and can be shared with
+	// other Uploaders and Downloaders to limit their combined rate.
+	BandwidthLimiter *BandwidthLimiter
+
+	// ProgressListener, if set, is reported the progress of uploads. See
+	// ProgressEvent for the events reported.
+	ProgressListener ProgressListener
 }
 
 // NewUploader creates a new Uploader instance to upload objects to S3. Pass In
@@ -165,6 +210,7 @@ type Uploader struct {
 // satisfies the client.ConfigProvider interface.
 //
 // Example:
+//
 //     // The session the S3 Uploader will use
 //     sess := session.Must(session.NewSession())
 //
@@ -196,6 +242,7 @@ func NewUploader(c client.ConfigProvider, options ...func(*Uploader)) *Uploader
 // a S3 service client to make S3 API calls.
 //
 // Example:
+//
 //     // The session the S3 Uploader will use
 //     sess := session.Must(session.NewSession())
 //
@@ -239,6 +286,7 @@ func NewUploaderWithClient(svc s3iface.S3API, options ...func(*Uploader)) *Uploa
 // It is safe to call this method concurrently across goroutines.
 //
 // Example:
+//
 //     // Upload input parameters
 //     upParams := &s3manager.UploadInput{
 //         Bucket: &bucketName,
@@ -277,14 +325,72 @@ func (u Uploader) Upload(input *UploadInput, options ...func(*Uploader)) (*Uploa
 //
 // It is safe to call this method concurrently across goroutines.
 func (u Uploader) UploadWithContext(ctx aws.Context, input *UploadInput, opts ...func(*Uploader)) (*UploadOutput, error) {
-	i := uploader{in: input, cfg: u, ctx: ctx}
+	i := u.newUploader(ctx, input, opts...)
+
+	return i.upload()
+}
+
+// ResumeUpload resumes the multipart upload with the upload ID provided,
+// uploading only the parts of the object which have not already been
+// uploaded.
+//
+// See ResumeUploadWithContext for more information.
+func (u Uploader) ResumeUpload(uploadID string, input *UploadInput, opts ...func(*Uploader)) (*UploadOutput, error) {
+	return u.ResumeUploadWithContext(aws.BackgroundContext(), uploadID, input, opts...)
+}
+
+// ResumeUploadWithContext resumes the multipart upload with the upload ID
+// provided, uploading only the parts of the object which have not already
+// been uploaded. The upload ID of a failed upload can be retrieved from the
+// MultiUploadFailure error returned by UploadWithContext when the Uploader's
+// LeavePartsOnError is set.
+//
+// The input's Body must provide the same content as the failed upload from
+// the start of the object. The parts already uploaded are listed with
+// ListParts, and are verified against the Body by their size and ETag before
+// being skipped. Parts which do not match are uploaded again. The ETag of
+// parts uploaded with SSE-C or SSE-KMS encryption is not the MD5 of the part,
+// so only the size of these parts is verified, and the caller must ensure the
+// Body has not changed. These uploads are not resumed automatically from the
+// Uploader's CheckpointStore.
+//
+// The parts will be uploaded with the same part size as the parts already
+// uploaded. If the upload ID is found in the Uploader's CheckpointStore the
+// checkpoint's part size is used.
+//
+// The upload will be aborted if it fails again, unless LeavePartsOnError is
+// set.
+//
+// It is safe to call this method concurrently across goroutines.
+//
+// Example:
+//     result, err := uploader.UploadWithContext(ctx, upParams, func(u *s3manager.Uploader) {
+//          u.LeavePartsOnError = true
+//     })
+//     if multierr, ok := err.(s3manager.MultiUploadFailure); ok {
+//          // Rewind the body, and resume the failed upload.
+//          file.Seek(0, io.SeekStart)
+//          result, err = uploader.ResumeUploadWithContext(ctx, multierr.UploadID(), upParams)
+//     }
+func (u Uploader) ResumeUploadWithContext(ctx aws.Context, uploadID string, input *UploadInput, opts ...func(*Uploader)) (*UploadOutput, error) {
+	i := u.newUploader(ctx, input, opts...)
+	i.resumeUploadID = uploadID
+
+	return i.upload()
+}
+
+// newUploader returns the internal uploader for the input with the
+// Uploader's options applied.
+func (u Uploader) newUploader(ctx aws.Context, input *UploadInput, opts ...func(*Uploader)) *uploader {
+	i := &uploader{in: input, cfg: u, ctx: ctx}
 
 	for _, opt := range opts {
 		opt(&i.cfg)
 	}
 	i.cfg.RequestOptions = append(i.cfg.RequestOptions, request.WithAppendUserAgent("S3Manager"))
+	i.progress = newProgressReporter(i.cfg.ProgressListener, input.Bucket, input.Key)
 
-	return i.upload()
+	return i
 }
 
 // UploadWithIterator will upload a batched amount of objects to S3. This operation uses
@@ -292,6 +398,7 @@ func (u Uploader) UploadWithContext(ctx aws.Context, input *UploadInput, opts ..
 // allows for custom defined functionality.
 //
 // Example:
+//
 //	svc:= s3manager.NewUploader(sess)
 //
 //	objects := []BatchUploadObject{
@@ -307,7 +414,18 @@ func (u Uploader) UploadWithContext(ctx aws.Context, input *UploadInput, opts ..
 //	if err := svc.UploadWithIterator(aws.BackgroundContext(), iter); err != nil {
 //		return err
 //	}
-func (u Uploader) UploadWithIterator(ctx aws.Context, iter BatchUploadIterator, opts ...func(*Uploader)) error {
+func (u Uploader) UploadWithIterator(ctx aws.Context, iter BatchUploadIterator, opts ...func(*Uploader)) (err error) {
+	cfg := u
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if progress := newBatchProgress(cfg.ProgressListener); progress != nil {
+		opts = append(opts[:len(opts):len(opts)], func(u *Uploader) {
+			u.ProgressListener = progress
+		})
+		defer func() { progress.done(err) }()
+	}
+
 	var errs []Error
 	for iter.Next() {
 		object := iter.UploadObject()
@@ -353,18 +471,49 @@ type uploader struct {
 	totalSize int64 // set to -1 if the size is not known
 
 	bufferPool sync.Pool
+
+	resumeUploadID string // upload ID of the multipart upload to resume
+
+	// checksums of the parts uploaded, and expected checksums of the parts
+	// computed before the upload started, if ValidateChecksums is enabled.
+	checksums      *partChecksums
+	expectSums     *partChecksums
+	expectChecksum string
+
+	progress *progressReporter
 }
 
 // internal logic for deciding whether to upload a single part or use a
 // multipart upload.
-func (u *uploader) upload() (*UploadOutput, error) {
+func (u *uploader) upload() (out *UploadOutput, err error) {
 	u.init()
 
+	u.progress.setTotal(u.totalSize)
+	u.progress.started()
+	defer func() { u.progress.done(err) }()
+
 	if u.cfg.PartSize < MinUploadPartSize {
 		msg := fmt.Sprintf("part size must be at least %d bytes", MinUploadPartSize)
 		return nil, awserr.New("ConfigError", msg, nil)
 	}
 
+	resume, err := u.resumeState()
+	if err != nil {
+		return nil, err
+	}
+	if u.cfg.ValidateChecksums {
+		if err := u.initChecksums(); err != nil {
+			return nil, err
+		}
+	}
+	if resume != nil {
+		reader, _, part, err := u.nextReader()
+		if err != nil && err != io.EOF {
+			return nil, awserr.New("ReadRequestBody", "read upload data failed", err)
+		}
+		return resume.uploadParts(reader, part, err)
+	}
+
 	// Do one read to determine if we have more than one part
 	reader, _, part, err := u.nextReader()
 	if err == io.EOF { // single part
@@ -377,6 +526,234 @@ func (u *uploader) upload() (*UploadOutput, error) {
 	return mu.upload(reader, part)
 }
 
+// resumeState returns the multiuploader of the multipart upload to resume,
+// with the parts which have already been uploaded. If there is no upload to
+// resume nil is returned.
+func (u *uploader) resumeState() (*multiuploader, error) {
+	uploadID := u.resumeUploadID
+	bucket, key := aws.StringValue(u.in.Bucket), aws.StringValue(u.in.Key)
+
+	var cp *UploadCheckpoint
+	if store := u.cfg.CheckpointStore; store != nil {
+		var err error
+		if cp, err = store.GetCheckpoint(bucket, key); err != nil {
+			return nil, awserr.New(ErrCodeUploadCheckpoint, "failed to get upload checkpoint", err)
+		}
+		if cp != nil && len(uploadID) != 0 && cp.UploadID != uploadID {
+			cp = nil
+		}
+		if cp != nil && len(uploadID) == 0 {
+			uploadID = cp.UploadID
+		}
+	}
+	if len(uploadID) == 0 {
+		return nil, nil
+	}
+	if cp != nil && len(u.resumeUploadID) == 0 && !u.partETagsAreMD5() {
+		// The parts of the checkpointed upload can only be verified by their
+		// size, so the upload is started again instead of being resumed.
+		u.abortCheckpointed(uploadID)
+		u.deleteCheckpoint()
+		return nil, nil
+	}
+
+	uploaded := map[int64]*s3.Part{}
+	params := &s3.ListPartsInput{
+		Bucket:       u.in.Bucket,
+		Key:          u.in.Key,
+		UploadId:     aws.String(uploadID),
+		RequestPayer: u.in.RequestPayer,
+	}
+	err := u.cfg.S3.ListPartsPagesWithContext(u.ctx, params,
+		func(page *s3.ListPartsOutput, lastPage bool) bool {
+			for _, p := range page.Parts {
+				uploaded[aws.Int64Value(p.PartNumber)] = p
+			}
+			return true
+		}, u.cfg.RequestOptions...)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchUpload &&
+			len(u.resumeUploadID) == 0 {
+			// The checkpointed upload no longer exists, start a new upload.
+			u.deleteCheckpoint()
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Parts must be uploaded with the part size the upload was started with.
+	switch {
+	case cp != nil && cp.PartSize >= MinUploadPartSize:
+		u.cfg.PartSize = cp.PartSize
+	case uploaded[1] != nil && aws.Int64Value(uploaded[1].Size) >= MinUploadPartSize:
+		u.cfg.PartSize = aws.Int64Value(uploaded[1].Size)
+	}
+
+	if cp == nil {
+		if err := u.saveCheckpoint(uploadID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &multiuploader{uploader: u, uploadID: uploadID, uploaded: uploaded}, nil
+}
+
+// saveCheckpoint saves the checkpoint of the multipart upload to the
+// Uploader's CheckpointStore, if set.
+func (u *uploader) saveCheckpoint(uploadID string) error {
+	if u.cfg.CheckpointStore == nil {
+		return nil
+	}
+
+	err := u.cfg.CheckpointStore.PutCheckpoint(&UploadCheckpoint{
+		Bucket:   aws.StringValue(u.in.Bucket),
+		Key:      aws.StringValue(u.in.Key),
+		UploadID: uploadID,
+		PartSize: u.cfg.PartSize,
+	})
+	if err != nil {
+		return awserr.New(ErrCodeUploadCheckpoint, "failed to put upload checkpoint", err)
+	}
+	return nil
+}
+
+// abortCheckpointed aborts the checkpointed multipart upload which will not be
+// resumed. Failing to abort it is logged, and does not fail the new upload.
+func (u *uploader) abortCheckpointed(uploadID string) {
+	_, err := u.cfg.S3.AbortMultipartUploadWithContext(u.ctx, &s3.AbortMultipartUploadInput{
+		Bucket:       u.in.Bucket,
+		Key:          u.in.Key,
+		UploadId:     aws.String(uploadID),
+		RequestPayer: u.in.RequestPayer,
+	}, u.cfg.RequestOptions...)
+	if err != nil {
+		logMessage(u.cfg.S3, aws.LogDebug, fmt.Sprintf("failed to abort checkpointed upload, %v", err))
+	}
+}
+
+// deleteCheckpoint deletes the upload's checkpoint from the Uploader's
+// CheckpointStore, if set.
+func (u *uploader) deleteCheckpoint() {
+	if u.cfg.CheckpointStore == nil {
+		return
+	}
+
+	err := u.cfg.CheckpointStore.DeleteCheckpoint(aws.StringValue(u.in.Bucket), aws.StringValue(u.in.Key))
+	if err != nil {
+		logMessage(u.cfg.S3, aws.LogDebug, fmt.Sprintf("failed to delete upload checkpoint, %v", err))
+	}
+}
+
+// initChecksums prepares the upload to validate the checksums of its parts.
+// If the body can be read twice the checksum of each part is computed before
+// the upload starts.
+func (u *uploader) initChecksums() error {
+	type readerAtSeeker interface {
+		io.ReaderAt
+		io.ReadSeeker
+	}
+
+	u.checksums = newPartChecksums()
+
+	r, ok := u.in.Body.(readerAtSeeker)
+	if !ok || u.totalSize < 0 {
+		return nil
+	}
+
+	u.expectSums = newPartChecksums()
+	var num int64 = 1
+	for pos := u.readerPos; pos < u.totalSize || num == 1; pos += u.cfg.PartSize {
+		n := u.cfg.PartSize
+		if left := u.totalSize - pos; left < n {
+			n = left
+		}
+
+		sum, err := s3.BodyMD5(io.NewSectionReader(r, pos, n))
+		if err != nil {
+			return awserr.New("ReadRequestBody", "read upload data failed", err)
+		}
+		u.expectSums.set(num, sum)
+		num++
+	}
+	u.expectChecksum = u.expectSums.composite()
+
+	return nil
+}
+
+// partChecksum returns the MD5 checksum of the part, validating it matches
+// the checksum computed before the upload started.
+func (u *uploader) partChecksum(num int64, r io.ReadSeeker) ([]byte, error) {
+	sum, err := s3.BodyMD5(r)
+	if err != nil {
+		return nil, awserr.New("ReadRequestBody", "read upload data failed", err)
+	}
+
+	if u.expectSums != nil {
+		if expect := u.expectSums.get(num); expect != nil && !bytes.Equal(expect, sum) {
+			return nil, u.checksumMismatch(num, hex.EncodeToString(expect), hex.EncodeToString(sum))
+		}
+	}
+
+	return sum, nil
+}
+
+// validatePartChecksums validates the composite checksum of the uploaded
+// parts matches the checksum computed before the upload started. It is
+// validated before the upload is completed, so that a mismatched upload is
+// aborted instead of committed.
+func (u *uploader) validatePartChecksums() error {
+	actual := u.checksums.composite()
+	if len(u.expectChecksum) > 0 && u.expectChecksum != actual {
+		return u.checksumMismatch(0, u.expectChecksum, actual)
+	}
+	return nil
+}
+
+// validateObjectETag validates the ETag of the uploaded object matches the
+// expected checksum, deleting the object if it does not.
+func (u *uploader) validateObjectETag(expect string, etag, versionID *string) error {
+	if !u.etagIsMD5() || trimETag(etag) == expect {
+		return nil
+	}
+
+	u.deleteObject(versionID)
+	return u.checksumMismatch(0, expect, trimETag(etag))
+}
+
+// deleteObject deletes the version of the uploaded object. Errors deleting
+// the object are logged, the checksum mismatch is the error returned.
+func (u *uploader) deleteObject(versionID *string) {
+	_, err := u.cfg.S3.DeleteObjectWithContext(u.ctx, &s3.DeleteObjectInput{
+		Bucket:       u.in.Bucket,
+		Key:          u.in.Key,
+		VersionId:    versionID,
+		RequestPayer: u.in.RequestPayer,
+	}, u.cfg.RequestOptions...)
+	if err != nil {
+		logMessage(u.cfg.S3, aws.LogDebug, fmt.Sprintf("failed to delete mismatched object, %v", err))
+	}
+}
+
+func (u *uploader) etagIsMD5() bool {
+	return etagIsMD5(u.in.SSECustomerAlgorithm, u.in.ServerSideEncryption)
+}
+
+// partETagsAreMD5 returns if the ETag of the upload's parts is the part's MD5,
+// which is not the case for parts encrypted with SSE-C or SSE-KMS.
+func (u *uploader) partETagsAreMD5() bool {
+	return u.in.SSECustomerKey == nil && u.etagIsMD5()
+}
+
+func (u *uploader) checksumMismatch(num int64, expect, actual string) error {
+	return &ChecksumMismatchError{
+		Bucket:     aws.StringValue(u.in.Bucket),
+		Key:        aws.StringValue(u.in.Key),
+		PartNumber: num,
+		Expected:   expect,
+		Actual:     actual,
+	}
+}
+
 // init will initialize all default options.
 func (u *uploader) init() {
 	if u.cfg.Concurrency == 0 {
@@ -467,6 +844,27 @@ func readFillBuf(r io.Reader, b []byte) (offset int, err error) {
 	return offset, err
 }
 
+// partBody returns the body the part is uploaded with, and the request
+// options to upload it with, limiting its bandwidth and reporting its
+// progress if enabled.
+func (u *uploader) partBody(num int64, r io.ReadSeeker) (io.ReadSeeker, []request.Option) {
+	opts := u.cfg.RequestOptions
+	opts = opts[:len(opts):len(opts)]
+
+	if u.cfg.BandwidthLimiter != nil {
+		body := newLimitedReadSeeker(u.ctx, r, u.cfg.BandwidthLimiter)
+		r = body
+		opts = append(opts, body.requestOption())
+	}
+	if u.progress != nil {
+		body := newProgressReadSeeker(r, u.progress, num)
+		r = body
+		opts = append(opts, body.requestOption())
+	}
+
+	return r, opts
+}
+
 // singlePart contains upload logic for uploading a single chunk via
 // a regular PutObject request. Multipart requests require at least two
 // parts, or at least 5MB of data.
@@ -475,14 +873,36 @@ func (u *uploader) singlePart(buf io.ReadSeeker) (*UploadOutput, error) {
 	awsutil.Copy(params, u.in)
 	params.Body = buf
 
+	var sum []byte
+	if u.cfg.ValidateChecksums {
+		var err error
+		if sum, err = u.partChecksum(1, buf); err != nil {
+			return nil, err
+		}
+		u.checksums.set(1, sum)
+		params.ContentMD5 = base64MD5(sum)
+		params.Metadata = checksumMetadata(params.Metadata, u.checksums.composite(), u.cfg.PartSize)
+	}
+
+	var opts []request.Option
+	params.Body, opts = u.partBody(1, buf)
+
 	// Need to use request form because URL generated in request is
 	// used in return.
 	req, out := u.cfg.S3.PutObjectRequest(params)
 	req.SetContext(u.ctx)
-	req.ApplyOptions(u.cfg.RequestOptions...)
+	req.ApplyOptions(opts...)
+	u.progress.report(PartStartedEvent, 1, 0, nil)
 	if err := req.Send(); err != nil {
 		return nil, err
 	}
+	u.progress.report(PartCompletedEvent, 1, 0, nil)
+
+	if sum != nil {
+		if err := u.validateObjectETag(hex.EncodeToString(sum), out.ETag, out.VersionId); err != nil {
+			return nil, err
+		}
+	}
 
 	url := req.HTTPRequest.URL.String()
 	return &UploadOutput{
@@ -499,6 +919,9 @@ type multiuploader struct {
 	err      error
 	uploadID string
 	parts    completedParts
+
+	// parts of a resumed upload which have already been uploaded.
+	uploaded map[int64]*s3.Part
 }
 
 // keeps track of a single chunk of data being sent to S3.
@@ -521,6 +944,9 @@ func (a completedParts) Less(i, j int) bool { return *a[i].PartNumber < *a[j].Pa
 func (u *multiuploader) upload(firstBuf io.ReadSeeker, firstPart []byte) (*UploadOutput, error) {
 	params := &s3.CreateMultipartUploadInput{}
 	awsutil.Copy(params, u.in)
+	if u.cfg.ValidateChecksums {
+		params.Metadata = checksumMetadata(params.Metadata, u.expectChecksum, u.cfg.PartSize)
+	}
 
 	// Create the multipart
 	resp, err := u.cfg.S3.CreateMultipartUploadWithContext(u.ctx, params, u.cfg.RequestOptions...)
@@ -529,6 +955,17 @@ func (u *multiuploader) upload(firstBuf io.ReadSeeker, firstPart []byte) (*Uploa
 	}
 	u.uploadID = *resp.UploadId
 
+	if err := u.saveCheckpoint(u.uploadID); err != nil {
+		u.seterr(err)
+	}
+
+	return u.uploadParts(firstBuf, firstPart, nil)
+}
+
+// uploadParts uploads the parts of the multipart upload, starting with the
+// first part provided, and completes the upload. If err is not nil no more
+// parts will be read from the upload's body.
+func (u *multiuploader) uploadParts(firstBuf io.ReadSeeker, firstPart []byte, err error) (*UploadOutput, error) {
 	// Create the workers
 	ch := make(chan chunk, u.cfg.Concurrency)
 	for i := 0; i < u.cfg.Concurrency; i++ {
@@ -538,7 +975,7 @@ func (u *multiuploader) upload(firstBuf io.ReadSeeker, firstPart []byte) (*Uploa
 
 	// Send part 1 to the workers
 	var num int64 = 1
-	ch <- chunk{buf: firstBuf, part: firstPart, num: num}
+	u.queueChunk(ch, chunk{buf: firstBuf, part: firstPart, num: num})
 
 	// Read and queue the rest of the parts
 	for u.geterr() == nil && err == nil {
@@ -577,7 +1014,7 @@ func (u *multiuploader) upload(firstBuf io.ReadSeeker, firstPart []byte) (*Uploa
 			break
 		}
 
-		ch <- chunk{buf: reader, part: part, num: num}
+		u.queueChunk(ch, chunk{buf: reader, part: part, num: num})
 	}
 
 	// Close the channel, wait for workers, and complete upload
@@ -604,6 +1041,15 @@ func (u *multiuploader) upload(firstBuf io.ReadSeeker, firstPart []byte) (*Uploa
 	getReq.Config.Credentials = credentials.AnonymousCredentials
 	uploadLocation, _, _ := getReq.PresignRequest(1)
 
+	u.deleteCheckpoint()
+
+	if u.cfg.ValidateChecksums {
+		err := u.validateObjectETag(u.checksums.composite(), complete.ETag, complete.VersionId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &UploadOutput{
 		Location:  uploadLocation,
 		VersionID: complete.VersionId,
@@ -611,6 +1057,57 @@ func (u *multiuploader) upload(firstBuf io.ReadSeeker, firstPart []byte) (*Uploa
 	}, nil
 }
 
+// queueChunk queues the chunk to be uploaded by the workers, unless the
+// chunk's part was already uploaded by the resumed upload.
+func (u *multiuploader) queueChunk(ch chan chunk, c chunk) {
+	if u.geterr() == nil && u.isPartUploaded(c) {
+		u.bufferPool.Put(c.part)
+		return
+	}
+
+	ch <- c
+}
+
+// isPartUploaded returns if the chunk's part has already been uploaded with
+// the same content, adding the part to the upload's completed parts if it
+// has.
+func (u *multiuploader) isPartUploaded(c chunk) bool {
+	p, ok := u.uploaded[c.num]
+	if !ok {
+		return false
+	}
+
+	n, err := aws.SeekerLen(c.buf)
+	if err != nil || n != aws.Int64Value(p.Size) {
+		return false
+	}
+
+	// The ETag of parts encrypted with SSE-C or SSE-KMS is not the part's
+	// MD5, so only the part size can be verified.
+	if u.partETagsAreMD5() || u.cfg.ValidateChecksums {
+		sum, err := u.partChecksum(c.num, c.buf)
+		if err != nil {
+			u.seterr(err)
+			return false
+		}
+		if u.partETagsAreMD5() && trimETag(p.ETag) != hex.EncodeToString(sum) {
+			return false
+		}
+		if u.checksums != nil {
+			u.checksums.set(c.num, sum)
+		}
+	}
+
+	u.progress.report(PartCompletedEvent, c.num, n, nil)
+
+	num := c.num
+	u.m.Lock()
+	u.parts = append(u.parts, &s3.CompletedPart{ETag: p.ETag, PartNumber: &num})
+	u.m.Unlock()
+
+	return true
+}
+
 // readChunk runs in worker goroutines to pull chunks off of the ch channel
 // and send() them as UploadPart requests.
 func (u *multiuploader) readChunk(ch chan chunk) {
@@ -642,13 +1139,36 @@ func (u *multiuploader) send(c chunk) error {
 		SSECustomerKey:       u.in.SSECustomerKey,
 		PartNumber:           &c.num,
 	}
-	resp, err := u.cfg.S3.UploadPartWithContext(u.ctx, params, u.cfg.RequestOptions...)
+
+	var sum []byte
+	if u.cfg.ValidateChecksums {
+		var err error
+		if sum, err = u.partChecksum(c.num, c.buf); err != nil {
+			u.bufferPool.Put(c.part)
+			return err
+		}
+		params.ContentMD5 = base64MD5(sum)
+	}
+
+	var opts []request.Option
+	params.Body, opts = u.partBody(c.num, c.buf)
+
+	u.progress.report(PartStartedEvent, c.num, 0, nil)
+	resp, err := u.cfg.S3.UploadPartWithContext(u.ctx, params, opts...)
 	// put the byte array back into the pool to conserve memory
 	u.bufferPool.Put(c.part)
 	if err != nil {
 		return err
 	}
 
+	if sum != nil {
+		if u.etagIsMD5() && trimETag(resp.ETag) != hex.EncodeToString(sum) {
+			return u.checksumMismatch(c.num, hex.EncodeToString(sum), trimETag(resp.ETag))
+		}
+		u.checksums.set(c.num, sum)
+	}
+	u.progress.report(PartCompletedEvent, c.num, 0, nil)
+
 	n := c.num
 	completed := &s3.CompletedPart{ETag: resp.ETag, PartNumber: &n}
 
@@ -681,6 +1201,9 @@ func (u *multiuploader) fail() {
 		return
 	}
 
+	// An aborted upload cannot be resumed.
+	u.deleteCheckpoint()
+
 	params := &s3.AbortMultipartUploadInput{
 		Bucket:   u.in.Bucket,
 		Key:      u.in.Key,
@@ -699,6 +1222,14 @@ func (u *multiuploader) complete() *s3.CompleteMultipartUploadOutput {
 		return nil
 	}
 
+	if u.cfg.ValidateChecksums {
+		if err := u.validatePartChecksums(); err != nil {
+			u.seterr(err)
+			u.fail()
+			return nil
+		}
+	}
+
 	// Parts must be sorted in PartNumber order.
 	sort.Sort(u.parts)
 
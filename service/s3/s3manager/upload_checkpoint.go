@@ -0,0 +1,139 @@
+package s3manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ErrCodeUploadCheckpoint is the error code returned when the Uploader is
+// unable to load or save an upload's checkpoint.
+const ErrCodeUploadCheckpoint = "UploadCheckpointError"
+
+// An UploadCheckpoint records the state of an in-progress multipart upload
+// needed to resume the upload.
+type UploadCheckpoint struct {
+	// The bucket the object is being uploaded to.
+	Bucket string
+
+	// The key the object is being uploaded to.
+	Key string
+
+	// The ID of the multipart upload.
+	UploadID string
+
+	// The size, in bytes, of the parts the upload was started with. The
+	// upload can only be resumed with the same part size.
+	PartSize int64
+}
+
+// UploadCheckpointStore provides the interface the Uploader uses to persist
+// the checkpoints of in-progress multipart uploads, so that an upload can be
+// resumed after the process restarts.
+//
+// An UploadCheckpointStore must be safe to use across multiple goroutines.
+type UploadCheckpointStore interface {
+	// GetCheckpoint returns the checkpoint of the upload to the bucket and
+	// key. A nil checkpoint is returned if there is no checkpoint for the
+	// upload.
+	GetCheckpoint(bucket, key string) (*UploadCheckpoint, error)
+
+	// PutCheckpoint saves the checkpoint, replacing any existing checkpoint
+	// for the checkpoint's bucket and key.
+	PutCheckpoint(*UploadCheckpoint) error
+
+	// DeleteCheckpoint removes the checkpoint of the upload to the bucket
+	// and key. Deleting a checkpoint which does not exist is not an error.
+	DeleteCheckpoint(bucket, key string) error
+}
+
+// FileUploadCheckpointStore is an UploadCheckpointStore which saves each
+// checkpoint as a JSON file within a directory.
+type FileUploadCheckpointStore struct {
+	// The directory checkpoint files are written to. The directory will be
+	// created if it does not exist.
+	Dir string
+}
+
+// NewFileUploadCheckpointStore returns a FileUploadCheckpointStore which
+// saves checkpoints in the directory provided.
+//
+// Example:
+//     store := s3manager.NewFileUploadCheckpointStore("/var/lib/myapp/uploads")
+//
+//     uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+//          u.CheckpointStore = store
+//          u.LeavePartsOnError = true
+//     })
+func NewFileUploadCheckpointStore(dir string) *FileUploadCheckpointStore {
+	return &FileUploadCheckpointStore{Dir: dir}
+}
+
+// GetCheckpoint reads the checkpoint of the upload to the bucket and key
+// from the store's directory.
+func (s *FileUploadCheckpointStore) GetCheckpoint(bucket, key string) (*UploadCheckpoint, error) {
+	b, err := ioutil.ReadFile(s.filename(bucket, key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	cp := &UploadCheckpoint{}
+	if err := json.Unmarshal(b, cp); err != nil {
+		return nil, err
+	}
+	if cp.Bucket != bucket || cp.Key != key {
+		return nil, nil
+	}
+
+	return cp, nil
+}
+
+// PutCheckpoint writes the checkpoint to the store's directory.
+func (s *FileUploadCheckpointStore) PutCheckpoint(cp *UploadCheckpoint) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a partially written checkpoint is
+	// never read.
+	f, err := ioutil.TempFile(s.Dir, ".checkpoint")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), s.filename(cp.Bucket, cp.Key))
+}
+
+// DeleteCheckpoint removes the checkpoint of the upload to the bucket and key
+// from the store's directory.
+func (s *FileUploadCheckpointStore) DeleteCheckpoint(bucket, key string) error {
+	err := os.Remove(s.filename(bucket, key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileUploadCheckpointStore) filename(bucket, key string) string {
+	h := sha256.Sum256([]byte(bucket + "/" + key))
+	return filepath.Join(s.Dir, hex.EncodeToString(h[:])+".json")
+}
@@ -0,0 +1,410 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+func partETag(b []byte) *string {
+	return aws.String(fmt.Sprintf(`"%x"`, md5.Sum(b)))
+}
+
+func listPartsSvc(parts []*s3.Part) (*s3.S3, *[]string, *[]interface{}) {
+	s, ops, args := loggingSvc(emptyList)
+	s.Handlers.Send.PushBack(func(r *request.Request) {
+		switch data := r.Data.(type) {
+		case *s3.ListPartsOutput:
+			data.Parts = parts
+		}
+	})
+
+	return s, ops, args
+}
+
+func completedPartETags(t *testing.T, args []interface{}) []string {
+	params, ok := args[len(args)-1].(*s3.CompleteMultipartUploadInput)
+	if !ok {
+		t.Fatalf("expect last operation to be complete multipart upload, got %T", args[len(args)-1])
+	}
+
+	var etags []string
+	for _, p := range params.MultipartUpload.Parts {
+		etags = append(etags, aws.StringValue(p.ETag))
+	}
+	return etags
+}
+
+func TestResumeUpload(t *testing.T) {
+	partSize := int(s3manager.MinUploadPartSize)
+	s, ops, args := listPartsSvc([]*s3.Part{
+		{PartNumber: aws.Int64(1), Size: aws.Int64(int64(partSize)), ETag: partETag(buf12MB[:partSize])},
+		{PartNumber: aws.Int64(2), Size: aws.Int64(int64(partSize)), ETag: aws.String(`"mismatched"`)},
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s, func(u *s3manager.Uploader) {
+		u.Concurrency = 1
+	})
+	resp, err := mgr.ResumeUpload("UPLOAD-ID", &s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []string{"ListParts", "UploadPart", "UploadPart", "CompleteMultipartUpload"}, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "UPLOAD-ID", resp.UploadID; e != a {
+		t.Errorf("expect %v upload ID, got %v", e, a)
+	}
+
+	// Only the mismatched and missing parts are uploaded again.
+	for i, num := range []int64{2, 3} {
+		params := (*args)[i+1].(*s3.UploadPartInput)
+		if e, a := num, aws.Int64Value(params.PartNumber); e != a {
+			t.Errorf("expect %v part number, got %v", e, a)
+		}
+		if e, a := "UPLOAD-ID", aws.StringValue(params.UploadId); e != a {
+			t.Errorf("expect %v upload ID, got %v", e, a)
+		}
+	}
+
+	expectETags := []string{aws.StringValue(partETag(buf12MB[:partSize])), "ETAG1", "ETAG2"}
+	if e, a := expectETags, completedPartETags(t, *args); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v completed parts, got %v", e, a)
+	}
+}
+
+func TestResumeUpload_PartSizeFromUploadedParts(t *testing.T) {
+	partSize := 6 * 1024 * 1024
+	s, ops, args := listPartsSvc([]*s3.Part{
+		{PartNumber: aws.Int64(1), Size: aws.Int64(int64(partSize)), ETag: partETag(buf12MB[:partSize])},
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s)
+	_, err := mgr.ResumeUpload("UPLOAD-ID", &s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewBuffer(buf12MB),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []string{"ListParts", "UploadPart", "CompleteMultipartUpload"}, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := partSize, buflen(val((*args)[1], "Body")); e != a {
+		t.Errorf("expect %v part size, got %v", e, a)
+	}
+}
+
+func TestResumeUpload_SSEVerifiesSizeOnly(t *testing.T) {
+	partSize := int64(s3manager.MinUploadPartSize)
+	s, ops, _ := listPartsSvc([]*s3.Part{
+		{PartNumber: aws.Int64(1), Size: aws.Int64(partSize), ETag: aws.String(`"kms-etag"`)},
+		{PartNumber: aws.Int64(2), Size: aws.Int64(partSize), ETag: aws.String(`"kms-etag"`)},
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s)
+	_, err := mgr.ResumeUpload("UPLOAD-ID", &s3manager.UploadInput{
+		Bucket:               aws.String("Bucket"),
+		Key:                  aws.String("Key"),
+		Body:                 bytes.NewReader(buf12MB),
+		ServerSideEncryption: aws.String(s3.ServerSideEncryptionAwsKms),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []string{"ListParts", "UploadPart", "CompleteMultipartUpload"}, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestResumeUpload_ListPartsFailure(t *testing.T) {
+	s, ops, _ := loggingSvc(emptyList)
+	s.Handlers.Send.PushBack(func(r *request.Request) {
+		if r.Operation.Name == "ListParts" {
+			r.Error = awserr.New(s3.ErrCodeNoSuchUpload, "no such upload", nil)
+		}
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s)
+	_, err := mgr.ResumeUpload("UPLOAD-ID", &s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := s3.ErrCodeNoSuchUpload, err.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v error code, got %v", e, a)
+	}
+
+	if e, a := []string{"ListParts"}, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+type mockCheckpointStore struct {
+	m           sync.Mutex
+	checkpoints map[string]s3manager.UploadCheckpoint
+}
+
+func newMockCheckpointStore() *mockCheckpointStore {
+	return &mockCheckpointStore{checkpoints: map[string]s3manager.UploadCheckpoint{}}
+}
+
+func (s *mockCheckpointStore) GetCheckpoint(bucket, key string) (*s3manager.UploadCheckpoint, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	cp, ok := s.checkpoints[bucket+"/"+key]
+	if !ok {
+		return nil, nil
+	}
+	return &cp, nil
+}
+
+func (s *mockCheckpointStore) PutCheckpoint(cp *s3manager.UploadCheckpoint) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.checkpoints[cp.Bucket+"/"+cp.Key] = *cp
+	return nil
+}
+
+func (s *mockCheckpointStore) DeleteCheckpoint(bucket, key string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	delete(s.checkpoints, bucket+"/"+key)
+	return nil
+}
+
+func TestUploadCheckpoint_ResumeAfterFailure(t *testing.T) {
+	store := newMockCheckpointStore()
+	input := &s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewReader(buf12MB),
+	}
+
+	s, ops, _ := loggingSvc(emptyList)
+	s.Handlers.Send.PushBack(func(r *request.Request) {
+		if data, ok := r.Data.(*s3.UploadPartOutput); ok && *data.ETag == "ETAG2" {
+			r.HTTPResponse.StatusCode = 400
+		}
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s, func(u *s3manager.Uploader) {
+		u.Concurrency = 1
+		u.LeavePartsOnError = true
+		u.CheckpointStore = store
+	})
+	if _, err := mgr.Upload(input); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := []string{"CreateMultipartUpload", "UploadPart", "UploadPart"}, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	cp, _ := store.GetCheckpoint("Bucket", "Key")
+	if cp == nil {
+		t.Fatalf("expect checkpoint to be saved")
+	}
+	expectCP := s3manager.UploadCheckpoint{
+		Bucket: "Bucket", Key: "Key", UploadID: "UPLOAD-ID",
+		PartSize: s3manager.DefaultUploadPartSize,
+	}
+	if e, a := expectCP, *cp; e != a {
+		t.Errorf("expect %v checkpoint, got %v", e, a)
+	}
+
+	// Restart the upload, which resumes from the checkpoint.
+	partSize := int(s3manager.DefaultUploadPartSize)
+	s, ops, args := listPartsSvc([]*s3.Part{
+		{PartNumber: aws.Int64(1), Size: aws.Int64(int64(partSize)), ETag: partETag(buf12MB[:partSize])},
+	})
+	mgr.S3 = s
+
+	input.Body = bytes.NewReader(buf12MB)
+	if _, err := mgr.Upload(input); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := []string{"ListParts", "UploadPart", "UploadPart", "CompleteMultipartUpload"}, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := 3, len(completedPartETags(t, *args)); e != a {
+		t.Errorf("expect %v completed parts, got %v", e, a)
+	}
+
+	if cp, _ := store.GetCheckpoint("Bucket", "Key"); cp != nil {
+		t.Errorf("expect checkpoint to be deleted, got %v", cp)
+	}
+}
+
+func TestUploadCheckpoint_UploadNoLongerExists(t *testing.T) {
+	store := newMockCheckpointStore()
+	store.PutCheckpoint(&s3manager.UploadCheckpoint{
+		Bucket: "Bucket", Key: "Key", UploadID: "OLD-UPLOAD-ID",
+		PartSize: s3manager.DefaultUploadPartSize,
+	})
+
+	s, ops, _ := loggingSvc(emptyList)
+	s.Handlers.Send.PushBack(func(r *request.Request) {
+		if r.Operation.Name == "ListParts" {
+			r.Error = awserr.New(s3.ErrCodeNoSuchUpload, "no such upload", nil)
+		}
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s, func(u *s3manager.Uploader) {
+		u.CheckpointStore = store
+	})
+	resp, err := mgr.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []string{"ListParts", "CreateMultipartUpload", "UploadPart", "UploadPart", "UploadPart", "CompleteMultipartUpload"}, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "UPLOAD-ID", resp.UploadID; e != a {
+		t.Errorf("expect %v upload ID, got %v", e, a)
+	}
+	if cp, _ := store.GetCheckpoint("Bucket", "Key"); cp != nil {
+		t.Errorf("expect checkpoint to be deleted, got %v", cp)
+	}
+}
+
+func TestUploadCheckpoint_SSENotResumed(t *testing.T) {
+	for _, input := range []*s3manager.UploadInput{
+		{ServerSideEncryption: aws.String(s3.ServerSideEncryptionAwsKms)},
+		{SSECustomerAlgorithm: aws.String("AES256"), SSECustomerKey: aws.String("key")},
+	} {
+		store := newMockCheckpointStore()
+		store.PutCheckpoint(&s3manager.UploadCheckpoint{
+			Bucket: "Bucket", Key: "Key", UploadID: "OLD-UPLOAD-ID",
+			PartSize: s3manager.DefaultUploadPartSize,
+		})
+
+		s, ops, args := loggingSvc(emptyList)
+		mgr := s3manager.NewUploaderWithClient(s, func(u *s3manager.Uploader) {
+			u.Concurrency = 1
+			u.CheckpointStore = store
+		})
+		input.Bucket = aws.String("Bucket")
+		input.Key = aws.String("Key")
+		input.Body = bytes.NewReader(buf12MB)
+		resp, err := mgr.Upload(input)
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+
+		if e, a := []string{"AbortMultipartUpload", "CreateMultipartUpload", "UploadPart", "UploadPart", "UploadPart", "CompleteMultipartUpload"}, *ops; !reflect.DeepEqual(e, a) {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+		if e, a := "OLD-UPLOAD-ID", aws.StringValue((*args)[0].(*s3.AbortMultipartUploadInput).UploadId); e != a {
+			t.Errorf("expect %v upload ID aborted, got %v", e, a)
+		}
+		if e, a := "UPLOAD-ID", resp.UploadID; e != a {
+			t.Errorf("expect %v upload ID, got %v", e, a)
+		}
+		if cp, _ := store.GetCheckpoint("Bucket", "Key"); cp != nil {
+			t.Errorf("expect checkpoint to be deleted, got %v", cp)
+		}
+	}
+}
+
+func TestUploadCheckpoint_DeletedOnAbort(t *testing.T) {
+	store := newMockCheckpointStore()
+
+	s, _, _ := loggingSvc(emptyList)
+	s.Handlers.Send.PushBack(func(r *request.Request) {
+		if data, ok := r.Data.(*s3.UploadPartOutput); ok && *data.ETag == "ETAG2" {
+			r.HTTPResponse.StatusCode = 400
+		}
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s, func(u *s3manager.Uploader) {
+		u.Concurrency = 1
+		u.CheckpointStore = store
+	})
+	if _, err := mgr.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewReader(buf12MB),
+	}); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	if cp, _ := store.GetCheckpoint("Bucket", "Key"); cp != nil {
+		t.Errorf("expect checkpoint to be deleted, got %v", cp)
+	}
+}
+
+func TestFileUploadCheckpointStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3manager-checkpoints")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := s3manager.NewFileUploadCheckpointStore(dir)
+
+	cp, err := store.GetCheckpoint("bucket", "key")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if cp != nil {
+		t.Errorf("expect no checkpoint, got %v", cp)
+	}
+
+	expect := s3manager.UploadCheckpoint{
+		Bucket: "bucket", Key: "key", UploadID: "upload-id", PartSize: 1024,
+	}
+	if err := store.PutCheckpoint(&expect); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	cp, err = store.GetCheckpoint("bucket", "key")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if cp == nil || *cp != expect {
+		t.Errorf("expect %v checkpoint, got %v", expect, cp)
+	}
+	if cp, _ := store.GetCheckpoint("bucket", "other"); cp != nil {
+		t.Errorf("expect no checkpoint for other key, got %v", cp)
+	}
+
+	if err := store.DeleteCheckpoint("bucket", "key"); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if err := store.DeleteCheckpoint("bucket", "key"); err != nil {
+		t.Fatalf("expect no error deleting missing checkpoint, got %v", err)
+	}
+	if cp, _ := store.GetCheckpoint("bucket", "key"); cp != nil {
+		t.Errorf("expect checkpoint to be deleted, got %v", cp)
+	}
+}
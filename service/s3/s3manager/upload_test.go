@@ -13,6 +13,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -993,3 +994,51 @@ func TestUploadWithContextCanceled(t *testing.T) {
 		t.Errorf("expected error message to contain %q, but did not %q", e, a)
 	}
 }
+
+func TestUploadDownload_BandwidthLimiter(t *testing.T) {
+	data := make([]byte, 1024*1024*12)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	svc, _ := checksumSvc()
+
+	// Shared by the uploader and downloader, 12MB at 48MB/s takes 250ms
+	// each way.
+	limiter := s3manager.NewBandwidthLimiter(1024 * 1024 * 48)
+
+	start := time.Now()
+	u := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+		u.BandwidthLimiter = limiter
+	})
+	_, err := u.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	d := s3manager.NewDownloaderWithClient(svc, func(d *s3manager.Downloader) {
+		d.BandwidthLimiter = limiter
+	})
+	w := &aws.WriteAtBuffer{}
+	n, err := d.Download(w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := int64(len(data)), n; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if !bytes.Equal(data, w.Bytes()) {
+		t.Errorf("expect downloaded data to match uploaded data")
+	}
+	if e, a := 400*time.Millisecond, time.Since(start); a < e {
+		t.Errorf("expect transfer to take at least %v, took %v", e, a)
+	}
+}